@@ -136,6 +136,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
 
       const data = await response.json()
       localStorage.setItem('token', data.token)
+      localStorage.setItem('refreshToken', data.refresh_token)
       dispatch({ type: 'LOGIN_SUCCESS', payload: { user: data.user, token: data.token } })
     } catch (error) {
       dispatch({ type: 'LOGIN_FAILURE' })
@@ -158,6 +159,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       console.error('Logout error:', error)
     } finally {
       localStorage.removeItem('token')
+      localStorage.removeItem('refreshToken')
       dispatch({ type: 'LOGOUT' })
     }
   }
@@ -0,0 +1,255 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventBehaviorAnomaly is the risk event type raised for trips that are out of
+// character for the driver
+const EventBehaviorAnomaly = "behavior_anomaly"
+
+// Features describing how a trip was driven
+const (
+	FeatureSpeedMean        = "speed_mean"
+	FeatureSpeedP95         = "speed_p95"
+	FeatureBrakingRate      = "harsh_braking_rate"
+	FeatureAccelerationRate = "rapid_acceleration_rate"
+)
+
+// features lists every feature in the order they are explained
+var features = []string{FeatureSpeedMean, FeatureSpeedP95, FeatureBrakingRate, FeatureAccelerationRate}
+
+// featureInfo describes how a feature is presented, and the least standard
+// deviation it is judged against so that a driver who never brakes hard is
+// not flagged for a single hard stop
+var featureInfo = map[string]struct {
+	label     string
+	unit      string
+	minStdDev float64
+}{
+	FeatureSpeedMean:        {"Average speed", "mph", 2},
+	FeatureSpeedP95:         {"95th percentile speed", "mph", 3},
+	FeatureBrakingRate:      {"Harsh braking", "per 100 mi", 5},
+	FeatureAccelerationRate: {"Rapid acceleration", "per 100 mi", 5},
+}
+
+// Config tunes the baselines and what counts as anomalous
+type Config struct {
+	// Alpha weighs each new trip in the exponentially weighted baselines; 0.1
+	// follows roughly the last 20 trips
+	Alpha float64
+	// MinTrips is how many trips a baseline needs before it is trusted
+	MinTrips int
+	// MinPeers is how many established drivers a fleet needs to serve as a
+	// baseline for drivers without one
+	MinPeers int
+	// Threshold is the z-score above which a feature is anomalous
+	Threshold float64
+	// MinTripMiles skips trips too short for meaningful rates
+	MinTripMiles float64
+	// Grace is how long after a trip ends it is checked, leaving time for its
+	// last risk events to be stored
+	Grace time.Duration
+	// MaxTripAge is how recent a trip must be to be flagged; older ones, such
+	// as the history found when detection is first enabled, only train the
+	// baselines
+	MaxTripAge time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Alpha:        0.1,
+		MinTrips:     10,
+		MinPeers:     3,
+		Threshold:    3,
+		MinTripMiles: 3,
+		Grace:        2 * time.Minute,
+		MaxTripAge:   24 * time.Hour,
+	}
+}
+
+// Stat is an exponentially weighted mean and variance of one feature
+type Stat struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+}
+
+// Baseline is what is usual for one driver, or for a fleet's drivers
+type Baseline struct {
+	Trips int             `json:"trips"`
+	Stats map[string]Stat `json:"stats"`
+}
+
+// Update folds a trip into the baseline. Until a baseline has 1/Alpha trips it
+// weighs them equally, so the first trip does not dominate.
+func (b *Baseline) Update(trip map[string]float64, alpha float64) {
+	if b.Stats == nil {
+		b.Stats = make(map[string]Stat)
+	}
+	b.Trips++
+	weight := math.Max(alpha, 1/float64(b.Trips))
+	for feature, value := range trip {
+		stat, ok := b.Stats[feature]
+		if !ok {
+			b.Stats[feature] = Stat{Mean: value}
+			continue
+		}
+		diff := value - stat.Mean
+		increment := weight * diff
+		stat.Mean += increment
+		stat.Variance = (1 - weight) * (stat.Variance + diff*increment)
+		b.Stats[feature] = stat
+	}
+}
+
+// Peers pools the established baselines of a fleet's other drivers: the mean
+// of their means, and a variance that covers both how each driver varies and
+// how drivers differ. It returns nil if there are fewer than MinPeers of them.
+func Peers(baselines []Baseline, config Config) *Baseline {
+	pooled := &Baseline{Stats: make(map[string]Stat)}
+	for _, feature := range features {
+		var means, variances []float64
+		for _, b := range baselines {
+			if stat, ok := b.Stats[feature]; ok && b.Trips >= config.MinTrips {
+				means = append(means, stat.Mean)
+				variances = append(variances, stat.Variance)
+			}
+		}
+		if len(means) < config.MinPeers {
+			continue
+		}
+		mean := average(means)
+		between := 0.0
+		for _, m := range means {
+			between += (m - mean) * (m - mean)
+		}
+		pooled.Stats[feature] = Stat{Mean: mean, Variance: average(variances) + between/float64(len(means))}
+		pooled.Trips = max(pooled.Trips, len(means))
+	}
+	if len(pooled.Stats) == 0 {
+		return nil
+	}
+	return pooled
+}
+
+// Deviation explains how one feature of a trip compares with the baselines
+type Deviation struct {
+	Feature      string   `json:"feature"`
+	Value        float64  `json:"value"`
+	DriverMean   *float64 `json:"driver_mean,omitempty"`
+	DriverStdDev *float64 `json:"driver_std_dev,omitempty"`
+	DriverZ      *float64 `json:"driver_z,omitempty"`
+	FleetMean    *float64 `json:"fleet_mean,omitempty"`
+	FleetStdDev  *float64 `json:"fleet_std_dev,omitempty"`
+	FleetZ       *float64 `json:"fleet_z,omitempty"`
+	// Reference is the baseline the feature was judged against, driver or fleet
+	Reference string `json:"reference"`
+}
+
+// Z is the z-score the feature was judged on
+func (d Deviation) Z() float64 {
+	if d.Reference == "driver" {
+		return *d.DriverZ
+	}
+	return *d.FleetZ
+}
+
+// Describe explains the deviation in a sentence fragment
+func (d Deviation) Describe() string {
+	info := featureInfo[d.Feature]
+	if d.Reference == "driver" {
+		return fmt.Sprintf("%s %.1f %s, %.1fσ above the driver's usual %.1f",
+			strings.ToLower(info.label), d.Value, info.unit, *d.DriverZ, *d.DriverMean)
+	}
+	return fmt.Sprintf("%s %.1f %s, %.1fσ above the fleet's usual %.1f",
+		strings.ToLower(info.label), d.Value, info.unit, *d.FleetZ, *d.FleetMean)
+}
+
+// Evaluate compares a trip with the driver's baseline and the fleet's, and
+// returns the features that are anomalously high, highest z-score first. A
+// driver with an established baseline is judged against their own history,
+// but only flagged when the trip is also above the fleet's norm, so cautious
+// drivers having an ordinary day pass. Other drivers are judged against the
+// fleet. Only riskier than usual counts; slower or smoother trips never do.
+func Evaluate(trip map[string]float64, driver *Baseline, fleet *Baseline, config Config) []Deviation {
+	established := driver != nil && driver.Trips >= config.MinTrips
+
+	var deviations []Deviation
+	for _, feature := range features {
+		value, ok := trip[feature]
+		if !ok {
+			continue
+		}
+		d := Deviation{Feature: feature, Value: round(value)}
+		minStdDev := featureInfo[feature].minStdDev
+
+		if stat, ok := lookup(driver, feature); ok && established {
+			d.DriverMean, d.DriverStdDev, d.DriverZ = describe(value, stat, minStdDev)
+		}
+		if stat, ok := lookup(fleet, feature); ok {
+			d.FleetMean, d.FleetStdDev, d.FleetZ = describe(value, stat, minStdDev)
+		}
+
+		switch {
+		case d.DriverZ != nil:
+			if *d.DriverZ < config.Threshold || (d.FleetZ != nil && *d.FleetZ <= 0) {
+				continue
+			}
+			d.Reference = "driver"
+		case d.FleetZ != nil:
+			if *d.FleetZ < config.Threshold {
+				continue
+			}
+			d.Reference = "fleet"
+		default:
+			continue
+		}
+		deviations = append(deviations, d)
+	}
+
+	sort.SliceStable(deviations, func(i, j int) bool { return deviations[i].Z() > deviations[j].Z() })
+	return deviations
+}
+
+func lookup(b *Baseline, feature string) (Stat, bool) {
+	if b == nil {
+		return Stat{}, false
+	}
+	stat, ok := b.Stats[feature]
+	return stat, ok
+}
+
+func describe(value float64, stat Stat, minStdDev float64) (mean, stdDev, z *float64) {
+	sd := math.Max(math.Sqrt(stat.Variance), minStdDev)
+	m, s, score := round(stat.Mean), round(sd), round((value-stat.Mean)/sd)
+	return &m, &s, &score
+}
+
+// Percentile returns the p-th percentile (0-100) of values by linear
+// interpolation; values are sorted in place
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,111 @@
+package anomaly
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func baseline(trips int, stats map[string]Stat) Baseline {
+	return Baseline{Trips: trips, Stats: stats}
+}
+
+func trip(speedMean, speedP95, braking, acceleration float64) map[string]float64 {
+	return map[string]float64{
+		FeatureSpeedMean:        speedMean,
+		FeatureSpeedP95:         speedP95,
+		FeatureBrakingRate:      braking,
+		FeatureAccelerationRate: acceleration,
+	}
+}
+
+func TestBaselineUpdate(t *testing.T) {
+	var b Baseline
+	for _, speed := range []float64{40, 50, 60} {
+		b.Update(map[string]float64{FeatureSpeedMean: speed}, 0.1)
+	}
+	// Equal weights while warming up: the plain mean and population variance
+	assert.Equal(t, 3, b.Trips)
+	assert.InDelta(t, 50, b.Stats[FeatureSpeedMean].Mean, 1e-9)
+	assert.InDelta(t, 200.0/3, b.Stats[FeatureSpeedMean].Variance, 1e-9)
+
+	for i := 0; i < 100; i++ {
+		b.Update(map[string]float64{FeatureSpeedMean: 30}, 0.1)
+	}
+	assert.InDelta(t, 30, b.Stats[FeatureSpeedMean].Mean, 0.01, "follows recent trips")
+	assert.Less(t, b.Stats[FeatureSpeedMean].Variance, 0.1)
+}
+
+func TestEvaluateAgainstDriverHistory(t *testing.T) {
+	config := DefaultConfig()
+	driver := baseline(30, map[string]Stat{
+		FeatureSpeedMean:        {Mean: 40, Variance: 16},
+		FeatureSpeedP95:         {Mean: 55, Variance: 16},
+		FeatureBrakingRate:      {Mean: 2, Variance: 4},
+		FeatureAccelerationRate: {Mean: 3, Variance: 4},
+	})
+	fleet := baseline(5, map[string]Stat{
+		FeatureSpeedMean:        {Mean: 42, Variance: 25},
+		FeatureSpeedP95:         {Mean: 58, Variance: 25},
+		FeatureBrakingRate:      {Mean: 4, Variance: 25},
+		FeatureAccelerationRate: {Mean: 4, Variance: 25},
+	})
+
+	assert.Empty(t, Evaluate(trip(42, 57, 5, 3), &driver, &fleet, config), "an ordinary trip")
+	assert.Empty(t, Evaluate(trip(20, 30, 0, 0), &driver, &fleet, config), "unusually gentle is not risky")
+
+	deviations := Evaluate(trip(41, 56, 30, 20), &driver, &fleet, config)
+	require.Len(t, deviations, 2)
+	assert.Equal(t, FeatureBrakingRate, deviations[0].Feature)
+	assert.Equal(t, "driver", deviations[0].Reference)
+	assert.InDelta(t, 5.6, deviations[0].Z(), 1e-9, "28 above the mean, judged on the 5 per 100 mi floor")
+	assert.InDelta(t, 5.2, *deviations[0].FleetZ, 1e-9)
+	assert.Equal(t, FeatureAccelerationRate, deviations[1].Feature)
+	assert.Equal(t, "harsh braking 30.0 per 100 mi, 5.6σ above the driver's usual 2.0", deviations[0].Describe())
+}
+
+func TestEvaluateSparesCautiousDrivers(t *testing.T) {
+	config := DefaultConfig()
+	// Usually 10 mph below the fleet, with very steady speeds
+	driver := baseline(30, map[string]Stat{FeatureSpeedMean: {Mean: 32, Variance: 1}})
+	fleet := baseline(5, map[string]Stat{FeatureSpeedMean: {Mean: 42, Variance: 25}})
+
+	trip := map[string]float64{FeatureSpeedMean: 40}
+	assert.Empty(t, Evaluate(trip, &driver, &fleet, config), "fast for them, still slower than the fleet")
+
+	trip[FeatureSpeedMean] = 50
+	deviations := Evaluate(trip, &driver, &fleet, config)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, "driver", deviations[0].Reference)
+}
+
+func TestEvaluateNewDriversAgainstFleet(t *testing.T) {
+	config := DefaultConfig()
+	driver := baseline(3, map[string]Stat{FeatureSpeedMean: {Mean: 70, Variance: 1}})
+	peers := []Baseline{
+		baseline(20, map[string]Stat{FeatureSpeedMean: {Mean: 38, Variance: 9}}),
+		baseline(20, map[string]Stat{FeatureSpeedMean: {Mean: 42, Variance: 9}}),
+		baseline(20, map[string]Stat{FeatureSpeedMean: {Mean: 40, Variance: 9}}),
+		baseline(2, map[string]Stat{FeatureSpeedMean: {Mean: 90, Variance: 9}}),
+	}
+	fleet := Peers(peers, config)
+	require.NotNil(t, fleet)
+	assert.InDelta(t, 40, fleet.Stats[FeatureSpeedMean].Mean, 1e-9, "new peers are left out")
+	assert.InDelta(t, 9+8.0/3, fleet.Stats[FeatureSpeedMean].Variance, 1e-9)
+
+	deviations := Evaluate(map[string]float64{FeatureSpeedMean: 70}, &driver, fleet, config)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, "fleet", deviations[0].Reference)
+	assert.Nil(t, deviations[0].DriverZ, "too few trips to judge the driver on")
+
+	assert.Nil(t, Peers(peers[:2], config), "too few peers")
+	assert.Empty(t, Evaluate(map[string]float64{FeatureSpeedMean: 70}, &driver, nil, config))
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, 0.0, Percentile(nil, 95))
+	assert.Equal(t, 7.0, Percentile([]float64{7}, 95))
+	assert.InDelta(t, 38.5, Percentile([]float64{40, 10, 30, 20}, 95), 1e-9)
+	assert.InDelta(t, 25, Percentile([]float64{40, 10, 30, 20}, 50), 1e-9)
+}
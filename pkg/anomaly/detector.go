@@ -0,0 +1,279 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+)
+
+// ErrConflict is returned when another risk-engine replica updated one of the
+// baselines first; roll back and try again on the next pass
+var ErrConflict = errors.New("driver baseline was updated concurrently")
+
+const (
+	// batchSize bounds the trips checked per call
+	batchSize = 500
+	// movingSpeed is the speed (mph) above which samples count towards the
+	// speed features, matching the trip segmenter
+	movingSpeed = 3.0
+)
+
+// Detector learns per-driver baselines from completed trips and flags trips
+// that are out of character for the driver
+type Detector struct {
+	config Config
+}
+
+func NewDetector(config Config) *Detector {
+	return &Detector{config: config}
+}
+
+// driverState is a driver's baseline while a batch is being checked
+type driverState struct {
+	row      models.DriverBaseline
+	baseline Baseline
+	// loadedTripID is the row's LastTripID when it was loaded
+	loadedTripID uint
+	dirty        bool
+}
+
+// Check folds the trips that ended at least Grace before now into their
+// drivers' baselines, oldest first, and returns a BEHAVIOR_ANOMALY risk event
+// for each recent trip that deviated from them. Trips older than MaxTripAge
+// only train the baselines. Run it inside a transaction and store the
+// returned events in it.
+func (d *Detector) Check(tx *gorm.DB, now time.Time) ([]models.RiskEvent, error) {
+	var trips []models.Trip
+	if err := tx.Model(&models.Trip{}).Select("trips.*").
+		Joins("LEFT JOIN driver_baselines ON driver_baselines.driver_id = trips.driver_id").
+		Where("trips.driver_id IS NOT NULL AND trips.end_time <= ?", now.Add(-d.config.Grace)).
+		Where("driver_baselines.id IS NULL OR trips.id > driver_baselines.last_trip_id").
+		Order("trips.id").
+		Limit(batchSize).
+		Find(&trips).Error; err != nil {
+		return nil, err
+	}
+	if len(trips) == 0 {
+		return nil, nil
+	}
+
+	fleets, err := d.loadBaselines(tx, trips)
+	if err != nil {
+		return nil, err
+	}
+	byDriver := make(map[uint]*driverState)
+	for _, drivers := range fleets {
+		for driverID, state := range drivers {
+			byDriver[driverID] = state
+		}
+	}
+
+	var risks []models.RiskEvent
+	for _, trip := range trips {
+		state := byDriver[*trip.DriverID]
+		if state == nil {
+			// The driver was deleted
+			continue
+		}
+		state.row.LastTripID = trip.ID
+		state.dirty = true
+
+		features, ok, err := d.features(tx, trip)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		var peers []Baseline
+		for driverID, other := range fleets[state.row.FleetID] {
+			if driverID != state.row.DriverID {
+				peers = append(peers, other.baseline)
+			}
+		}
+		deviations := Evaluate(features, &state.baseline, Peers(peers, d.config), d.config)
+		if len(deviations) > 0 && trip.EndTime.After(now.Add(-d.config.MaxTripAge)) {
+			risks = append(risks, anomalyEvent(trip, deviations))
+		}
+		state.baseline.Update(features, d.config.Alpha)
+	}
+
+	for _, state := range byDriver {
+		if state.dirty {
+			if err := save(tx, state); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return risks, nil
+}
+
+// loadBaselines loads the baselines of every driver in the fleets of the
+// trips' drivers, by fleet and driver. Drivers without one get a new one.
+func (d *Detector) loadBaselines(tx *gorm.DB, trips []models.Trip) (map[uint]map[uint]*driverState, error) {
+	var driverIDs []uint
+	for _, trip := range trips {
+		driverIDs = append(driverIDs, *trip.DriverID)
+	}
+	var drivers []models.Driver
+	if err := tx.Select("id", "fleet_id").Where("id IN ?", driverIDs).Find(&drivers).Error; err != nil {
+		return nil, err
+	}
+	var fleetIDs []uint
+	for _, driver := range drivers {
+		fleetIDs = append(fleetIDs, driver.FleetID)
+	}
+
+	var rows []models.DriverBaseline
+	if err := tx.Where("fleet_id IN ?", fleetIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	fleets := make(map[uint]map[uint]*driverState)
+	add := func(row models.DriverBaseline) error {
+		state := &driverState{row: row, loadedTripID: row.LastTripID}
+		if row.Stats != "" {
+			if err := json.Unmarshal([]byte(row.Stats), &state.baseline.Stats); err != nil {
+				return fmt.Errorf("driver %d baseline: %w", row.DriverID, err)
+			}
+		}
+		state.baseline.Trips = row.Trips
+		if fleets[row.FleetID] == nil {
+			fleets[row.FleetID] = make(map[uint]*driverState)
+		}
+		fleets[row.FleetID][row.DriverID] = state
+		return nil
+	}
+	for _, row := range rows {
+		if err := add(row); err != nil {
+			return nil, err
+		}
+	}
+	for _, driver := range drivers {
+		if _, ok := fleets[driver.FleetID][driver.ID]; !ok {
+			_ = add(models.DriverBaseline{DriverID: driver.ID, FleetID: driver.FleetID})
+		}
+	}
+	return fleets, nil
+}
+
+// features measures a trip from its telemetry and risk events. Trips that are
+// too short or have no speed readings are not measured.
+func (d *Detector) features(tx *gorm.DB, trip models.Trip) (map[string]float64, bool, error) {
+	if trip.DistanceMiles < d.config.MinTripMiles {
+		return nil, false, nil
+	}
+
+	var speeds []float64
+	if err := tx.Model(&models.TelemetryEvent{}).
+		Where("vehicle_id = ? AND timestamp >= ? AND timestamp <= ? AND speed > ?",
+			trip.VehicleID, trip.StartTime, trip.EndTime, movingSpeed).
+		Pluck("speed", &speeds).Error; err != nil {
+		return nil, false, err
+	}
+	if len(speeds) == 0 {
+		return nil, false, nil
+	}
+
+	var counts []struct {
+		EventType string
+		Count     int
+	}
+	if err := tx.Model(&models.RiskEvent{}).
+		Select("event_type, COUNT(*) AS count").
+		Where("vehicle_id = ? AND timestamp >= ? AND timestamp <= ? AND event_type IN ?",
+			trip.VehicleID, trip.StartTime, trip.EndTime, []string{risk.RuleHarshBraking, risk.RuleRapidAcceleration}).
+		Group("event_type").
+		Scan(&counts).Error; err != nil {
+		return nil, false, err
+	}
+	perHundredMiles := make(map[string]float64)
+	for _, c := range counts {
+		perHundredMiles[c.EventType] = float64(c.Count) / trip.DistanceMiles * 100
+	}
+
+	return map[string]float64{
+		FeatureSpeedMean:        average(speeds),
+		FeatureSpeedP95:         Percentile(speeds, 95),
+		FeatureBrakingRate:      perHundredMiles[risk.RuleHarshBraking],
+		FeatureAccelerationRate: perHundredMiles[risk.RuleRapidAcceleration],
+	}, true, nil
+}
+
+// save stores a baseline, failing with ErrConflict if it changed since it was
+// loaded
+func save(tx *gorm.DB, state *driverState) error {
+	stats, err := json.Marshal(state.baseline.Stats)
+	if err != nil {
+		return err
+	}
+	if state.baseline.Stats == nil {
+		stats = []byte("{}")
+	}
+
+	state.row.Trips = state.baseline.Trips
+	state.row.Stats = string(stats)
+	if state.row.ID == 0 {
+		if err := tx.Omit("Driver").Create(&state.row).Error; err != nil {
+			// Most likely another replica created it first
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return nil
+	}
+
+	result := tx.Model(&models.DriverBaseline{}).
+		Where("id = ? AND last_trip_id = ?", state.row.ID, state.loadedTripID).
+		Updates(map[string]interface{}{
+			"trips":        state.row.Trips,
+			"last_trip_id": state.row.LastTripID,
+			"stats":        state.row.Stats,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// anomalyEvent explains a trip's deviations as a risk event. Severity grows
+// with the largest z-score and with how many features deviated.
+func anomalyEvent(trip models.Trip, deviations []Deviation) models.RiskEvent {
+	score := math.Min(95, 30+deviations[0].Z()*5+float64(len(deviations)-1)*10)
+	severity := "medium"
+	if score >= 70 {
+		severity = "high"
+	}
+
+	explanations := make([]string, len(deviations))
+	for i, deviation := range deviations {
+		explanations[i] = deviation.Describe()
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"trip_id":    trip.ID,
+		"deviations": deviations,
+	})
+
+	return models.RiskEvent{
+		VehicleID:   trip.VehicleID,
+		DriverID:    trip.DriverID,
+		EventType:   EventBehaviorAnomaly,
+		Severity:    severity,
+		RiskScore:   math.Round(score),
+		Timestamp:   trip.EndTime,
+		Latitude:    trip.EndLatitude,
+		Longitude:   trip.EndLongitude,
+		Description: "Trip out of character: " + strings.Join(explanations, "; "),
+		Data:        string(data),
+	}
+}
@@ -0,0 +1,128 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func setupDB(t *testing.T) (*gorm.DB, models.Driver) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	driver := models.Driver{FleetID: fleet.ID, EmployeeID: "E1", FirstName: "Sam", LastName: "Lee", Email: "sam@example.com"}
+	require.NoError(t, db.Omit("Fleet").Create(&driver).Error)
+	return db, driver
+}
+
+// drive stores a 20 minute trip of vehicle 1 by the driver that ended at end,
+// with samples around the speed and the number of harsh braking events
+func drive(t *testing.T, db *gorm.DB, driver models.Driver, end time.Time, miles, speed float64, brakes int) models.Trip {
+	start := end.Add(-20 * time.Minute)
+	for i := 0; i < 10; i++ {
+		s := speed + float64(i%3-1)
+		require.NoError(t, db.Omit("Vehicle").Create(&models.TelemetryEvent{
+			VehicleID: 1, EventType: "location", Timestamp: start.Add(time.Duration(i) * 2 * time.Minute), Speed: &s,
+		}).Error)
+	}
+	for i := 0; i < brakes; i++ {
+		require.NoError(t, db.Omit("Vehicle", "Driver").Create(&models.RiskEvent{
+			VehicleID: 1, DriverID: &driver.ID, EventType: risk.RuleHarshBraking, Severity: "medium",
+			Timestamp: start.Add(time.Duration(i+1) * time.Minute),
+		}).Error)
+	}
+	trip := models.Trip{VehicleID: 1, DriverID: &driver.ID, StartTime: start, EndTime: end, DistanceMiles: miles}
+	require.NoError(t, db.Omit("Vehicle", "Driver").Create(&trip).Error)
+	return trip
+}
+
+func check(t *testing.T, db *gorm.DB) []models.RiskEvent {
+	var risks []models.RiskEvent
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		risks, err = NewDetector(DefaultConfig()).Check(tx, now)
+		return err
+	})
+	require.NoError(t, err)
+	return risks
+}
+
+func TestCheckLearnsBaselineAndFlagsAnomalousTrips(t *testing.T) {
+	db, driver := setupDB(t)
+
+	// Two weeks of ordinary driving, found when detection is switched on
+	for day := 14; day > 2; day-- {
+		drive(t, db, driver, now.Add(-time.Duration(day)*24*time.Hour), 10, 40, day%2)
+	}
+	assert.Empty(t, check(t, db), "history only trains the baseline")
+
+	var stored models.DriverBaseline
+	require.NoError(t, db.Where("driver_id = ?", driver.ID).First(&stored).Error)
+	assert.Equal(t, 12, stored.Trips)
+	assert.Equal(t, driver.FleetID, stored.FleetID)
+
+	drive(t, db, driver, now.Add(-3*time.Hour), 10, 41, 0)
+	drive(t, db, driver, now.Add(-2*time.Hour), 1, 80, 3)
+	assert.Empty(t, check(t, db), "an ordinary trip, and one too short to judge")
+
+	trip := drive(t, db, driver, now.Add(-time.Hour), 10, 40, 6)
+	drive(t, db, driver, now.Add(-time.Minute), 10, 40, 6)
+	risks := check(t, db)
+	require.Len(t, risks, 1, "the last trip is still within the grace period")
+
+	anomaly := risks[0]
+	assert.Equal(t, EventBehaviorAnomaly, anomaly.EventType)
+	assert.Equal(t, driver.ID, *anomaly.DriverID)
+	assert.Equal(t, trip.EndTime, anomaly.Timestamp)
+	assert.Contains(t, anomaly.Description, "harsh braking 60.0 per 100 mi")
+
+	var data struct {
+		TripID     uint        `json:"trip_id"`
+		Deviations []Deviation `json:"deviations"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(anomaly.Data), &data))
+	assert.Equal(t, trip.ID, data.TripID)
+	require.Len(t, data.Deviations, 1)
+	assert.Equal(t, FeatureBrakingRate, data.Deviations[0].Feature)
+	assert.Equal(t, "driver", data.Deviations[0].Reference)
+
+	require.NoError(t, db.Where("driver_id = ?", driver.ID).First(&stored).Error)
+	assert.Equal(t, 14, stored.Trips, "the short trip was skipped")
+	assert.Equal(t, trip.ID, stored.LastTripID)
+	assert.Empty(t, check(t, db))
+}
+
+func TestSaveDetectsConcurrentUpdates(t *testing.T) {
+	db, driver := setupDB(t)
+	row := models.DriverBaseline{DriverID: driver.ID, FleetID: driver.FleetID, Stats: "{}", LastTripID: 5}
+	require.NoError(t, db.Omit("Driver").Create(&row).Error)
+
+	state := &driverState{row: row, loadedTripID: row.LastTripID}
+	state.row.LastTripID = 7
+	state.baseline.Update(map[string]float64{FeatureSpeedMean: 40}, 0.1)
+
+	require.NoError(t, db.Model(&row).Update("last_trip_id", 6).Error)
+	assert.ErrorIs(t, save(db, state), ErrConflict)
+
+	state.loadedTripID = 6
+	require.NoError(t, save(db, state))
+	require.NoError(t, db.First(&row, row.ID).Error)
+	assert.Equal(t, uint(7), row.LastTripID)
+	assert.Equal(t, 1, row.Trips)
+
+	duplicate := &driverState{row: models.DriverBaseline{DriverID: driver.ID, FleetID: driver.FleetID}}
+	assert.ErrorIs(t, save(db, duplicate), ErrConflict)
+}
@@ -0,0 +1,139 @@
+package assignment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Assign puts driverID behind the wheel of vehicleID from at onwards. The
+// driver's assignment to any other vehicle and the vehicle's previous driver
+// assignment are closed, and the vehicles' current driver_id is kept in sync.
+// Run it inside a transaction.
+func Assign(tx *gorm.DB, vehicleID, driverID uint, at time.Time) error {
+	var current []models.VehicleAssignment
+	if err := tx.Where("end_time IS NULL AND (vehicle_id = ? OR driver_id = ?)", vehicleID, driverID).
+		Find(&current).Error; err != nil {
+		return err
+	}
+
+	for _, open := range current {
+		if open.VehicleID == vehicleID && open.DriverID == driverID {
+			// Already assigned
+			return nil
+		}
+	}
+
+	for _, open := range current {
+		if err := tx.Model(&models.VehicleAssignment{}).Where("id = ?", open.ID).Update("end_time", at).Error; err != nil {
+			return err
+		}
+	}
+
+	// A driver can only be behind the wheel of one vehicle at a time
+	if err := tx.Model(&models.Vehicle{}).
+		Where("driver_id = ? AND id <> ?", driverID, vehicleID).
+		Update("driver_id", nil).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Omit("Vehicle", "Driver").Create(&models.VehicleAssignment{
+		VehicleID: vehicleID,
+		DriverID:  driverID,
+		StartTime: at,
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Vehicle{}).Where("id = ?", vehicleID).Update("driver_id", driverID).Error
+}
+
+// Resolver looks up the driver assigned to vehicles for streams of events,
+// caching the stretch of each vehicle's history around the last lookup. A
+// cached stretch is trusted for ttl, after which reassignments made since are
+// picked up.
+type Resolver struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu    sync.Mutex
+	spans map[uint]span
+}
+
+// span is a stretch of a vehicle's history with a single driver, or none.
+// A zero from or to leaves that side unbounded.
+type span struct {
+	from, to time.Time
+	driverID *uint
+	loaded   time.Time
+}
+
+func (s span) contains(at time.Time) bool {
+	return !at.Before(s.from) && (s.to.IsZero() || at.Before(s.to))
+}
+
+func NewResolver(db *gorm.DB, ttl time.Duration) *Resolver {
+	return &Resolver{db: db, ttl: ttl, spans: make(map[uint]span)}
+}
+
+// DriverAt returns the driver assigned to the vehicle at the given time, or
+// nil if nobody was
+func (r *Resolver) DriverAt(ctx context.Context, vehicleID uint, at time.Time) (*uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cached, ok := r.spans[vehicleID]; ok && time.Since(cached.loaded) < r.ttl && cached.contains(at) {
+		return cached.driverID, nil
+	}
+
+	found, err := lookupSpan(r.db.WithContext(ctx), vehicleID, at)
+	if err != nil {
+		return nil, err
+	}
+	r.spans[vehicleID] = found
+	return found.driverID, nil
+}
+
+func lookupSpan(db *gorm.DB, vehicleID uint, at time.Time) (span, error) {
+	found := span{loaded: time.Now()}
+
+	var assignments []models.VehicleAssignment
+	err := db.Where("vehicle_id = ? AND start_time <= ? AND (end_time IS NULL OR end_time > ?)", vehicleID, at, at).
+		Order("start_time DESC").
+		Limit(1).
+		Find(&assignments).Error
+	if err != nil {
+		return found, err
+	}
+	if len(assignments) > 0 {
+		current := assignments[0]
+		found.from, found.driverID = current.StartTime, &current.DriverID
+		if current.EndTime != nil {
+			found.to = *current.EndTime
+		}
+		return found, nil
+	}
+
+	// Nobody was assigned: the gap runs from the previous assignment's end to
+	// the next one's start
+	var previous, next []models.VehicleAssignment
+	if err := db.Where("vehicle_id = ? AND end_time <= ?", vehicleID, at).
+		Order("end_time DESC").Limit(1).Find(&previous).Error; err != nil {
+		return found, err
+	}
+	if err := db.Where("vehicle_id = ? AND start_time > ?", vehicleID, at).
+		Order("start_time ASC").Limit(1).Find(&next).Error; err != nil {
+		return found, err
+	}
+	if len(previous) > 0 {
+		found.from = *previous[0].EndTime
+	}
+	if len(next) > 0 {
+		found.to = next[0].StartTime
+	}
+	return found, nil
+}
@@ -0,0 +1,137 @@
+package assignment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func setupDB(t *testing.T) (*gorm.DB, []models.Vehicle, []models.Driver) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+
+	vehicles := []models.Vehicle{
+		{FleetID: fleet.ID, VIN: "VIN1", Make: "Ford", Model: "Transit", Year: 2022},
+		{FleetID: fleet.ID, VIN: "VIN2", Make: "Ford", Model: "Transit", Year: 2022},
+	}
+	drivers := []models.Driver{
+		{FleetID: fleet.ID, EmployeeID: "E1", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"},
+		{FleetID: fleet.ID, EmployeeID: "E2", FirstName: "Alan", LastName: "Turing", Email: "alan@example.com"},
+	}
+	require.NoError(t, db.Omit("Fleet").Create(&vehicles).Error)
+	require.NoError(t, db.Omit("Fleet").Create(&drivers).Error)
+	return db, vehicles, drivers
+}
+
+func driverAt(t *testing.T, db *gorm.DB, vehicleID uint, at time.Time) *uint {
+	driverID, err := NewResolver(db, 0).DriverAt(t.Context(), vehicleID, at)
+	require.NoError(t, err)
+	return driverID
+}
+
+func TestDriverAtFollowsAssignmentHistory(t *testing.T) {
+	db, vehicles, drivers := setupDB(t)
+	van, ada, alan := vehicles[0].ID, drivers[0].ID, drivers[1].ID
+	morning := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	noon := morning.Add(4 * time.Hour)
+
+	require.NoError(t, Assign(db, van, ada, morning))
+	require.NoError(t, Assign(db, van, alan, noon))
+
+	assert.Nil(t, driverAt(t, db, van, morning.Add(-time.Minute)), "nobody before the first assignment")
+	assert.Equal(t, ada, *driverAt(t, db, van, morning))
+	assert.Equal(t, ada, *driverAt(t, db, van, noon.Add(-time.Second)))
+	assert.Equal(t, alan, *driverAt(t, db, van, noon))
+	assert.Equal(t, alan, *driverAt(t, db, van, noon.AddDate(0, 1, 0)))
+
+	var vehicle models.Vehicle
+	require.NoError(t, db.First(&vehicle, van).Error)
+	assert.Equal(t, alan, *vehicle.DriverID)
+}
+
+func TestAssignMovesDriverBetweenVehicles(t *testing.T) {
+	db, vehicles, drivers := setupDB(t)
+	van, truck, ada := vehicles[0].ID, vehicles[1].ID, drivers[0].ID
+	morning := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	noon := morning.Add(4 * time.Hour)
+
+	require.NoError(t, Assign(db, van, ada, morning))
+	// Reassigning the same driver is a no-op
+	require.NoError(t, Assign(db, van, ada, morning.Add(time.Hour)))
+	require.NoError(t, Assign(db, truck, ada, noon))
+
+	assert.Equal(t, ada, *driverAt(t, db, van, noon.Add(-time.Second)))
+	assert.Nil(t, driverAt(t, db, van, noon), "the van has no driver once Ada moved to the truck")
+	assert.Equal(t, ada, *driverAt(t, db, truck, noon))
+
+	var count int64
+	require.NoError(t, db.Model(&models.VehicleAssignment{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	var vehicle models.Vehicle
+	require.NoError(t, db.First(&vehicle, van).Error)
+	assert.Nil(t, vehicle.DriverID)
+}
+
+func TestResolverCachesHistory(t *testing.T) {
+	db, vehicles, drivers := setupDB(t)
+	van, ada, alan := vehicles[0].ID, drivers[0].ID, drivers[1].ID
+	morning := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	noon := morning.Add(4 * time.Hour)
+	evening := noon.Add(6 * time.Hour)
+
+	require.NoError(t, Assign(db, van, ada, morning))
+	require.NoError(t, Assign(db, vehicles[1].ID, ada, noon))
+	require.NoError(t, Assign(db, van, alan, evening))
+
+	resolver := NewResolver(db, time.Hour)
+	resolve := func(at time.Time) *uint {
+		driverID, err := resolver.DriverAt(t.Context(), van, at)
+		require.NoError(t, err)
+		return driverID
+	}
+
+	assert.Nil(t, resolve(morning.Add(-time.Minute)))
+	assert.Equal(t, ada, *resolve(morning))
+	assert.Equal(t, ada, *resolve(noon.Add(-time.Second)))
+	assert.Nil(t, resolve(noon), "unassigned between Ada leaving and Alan arriving")
+	assert.Nil(t, resolve(evening.Add(-time.Second)))
+	assert.Equal(t, alan, *resolve(evening))
+	assert.Equal(t, ada, *resolve(morning.Add(time.Hour)), "going back in time reloads")
+}
+
+func TestMigrateSeedsAssignmentsOfCurrentDrivers(t *testing.T) {
+	db, vehicles, drivers := setupDB(t)
+	van, truck, ada, alan := vehicles[0].ID, vehicles[1].ID, drivers[0].ID, drivers[1].ID
+	morning := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	noon := morning.Add(4 * time.Hour)
+
+	// Drivers set before assignment history was kept; the truck also had an
+	// earlier, closed assignment
+	require.NoError(t, db.Model(&models.Vehicle{}).Where("id = ?", truck).Update("created_at", morning.AddDate(0, 0, -1)).Error)
+	require.NoError(t, Assign(db, truck, alan, morning))
+	require.NoError(t, db.Model(&models.VehicleAssignment{}).Where("vehicle_id = ?", truck).Update("end_time", noon).Error)
+	require.NoError(t, db.Model(&models.Vehicle{}).Where("id = ?", van).Update("driver_id", ada).Error)
+	require.NoError(t, db.Model(&models.Vehicle{}).Where("id = ?", truck).Update("driver_id", alan).Error)
+
+	require.NoError(t, models.Migrate(db))
+	assert.Equal(t, ada, *driverAt(t, db, van, time.Now()))
+	assert.Equal(t, alan, *driverAt(t, db, truck, noon))
+	assert.Equal(t, alan, *driverAt(t, db, truck, morning), "the earlier assignment is kept")
+
+	// Seeding again leaves the open assignments alone
+	require.NoError(t, models.Migrate(db))
+	var count int64
+	require.NoError(t, db.Model(&models.VehicleAssignment{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
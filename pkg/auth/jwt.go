@@ -13,10 +13,11 @@ var (
 )
 
 type JWTClaims struct {
-	UserID   string   `json:"user_id"`
-	Email    string   `json:"email"`
-	Role     string   `json:"role"`
-	FleetIDs []string `json:"fleet_ids,omitempty"`
+	UserID    string   `json:"user_id"`
+	Email     string   `json:"email"`
+	Role      string   `json:"role"`
+	FleetIDs  []string `json:"fleet_ids,omitempty"`
+	SessionID string   `json:"sid,omitempty"` // session family the token was issued for
 	jwt.RegisteredClaims
 }
 
@@ -32,12 +33,18 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	}
 }
 
-func (manager *JWTManager) Generate(userID, email, role string, fleetIDs []string) (string, error) {
+// TokenDuration returns how long generated access tokens stay valid
+func (manager *JWTManager) TokenDuration() time.Duration {
+	return manager.tokenDuration
+}
+
+func (manager *JWTManager) Generate(userID, email, role string, fleetIDs []string, sessionID string) (string, error) {
 	claims := JWTClaims{
-		UserID:   userID,
-		Email:    email,
-		Role:     role,
-		FleetIDs: fleetIDs,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		FleetIDs:  fleetIDs,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(manager.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
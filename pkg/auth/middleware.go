@@ -2,10 +2,14 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type contextKey string
@@ -14,14 +18,43 @@ const (
 	UserContextKey contextKey = "user"
 )
 
+var (
+	ErrSessionRevoked     = errors.New("session has been revoked")
+	ErrSessionUnavailable = errors.New("unable to verify session")
+)
+
 type AuthMiddleware struct {
 	jwtManager *JWTManager
+	sessions   *SessionStore
 }
 
-func NewAuthMiddleware(jwtManager *JWTManager) *AuthMiddleware {
+// NewAuthMiddleware creates the middleware; when sessions is nil tokens are
+// only checked for a valid signature and expiry
+func NewAuthMiddleware(jwtManager *JWTManager, sessions *SessionStore) *AuthMiddleware {
 	return &AuthMiddleware{
 		jwtManager: jwtManager,
+		sessions:   sessions,
+	}
+}
+
+// Authenticate verifies an access token and that its session has not been revoked
+func (m *AuthMiddleware) Authenticate(ctx context.Context, token string) (*JWTClaims, error) {
+	claims, err := m.jwtManager.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.sessions != nil {
+		active, err := m.sessions.IsActive(ctx, claims.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSessionUnavailable, err)
+		}
+		if !active {
+			return nil, ErrSessionRevoked
+		}
 	}
+
+	return claims, nil
 }
 
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
@@ -40,16 +73,26 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := m.jwtManager.Verify(bearerToken[1])
-		if err != nil {
+		claims, err := m.Authenticate(c.Request.Context(), bearerToken[1])
+		switch {
+		case errors.Is(err, ErrSessionRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		case errors.Is(err, ErrSessionUnavailable):
+			// Fail closed when the session store cannot be reached
+			logrus.WithError(err).Error("Failed to verify session")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify session"})
+			c.Abort()
+			return
+		case err != nil:
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
 		// Add user claims to context
-		ctx := context.WithValue(c.Request.Context(), UserContextKey, claims)
-		c.Request = c.Request.WithContext(ctx)
+		c.Request = c.Request.WithContext(ContextWithUser(c.Request.Context(), claims))
 
 		c.Next()
 	}
@@ -76,7 +119,9 @@ func (m *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
-func (m *AuthMiddleware) RequireFleetAccess(fleetID string) gin.HandlerFunc {
+// RequireFleetAccess rejects requests for a fleet the user cannot access. The
+// fleet ID is read from the named URL parameter, e.g. "id" for /fleets/:id.
+func (m *AuthMiddleware) RequireFleetAccess(param string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, exists := GetUserFromContext(c.Request.Context())
 		if !exists {
@@ -85,25 +130,29 @@ func (m *AuthMiddleware) RequireFleetAccess(fleetID string) gin.HandlerFunc {
 			return
 		}
 
-		// Super admin has access to all fleets
-		if claims.Role == "super_admin" {
-			c.Next()
+		fleetID, err := strconv.ParseUint(c.Param(param), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fleet ID"})
+			c.Abort()
 			return
 		}
 
-		// Check if user has access to this specific fleet
-		for _, id := range claims.FleetIDs {
-			if id == fleetID {
-				c.Next()
-				return
-			}
+		// Super admins have access to every fleet
+		if !claims.CanAccessFleet(uint(fleetID)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this fleet"})
+			c.Abort()
+			return
 		}
 
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this fleet"})
-		c.Abort()
+		c.Next()
 	}
 }
 
+// ContextWithUser returns a copy of ctx carrying the authenticated user's claims
+func ContextWithUser(ctx context.Context, claims *JWTClaims) context.Context {
+	return context.WithValue(ctx, UserContextKey, claims)
+}
+
 func GetUserFromContext(ctx context.Context) (*JWTClaims, bool) {
 	claims, ok := ctx.Value(UserContextKey).(*JWTClaims)
 	return claims, ok
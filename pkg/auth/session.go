@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// TokenPair is issued on login and on every refresh
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        string    `json:"session_id"`
+}
+
+// SessionManager issues short-lived access tokens backed by rotating,
+// opaque refresh tokens. Only SHA-256 hashes of refresh tokens are stored.
+type SessionManager struct {
+	db         *gorm.DB
+	store      *SessionStore
+	jwtManager *JWTManager
+	refreshTTL time.Duration
+}
+
+func NewSessionManager(store *SessionStore, jwtManager *JWTManager, refreshTTL time.Duration) *SessionManager {
+	return &SessionManager{
+		db:         store.db,
+		store:      store,
+		jwtManager: jwtManager,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// StartSession opens a new session family for a freshly authenticated user
+func (m *SessionManager) StartSession(ctx context.Context, user *models.User) (*TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, session, err := m.newSession(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.db.WithContext(ctx).Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	return m.issue(user, session, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// is rotated and can never be used again; presenting an already rotated token
+// revokes every session in its family and returns ErrRefreshTokenReused.
+func (m *SessionManager) Refresh(ctx context.Context, refreshToken string) (*TokenPair, *models.User, error) {
+	db := m.db.WithContext(ctx)
+
+	var current models.Session
+	if err := db.Where("token_hash = ?", HashRefreshToken(refreshToken)).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if current.RotatedAt != nil {
+		m.revokeReusedFamily(ctx, &current)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	var user models.User
+	if err := db.First(&user, current.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+	if user.Status != "active" {
+		if err := m.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	nextToken, next, err := m.newSession(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reused := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// The conditional update makes concurrent refreshes with the same token race
+		// for a single winner; the loser is treated as a replay
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return nil
+		}
+		return tx.Create(next).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if reused {
+		m.revokeReusedFamily(ctx, &current)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	pair, err := m.issue(&user, next, nextToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &user, nil
+}
+
+// RevokeFamily revokes every session in a family; see SessionStore.RevokeFamily
+func (m *SessionManager) RevokeFamily(ctx context.Context, familyID string) error {
+	return m.store.RevokeFamily(ctx, familyID)
+}
+
+func (m *SessionManager) revokeReusedFamily(ctx context.Context, session *models.Session) {
+	logrus.WithFields(logrus.Fields{
+		"user_id":   session.UserID,
+		"family_id": session.FamilyID,
+	}).Warn("Refresh token reuse detected, revoking session family")
+
+	if err := m.RevokeFamily(ctx, session.FamilyID); err != nil {
+		logrus.WithError(err).WithField("family_id", session.FamilyID).Error("Failed to revoke session family")
+	}
+}
+
+// newSession prepares a session row and returns the plaintext refresh token for it
+func (m *SessionManager) newSession(userID uint, familyID string) (string, *models.Session, error) {
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return refreshToken, &models.Session{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(m.refreshTTL),
+	}, nil
+}
+
+// issue signs an access token for the session's family
+func (m *SessionManager) issue(user *models.User, session *models.Session, refreshToken string) (*TokenPair, error) {
+	var fleetIDs []string
+	if user.FleetIDs != "" {
+		if err := json.Unmarshal([]byte(user.FleetIDs), &fleetIDs); err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Warn("Ignoring malformed fleet IDs")
+		}
+	}
+
+	accessToken, err := m.jwtManager.Generate(
+		strconv.Itoa(int(user.ID)),
+		user.Email,
+		user.Role,
+		fleetIDs,
+		session.FamilyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		ExpiresAt:        time.Now().Add(m.jwtManager.TokenDuration()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		SessionID:        session.FamilyID,
+	}, nil
+}
+
+// HashRefreshToken returns the form in which refresh tokens are stored
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
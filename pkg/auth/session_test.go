@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func setupSessionManager(t *testing.T) (*SessionManager, *gorm.DB, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	user := &models.User{
+		Email:    "manager@example.com",
+		Password: "password123",
+		Role:     "fleet_manager",
+		Status:   "active",
+		FleetIDs: `["1"]`,
+	}
+	require.NoError(t, db.Create(user).Error)
+
+	jwtManager := NewJWTManager("test-secret", 15*time.Minute)
+	return NewSessionManager(NewSessionStore(db, nil), jwtManager, time.Hour), db, user
+}
+
+func TestSessionManagerStartSession(t *testing.T) {
+	sessions, db, user := setupSessionManager(t)
+	ctx := context.Background()
+
+	tokens, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// The access token carries the session family
+	claims, err := sessions.jwtManager.Verify(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.SessionID, claims.SessionID)
+	assert.Equal(t, []string{"1"}, claims.FleetIDs)
+
+	// Only the hash of the refresh token is stored
+	var session models.Session
+	require.NoError(t, db.Where("family_id = ?", tokens.SessionID).First(&session).Error)
+	assert.Equal(t, HashRefreshToken(tokens.RefreshToken), session.TokenHash)
+	assert.NotEqual(t, tokens.RefreshToken, session.TokenHash)
+}
+
+func TestSessionManagerRefreshRotates(t *testing.T) {
+	sessions, db, user := setupSessionManager(t)
+	ctx := context.Background()
+
+	first, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+
+	second, refreshedUser, err := sessions.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, refreshedUser.ID)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, first.SessionID, second.SessionID)
+
+	// The new token keeps working
+	third, _, err := sessions.Refresh(ctx, second.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, second.RefreshToken, third.RefreshToken)
+
+	var count int64
+	db.Model(&models.Session{}).Where("family_id = ? AND rotated_at IS NOT NULL", first.SessionID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	_, _, err = sessions.Refresh(ctx, "not-a-real-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestSessionManagerReuseRevokesFamily(t *testing.T) {
+	sessions, db, user := setupSessionManager(t)
+	ctx := context.Background()
+
+	stolen, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	other, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+
+	latest, _, err := sessions.Refresh(ctx, stolen.RefreshToken)
+	require.NoError(t, err)
+
+	// Replaying the rotated token is detected...
+	_, _, err = sessions.Refresh(ctx, stolen.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// ...and the legitimate successor is revoked along with it
+	_, _, err = sessions.Refresh(ctx, latest.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	var active int64
+	db.Model(&models.Session{}).Where("family_id = ? AND revoked_at IS NULL", stolen.SessionID).Count(&active)
+	assert.Zero(t, active)
+
+	// Other logins of the same user are unaffected
+	_, _, err = sessions.Refresh(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestSessionManagerRejectsExpiredAndInactive(t *testing.T) {
+	sessions, db, user := setupSessionManager(t)
+	ctx := context.Background()
+
+	expired, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.Session{}).
+		Where("family_id = ?", expired.SessionID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	_, _, err = sessions.Refresh(ctx, expired.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	tokens, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(user).Update("status", "suspended").Error)
+
+	_, _, err = sessions.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestSessionManagerRevokeFamily(t *testing.T) {
+	sessions, _, user := setupSessionManager(t)
+	ctx := context.Background()
+
+	tokens, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	require.NoError(t, sessions.RevokeFamily(ctx, tokens.SessionID))
+
+	_, _, err = sessions.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
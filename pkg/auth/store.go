@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+const (
+	sessionCachePrefix = "session:"
+	sessionActive      = "active"
+	sessionRevoked     = "revoked"
+
+	// Active lookups are cached briefly; revocations write the cache directly,
+	// so the TTL only bounds staleness if that write fails
+	activeCacheTTL = time.Minute
+	// Revoked families never become active again, so they can be cached for
+	// longer than any access token lives
+	revokedCacheTTL = 24 * time.Hour
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore answers whether a session family is still active. The database
+// is the source of truth; Redis, when available, caches the answer so
+// RequireAuth does not hit the database on every request.
+type SessionStore struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+// NewSessionStore creates a store; redisClient may be nil to always use the database
+func NewSessionStore(db *gorm.DB, redisClient *redis.Client) *SessionStore {
+	return &SessionStore{
+		db:    db,
+		redis: redisClient,
+	}
+}
+
+// IsActive reports whether the family still holds an unrevoked, unexpired session
+func (s *SessionStore) IsActive(ctx context.Context, familyID string) (bool, error) {
+	if familyID == "" {
+		return false, nil
+	}
+
+	if s.redis != nil {
+		state, err := s.redis.Get(ctx, sessionCachePrefix+familyID).Result()
+		if err == nil {
+			return state == sessionActive, nil
+		}
+		if err != redis.Nil {
+			logrus.WithError(err).Warn("Session cache unavailable, falling back to database")
+		}
+	}
+
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", familyID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	active := count > 0
+	if active {
+		s.cache(ctx, familyID, sessionActive, activeCacheTTL)
+	} else {
+		s.cache(ctx, familyID, sessionRevoked, revokedCacheTTL)
+	}
+	return active, nil
+}
+
+// ListActive returns the current session of each of the user's active families
+func (s *SessionStore) ListActive(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeFamily revokes every session in a family, logging the user out of
+// that login everywhere its tokens were shared
+func (s *SessionStore) RevokeFamily(ctx context.Context, familyID string) error {
+	err := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	s.cache(ctx, familyID, sessionRevoked, revokedCacheTTL)
+	return nil
+}
+
+// RevokeSession revokes the family of one of the user's sessions
+func (s *SessionStore) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	var session models.Session
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.RevokeFamily(ctx, session.FamilyID)
+}
+
+// RevokeUser revokes all of the user's sessions, returning how many logins were ended
+func (s *SessionStore) RevokeUser(ctx context.Context, userID uint) (int, error) {
+	var familyIDs []string
+	err := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Distinct().
+		Pluck("family_id", &familyIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(familyIDs) == 0 {
+		return 0, nil
+	}
+
+	err = s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, familyID := range familyIDs {
+		s.cache(ctx, familyID, sessionRevoked, revokedCacheTTL)
+	}
+	return len(familyIDs), nil
+}
+
+func (s *SessionStore) cache(ctx context.Context, familyID, state string, ttl time.Duration) {
+	if s.redis == nil {
+		return
+	}
+	if err := s.redis.Set(ctx, sessionCachePrefix+familyID, state, ttl).Err(); err != nil {
+		logrus.WithError(err).WithField("family_id", familyID).Warn("Failed to cache session state")
+	}
+}
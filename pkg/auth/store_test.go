@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func TestSessionStoreRevocation(t *testing.T) {
+	sessions, db, user := setupSessionManager(t)
+	store := sessions.store
+	ctx := context.Background()
+
+	first, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	second, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+
+	active, err := store.IsActive(ctx, first.SessionID)
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	active, err = store.IsActive(ctx, "")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	listed, err := store.ListActive(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	// A session can only be revoked through its owner
+	assert.ErrorIs(t, store.RevokeSession(ctx, user.ID+1, listed[0].ID), ErrSessionNotFound)
+	require.NoError(t, store.RevokeSession(ctx, user.ID, listed[0].ID))
+
+	listed, err = store.ListActive(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	revoked, err := store.RevokeUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+
+	for _, familyID := range []string{first.SessionID, second.SessionID} {
+		active, err := store.IsActive(ctx, familyID)
+		require.NoError(t, err)
+		assert.False(t, active)
+	}
+
+	var remaining int64
+	db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&remaining)
+	assert.Zero(t, remaining)
+}
+
+func TestRequireAuthRejectsRevokedSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sessions, _, user := setupSessionManager(t)
+	ctx := context.Background()
+
+	middleware := NewAuthMiddleware(sessions.jwtManager, sessions.store)
+	router := gin.New()
+	router.GET("/me", middleware.RequireAuth(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tokens, err := sessions.StartSession(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, request(tokens.AccessToken).Code)
+
+	require.NoError(t, sessions.RevokeFamily(ctx, tokens.SessionID))
+	w := request(tokens.AccessToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "revoked")
+
+	// Tokens issued without a session cannot be checked and are refused
+	legacy, err := sessions.jwtManager.Generate("1", user.Email, user.Role, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, request(legacy).Code)
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+)
+
+// RoleSuperAdmin can read and write every fleet
+const RoleSuperAdmin = "super_admin"
+
+// tenantRule describes how rows of a table are tied to a fleet. Rows either
+// carry the fleet ID in column directly, or reference a parent table that does.
+type tenantRule struct {
+	column string
+	parent string
+}
+
+// tenantTables lists the tables isolated per fleet; tables not listed here
+// (users, sessions) are left unscoped
+var tenantTables = map[string]tenantRule{
+	"fleets":                 {column: "id"},
+	"vehicles":               {column: "fleet_id"},
+	"drivers":                {column: "fleet_id"},
+	"alerts":                 {column: "fleet_id"},
+	"risk_events":            {column: "vehicle_id", parent: "vehicles"},
+	"telemetry_events":       {column: "vehicle_id", parent: "vehicles"},
+	"trips":                  {column: "vehicle_id", parent: "vehicles"},
+	"vehicle_assignments":    {column: "vehicle_id", parent: "vehicles"},
+	"driver_scores":          {column: "driver_id", parent: "drivers"},
+	"driver_score_snapshots": {column: "driver_id", parent: "drivers"},
+	"risk_policies":          {column: "fleet_id"},
+	"incidents":              {column: "fleet_id"},
+	"incident_updates":       {column: "incident_id", parent: "incidents"},
+	"geofences":              {column: "fleet_id"},
+	"geofence_events":        {column: "geofence_id", parent: "geofences"},
+	"planned_routes":         {column: "fleet_id"},
+	"route_assignments":      {column: "route_id", parent: "planned_routes"},
+	"driver_baselines":       {column: "fleet_id"},
+}
+
+// IsSuperAdmin reports whether the claims bypass fleet isolation
+func (c *JWTClaims) IsSuperAdmin() bool {
+	return c.Role == RoleSuperAdmin
+}
+
+// AllowedFleetIDs returns the fleets the user may access, ignoring malformed IDs
+func (c *JWTClaims) AllowedFleetIDs() []uint {
+	ids := make([]uint, 0, len(c.FleetIDs))
+	for _, id := range c.FleetIDs {
+		if parsed, err := strconv.ParseUint(id, 10, 64); err == nil {
+			ids = append(ids, uint(parsed))
+		}
+	}
+	return ids
+}
+
+// CanAccessFleet reports whether the user may read or write the given fleet
+func (c *JWTClaims) CanAccessFleet(fleetID uint) bool {
+	if c.IsSuperAdmin() {
+		return true
+	}
+	for _, id := range c.AllowedFleetIDs() {
+		if id == fleetID {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterTenantScope installs GORM callbacks that restrict every query,
+// update and delete on fleet-owned tables to the fleets of the user found in
+// the statement context, and reject creates and updates that would place rows
+// in another fleet. Statements without a user in their context (background
+// workers, ingestion) are not restricted, so request handlers must pass the
+// request context with db.WithContext.
+func RegisterTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:scope_query", scopeToFleets); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:scope_row", scopeToFleets); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:scope_update", scopeWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:scope_delete", scopeToFleets); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenant:check_create", checkCreate)
+}
+
+// tenantContext returns the caller's claims and the table rule when the
+// statement needs fleet isolation
+func tenantContext(db *gorm.DB) (*JWTClaims, tenantRule, bool) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return nil, tenantRule{}, false
+	}
+
+	claims, ok := GetUserFromContext(db.Statement.Context)
+	if !ok || claims.IsSuperAdmin() {
+		return nil, tenantRule{}, false
+	}
+
+	rule, ok := tenantTables[db.Statement.Table]
+	if !ok {
+		return nil, tenantRule{}, false
+	}
+
+	return claims, rule, true
+}
+
+// scopeToFleets adds the caller's fleet filter to the statement's WHERE clause
+func scopeToFleets(db *gorm.DB) {
+	claims, rule, ok := tenantContext(db)
+	if !ok {
+		return
+	}
+
+	sql := fmt.Sprintf("%s.%s IN ?", db.Statement.Table, rule.column)
+	if rule.parent != "" {
+		sql = fmt.Sprintf("%s.%s IN (SELECT id FROM %s WHERE fleet_id IN ?)", db.Statement.Table, rule.column, rule.parent)
+	}
+	condition := clause.Expr{SQL: sql, Vars: []interface{}{claims.AllowedFleetIDs()}}
+
+	// Group the existing conditions so an OR in them cannot bypass the fleet filter
+	exprs := []clause.Expression{condition}
+	if existing, ok := db.Statement.Clauses["WHERE"]; ok {
+		if where, ok := existing.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			exprs = []clause.Expression{clause.And(where.Exprs...), condition}
+		}
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: exprs})
+}
+
+// scopeWrite limits updates to the caller's rows and rejects moving rows into
+// fleets the caller cannot access
+func scopeWrite(db *gorm.DB) {
+	claims, rule, ok := tenantContext(db)
+	if !ok {
+		return
+	}
+
+	scopeToFleets(db)
+
+	// Updates to fleets themselves are covered by the WHERE clause
+	if db.Statement.Table == "fleets" {
+		return
+	}
+	checkOwnership(db, claims, rule)
+}
+
+// checkCreate rejects inserts of rows belonging to fleets the caller cannot access
+func checkCreate(db *gorm.DB) {
+	claims, rule, ok := tenantContext(db)
+	if !ok {
+		return
+	}
+
+	// Only super admins may create fleets; anyone else would lose access to the new fleet
+	if db.Statement.Table == "fleets" {
+		db.AddError(fmt.Errorf("%w: only super admins can create fleets", errors.ErrFleetAccessDenied))
+		return
+	}
+	checkOwnership(db, claims, rule)
+}
+
+// checkOwnership verifies that every value being written to the rule's
+// column belongs to one of the caller's fleets
+func checkOwnership(db *gorm.DB, claims *JWTClaims, rule tenantRule) {
+	values := writtenValues(db.Statement, rule.column)
+	if len(values) == 0 {
+		return
+	}
+
+	if rule.parent == "" {
+		for _, id := range values {
+			if !claims.CanAccessFleet(id) {
+				db.AddError(fmt.Errorf("%w: fleet %d", errors.ErrFleetAccessDenied, id))
+				return
+			}
+		}
+		return
+	}
+
+	// The parent lookup runs in the same context, so it is scoped to the caller's fleets too
+	var count int64
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Table(rule.parent).
+		Where("id IN ?", values).
+		Count(&count).Error
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if count != int64(len(values)) {
+		db.AddError(fmt.Errorf("%w: %s outside caller's fleets", errors.ErrFleetAccessDenied, rule.parent))
+	}
+}
+
+// writtenValues collects the distinct non-zero values assigned to column by
+// the statement's destination, which may be a struct, a slice of structs or a
+// column map
+func writtenValues(stmt *gorm.Statement, column string) []uint {
+	seen := make(map[uint]bool)
+	var values []uint
+	add := func(v interface{}) {
+		id, ok := toUint(v)
+		if ok && id != 0 && !seen[id] {
+			seen[id] = true
+			values = append(values, id)
+		}
+	}
+
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		for key, v := range dest {
+			if key == column || (stmt.Schema != nil && stmt.Schema.LookUpField(key) != nil && stmt.Schema.LookUpField(key).DBName == column) {
+				add(v)
+			}
+		}
+		return values
+	case []map[string]interface{}:
+		for _, row := range dest {
+			add(row[column])
+		}
+		return values
+	}
+
+	if stmt.Schema == nil {
+		return values
+	}
+	field := stmt.Schema.LookUpField(column)
+	if field == nil {
+		return values
+	}
+
+	destValue := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	switch destValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < destValue.Len(); i++ {
+			if v, zero := field.ValueOf(stmt.Context, reflect.Indirect(destValue.Index(i))); !zero {
+				add(v)
+			}
+		}
+	case reflect.Struct:
+		if v, zero := field.ValueOf(stmt.Context, destValue); !zero {
+			add(v)
+		}
+	}
+
+	return values
+}
+
+func toUint(v interface{}) (uint, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(rv.Uint()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, false
+		}
+		return uint(rv.Int()), true
+	}
+	return 0, false
+}
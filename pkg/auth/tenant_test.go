@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+type tenantFixture struct {
+	db             *gorm.DB
+	ownFleet       models.Fleet
+	otherFleet     models.Fleet
+	ownVehicle     models.Vehicle
+	otherVehicle   models.Vehicle
+	otherDriver    models.Driver
+	otherRiskEvent models.RiskEvent
+}
+
+func setupTenantDB(t *testing.T) *tenantFixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+	require.NoError(t, RegisterTenantScope(db))
+
+	// Fixtures are created without a user in the context, so they are not scoped
+	f := &tenantFixture{
+		db:         db,
+		ownFleet:   models.Fleet{Name: "Own Fleet", Status: "active"},
+		otherFleet: models.Fleet{Name: "Other Fleet", Status: "active"},
+	}
+	require.NoError(t, db.Create(&f.ownFleet).Error)
+	require.NoError(t, db.Create(&f.otherFleet).Error)
+
+	ownDriver := models.Driver{EmployeeID: "OWN-1", Email: "own@example.com", FleetID: f.ownFleet.ID}
+	f.otherDriver = models.Driver{EmployeeID: "OTHER-1", Email: "other@example.com", FleetID: f.otherFleet.ID}
+	require.NoError(t, db.Omit("Fleet").Create(&ownDriver).Error)
+	require.NoError(t, db.Omit("Fleet").Create(&f.otherDriver).Error)
+
+	f.ownVehicle = models.Vehicle{VIN: "1HGCM82633A000001", FleetID: f.ownFleet.ID}
+	f.otherVehicle = models.Vehicle{VIN: "1HGCM82633A000002", FleetID: f.otherFleet.ID}
+	require.NoError(t, db.Omit("Fleet").Create(&f.ownVehicle).Error)
+	require.NoError(t, db.Omit("Fleet").Create(&f.otherVehicle).Error)
+
+	ownRiskEvent := models.RiskEvent{VehicleID: f.ownVehicle.ID, EventType: "speeding", Timestamp: time.Now(), Data: "{}"}
+	f.otherRiskEvent = models.RiskEvent{VehicleID: f.otherVehicle.ID, EventType: "speeding", Timestamp: time.Now(), Data: "{}"}
+	require.NoError(t, db.Omit("Vehicle").Create(&ownRiskEvent).Error)
+	require.NoError(t, db.Omit("Vehicle").Create(&f.otherRiskEvent).Error)
+
+	require.NoError(t, db.Omit("Fleet").Create(&models.Alert{FleetID: f.ownFleet.ID, Type: "risk", Title: "Own"}).Error)
+	require.NoError(t, db.Omit("Fleet").Create(&models.Alert{FleetID: f.otherFleet.ID, Type: "risk", Title: "Other"}).Error)
+
+	return f
+}
+
+// as returns a session acting on behalf of a user with the given role and fleets
+func (f *tenantFixture) as(role string, fleetIDs ...uint) *gorm.DB {
+	claims := &JWTClaims{UserID: "1", Email: "user@example.com", Role: role}
+	for _, id := range fleetIDs {
+		claims.FleetIDs = append(claims.FleetIDs, strconv.FormatUint(uint64(id), 10))
+	}
+	return f.db.WithContext(ContextWithUser(context.Background(), claims))
+}
+
+func TestTenantScopeReads(t *testing.T) {
+	f := setupTenantDB(t)
+	db := f.as("fleet_manager", f.ownFleet.ID)
+
+	var fleets []models.Fleet
+	require.NoError(t, db.Find(&fleets).Error)
+	require.Len(t, fleets, 1)
+	assert.Equal(t, f.ownFleet.ID, fleets[0].ID)
+
+	var vehicles []models.Vehicle
+	require.NoError(t, db.Preload("Fleet").Find(&vehicles).Error)
+	require.Len(t, vehicles, 1)
+	assert.Equal(t, f.ownVehicle.ID, vehicles[0].ID)
+
+	var alerts []models.Alert
+	require.NoError(t, db.Find(&alerts).Error)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "Own", alerts[0].Title)
+
+	// Tables linked through a vehicle are scoped through the vehicle's fleet
+	var events []models.RiskEvent
+	require.NoError(t, db.Find(&events).Error)
+	require.Len(t, events, 1)
+	assert.Equal(t, f.ownVehicle.ID, events[0].VehicleID)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Driver{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTenantScopeRejectsCrossFleetReads(t *testing.T) {
+	f := setupTenantDB(t)
+	db := f.as("fleet_manager", f.ownFleet.ID)
+
+	var vehicle models.Vehicle
+	err := db.First(&vehicle, f.otherVehicle.ID).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var driver models.Driver
+	err = db.First(&driver, f.otherDriver.ID).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var event models.RiskEvent
+	err = db.First(&event, f.otherRiskEvent.ID).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// An OR in the caller's conditions must not widen the scope
+	var vehicles []models.Vehicle
+	require.NoError(t, db.Where("id = ?", f.ownVehicle.ID).Or("id = ?", f.otherVehicle.ID).Find(&vehicles).Error)
+	require.Len(t, vehicles, 1)
+	assert.Equal(t, f.ownVehicle.ID, vehicles[0].ID)
+
+	// A user without fleets sees nothing
+	var fleets []models.Fleet
+	require.NoError(t, f.as("fleet_manager").Find(&fleets).Error)
+	assert.Empty(t, fleets)
+}
+
+func TestTenantScopeRejectsCrossFleetWrites(t *testing.T) {
+	f := setupTenantDB(t)
+	db := f.as("fleet_manager", f.ownFleet.ID)
+
+	// Creating a vehicle in another fleet
+	err := db.Omit("Fleet").Create(&models.Vehicle{VIN: "1HGCM82633A000003", FleetID: f.otherFleet.ID}).Error
+	assert.ErrorIs(t, err, errors.ErrFleetAccessDenied)
+
+	// Creating a risk event for another fleet's vehicle
+	err = db.Omit("Vehicle").Create(&models.RiskEvent{VehicleID: f.otherVehicle.ID, EventType: "speeding", Data: "{}"}).Error
+	assert.ErrorIs(t, err, errors.ErrFleetAccessDenied)
+
+	// Creating fleets is reserved for super admins
+	err = db.Create(&models.Fleet{Name: "New Fleet"}).Error
+	assert.ErrorIs(t, err, errors.ErrFleetAccessDenied)
+
+	// Updating another fleet's vehicle leaves it untouched
+	result := db.Model(&models.Vehicle{}).Where("id = ?", f.otherVehicle.ID).Update("status", "inactive")
+	require.NoError(t, result.Error)
+	assert.Zero(t, result.RowsAffected)
+
+	// Moving an own vehicle into another fleet
+	err = db.Model(&f.ownVehicle).Updates(map[string]interface{}{"fleet_id": f.otherFleet.ID}).Error
+	assert.ErrorIs(t, err, errors.ErrFleetAccessDenied)
+
+	// Deleting another fleet's driver is a no-op
+	result = db.Delete(&models.Driver{}, f.otherDriver.ID)
+	require.NoError(t, result.Error)
+	assert.Zero(t, result.RowsAffected)
+
+	// Verify without a user that nothing was changed
+	var otherStored, ownStored models.Vehicle
+	require.NoError(t, f.db.First(&otherStored, f.otherVehicle.ID).Error)
+	assert.Equal(t, "active", otherStored.Status)
+	require.NoError(t, f.db.First(&ownStored, f.ownVehicle.ID).Error)
+	assert.Equal(t, f.ownFleet.ID, ownStored.FleetID)
+	require.NoError(t, f.db.First(&models.Driver{}, f.otherDriver.ID).Error)
+
+	// Writes within the caller's fleet still work
+	result = db.Model(&models.Vehicle{}).Where("id = ?", f.ownVehicle.ID).Update("status", "maintenance")
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+	assert.NoError(t, db.Omit("Fleet").Create(&models.Vehicle{VIN: "1HGCM82633A000004", FleetID: f.ownFleet.ID}).Error)
+}
+
+func TestTenantScopeBypass(t *testing.T) {
+	f := setupTenantDB(t)
+
+	var vehicles []models.Vehicle
+	require.NoError(t, f.as(RoleSuperAdmin).Find(&vehicles).Error)
+	assert.Len(t, vehicles, 2)
+	assert.NoError(t, f.as(RoleSuperAdmin).Create(&models.Fleet{Name: "New Fleet"}).Error)
+
+	// Background work without a user is not scoped
+	require.NoError(t, f.db.WithContext(context.Background()).Find(&vehicles).Error)
+	assert.Len(t, vehicles, 2)
+}
+
+func TestClaimsFleetAccess(t *testing.T) {
+	claims := &JWTClaims{Role: "fleet_manager", FleetIDs: []string{"1", "bogus", "3"}}
+	assert.Equal(t, []uint{1, 3}, claims.AllowedFleetIDs())
+	assert.True(t, claims.CanAccessFleet(3))
+	assert.False(t, claims.CanAccessFleet(2))
+
+	admin := &JWTClaims{Role: RoleSuperAdmin}
+	assert.True(t, admin.CanAccessFleet(2))
+}
+
+func TestRequireFleetAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middleware := NewAuthMiddleware(NewJWTManager("test-secret", time.Hour), nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		claims := &JWTClaims{Role: "fleet_manager", FleetIDs: []string{"1"}}
+		c.Request = c.Request.WithContext(ContextWithUser(c.Request.Context(), claims))
+	})
+	router.GET("/fleets/:id", middleware.RequireFleetAccess("id"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := map[string]int{
+		"/fleets/1":   http.StatusOK,
+		"/fleets/2":   http.StatusForbidden,
+		"/fleets/abc": http.StatusBadRequest,
+	}
+	for path, status := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, status, w.Code, path)
+	}
+}
+
+func TestFleetAccessDeniedMapsToForbidden(t *testing.T) {
+	f := setupTenantDB(t)
+	err := f.as("fleet_manager", f.ownFleet.ID).Create(&models.Fleet{Name: "New Fleet"}).Error
+
+	appErr := errors.DatabaseError("create_fleet", err)
+	assert.Equal(t, http.StatusForbidden, appErr.HTTPStatus)
+	assert.True(t, stderrors.Is(appErr.Internal, errors.ErrFleetAccessDenied))
+}
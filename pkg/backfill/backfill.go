@@ -0,0 +1,312 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/assignment"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/incident"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+)
+
+// Job statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+)
+
+// Options select the telemetry to reprocess
+type Options struct {
+	FleetID uint
+	From    time.Time
+	To      time.Time
+	// Replace deletes the risk events the rules previously raised in the
+	// range and reanalyzes all of its telemetry. Geofence, route and anomaly
+	// events are not regenerated and are kept. Otherwise only telemetry that was never
+	// processed is analyzed, alongside the existing risk events.
+	Replace   bool
+	BatchSize int
+}
+
+func (o Options) validate(now time.Time, liveWindow time.Duration) error {
+	if o.FleetID == 0 {
+		return errors.New("a fleet is required")
+	}
+	if !o.From.Before(o.To) {
+		return errors.New("from must be before to")
+	}
+	if o.To.After(now.Add(-liveWindow)) {
+		return fmt.Errorf("to must be at least %s ago, the live engine processes newer telemetry", liveWindow)
+	}
+	if o.BatchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	return nil
+}
+
+// Progress is reported after every committed batch
+type Progress struct {
+	Job   *models.BackfillJob
+	Total int64
+}
+
+// Percent is the share of the job's events processed so far
+func (p Progress) Percent() float64 {
+	if p.Total == 0 {
+		return 100
+	}
+	return float64(p.Job.EventsProcessed) / float64(p.Total) * 100
+}
+
+// Run reprocesses a fleet's telemetry in [From, To) with the given policies.
+// Vehicles are replayed one at a time in timestamp order, so that episode
+// detectors see each vehicle's stream as the live engine would. Each batch of
+// risk events is committed together with the job's cursor; an interrupted run
+// with the same options resumes from the last batch. Episodes open across the
+// point of interruption are only reported from the resumed part.
+//
+// Backfilled risk events describe the past, so they do not raise alerts or
+// open incidents; the crash incidents of replaced risk events are linked to
+// the regenerated crash events.
+// liveWindow is how far back the live engine looks; the range must end before
+// it to avoid analyzing events twice.
+func Run(ctx context.Context, db *gorm.DB, policies *risk.PolicyStore, opts Options, liveWindow time.Duration, progress func(Progress)) (*models.BackfillJob, error) {
+	if err := opts.validate(time.Now(), liveWindow); err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	job, err := startJob(db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	classes, err := vehicleClasses(db, opts.FleetID)
+	if err != nil {
+		return job, err
+	}
+
+	var total int64
+	if err := pendingEvents(db, job).Count(&total).Error; err != nil {
+		return job, err
+	}
+	total += job.EventsProcessed
+
+	drivers := assignment.NewResolver(db, time.Hour)
+
+	// Episodes still open at the end of a vehicle's stream are closed with it
+	flushAt := opts.To.Add(24 * time.Hour)
+	previousVehicle := job.CursorVehicleID
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return job, err
+		}
+
+		var events []models.TelemetryEvent
+		if err := pendingEvents(db, job).
+			Order("vehicle_id ASC, timestamp ASC, id ASC").
+			Limit(opts.BatchSize).
+			Find(&events).Error; err != nil {
+			return job, err
+		}
+
+		var risks []models.RiskEvent
+		for i := range events {
+			event := &events[i]
+			if previousVehicle != 0 && event.VehicleID != previousVehicle {
+				risks = append(risks, policies.Flush(flushAt)...)
+			}
+			previousVehicle = event.VehicleID
+			if event.DriverID, err = drivers.DriverAt(ctx, event.VehicleID, event.Timestamp); err != nil {
+				return job, err
+			}
+			risks = append(risks, policies.AnalyzerFor(opts.FleetID, classes[event.VehicleID]).Analyze(event)...)
+		}
+		if len(events) == 0 {
+			risks = append(risks, policies.Flush(flushAt)...)
+		}
+
+		for i := range risks {
+			if risks[i].DriverID == nil {
+				driverID, err := drivers.DriverAt(ctx, risks[i].VehicleID, risks[i].Timestamp)
+				if err != nil {
+					return job, err
+				}
+				risks[i].DriverID = driverID
+			}
+		}
+
+		if err := commitBatch(db, job, events, risks); err != nil {
+			return job, err
+		}
+		if progress != nil {
+			progress(Progress{Job: job, Total: total})
+		}
+		if len(events) == 0 {
+			return job, nil
+		}
+	}
+}
+
+// startJob resumes the unfinished job with the same options, or starts a new
+// one, clearing the range's risk events first when replacing them
+func startJob(db *gorm.DB, opts Options) (*models.BackfillJob, error) {
+	var running []models.BackfillJob
+	if err := db.Where("fleet_id = ? AND status = ?", opts.FleetID, StatusRunning).
+		Order("id DESC").
+		Find(&running).Error; err != nil {
+		return nil, err
+	}
+	for i := range running {
+		job := &running[i]
+		if job.FromTime.Equal(opts.From) && job.ToTime.Equal(opts.To) && job.Replace == opts.Replace {
+			return job, nil
+		}
+	}
+
+	job := &models.BackfillJob{
+		FleetID:  opts.FleetID,
+		FromTime: opts.From,
+		ToTime:   opts.To,
+		Replace:  opts.Replace,
+		Status:   StatusRunning,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if opts.Replace {
+			generated := tx.Model(&models.RiskEvent{}).
+				Select("id").
+				Where("vehicle_id IN (?)", fleetVehicles(tx, opts.FleetID)).
+				Where("timestamp >= ? AND timestamp < ?", opts.From, opts.To).
+				Where("event_type IN ?", risk.EventTypes())
+
+			// Alerts and incidents outlive the risk events they were raised
+			// for; crash incidents are linked to the regenerated crash again
+			if err := tx.Model(&models.Alert{}).
+				Where("risk_event_id IN (?)", generated).
+				Update("risk_event_id", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Incident{}).
+				Where("risk_event_id IN (?)", generated).
+				Update("risk_event_id", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("vehicle_id IN (?)", fleetVehicles(tx, opts.FleetID)).
+				Where("timestamp >= ? AND timestamp < ?", opts.From, opts.To).
+				Where("event_type IN ?", risk.EventTypes()).
+				Delete(&models.RiskEvent{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Fleet").Create(job).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// commitBatch stores the batch's risk events, marks its events processed and
+// advances the job's cursor in one transaction. An empty batch completes the job.
+func commitBatch(db *gorm.DB, job *models.BackfillJob, events []models.TelemetryEvent, risks []models.RiskEvent) error {
+	next := *job
+	next.EventsProcessed += int64(len(events))
+	next.RiskEvents += int64(len(risks))
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		next.CursorVehicleID = last.VehicleID
+		next.CursorTimestamp = &last.Timestamp
+		next.CursorEventID = last.ID
+	} else {
+		now := time.Now()
+		next.Status = StatusCompleted
+		next.CompletedAt = &now
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(risks) > 0 {
+			if err := tx.Create(&risks).Error; err != nil {
+				return err
+			}
+			if err := relinkIncidents(tx, risks); err != nil {
+				return err
+			}
+		}
+
+		if len(events) > 0 {
+			ids := make([]uint, len(events))
+			for i, event := range events {
+				ids[i] = event.ID
+			}
+			if err := tx.Model(&models.TelemetryEvent{}).
+				Where("id IN ? AND processed_at IS NULL", ids).
+				Update("processed_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(job).
+			Select("status", "cursor_vehicle_id", "cursor_timestamp", "cursor_event_id", "events_processed", "risk_events", "completed_at").
+			Updates(&next).Error
+	})
+	if err != nil {
+		return err
+	}
+	*job = next
+	return nil
+}
+
+// relinkIncidents points the crash incidents left without a risk event by
+// a replacing job at the regenerated crash events of the same moment
+func relinkIncidents(tx *gorm.DB, risks []models.RiskEvent) error {
+	for _, event := range risks {
+		if event.EventType != risk.RuleCrash {
+			continue
+		}
+		if err := tx.Model(&models.Incident{}).
+			Where("vehicle_id = ? AND type = ? AND occurred_at = ? AND risk_event_id IS NULL", event.VehicleID, incident.TypeCrash, event.Timestamp).
+			Update("risk_event_id", event.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingEvents selects the job's telemetry after its cursor
+func pendingEvents(db *gorm.DB, job *models.BackfillJob) *gorm.DB {
+	query := db.Model(&models.TelemetryEvent{}).
+		Where("vehicle_id IN (?)", fleetVehicles(db, job.FleetID)).
+		Where("timestamp >= ? AND timestamp < ?", job.FromTime, job.ToTime)
+	if !job.Replace {
+		query = query.Where("processed_at IS NULL")
+	}
+	if job.CursorTimestamp != nil {
+		query = query.Where("vehicle_id > ? OR (vehicle_id = ? AND (timestamp > ? OR (timestamp = ? AND id > ?)))",
+			job.CursorVehicleID, job.CursorVehicleID, *job.CursorTimestamp, *job.CursorTimestamp, job.CursorEventID)
+	}
+	return query
+}
+
+func fleetVehicles(db *gorm.DB, fleetID uint) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.Vehicle{}).Select("id").Where("fleet_id = ?", fleetID)
+}
+
+// vehicleClasses returns the class of each of the fleet's vehicles, which
+// selects the risk policy their events are analyzed with
+func vehicleClasses(db *gorm.DB, fleetID uint) (map[uint]string, error) {
+	var vehicles []models.Vehicle
+	if err := db.Select("id", "vehicle_class").Where("fleet_id = ?", fleetID).Find(&vehicles).Error; err != nil {
+		return nil, err
+	}
+	classes := make(map[uint]string, len(vehicles))
+	for _, vehicle := range vehicles {
+		classes[vehicle.ID] = vehicle.VehicleClass
+	}
+	return classes, nil
+}
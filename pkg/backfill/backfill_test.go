@@ -0,0 +1,217 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+)
+
+var day = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// setupDB stores two vehicles that each sped for a minute around noon
+func setupDB(t *testing.T) (*gorm.DB, models.Fleet, []models.Vehicle) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+
+	vehicles := make([]models.Vehicle, 2)
+	for i := range vehicles {
+		vehicles[i] = models.Vehicle{FleetID: fleet.ID, VIN: fmt.Sprintf("VIN%d", i), Make: "Ford", Model: "Transit", Year: 2022}
+		require.NoError(t, db.Omit("Fleet").Create(&vehicles[i]).Error)
+
+		var events []models.TelemetryEvent
+		for second := 0; second <= 60; second++ {
+			speed := 90.0
+			if second == 60 {
+				speed = 50
+			}
+			events = append(events, models.TelemetryEvent{
+				VehicleID: vehicles[i].ID,
+				EventType: "speed",
+				Timestamp: day.Add(12*time.Hour + time.Duration(second)*time.Second),
+				Speed:     &speed,
+				Data:      "{}",
+			})
+		}
+		require.NoError(t, db.Omit("Vehicle").Create(&events).Error)
+	}
+	return db, fleet, vehicles
+}
+
+func newPolicies(t *testing.T, db *gorm.DB) *risk.PolicyStore {
+	policies, err := risk.NewPolicyStore(db, risk.DefaultConfig())
+	require.NoError(t, err)
+	return policies
+}
+
+func riskEvents(t *testing.T, db *gorm.DB) []models.RiskEvent {
+	var risks []models.RiskEvent
+	require.NoError(t, db.Order("vehicle_id").Find(&risks).Error)
+	return risks
+}
+
+func TestBackfillReplacesRiskEvents(t *testing.T) {
+	db, fleet, vehicles := setupDB(t)
+
+	// Generated earlier under different rules, with an alert raised for it
+	stale := models.RiskEvent{VehicleID: vehicles[0].ID, EventType: "speeding", Severity: "high", Timestamp: day.Add(12 * time.Hour)}
+	require.NoError(t, db.Omit("Vehicle", "Driver").Create(&stale).Error)
+	alert := models.Alert{FleetID: fleet.ID, RiskEventID: &stale.ID, Type: "risk", Priority: "high", Title: "Speeding Alert"}
+	require.NoError(t, db.Omit("Fleet", "Vehicle", "Driver", "RiskEvent").Create(&alert).Error)
+	require.NoError(t, db.Model(&models.TelemetryEvent{}).Where("1 = 1").Update("processed_at", time.Now()).Error)
+
+	opts := Options{FleetID: fleet.ID, From: day, To: day.AddDate(0, 0, 1), Replace: true, BatchSize: 50}
+	var reports []Progress
+	job, err := Run(t.Context(), db, newPolicies(t, db), opts, time.Hour, func(p Progress) {
+		reports = append(reports, p)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, int64(122), job.EventsProcessed)
+	assert.Equal(t, int64(2), job.RiskEvents)
+	require.NotEmpty(t, reports)
+	assert.Equal(t, int64(122), reports[0].Total)
+	assert.Equal(t, 100.0, reports[len(reports)-1].Percent())
+
+	risks := riskEvents(t, db)
+	require.Len(t, risks, 2)
+	for i, event := range risks {
+		assert.NotEqual(t, stale.ID, event.ID)
+		assert.Equal(t, vehicles[i].ID, event.VehicleID)
+		assert.Equal(t, risk.RuleSpeeding, event.EventType)
+	}
+
+	require.NoError(t, db.First(&alert, alert.ID).Error)
+	assert.Nil(t, alert.RiskEventID, "the alert is kept but no longer points at the deleted event")
+	var alerts int64
+	require.NoError(t, db.Model(&models.Alert{}).Count(&alerts).Error)
+	assert.Equal(t, int64(1), alerts, "backfilled events do not raise alerts")
+}
+
+func TestBackfillKeepsEventsRaisedOutsideTheRules(t *testing.T) {
+	db, fleet, vehicles := setupDB(t)
+
+	kept := []string{"zone_speeding", "unauthorized_zone", "route_deviation", "behavior_anomaly"}
+	for _, eventType := range kept {
+		event := models.RiskEvent{VehicleID: vehicles[0].ID, EventType: eventType, Severity: "medium", Timestamp: day.Add(12 * time.Hour)}
+		require.NoError(t, db.Omit("Vehicle", "Driver").Create(&event).Error)
+	}
+
+	opts := Options{FleetID: fleet.ID, From: day, To: day.AddDate(0, 0, 1), Replace: true, BatchSize: 50}
+	_, err := Run(t.Context(), db, newPolicies(t, db), opts, time.Hour, nil)
+	require.NoError(t, err)
+
+	var types []string
+	require.NoError(t, db.Model(&models.RiskEvent{}).Where("vehicle_id = ?", vehicles[0].ID).Order("id").Pluck("event_type", &types).Error)
+	assert.Equal(t, append(kept, risk.RuleSpeeding), types)
+}
+
+func TestBackfillRelinksCrashIncidents(t *testing.T) {
+	db, fleet, vehicles := setupDB(t)
+
+	// An impact and a stop in the afternoon
+	impactAt := day.Add(15 * time.Hour)
+	samples := []struct {
+		offset              time.Duration
+		speed, acceleration float64
+	}{
+		{-2 * time.Second, 45, 0},
+		{-time.Second, 45, 0},
+		{0, 42, -45},
+		{3 * time.Second, 0, 0},
+	}
+	for _, sample := range samples {
+		event := models.TelemetryEvent{
+			VehicleID:    vehicles[0].ID,
+			EventType:    "speed",
+			Timestamp:    impactAt.Add(sample.offset),
+			Speed:        &sample.speed,
+			Acceleration: &sample.acceleration,
+			Data:         "{}",
+		}
+		require.NoError(t, db.Omit("Vehicle").Create(&event).Error)
+	}
+
+	crash := models.RiskEvent{VehicleID: vehicles[0].ID, EventType: risk.RuleCrash, Severity: "critical", Timestamp: impactAt}
+	require.NoError(t, db.Omit("Vehicle", "Driver").Create(&crash).Error)
+	opened := models.Incident{FleetID: fleet.ID, VehicleID: vehicles[0].ID, RiskEventID: &crash.ID, Type: "crash", Status: "acknowledged", OccurredAt: impactAt}
+	require.NoError(t, db.Omit("Fleet", "Vehicle", "Driver", "RiskEvent", "Updates").Create(&opened).Error)
+
+	opts := Options{FleetID: fleet.ID, From: day, To: day.AddDate(0, 0, 1), Replace: true, BatchSize: 50}
+	_, err := Run(t.Context(), db, newPolicies(t, db), opts, time.Hour, nil)
+	require.NoError(t, err)
+
+	var regenerated models.RiskEvent
+	require.NoError(t, db.Where("event_type = ?", risk.RuleCrash).First(&regenerated).Error)
+	assert.NotEqual(t, crash.ID, regenerated.ID)
+
+	var incident models.Incident
+	require.NoError(t, db.First(&incident, opened.ID).Error)
+	require.NotNil(t, incident.RiskEventID)
+	assert.Equal(t, regenerated.ID, *incident.RiskEventID)
+	assert.Equal(t, "acknowledged", incident.Status, "the response to the incident is kept")
+}
+
+func TestBackfillWithoutReplaceOnlyAnalyzesUnprocessedTelemetry(t *testing.T) {
+	db, fleet, vehicles := setupDB(t)
+	require.NoError(t, db.Model(&models.TelemetryEvent{}).
+		Where("vehicle_id = ?", vehicles[0].ID).
+		Update("processed_at", time.Now()).Error)
+
+	opts := Options{FleetID: fleet.ID, From: day, To: day.AddDate(0, 0, 1), BatchSize: 1000}
+	job, err := Run(t.Context(), db, newPolicies(t, db), opts, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(61), job.EventsProcessed)
+
+	risks := riskEvents(t, db)
+	require.Len(t, risks, 1)
+	assert.Equal(t, vehicles[1].ID, risks[0].VehicleID)
+
+	var unprocessed int64
+	require.NoError(t, db.Model(&models.TelemetryEvent{}).Where("processed_at IS NULL").Count(&unprocessed).Error)
+	assert.Zero(t, unprocessed)
+}
+
+func TestBackfillResumesAfterInterruption(t *testing.T) {
+	db, fleet, _ := setupDB(t)
+	opts := Options{FleetID: fleet.ID, From: day, To: day.AddDate(0, 0, 1), Replace: true, BatchSize: 61}
+
+	// Interrupted once the first vehicle is done
+	ctx, cancel := context.WithCancel(t.Context())
+	first, err := Run(ctx, db, newPolicies(t, db), opts, time.Hour, func(Progress) { cancel() })
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusRunning, first.Status)
+	assert.Equal(t, int64(61), first.EventsProcessed)
+	require.Len(t, riskEvents(t, db), 1)
+
+	// Running the same command again picks up where it stopped, without
+	// clearing the events already stored
+	resumed, err := Run(t.Context(), db, newPolicies(t, db), opts, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, resumed.ID)
+	assert.Equal(t, StatusCompleted, resumed.Status)
+	assert.Equal(t, int64(122), resumed.EventsProcessed)
+	assert.Equal(t, int64(2), resumed.RiskEvents)
+	assert.Len(t, riskEvents(t, db), 2)
+}
+
+func TestBackfillRejectsTheLiveWindow(t *testing.T) {
+	db, fleet, _ := setupDB(t)
+	opts := Options{FleetID: fleet.ID, From: time.Now().Add(-3 * time.Hour), To: time.Now(), BatchSize: 100}
+
+	_, err := Run(t.Context(), db, newPolicies(t, db), opts, time.Hour, nil)
+	assert.ErrorContains(t, err, "live engine")
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds application configuration
@@ -19,6 +20,9 @@ type ServerConfig struct {
 	Host      string
 	Env       string
 	JWTSecret string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 // DatabaseConfig holds database configuration
@@ -53,6 +57,9 @@ func Load() *Config {
 			Host:      getEnv("API_HOST", "0.0.0.0"),
 			Env:       getEnv("ENV", "development"),
 			JWTSecret: getEnv("JWT_SECRET", ""),
+
+			AccessTokenTTL:  getEnvAsDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvAsDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -98,4 +105,13 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
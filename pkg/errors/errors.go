@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
 
@@ -24,8 +25,17 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// ErrFleetAccessDenied is reported when a query or write reaches data outside
+// the caller's fleets
+var ErrFleetAccessDenied = stderrors.New("access denied to this fleet")
+
 // Error constructors for common scenarios
 func DatabaseError(operation string, err error) *AppError {
+	// Tenant violations surface from the database layer but are not server faults
+	if stderrors.Is(err, ErrFleetAccessDenied) {
+		return FleetAccessDeniedError(operation, err)
+	}
+
 	return &AppError{
 		Code:       "database_error",
 		Message:    fmt.Sprintf("Database operation failed: %s", operation),
@@ -60,6 +70,18 @@ func NotFoundError(resource string, id interface{}) *AppError {
 	}
 }
 
+func FleetAccessDeniedError(operation string, err error) *AppError {
+	return &AppError{
+		Code:       "fleet_access_denied",
+		Message:    "Access denied to this fleet",
+		HTTPStatus: http.StatusForbidden,
+		Internal:   err,
+		Context: map[string]interface{}{
+			"operation": operation,
+		},
+	}
+}
+
 func TelemetryIngestionError(vehicleID uint, err error) *AppError {
 	return &AppError{
 		Code:       "telemetry_ingestion_error",
@@ -0,0 +1,82 @@
+package geo
+
+import "math"
+
+// EarthRadiusMiles is the mean radius of the Earth
+const EarthRadiusMiles = 3958.8
+
+// MetersPerMile converts distances in miles to meters
+const MetersPerMile = 1609.344
+
+// Point is a coordinate, such as a polygon vertex or a waypoint of a route
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Valid reports whether the coordinate is within range
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// DistanceMiles returns the great-circle distance between two coordinates
+// using the haversine formula
+func DistanceMiles(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusMiles * math.Asin(math.Sqrt(a))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// BearingDegrees returns the initial compass bearing from the first coordinate
+// to the second, in degrees clockwise from north in [0, 360)
+func BearingDegrees(lat1, lon1, lat2, lon2 float64) float64 {
+	dLon := radians(lon2 - lon1)
+	y := math.Sin(dLon) * math.Cos(radians(lat2))
+	x := math.Cos(radians(lat1))*math.Sin(radians(lat2)) -
+		math.Sin(radians(lat1))*math.Cos(radians(lat2))*math.Cos(dLon)
+	bearing := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(bearing+360, 360)
+}
+
+// HeadingChange returns the signed turn from one bearing to another in degrees,
+// in [-180, 180); positive turns are clockwise
+func HeadingChange(from, to float64) float64 {
+	return math.Mod(math.Mod(to-from+180, 360)+360, 360) - 180
+}
+
+// DistanceToPathMeters returns the shortest distance from a coordinate to a
+// polyline. Segments are measured on a flat projection around the coordinate,
+// which is accurate for the short segments of a road route.
+func DistanceToPathMeters(lat, lon float64, path []Point) float64 {
+	metersPerDegree := radians(1) * EarthRadiusMiles * MetersPerMile
+	scale := math.Cos(radians(lat))
+	project := func(p Point) (float64, float64) {
+		return (p.Longitude - lon) * scale * metersPerDegree, (p.Latitude - lat) * metersPerDegree
+	}
+
+	best := math.Inf(1)
+	for i := range path {
+		ax, ay := project(path[i])
+		if i == 0 {
+			best = math.Hypot(ax, ay)
+			continue
+		}
+		bx, by := project(path[i-1])
+
+		// Closest point of the segment to the origin
+		dx, dy := ax-bx, ay-by
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(bx*dx+by*dy)/length))
+		}
+		best = math.Min(best, math.Hypot(bx+t*dx, by+t*dy))
+	}
+	return best
+}
@@ -0,0 +1,44 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistanceMiles(t *testing.T) {
+	// San Francisco to Los Angeles
+	assert.InDelta(t, 347.4, DistanceMiles(37.7749, -122.4194, 34.0522, -118.2437), 1.0)
+
+	// One degree of latitude
+	assert.InDelta(t, 69.1, DistanceMiles(40, -100, 41, -100), 0.1)
+
+	assert.Zero(t, DistanceMiles(40, -100, 40, -100))
+}
+
+func TestBearingDegrees(t *testing.T) {
+	assert.InDelta(t, 0, BearingDegrees(40, -100, 41, -100), 0.01)
+	assert.InDelta(t, 180, BearingDegrees(41, -100, 40, -100), 0.01)
+	assert.InDelta(t, 90, BearingDegrees(0, 10, 0, 11), 0.01)
+	assert.InDelta(t, 270, BearingDegrees(0, 11, 0, 10), 0.01)
+}
+
+func TestHeadingChange(t *testing.T) {
+	assert.Equal(t, 90.0, HeadingChange(0, 90))
+	assert.Equal(t, -90.0, HeadingChange(90, 0))
+	assert.Equal(t, 20.0, HeadingChange(350, 10))
+	assert.Equal(t, -20.0, HeadingChange(10, 350))
+	assert.Equal(t, -180.0, HeadingChange(0, 180))
+}
+
+func TestDistanceToPathMeters(t *testing.T) {
+	// An L-shaped route: 0.01 degrees east along the equator, then north
+	path := []Point{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 0.01}, {Latitude: 0.01, Longitude: 0.01}}
+	metersPerThousandth := DistanceMiles(0, 0, 0.001, 0) * MetersPerMile
+
+	assert.InDelta(t, 0, DistanceToPathMeters(0, 0.005, path), 0.01)
+	assert.InDelta(t, metersPerThousandth, DistanceToPathMeters(0.001, 0.005, path), 0.5, "beside the first leg")
+	assert.InDelta(t, 2*metersPerThousandth, DistanceToPathMeters(0.005, 0.012, path), 0.5, "beside the second leg")
+	assert.InDelta(t, 3*metersPerThousandth, DistanceToPathMeters(0, -0.003, path), 0.5, "before the start")
+	assert.InDelta(t, metersPerThousandth, DistanceToPathMeters(0, 0, []Point{{Latitude: 0.001, Longitude: 0}}), 0.5, "a single waypoint")
+}
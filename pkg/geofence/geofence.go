@@ -0,0 +1,212 @@
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Shapes of a geofence
+const (
+	ShapeCircle  = "circle"
+	ShapePolygon = "polygon"
+)
+
+// Categories of a geofence
+const (
+	CategoryDepot        = "depot"
+	CategoryCustomerSite = "customer_site"
+	CategorySchoolZone   = "school_zone"
+	CategoryOther        = "other"
+)
+
+// Statuses of a geofence; inactive fences are kept but not monitored
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+// Types of a GeofenceEvent
+const (
+	EventEnter = "enter"
+	EventExit  = "exit"
+)
+
+// Risk event types raised by the Monitor
+const (
+	EventZoneSpeeding     = "zone_speeding"
+	EventUnauthorizedZone = "unauthorized_zone"
+)
+
+// Limits on the shapes a fence can take. Larger areas make poor fences and
+// bloat the index; draw them as several smaller ones.
+const (
+	MaxRadiusMeters = 50000
+	MaxVertices     = 500
+	maxSpanDegrees  = 5
+)
+
+// Fence is a geofence compiled for point lookups
+type Fence struct {
+	ID         uint
+	FleetID    uint
+	Name       string
+	Category   string
+	SpeedLimit *float64 // mph
+	Restricted bool
+
+	allowed map[string]bool
+
+	shape       string
+	center      geo.Point
+	radiusMiles float64
+	polygon     []geo.Point
+	box         bounds
+}
+
+// bounds is a bounding box in degrees
+type bounds struct {
+	minLat, maxLat, minLng, maxLng float64
+}
+
+func (b bounds) contains(lat, lng float64) bool {
+	return lat >= b.minLat && lat <= b.maxLat && lng >= b.minLng && lng <= b.maxLng
+}
+
+// Compile validates a stored geofence and prepares it for lookups
+func Compile(g models.Geofence) (*Fence, error) {
+	fence := &Fence{
+		ID:         g.ID,
+		FleetID:    g.FleetID,
+		Name:       g.Name,
+		Category:   g.Category,
+		SpeedLimit: g.SpeedLimit,
+		Restricted: g.Restricted,
+		allowed:    make(map[string]bool),
+		shape:      g.Shape,
+	}
+	if g.SpeedLimit != nil && *g.SpeedLimit <= 0 {
+		return nil, errors.New("speed limit must be positive")
+	}
+	for _, class := range ParseVehicleClasses(g.AllowedVehicleClasses) {
+		fence.allowed[class] = true
+	}
+
+	switch g.Shape {
+	case ShapeCircle:
+		if g.CenterLatitude == nil || g.CenterLongitude == nil {
+			return nil, errors.New("a circle needs a center")
+		}
+		fence.center = geo.Point{Latitude: *g.CenterLatitude, Longitude: *g.CenterLongitude}
+		if err := validatePoint(fence.center); err != nil {
+			return nil, fmt.Errorf("center: %w", err)
+		}
+		if g.RadiusMeters == nil || *g.RadiusMeters <= 0 || *g.RadiusMeters > MaxRadiusMeters {
+			return nil, fmt.Errorf("a circle needs a radius between 0 and %d meters", MaxRadiusMeters)
+		}
+		fence.radiusMiles = *g.RadiusMeters / geo.MetersPerMile
+
+		latSpan := fence.radiusMiles / geo.EarthRadiusMiles * 180 / math.Pi
+		lngSpan := latSpan / math.Max(math.Cos(fence.center.Latitude*math.Pi/180), 0.01)
+		fence.box = bounds{
+			minLat: fence.center.Latitude - latSpan,
+			maxLat: fence.center.Latitude + latSpan,
+			minLng: fence.center.Longitude - lngSpan,
+			maxLng: fence.center.Longitude + lngSpan,
+		}
+
+	case ShapePolygon:
+		vertices, err := ParsePolygon(g.Polygon)
+		if err != nil {
+			return nil, err
+		}
+		fence.polygon = vertices
+		fence.box = bounds{minLat: 90, maxLat: -90, minLng: 180, maxLng: -180}
+		for _, vertex := range vertices {
+			fence.box.minLat = math.Min(fence.box.minLat, vertex.Latitude)
+			fence.box.maxLat = math.Max(fence.box.maxLat, vertex.Latitude)
+			fence.box.minLng = math.Min(fence.box.minLng, vertex.Longitude)
+			fence.box.maxLng = math.Max(fence.box.maxLng, vertex.Longitude)
+		}
+		if fence.box.maxLat-fence.box.minLat > maxSpanDegrees || fence.box.maxLng-fence.box.minLng > maxSpanDegrees {
+			return nil, fmt.Errorf("a polygon may span at most %d degrees", maxSpanDegrees)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown shape %q", g.Shape)
+	}
+	return fence, nil
+}
+
+// ParsePolygon decodes and validates a stored polygon. A closing vertex equal
+// to the first one is dropped.
+func ParsePolygon(polygon string) ([]geo.Point, error) {
+	var vertices []geo.Point
+	if err := json.Unmarshal([]byte(polygon), &vertices); err != nil {
+		return nil, errors.New("polygon must be a JSON array of points")
+	}
+	if len(vertices) > 1 && vertices[0] == vertices[len(vertices)-1] {
+		vertices = vertices[:len(vertices)-1]
+	}
+	if len(vertices) < 3 {
+		return nil, errors.New("a polygon needs at least 3 vertices")
+	}
+	if len(vertices) > MaxVertices {
+		return nil, fmt.Errorf("a polygon may have at most %d vertices", MaxVertices)
+	}
+	for i, vertex := range vertices {
+		if err := validatePoint(vertex); err != nil {
+			return nil, fmt.Errorf("vertex %d: %w", i, err)
+		}
+	}
+	return vertices, nil
+}
+
+func validatePoint(p geo.Point) error {
+	if !p.Valid() {
+		return errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+	return nil
+}
+
+// ParseVehicleClasses splits a stored list of vehicle classes
+func ParseVehicleClasses(classes string) []string {
+	var parsed []string
+	for _, class := range strings.Split(classes, ",") {
+		if class = strings.ToLower(strings.TrimSpace(class)); class != "" {
+			parsed = append(parsed, class)
+		}
+	}
+	return parsed
+}
+
+// Contains reports whether the point lies inside the fence
+func (f *Fence) Contains(lat, lng float64) bool {
+	if !f.box.contains(lat, lng) {
+		return false
+	}
+	if f.shape == ShapeCircle {
+		return geo.DistanceMiles(f.center.Latitude, f.center.Longitude, lat, lng) <= f.radiusMiles
+	}
+
+	// Ray casting; at fence sizes the curvature of the earth does not matter
+	inside := false
+	for i, j := 0, len(f.polygon)-1; i < len(f.polygon); j, i = i, i+1 {
+		a, b := f.polygon[i], f.polygon[j]
+		if (a.Latitude > lat) != (b.Latitude > lat) &&
+			lng < (b.Longitude-a.Longitude)*(lat-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Allows reports whether a vehicle of the class may enter the fence
+func (f *Fence) Allows(vehicleClass string) bool {
+	return !f.Restricted || f.allowed[strings.ToLower(vehicleClass)]
+}
@@ -0,0 +1,123 @@
+package geofence
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func circle(id, fleetID uint, lat, lng, radiusMeters float64) models.Geofence {
+	return models.Geofence{ID: id, FleetID: fleetID, Name: "Circle", Shape: ShapeCircle,
+		CenterLatitude: &lat, CenterLongitude: &lng, RadiusMeters: &radiusMeters, Status: StatusActive}
+}
+
+func polygon(id, fleetID uint, vertices ...geo.Point) models.Geofence {
+	encoded, _ := json.Marshal(vertices)
+	return models.Geofence{ID: id, FleetID: fleetID, Name: "Polygon", Shape: ShapePolygon, Polygon: string(encoded), Status: StatusActive}
+}
+
+func pt(lat, lng float64) geo.Point {
+	return geo.Point{Latitude: lat, Longitude: lng}
+}
+
+func compile(t *testing.T, g models.Geofence) *Fence {
+	fence, err := Compile(g)
+	require.NoError(t, err)
+	return fence
+}
+
+func TestCircleContains(t *testing.T) {
+	// 500 m around a depot in Chicago
+	fence := compile(t, circle(1, 1, 41.8781, -87.6298, 500))
+
+	assert.True(t, fence.Contains(41.8781, -87.6298))
+	assert.True(t, fence.Contains(41.8810, -87.6298), "about 320 m north")
+	assert.False(t, fence.Contains(41.8840, -87.6298), "about 650 m north")
+	assert.False(t, fence.Contains(41.8781, -87.6220), "about 650 m east")
+}
+
+func TestPolygonContains(t *testing.T) {
+	// An L-shaped yard
+	fence := compile(t, polygon(1, 1,
+		pt(0, 0), pt(0, 0.02), pt(0.01, 0.02), pt(0.01, 0.01), pt(0.02, 0.01), pt(0.02, 0), pt(0, 0)))
+
+	assert.True(t, fence.Contains(0.005, 0.015))
+	assert.True(t, fence.Contains(0.015, 0.005))
+	assert.False(t, fence.Contains(0.015, 0.015), "the notch of the L")
+	assert.False(t, fence.Contains(0.03, 0.005))
+}
+
+func TestCompileRejectsInvalidShapes(t *testing.T) {
+	lat, lng := 41.0, -87.0
+	tests := map[string]models.Geofence{
+		"no radius":     {Shape: ShapeCircle, CenterLatitude: &lat, CenterLongitude: &lng},
+		"huge radius":   circle(1, 1, lat, lng, MaxRadiusMeters+1),
+		"no center":     {Shape: ShapeCircle, RadiusMeters: &lat},
+		"bad latitude":  circle(1, 1, 91, lng, 100),
+		"two vertices":  polygon(1, 1, pt(0, 0), pt(0, 1)),
+		"not json":      {Shape: ShapePolygon, Polygon: "0,0 1,1 2,2"},
+		"huge polygon":  polygon(1, 1, pt(0, 0), pt(0, 10), pt(10, 10)),
+		"unknown shape": {Shape: "square"},
+		"negative limit": func() models.Geofence {
+			g := circle(1, 1, lat, lng, 100)
+			limit := -5.0
+			g.SpeedLimit = &limit
+			return g
+		}(),
+	}
+	for name, g := range tests {
+		_, err := Compile(g)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRestrictedFencesAllowListedClasses(t *testing.T) {
+	g := circle(1, 1, 41, -87, 100)
+	assert.True(t, compile(t, g).Allows("van"))
+
+	g.Restricted = true
+	g.AllowedVehicleClasses = "Truck, tanker"
+	fence := compile(t, g)
+	assert.False(t, fence.Allows("van"))
+	assert.False(t, fence.Allows(""))
+	assert.True(t, fence.Allows("truck"))
+	assert.True(t, fence.Allows("Tanker"))
+}
+
+func TestIndexLookup(t *testing.T) {
+	var fences []*Fence
+	// A grid of 2,500 small fences for fleet 1, about 1 km apart
+	id := uint(1)
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 50; j++ {
+			fences = append(fences, compile(t, circle(id, 1, 40+float64(i)*0.01, -80+float64(j)*0.01, 200)))
+			id++
+		}
+	}
+	// A large fence covering the whole grid and one of another fleet
+	fences = append(fences,
+		compile(t, polygon(5000, 1, pt(39, -81), pt(39, -79), pt(41, -79), pt(41, -81))),
+		compile(t, circle(6000, 2, 40.1, -79.9, 200)))
+	index := NewIndex(fences)
+	assert.Equal(t, 2502, index.Len())
+
+	found := index.Lookup(1, 40.1, -79.9)
+	require.Len(t, found, 2)
+	assert.Equal(t, uint(10*50+10+1), found[0].ID)
+	assert.Equal(t, uint(5000), found[1].ID)
+
+	found = index.Lookup(1, 40.105, -79.9)
+	require.Len(t, found, 1, "between two small fences")
+	assert.Equal(t, uint(5000), found[0].ID)
+
+	found = index.Lookup(2, 40.1, -79.9)
+	require.Len(t, found, 1)
+	assert.Equal(t, uint(6000), found[0].ID)
+
+	assert.Empty(t, index.Lookup(3, 40.1, -79.9))
+}
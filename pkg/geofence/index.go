@@ -0,0 +1,84 @@
+package geofence
+
+import (
+	"math"
+	"sort"
+)
+
+// cellDegrees is the size of an index grid cell, about 5.5 km of latitude
+const cellDegrees = 0.05
+
+// maxFenceCells caps the cells a fence is filed under. Larger fences are kept
+// in a per-fleet list that every lookup checks.
+const maxFenceCells = 256
+
+type cellKey struct {
+	fleetID  uint
+	lat, lng int
+}
+
+// Index finds the fences around a point without testing every fence of the
+// fleet. Fences are filed under the grid cells their bounding box overlaps,
+// so a lookup only tests the few fences sharing the point's cell.
+type Index struct {
+	cells  map[cellKey][]*Fence
+	large  map[uint][]*Fence // by fleet
+	fences map[uint]*Fence   // by ID
+}
+
+// NewIndex builds an index over fences
+func NewIndex(fences []*Fence) *Index {
+	index := &Index{
+		cells:  make(map[cellKey][]*Fence),
+		large:  make(map[uint][]*Fence),
+		fences: make(map[uint]*Fence, len(fences)),
+	}
+	for _, fence := range fences {
+		index.fences[fence.ID] = fence
+
+		minLat, maxLat := cell(fence.box.minLat), cell(fence.box.maxLat)
+		minLng, maxLng := cell(fence.box.minLng), cell(fence.box.maxLng)
+		if (maxLat-minLat+1)*(maxLng-minLng+1) > maxFenceCells {
+			index.large[fence.FleetID] = append(index.large[fence.FleetID], fence)
+			continue
+		}
+		for lat := minLat; lat <= maxLat; lat++ {
+			for lng := minLng; lng <= maxLng; lng++ {
+				key := cellKey{fence.FleetID, lat, lng}
+				index.cells[key] = append(index.cells[key], fence)
+			}
+		}
+	}
+	return index
+}
+
+func cell(degrees float64) int {
+	return int(math.Floor(degrees / cellDegrees))
+}
+
+// Lookup returns the fleet's fences containing the point, ordered by ID
+func (ix *Index) Lookup(fleetID uint, lat, lng float64) []*Fence {
+	var found []*Fence
+	for _, fence := range ix.cells[cellKey{fleetID, cell(lat), cell(lng)}] {
+		if fence.Contains(lat, lng) {
+			found = append(found, fence)
+		}
+	}
+	for _, fence := range ix.large[fleetID] {
+		if fence.Contains(lat, lng) {
+			found = append(found, fence)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+// Fence returns an indexed fence by ID, or nil
+func (ix *Index) Fence(id uint) *Fence {
+	return ix.fences[id]
+}
+
+// Len returns the number of indexed fences
+func (ix *Index) Len() int {
+	return len(ix.fences)
+}
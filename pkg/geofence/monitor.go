@@ -0,0 +1,431 @@
+package geofence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Config tunes how the monitor reports zone speeding
+type Config struct {
+	// SpeedTolerance (mph) is how far above a zone's limit speeding starts
+	SpeedTolerance float64
+	// MinSpeedingDuration drops zone speeding shorter than this
+	MinSpeedingDuration time.Duration
+	// MaxGap ends a zone speeding episode when the vehicle stops reporting this long
+	MaxGap time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SpeedTolerance:      3,
+		MinSpeedingDuration: 5 * time.Second,
+		MaxGap:              time.Minute,
+	}
+}
+
+// speedingGrades grades zone speeding by how far the peak speed was over the
+// limit (mph), in ascending order
+var speedingGrades = []struct {
+	over      float64
+	severity  string
+	riskScore float64
+}{
+	{0, "medium", 60},
+	{10, "high", 80},
+	{20, "critical", 95},
+}
+
+// Result is what the monitor made of one telemetry event
+type Result struct {
+	Events []models.GeofenceEvent
+	Risks  []models.RiskEvent
+}
+
+// Monitor evaluates telemetry against the active geofences of each vehicle's
+// fleet. It records vehicles entering and leaving fences, raises
+// UNAUTHORIZED_ZONE when a vehicle enters a restricted fence its class is not
+// allowed in, and ZONE_SPEEDING when it exceeds a fence's speed limit. Events
+// must be observed in timestamp order per vehicle; late events are ignored.
+// Refresh reloads the fences when they change.
+type Monitor struct {
+	db     *gorm.DB
+	config Config
+
+	mu          sync.Mutex
+	index       *Index
+	fingerprint string
+	vehicles    map[uint]*vehicleState
+}
+
+type vehicleState struct {
+	last   time.Time
+	visits map[uint]*visit // by fence ID
+}
+
+// visit is a vehicle's stay inside a fence
+type visit struct {
+	enteredAt time.Time
+	speeding  *zoneSpeeding
+}
+
+// zoneSpeeding is an ongoing zone speeding episode
+type zoneSpeeding struct {
+	fenceID   uint
+	fenceName string
+	limit     float64
+	driverID  *uint
+
+	start     time.Time
+	last      time.Time
+	latitude  *float64
+	longitude *float64
+	peak      float64
+}
+
+func NewMonitor(db *gorm.DB, config Config) *Monitor {
+	return &Monitor{
+		db:       db,
+		config:   config,
+		index:    NewIndex(nil),
+		vehicles: make(map[uint]*vehicleState),
+	}
+}
+
+// Fences returns the number of fences being monitored
+func (m *Monitor) Fences() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.index.Len()
+}
+
+// Refresh reloads the fences if any was created, updated or deleted since the
+// last load, and reports whether it did. Invalid fences are logged and
+// skipped. Vehicles inside a fence that is gone or inactive leave it silently.
+func (m *Monitor) Refresh(ctx context.Context) (bool, error) {
+	var geofences []models.Geofence
+	if err := m.db.WithContext(ctx).Order("id").Find(&geofences).Error; err != nil {
+		return false, err
+	}
+
+	fingerprint := geofenceFingerprint(geofences)
+	m.mu.Lock()
+	unchanged := fingerprint == m.fingerprint
+	m.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	fences := make([]*Fence, 0, len(geofences))
+	for _, g := range geofences {
+		if g.Status != StatusActive {
+			continue
+		}
+		fence, err := Compile(g)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"geofence_id": g.ID,
+				"fleet_id":    g.FleetID,
+			}).Error("Ignoring invalid geofence")
+			continue
+		}
+		fences = append(fences, fence)
+	}
+	index := NewIndex(fences)
+
+	m.mu.Lock()
+	m.index = index
+	m.fingerprint = fingerprint
+	for _, state := range m.vehicles {
+		for id := range state.visits {
+			if index.Fence(id) == nil {
+				delete(state.visits, id)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"geofences": len(geofences),
+		"active":    index.Len(),
+	}).Info("Geofences reloaded")
+	return true, nil
+}
+
+// Restore picks up the visits still open according to the stored events, so
+// that vehicles parked inside a fence are not reported entering it again after
+// a restart. Call it after the first Refresh.
+func (m *Monitor) Restore(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+	latest := db.Model(&models.GeofenceEvent{}).Select("MAX(id)").Group("vehicle_id, geofence_id")
+	return m.restore(db.Where("id IN (?) AND type = ?", latest, EventEnter))
+}
+
+// Reset drops the vehicle's state, including any zone speeding in progress,
+// and restores its open visits from the stored events
+func (m *Monitor) Reset(ctx context.Context, vehicleID uint) error {
+	db := m.db.WithContext(ctx)
+	latest := db.Model(&models.GeofenceEvent{}).Select("MAX(id)").Where("vehicle_id = ?", vehicleID).Group("geofence_id")
+
+	m.mu.Lock()
+	delete(m.vehicles, vehicleID)
+	m.mu.Unlock()
+	return m.restore(db.Where("id IN (?) AND type = ?", latest, EventEnter))
+}
+
+// restore opens the visits of the enter events the query selects
+func (m *Monitor) restore(query *gorm.DB) error {
+	var open []models.GeofenceEvent
+	if err := query.Find(&open).Error; err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range open {
+		if m.index.Fence(event.GeofenceID) == nil {
+			continue
+		}
+		state := m.vehicle(event.VehicleID)
+		if _, ok := state.visits[event.GeofenceID]; !ok {
+			state.visits[event.GeofenceID] = &visit{enteredAt: event.Timestamp}
+		}
+		if event.Timestamp.After(state.last) {
+			state.last = event.Timestamp
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) vehicle(id uint) *vehicleState {
+	state, ok := m.vehicles[id]
+	if !ok {
+		state = &vehicleState{visits: make(map[uint]*visit)}
+		m.vehicles[id] = state
+	}
+	return state
+}
+
+// Observe feeds one event of the vehicle to the monitor. Events without a
+// position cannot move a vehicle in or out of a fence but still count towards
+// zone speeding in the fences it is in.
+func (m *Monitor) Observe(event *models.TelemetryEvent, vehicle models.Vehicle) Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result Result
+	state, ok := m.vehicles[event.VehicleID]
+	if !ok {
+		if event.Latitude == nil || event.Longitude == nil {
+			return result
+		}
+		state = m.vehicle(event.VehicleID)
+	} else if event.Timestamp.Before(state.last) {
+		return result
+	}
+
+	// Speeding interrupted by a gap ends where the vehicle was last heard from
+	for _, id := range visitIDs(state) {
+		v := state.visits[id]
+		if v.speeding != nil && event.Timestamp.Sub(v.speeding.last) > m.config.MaxGap {
+			result.Risks = m.closeSpeeding(result.Risks, event.VehicleID, v)
+		}
+	}
+	state.last = event.Timestamp
+
+	if event.Latitude != nil && event.Longitude != nil {
+		inside := m.index.Lookup(vehicle.FleetID, *event.Latitude, *event.Longitude)
+		isInside := make(map[uint]bool, len(inside))
+		for _, fence := range inside {
+			isInside[fence.ID] = true
+		}
+
+		for _, id := range visitIDs(state) {
+			if isInside[id] {
+				continue
+			}
+			v := state.visits[id]
+			result.Risks = m.closeSpeeding(result.Risks, event.VehicleID, v)
+			dwell := int(event.Timestamp.Sub(v.enteredAt).Seconds())
+			result.Events = append(result.Events, newEvent(m.index.Fence(id), event, EventExit, &dwell))
+			delete(state.visits, id)
+		}
+
+		for _, fence := range inside {
+			if _, ok := state.visits[fence.ID]; ok {
+				continue
+			}
+			state.visits[fence.ID] = &visit{enteredAt: event.Timestamp}
+			result.Events = append(result.Events, newEvent(fence, event, EventEnter, nil))
+			if !fence.Allows(vehicle.VehicleClass) {
+				result.Risks = append(result.Risks, unauthorizedEntry(fence, event, vehicle.VehicleClass))
+			}
+		}
+	}
+
+	if event.Speed != nil {
+		for _, id := range visitIDs(state) {
+			fence := m.index.Fence(id)
+			if fence.SpeedLimit != nil {
+				result.Risks = m.trackSpeeding(result.Risks, fence, state.visits[id], event)
+			}
+		}
+	}
+	return result
+}
+
+// Flush ends the zone speeding of vehicles that have not reported within
+// MaxGap of now. Their visits stay open: a vehicle parked in a depot goes
+// quiet without leaving it.
+func (m *Monitor) Flush(now time.Time) []models.RiskEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var risks []models.RiskEvent
+	for vehicleID, state := range m.vehicles {
+		for _, id := range visitIDs(state) {
+			v := state.visits[id]
+			if v.speeding != nil && now.Sub(v.speeding.last) > m.config.MaxGap {
+				risks = m.closeSpeeding(risks, vehicleID, v)
+			}
+		}
+	}
+	return risks
+}
+
+func (m *Monitor) trackSpeeding(risks []models.RiskEvent, fence *Fence, v *visit, event *models.TelemetryEvent) []models.RiskEvent {
+	speed := *event.Speed
+	if speed <= *fence.SpeedLimit+m.config.SpeedTolerance {
+		return m.closeSpeeding(risks, event.VehicleID, v)
+	}
+
+	if v.speeding == nil {
+		v.speeding = &zoneSpeeding{
+			fenceID:   fence.ID,
+			fenceName: fence.Name,
+			limit:     *fence.SpeedLimit,
+			driverID:  event.DriverID,
+			start:     event.Timestamp,
+			latitude:  event.Latitude,
+			longitude: event.Longitude,
+		}
+	}
+	v.speeding.last = event.Timestamp
+	if speed > v.speeding.peak {
+		v.speeding.peak = speed
+	}
+	return risks
+}
+
+// closeSpeeding ends the visit's zone speeding episode, if any, and reports it
+// if it lasted long enough
+func (m *Monitor) closeSpeeding(risks []models.RiskEvent, vehicleID uint, v *visit) []models.RiskEvent {
+	episode := v.speeding
+	if episode == nil {
+		return risks
+	}
+	v.speeding = nil
+
+	duration := episode.last.Sub(episode.start)
+	if duration < m.config.MinSpeedingDuration {
+		return risks
+	}
+
+	over := episode.peak - episode.limit
+	grade := speedingGrades[0]
+	for _, candidate := range speedingGrades {
+		if over >= candidate.over {
+			grade = candidate
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"geofence_id":      episode.fenceID,
+		"geofence_name":    episode.fenceName,
+		"speed_limit":      episode.limit,
+		"peak_speed":       episode.peak,
+		"duration_seconds": duration.Seconds(),
+	})
+	return append(risks, models.RiskEvent{
+		VehicleID: vehicleID,
+		DriverID:  episode.driverID,
+		EventType: EventZoneSpeeding,
+		Severity:  grade.severity,
+		RiskScore: grade.riskScore,
+		Timestamp: episode.start,
+		Latitude:  episode.latitude,
+		Longitude: episode.longitude,
+		Description: fmt.Sprintf("Speeding in %s: %.0f mph in a %.0f mph zone for %.0f seconds",
+			episode.fenceName, episode.peak, episode.limit, duration.Seconds()),
+		Data: string(data),
+	})
+}
+
+func unauthorizedEntry(fence *Fence, event *models.TelemetryEvent, vehicleClass string) models.RiskEvent {
+	data, _ := json.Marshal(map[string]interface{}{
+		"geofence_id":   fence.ID,
+		"geofence_name": fence.Name,
+		"vehicle_class": vehicleClass,
+	})
+	return models.RiskEvent{
+		VehicleID:   event.VehicleID,
+		DriverID:    event.DriverID,
+		EventType:   EventUnauthorizedZone,
+		Severity:    "high",
+		RiskScore:   80,
+		Timestamp:   event.Timestamp,
+		Latitude:    event.Latitude,
+		Longitude:   event.Longitude,
+		Description: fmt.Sprintf("Unauthorized entry into restricted zone %s", fence.Name),
+		Data:        string(data),
+	}
+}
+
+// newEvent records a vehicle crossing the fence. The fence is attached for
+// notifications; it is not saved with the event.
+func newEvent(fence *Fence, event *models.TelemetryEvent, eventType string, dwellSeconds *int) models.GeofenceEvent {
+	return models.GeofenceEvent{
+		GeofenceID: fence.ID,
+		Geofence: models.Geofence{
+			ID:       fence.ID,
+			FleetID:  fence.FleetID,
+			Name:     fence.Name,
+			Category: fence.Category,
+		},
+		VehicleID:    event.VehicleID,
+		DriverID:     event.DriverID,
+		Type:         eventType,
+		Timestamp:    event.Timestamp,
+		Latitude:     event.Latitude,
+		Longitude:    event.Longitude,
+		DwellSeconds: dwellSeconds,
+	}
+}
+
+// visitIDs returns the fences the vehicle is in, in ID order
+func visitIDs(state *vehicleState) []uint {
+	ids := make([]uint, 0, len(state.visits))
+	for id := range state.visits {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// geofenceFingerprint changes whenever a fence is added, removed or updated
+func geofenceFingerprint(geofences []models.Geofence) string {
+	var b strings.Builder
+	for _, g := range geofences {
+		fmt.Fprintf(&b, "%d:%d;", g.ID, g.UpdatedAt.UnixNano())
+	}
+	return b.String()
+}
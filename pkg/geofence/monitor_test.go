@@ -0,0 +1,185 @@
+package geofence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+func setupDB(t *testing.T) (*gorm.DB, models.Fleet) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	return db, fleet
+}
+
+func newMonitor(t *testing.T, db *gorm.DB, geofences ...models.Geofence) *Monitor {
+	for i := range geofences {
+		geofences[i].ID = 0
+		require.NoError(t, db.Omit("Fleet").Create(&geofences[i]).Error)
+	}
+	monitor := NewMonitor(db, DefaultConfig())
+	_, err := monitor.Refresh(t.Context())
+	require.NoError(t, err)
+	return monitor
+}
+
+// at is a sample of vehicle 1 seconds after start; lat and lng are offsets in
+// thousandths of a degree from the fences' center
+func at(seconds int, lat, lng, speed float64) *models.TelemetryEvent {
+	latitude, longitude := 41+lat/1000, -87+lng/1000
+	return &models.TelemetryEvent{VehicleID: 1, Timestamp: start.Add(time.Duration(seconds) * time.Second),
+		Latitude: &latitude, Longitude: &longitude, Speed: &speed}
+}
+
+func observeAll(m *Monitor, vehicle models.Vehicle, samples ...*models.TelemetryEvent) Result {
+	var all Result
+	for _, sample := range samples {
+		result := m.Observe(sample, vehicle)
+		all.Events = append(all.Events, result.Events...)
+		all.Risks = append(all.Risks, result.Risks...)
+	}
+	return all
+}
+
+func TestMonitorRecordsEntryExitAndDwell(t *testing.T) {
+	db, fleet := setupDB(t)
+	depot := circle(0, fleet.ID, 41, -87, 300)
+	depot.Name = "North depot"
+	monitor := newMonitor(t, db, depot)
+	vehicle := models.Vehicle{ID: 1, FleetID: fleet.ID}
+
+	result := observeAll(monitor, vehicle,
+		at(0, 10, 0, 20),   // outside
+		at(60, 0, 0, 5),    // in the depot
+		at(660, 1, 0, 0),   // parked
+		at(900, 10, 0, 25), // gone
+	)
+
+	assert.Empty(t, result.Risks)
+	require.Len(t, result.Events, 2)
+	enter, exit := result.Events[0], result.Events[1]
+	assert.Equal(t, EventEnter, enter.Type)
+	assert.Equal(t, "North depot", enter.Geofence.Name)
+	assert.Equal(t, start.Add(time.Minute), enter.Timestamp)
+	assert.Nil(t, enter.DwellSeconds)
+	assert.Equal(t, EventExit, exit.Type)
+	require.NotNil(t, exit.DwellSeconds)
+	assert.Equal(t, 840, *exit.DwellSeconds)
+}
+
+func TestMonitorRaisesUnauthorizedEntry(t *testing.T) {
+	db, fleet := setupDB(t)
+	yard := circle(0, fleet.ID, 41, -87, 300)
+	yard.Name = "Fuel yard"
+	yard.Restricted = true
+	yard.AllowedVehicleClasses = "tanker"
+	monitor := newMonitor(t, db, yard)
+
+	result := observeAll(monitor, models.Vehicle{ID: 1, FleetID: fleet.ID, VehicleClass: "van"}, at(0, 0, 0, 10), at(30, 0, 0, 5))
+	require.Len(t, result.Risks, 1, "raised once per visit")
+	assert.Equal(t, EventUnauthorizedZone, result.Risks[0].EventType)
+	assert.Equal(t, "high", result.Risks[0].Severity)
+
+	tanker := at(0, 0, 0, 10)
+	tanker.VehicleID = 2
+	assert.Empty(t, monitor.Observe(tanker, models.Vehicle{ID: 2, FleetID: fleet.ID, VehicleClass: "tanker"}).Risks)
+
+	otherFleet := at(0, 0, 0, 10)
+	otherFleet.VehicleID = 3
+	assert.Empty(t, monitor.Observe(otherFleet, models.Vehicle{ID: 3, FleetID: fleet.ID + 1}).Events)
+}
+
+func TestMonitorGradesZoneSpeeding(t *testing.T) {
+	db, fleet := setupDB(t)
+	school := circle(0, fleet.ID, 41, -87, 500)
+	school.Name = "Lincoln Elementary"
+	limit := 20.0
+	school.SpeedLimit = &limit
+	monitor := newMonitor(t, db, school)
+	vehicle := models.Vehicle{ID: 1, FleetID: fleet.ID}
+
+	result := observeAll(monitor, vehicle,
+		at(0, 0, 0, 22), // within the tolerance
+		at(1, 0, 0, 28),
+		at(5, 0, 0, 33),
+		at(9, 0, 0, 26),
+		at(10, 0, 0, 18),
+		at(11, 0, 0, 30), // too short to count
+		at(12, 0, 0, 19),
+	)
+	require.Len(t, result.Risks, 1)
+	risk := result.Risks[0]
+	assert.Equal(t, EventZoneSpeeding, risk.EventType)
+	assert.Equal(t, "high", risk.Severity, "13 mph over the limit")
+	assert.Equal(t, start.Add(time.Second), risk.Timestamp)
+	assert.Contains(t, risk.Description, "Lincoln Elementary")
+
+	// Leaving the zone ends an episode, and a vehicle going quiet ends one on flush
+	result = observeAll(monitor, vehicle, at(20, 0, 0, 45), at(30, 0, 0, 45), at(31, 10, 0, 45))
+	require.Len(t, result.Risks, 1)
+	assert.Equal(t, "critical", result.Risks[0].Severity)
+
+	observeAll(monitor, vehicle, at(40, 0, 0, 30), at(50, 0, 0, 30))
+	assert.Empty(t, monitor.Flush(start.Add(90*time.Second)))
+	assert.Len(t, monitor.Flush(start.Add(2*time.Minute)), 1)
+}
+
+func TestRefreshDropsRemovedFencesAndRestoreReopensVisits(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor := newMonitor(t, db, circle(0, fleet.ID, 41, -87, 300))
+	vehicle := models.Vehicle{ID: 1, FleetID: fleet.ID}
+
+	result := observeAll(monitor, vehicle, at(0, 0, 0, 0))
+	require.Len(t, result.Events, 1)
+	require.NoError(t, db.Omit("Geofence", "Vehicle", "Driver").Create(&result.Events[0]).Error)
+
+	// After a restart the vehicle is still known to be inside
+	restarted := NewMonitor(db, DefaultConfig())
+	_, err := restarted.Refresh(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, restarted.Restore(t.Context()))
+	assert.Empty(t, observeAll(restarted, vehicle, at(60, 0, 0, 0)).Events)
+
+	// Deactivating the fence ends the visit without an exit
+	require.NoError(t, db.Model(&models.Geofence{}).Where("id = ?", result.Events[0].GeofenceID).
+		Updates(map[string]interface{}{"status": StatusInactive, "updated_at": time.Now()}).Error)
+	reloaded, err := restarted.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Zero(t, restarted.Fences())
+	assert.Empty(t, observeAll(restarted, vehicle, at(120, 10, 0, 20)).Events)
+
+	reloaded, err = restarted.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+}
+
+func TestResetRestoresStoredVisits(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor := newMonitor(t, db, circle(0, fleet.ID, 41, -87, 300))
+	vehicle := models.Vehicle{ID: 1, FleetID: fleet.ID}
+
+	result := observeAll(monitor, vehicle, at(0, 0, 0, 0))
+	require.Len(t, result.Events, 1)
+	require.NoError(t, db.Omit("Geofence", "Vehicle", "Driver").Create(&result.Events[0]).Error)
+
+	// The exit is observed but never stored
+	require.Len(t, observeAll(monitor, vehicle, at(60, 10, 0, 20)).Events, 1)
+
+	require.NoError(t, monitor.Reset(t.Context(), vehicle.ID))
+	exit := observeAll(monitor, vehicle, at(60, 10, 0, 20)).Events
+	require.Len(t, exit, 1)
+	assert.Equal(t, EventExit, exit[0].Type)
+}
@@ -0,0 +1,231 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Response statuses, in the order an incident moves through them
+const (
+	StatusOpen         = "open"
+	StatusAcknowledged = "acknowledged"
+	StatusDispatched   = "dispatched"
+	StatusResolved     = "resolved"
+	StatusClosed       = "closed"
+)
+
+var statusOrder = map[string]int{
+	StatusOpen:         0,
+	StatusAcknowledged: 1,
+	StatusDispatched:   2,
+	StatusResolved:     3,
+	StatusClosed:       4,
+}
+
+// TypeCrash is the incident type of crashes reported by the risk engine
+const TypeCrash = "crash"
+
+// CaptureWindow is how much telemetry is kept on either side of an incident
+const CaptureWindow = 60 * time.Second
+
+// Sample is one telemetry event in an incident's capture
+type Sample struct {
+	ID           uint      `json:"id"`
+	EventType    string    `json:"event_type"`
+	Timestamp    time.Time `json:"timestamp"`
+	Latitude     *float64  `json:"latitude,omitempty"`
+	Longitude    *float64  `json:"longitude,omitempty"`
+	Speed        *float64  `json:"speed,omitempty"`
+	Acceleration *float64  `json:"acceleration,omitempty"`
+	Data         string    `json:"data,omitempty"`
+}
+
+// OpenCrash records a crash risk event as an incident and raises its
+// escalated, critical alert. Run it inside the transaction that stores the
+// risk event, after the risk event was created.
+func OpenCrash(tx *gorm.DB, event *models.RiskEvent) (*models.Incident, models.Alert, error) {
+	var vehicle models.Vehicle
+	if err := tx.Select("id", "fleet_id", "make", "model", "license_plate").First(&vehicle, event.VehicleID).Error; err != nil {
+		return nil, models.Alert{}, err
+	}
+
+	telemetry, err := capture(tx, event.VehicleID, event.Timestamp)
+	if err != nil {
+		return nil, models.Alert{}, err
+	}
+
+	incident := &models.Incident{
+		FleetID:     vehicle.FleetID,
+		VehicleID:   event.VehicleID,
+		DriverID:    event.DriverID,
+		RiskEventID: &event.ID,
+		Type:        TypeCrash,
+		Severity:    "critical",
+		Status:      StatusOpen,
+		OccurredAt:  event.Timestamp,
+		Latitude:    event.Latitude,
+		Longitude:   event.Longitude,
+		Description: event.Description,
+		Telemetry:   telemetry,
+	}
+	if err := tx.Omit("Fleet", "Vehicle", "Driver", "RiskEvent", "Updates").Create(incident).Error; err != nil {
+		return nil, models.Alert{}, err
+	}
+	if err := tx.Create(&models.IncidentUpdate{IncidentID: incident.ID, Status: StatusOpen, Note: event.Description}).Error; err != nil {
+		return nil, models.Alert{}, err
+	}
+
+	alert := models.Alert{
+		FleetID:     vehicle.FleetID,
+		VehicleID:   &event.VehicleID,
+		DriverID:    event.DriverID,
+		RiskEventID: &event.ID,
+		IncidentID:  &incident.ID,
+		Type:        "incident",
+		Priority:    "critical",
+		Title:       strings.TrimSpace(fmt.Sprintf("Possible crash: %s %s %s", vehicle.Make, vehicle.Model, vehicle.LicensePlate)),
+		Message:     event.Description,
+		Status:      "unread",
+		Escalated:   true,
+	}
+	if err := tx.Omit("Fleet", "Vehicle", "Driver", "RiskEvent").Create(&alert).Error; err != nil {
+		return nil, models.Alert{}, err
+	}
+	return incident, alert, nil
+}
+
+// capture returns the vehicle's telemetry within CaptureWindow of at as JSON
+func capture(db *gorm.DB, vehicleID uint, at time.Time) (string, error) {
+	var events []models.TelemetryEvent
+	if err := db.Where("vehicle_id = ? AND timestamp >= ? AND timestamp <= ?", vehicleID, at.Add(-CaptureWindow), at.Add(CaptureWindow)).
+		Order("timestamp ASC, id ASC").
+		Find(&events).Error; err != nil {
+		return "", err
+	}
+
+	samples := make([]Sample, len(events))
+	for i, event := range events {
+		samples[i] = Sample{
+			ID:           event.ID,
+			EventType:    event.EventType,
+			Timestamp:    event.Timestamp,
+			Latitude:     event.Latitude,
+			Longitude:    event.Longitude,
+			Speed:        event.Speed,
+			Acceleration: event.Acceleration,
+			Data:         event.Data,
+		}
+	}
+	encoded, err := json.Marshal(samples)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// CompleteCaptures recaptures the telemetry of incidents whose window closed
+// at least grace before now, so that samples ingested after the incident was
+// opened are included, and marks their capture complete
+func CompleteCaptures(ctx context.Context, db *gorm.DB, now time.Time, grace time.Duration) (int, error) {
+	db = db.WithContext(ctx)
+
+	var pending []models.Incident
+	if err := db.Select("id", "vehicle_id", "occurred_at").
+		Where("telemetry_captured_at IS NULL AND occurred_at <= ?", now.Add(-CaptureWindow-grace)).
+		Find(&pending).Error; err != nil {
+		return 0, err
+	}
+
+	for _, incident := range pending {
+		telemetry, err := capture(db, incident.VehicleID, incident.OccurredAt)
+		if err != nil {
+			return 0, err
+		}
+		if err := db.Model(&models.Incident{}).Where("id = ?", incident.ID).
+			Updates(map[string]interface{}{"telemetry": telemetry, "telemetry_captured_at": now}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(pending), nil
+}
+
+// InvalidTransitionError is returned for status changes that go backwards,
+// including any out of closed
+type InvalidTransitionError struct {
+	From, To string
+}
+
+func (e InvalidTransitionError) Error() string {
+	return fmt.Sprintf("incident cannot move from %s to %s", e.From, e.To)
+}
+
+// ValidStatus reports whether status is a response status
+func ValidStatus(status string) bool {
+	_, ok := statusOrder[status]
+	return ok
+}
+
+// Transition moves the incident forward to status, stamping the time it was
+// reached and recording the change with an optional note. Steps may be
+// skipped, e.g. closing a false alarm straight from open, but an incident
+// never moves backwards, even when incident was loaded before someone else
+// moved it on. Setting the current status again only records the note. Run it
+// inside a transaction.
+func Transition(tx *gorm.DB, incident *models.Incident, status, note string, userID *uint, now time.Time) error {
+	if !ValidStatus(status) {
+		return fmt.Errorf("unknown incident status %q", status)
+	}
+	if statusOrder[status] < statusOrder[incident.Status] {
+		return InvalidTransitionError{From: incident.Status, To: status}
+	}
+
+	if status != incident.Status {
+		updates := map[string]interface{}{"status": status}
+		stamp := func(column string, field **time.Time) {
+			if *field == nil {
+				updates[column] = now
+				*field = &now
+			}
+		}
+		switch status {
+		case StatusAcknowledged:
+			stamp("acknowledged_at", &incident.AcknowledgedAt)
+		case StatusDispatched:
+			stamp("dispatched_at", &incident.DispatchedAt)
+		case StatusResolved:
+			stamp("resolved_at", &incident.ResolvedAt)
+		case StatusClosed:
+			stamp("closed_at", &incident.ClosedAt)
+		}
+		result := tx.Model(&models.Incident{}).Where("id = ? AND status = ?", incident.ID, incident.Status).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Someone else moved the incident on since it was loaded: check
+			// the transition against where it is now
+			var current models.Incident
+			if err := tx.First(&current, incident.ID).Error; err != nil {
+				return err
+			}
+			*incident = current
+			return Transition(tx, incident, status, note, userID, now)
+		}
+		incident.Status = status
+	}
+
+	return tx.Create(&models.IncidentUpdate{
+		IncidentID: incident.ID,
+		Status:     status,
+		Note:       note,
+		UserID:     userID,
+		CreatedAt:  now,
+	}).Error
+}
@@ -0,0 +1,175 @@
+package incident
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+var crashTime = time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+func setupDB(t *testing.T) (*gorm.DB, models.Vehicle) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	vehicle := models.Vehicle{FleetID: fleet.ID, VIN: "VIN1", Make: "Ford", Model: "Transit", Year: 2022, LicensePlate: "ABC123"}
+	require.NoError(t, db.Omit("Fleet").Create(&vehicle).Error)
+	return db, vehicle
+}
+
+// record stores a telemetry sample offset from the crash
+func record(t *testing.T, db *gorm.DB, vehicleID uint, offset time.Duration) {
+	speed := 30.0
+	event := models.TelemetryEvent{VehicleID: vehicleID, EventType: "gps", Timestamp: crashTime.Add(offset), Speed: &speed}
+	require.NoError(t, db.Omit("Vehicle").Create(&event).Error)
+}
+
+func openCrash(t *testing.T, db *gorm.DB, vehicleID uint) (*models.Incident, models.Alert) {
+	event := models.RiskEvent{VehicleID: vehicleID, EventType: "crash", Severity: "critical", RiskScore: 100,
+		Timestamp: crashTime, Description: "Possible crash: 4.6 g impact at 42 mph, vehicle stopped"}
+	require.NoError(t, db.Omit("Vehicle", "Driver").Create(&event).Error)
+
+	var incident *models.Incident
+	var alert models.Alert
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		incident, alert, err = OpenCrash(tx, &event)
+		return err
+	}))
+	return incident, alert
+}
+
+func capturedOffsets(t *testing.T, telemetry string) []time.Duration {
+	var samples []Sample
+	require.NoError(t, json.Unmarshal([]byte(telemetry), &samples))
+	offsets := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		offsets[i] = sample.Timestamp.Sub(crashTime)
+	}
+	return offsets
+}
+
+func TestOpenCrashCapturesTelemetryAndEscalates(t *testing.T) {
+	db, vehicle := setupDB(t)
+	for _, offset := range []time.Duration{-2 * time.Minute, -30 * time.Second, 0, 20 * time.Second} {
+		record(t, db, vehicle.ID, offset)
+	}
+
+	incident, alert := openCrash(t, db, vehicle.ID)
+
+	assert.Equal(t, StatusOpen, incident.Status)
+	assert.Equal(t, vehicle.FleetID, incident.FleetID)
+	assert.Nil(t, incident.TelemetryCapturedAt)
+	assert.Equal(t, []time.Duration{-30 * time.Second, 0, 20 * time.Second}, capturedOffsets(t, incident.Telemetry))
+
+	assert.True(t, alert.Escalated)
+	assert.Equal(t, "incident", alert.Type)
+	assert.Equal(t, "critical", alert.Priority)
+	assert.Equal(t, "Possible crash: Ford Transit ABC123", alert.Title)
+	require.NotNil(t, alert.IncidentID)
+	assert.Equal(t, incident.ID, *alert.IncidentID)
+
+	var updates []models.IncidentUpdate
+	require.NoError(t, db.Where("incident_id = ?", incident.ID).Find(&updates).Error)
+	require.Len(t, updates, 1)
+	assert.Equal(t, StatusOpen, updates[0].Status)
+}
+
+func TestCompleteCapturesWaitsForTheWindowToClose(t *testing.T) {
+	db, vehicle := setupDB(t)
+	record(t, db, vehicle.ID, 0)
+	incident, _ := openCrash(t, db, vehicle.ID)
+
+	// Samples that arrive after the incident was opened
+	record(t, db, vehicle.ID, 45*time.Second)
+	record(t, db, vehicle.ID, 90*time.Second)
+
+	completed, err := CompleteCaptures(t.Context(), db, crashTime.Add(90*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, completed, "the window has not closed long enough ago")
+
+	now := crashTime.Add(2*time.Minute + time.Second)
+	completed, err = CompleteCaptures(t.Context(), db, now, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+
+	var stored models.Incident
+	require.NoError(t, db.First(&stored, incident.ID).Error)
+	require.NotNil(t, stored.TelemetryCapturedAt)
+	assert.Equal(t, []time.Duration{0, 45 * time.Second}, capturedOffsets(t, stored.Telemetry))
+
+	completed, err = CompleteCaptures(t.Context(), db, now.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, completed, "captures are completed once")
+}
+
+func TestTransitionOnlyMovesForward(t *testing.T) {
+	db, vehicle := setupDB(t)
+	incident, _ := openCrash(t, db, vehicle.ID)
+	userID := uint(3)
+	now := crashTime.Add(5 * time.Minute)
+
+	require.NoError(t, Transition(db, incident, StatusDispatched, "Ambulance on the way", &userID, now))
+	assert.Equal(t, StatusDispatched, incident.Status)
+	assert.Nil(t, incident.AcknowledgedAt, "skipped steps are not stamped")
+	require.NotNil(t, incident.DispatchedAt)
+
+	err := Transition(db, incident, StatusAcknowledged, "", &userID, now)
+	assert.Equal(t, InvalidTransitionError{From: StatusDispatched, To: StatusAcknowledged}, err)
+
+	require.NoError(t, Transition(db, incident, StatusClosed, "Driver unharmed", &userID, now.Add(time.Hour)))
+	assert.Error(t, Transition(db, incident, StatusResolved, "", &userID, now.Add(time.Hour)))
+	assert.Error(t, Transition(db, incident, "reopened", "", &userID, now.Add(time.Hour)))
+
+	var stored models.Incident
+	require.NoError(t, db.First(&stored, incident.ID).Error)
+	assert.Equal(t, StatusClosed, stored.Status)
+	require.NotNil(t, stored.ClosedAt)
+	assert.True(t, stored.ClosedAt.Equal(now.Add(time.Hour)))
+
+	var updates []models.IncidentUpdate
+	require.NoError(t, db.Where("incident_id = ?", incident.ID).Order("id").Find(&updates).Error)
+	require.Len(t, updates, 3)
+	assert.Equal(t, []string{StatusOpen, StatusDispatched, StatusClosed},
+		[]string{updates[0].Status, updates[1].Status, updates[2].Status})
+	assert.Equal(t, &userID, updates[1].UserID)
+}
+
+func TestTransitionChecksTheStoredStatus(t *testing.T) {
+	db, vehicle := setupDB(t)
+	incident, _ := openCrash(t, db, vehicle.ID)
+	now := crashTime.Add(5 * time.Minute)
+
+	// Two dispatchers load the open incident; the first closes it
+	stale, staler := *incident, *incident
+	require.NoError(t, Transition(db, incident, StatusClosed, "False alarm", nil, now))
+
+	err := Transition(db, &stale, StatusAcknowledged, "", nil, now.Add(time.Minute))
+	assert.Equal(t, InvalidTransitionError{From: StatusClosed, To: StatusAcknowledged}, err)
+	assert.Equal(t, StatusClosed, stale.Status)
+
+	var stored models.Incident
+	require.NoError(t, db.First(&stored, incident.ID).Error)
+	assert.Equal(t, StatusClosed, stored.Status)
+	require.NotNil(t, stored.ClosedAt)
+	assert.True(t, stored.ClosedAt.Equal(now), "the first close is kept")
+
+	// Closing it as well only adds the note
+	require.NoError(t, Transition(db, &staler, StatusClosed, "Confirmed with driver", nil, now.Add(time.Minute)))
+	require.NoError(t, db.First(&stored, incident.ID).Error)
+	assert.True(t, stored.ClosedAt.Equal(now))
+
+	var updates int64
+	require.NoError(t, db.Model(&models.IncidentUpdate{}).Where("incident_id = ?", incident.ID).Count(&updates).Error)
+	assert.Equal(t, int64(3), updates)
+}
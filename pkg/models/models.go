@@ -15,6 +15,7 @@ type Vehicle struct {
 	Model       string    `json:"model"`
 	Year        int       `json:"year"`
 	LicensePlate string   `json:"license_plate"`
+	VehicleClass string   `json:"vehicle_class" gorm:"size:50"` // e.g. sedan, van, heavy_truck; selects a class risk policy
 	FleetID     uint      `json:"fleet_id"`
 	Fleet       Fleet     `json:"fleet"`
 	DriverID    *uint     `json:"driver_id"`
@@ -57,7 +58,7 @@ type TelemetryEvent struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	VehicleID   uint      `json:"vehicle_id"`
 	Vehicle     Vehicle   `json:"vehicle"`
-	EventType   string    `json:"event_type"` // location, speed, acceleration, harsh_braking, etc.
+	EventType   string    `json:"event_type"` // location, speed, acceleration, harsh_braking, phone_use, etc.
 	Timestamp   time.Time `json:"timestamp"`
 	Latitude    *float64  `json:"latitude"`
 	Longitude   *float64  `json:"longitude"`
@@ -66,6 +67,17 @@ type TelemetryEvent struct {
 	Data        string    `json:"data" gorm:"type:json"` // Additional event-specific data
 	ProcessedAt *time.Time `json:"processed_at"`
 	CreatedAt   time.Time `json:"created_at"`
+	// DriverID is the driver assigned at Timestamp. It is resolved by the risk
+	// engine before analysis and not stored.
+	DriverID *uint `json:"driver_id,omitempty" gorm:"-"`
+}
+
+// TelemetryLease assigns a vehicle's unprocessed telemetry to one risk-engine
+// replica until ExpiresAt
+type TelemetryLease struct {
+	VehicleID uint      `json:"vehicle_id" gorm:"primaryKey;autoIncrement:false"`
+	Owner     string    `json:"owner" gorm:"size:100;index"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // RiskEvent represents detected risky behavior
@@ -75,7 +87,7 @@ type RiskEvent struct {
 	Vehicle     Vehicle   `json:"vehicle"`
 	DriverID    *uint     `json:"driver_id"`
 	Driver      *Driver   `json:"driver,omitempty"`
-	EventType   string    `json:"event_type"` // speeding, harsh_braking, rapid_acceleration, fatigue
+	EventType   string    `json:"event_type"` // speeding, harsh_braking, rapid_acceleration, harsh_cornering, fatigue, aggressive_driving, distracted_driving, tailgating, seatbelt_violation
 	Severity    string    `json:"severity"`   // low, medium, high, critical
 	RiskScore   float64   `json:"risk_score"` // 0-100
 	Timestamp   time.Time `json:"timestamp"`
@@ -84,8 +96,12 @@ type RiskEvent struct {
 	Description string    `json:"description"`
 	Data        string    `json:"data" gorm:"type:json"`
 	Status      string    `json:"status" gorm:"default:open"` // open, acknowledged, resolved
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	// ModelVersion is the version of the model that scored the event, or
+	// empty when RiskScore comes from the rule thresholds alone. Geofence,
+	// route and anomaly events are never scored by the model.
+	ModelVersion string    `json:"model_version" gorm:"size:100"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // Alert represents system-generated alerts
@@ -99,11 +115,14 @@ type Alert struct {
 	Driver      *Driver   `json:"driver,omitempty"`
 	RiskEventID *uint     `json:"risk_event_id"`
 	RiskEvent   *RiskEvent `json:"risk_event,omitempty"`
-	Type        string    `json:"type"`     // risk, maintenance, system
+	IncidentID  *uint     `json:"incident_id"`
+	Type        string    `json:"type"`     // risk, incident, maintenance, system
 	Priority    string    `json:"priority"` // low, medium, high, critical
 	Title       string    `json:"title"`
 	Message     string    `json:"message"`
 	Status      string    `json:"status" gorm:"default:unread"` // unread, read, dismissed
+	// Escalated alerts, such as those for crashes, need an immediate response
+	Escalated   bool      `json:"escalated"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -119,11 +138,234 @@ type DriverScore struct {
 	TotalMiles     float64   `json:"total_miles"`
 	TotalTrips     int       `json:"total_trips"`
 	RiskEvents     int       `json:"risk_events"`
+	IdleSeconds    int       `json:"idle_seconds"`
+	RiskEventsPer100Miles float64 `json:"risk_events_per_100_miles"`
 	LastUpdated    time.Time `json:"last_updated"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
+// DriverScoreSnapshot is a driver's score as of one scoring run, kept so that
+// trends can be followed. Breakdown is a JSON array of the points each event
+// type and severity took off the safety score.
+type DriverScoreSnapshot struct {
+	ID                    uint      `json:"id" gorm:"primaryKey"`
+	DriverID              uint      `json:"driver_id" gorm:"index:idx_driver_score_snapshots_driver_time"`
+	Driver                Driver    `json:"driver"`
+	OverallScore          float64   `json:"overall_score"`
+	SafetyScore           float64   `json:"safety_score"`
+	EfficiencyScore       float64   `json:"efficiency_score"`
+	TotalMiles            float64   `json:"total_miles"`
+	TotalTrips            int       `json:"total_trips"`
+	RiskEvents            int       `json:"risk_events"`
+	IdleSeconds           int       `json:"idle_seconds"`
+	RiskEventsPer100Miles float64   `json:"risk_events_per_100_miles"`
+	Breakdown             string    `json:"breakdown" gorm:"type:json"`
+	ScoredAt              time.Time `json:"scored_at" gorm:"index:idx_driver_score_snapshots_driver_time"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// VehicleAssignment records a driver's time behind the wheel of a vehicle.
+// EndTime is nil while the assignment is current.
+type VehicleAssignment struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	VehicleID uint       `json:"vehicle_id" gorm:"index:idx_vehicle_assignments_vehicle_start"`
+	Vehicle   Vehicle    `json:"vehicle"`
+	DriverID  uint       `json:"driver_id" gorm:"index"`
+	Driver    Driver     `json:"driver"`
+	StartTime time.Time  `json:"start_time" gorm:"index:idx_vehicle_assignments_vehicle_start"`
+	EndTime   *time.Time `json:"end_time"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Trip is a continuous stretch of driving segmented from a vehicle's telemetry
+type Trip struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	VehicleID       uint      `json:"vehicle_id" gorm:"index:idx_trips_vehicle_start"`
+	Vehicle         Vehicle   `json:"vehicle"`
+	DriverID        *uint     `json:"driver_id" gorm:"index"`
+	Driver          *Driver   `json:"driver,omitempty"`
+	StartTime       time.Time `json:"start_time" gorm:"index:idx_trips_vehicle_start"`
+	EndTime         time.Time `json:"end_time"`
+	StartLatitude   *float64  `json:"start_latitude"`
+	StartLongitude  *float64  `json:"start_longitude"`
+	EndLatitude     *float64  `json:"end_latitude"`
+	EndLongitude    *float64  `json:"end_longitude"`
+	DistanceMiles   float64   `json:"distance_miles"`
+	DurationSeconds int       `json:"duration_seconds"`
+	MaxSpeed        float64   `json:"max_speed"`    // mph
+	IdleSeconds     int       `json:"idle_seconds"` // stationary with the engine running
+	FuelUsed        *float64  `json:"fuel_used"`    // fuel level points consumed; nil without fuel readings
+	EndReason       string    `json:"end_reason"`   // ignition_off, stationary, gap
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RiskPolicy overrides the risk engine's rule configuration for a fleet, or
+// for one vehicle class within a fleet. Class policies are layered on top of
+// the fleet policy, which is layered on top of the engine's defaults.
+type RiskPolicy struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	FleetID      uint      `json:"fleet_id" gorm:"uniqueIndex:idx_risk_policies_scope"`
+	Fleet        Fleet     `json:"fleet"`
+	VehicleClass string    `json:"vehicle_class" gorm:"uniqueIndex:idx_risk_policies_scope;size:50"` // empty for the fleet-wide policy
+	Rules        string    `json:"rules" gorm:"type:json"`                                            // rule overrides, in the risk rules file format
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// BackfillJob tracks a risk engine reprocessing run over a fleet's telemetry.
+// The cursor is the last committed event in (vehicle, timestamp, ID) order, so
+// an interrupted run can resume where it stopped.
+type BackfillJob struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	FleetID         uint       `json:"fleet_id" gorm:"index"`
+	Fleet           Fleet      `json:"fleet"`
+	FromTime        time.Time  `json:"from_time"`
+	ToTime          time.Time  `json:"to_time"`
+	Replace         bool       `json:"replace"`
+	Status          string     `json:"status" gorm:"size:20;default:running"` // running, completed
+	CursorVehicleID uint       `json:"cursor_vehicle_id"`
+	CursorTimestamp *time.Time `json:"cursor_timestamp"`
+	CursorEventID   uint       `json:"cursor_event_id"`
+	EventsProcessed int64      `json:"events_processed"`
+	RiskEvents      int64      `json:"risk_events"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Incident is an emergency, such as a suspected crash, that needs a response
+// until it is closed. Telemetry is a JSON array of the vehicle's samples from
+// a minute before to a minute after OccurredAt; TelemetryCapturedAt is set once
+// the minute after has been recorded in full.
+type Incident struct {
+	ID                  uint             `json:"id" gorm:"primaryKey"`
+	FleetID             uint             `json:"fleet_id" gorm:"index:idx_incidents_fleet_status"`
+	Fleet               Fleet            `json:"fleet"`
+	VehicleID           uint             `json:"vehicle_id" gorm:"index"`
+	Vehicle             Vehicle          `json:"vehicle"`
+	DriverID            *uint            `json:"driver_id"`
+	Driver              *Driver          `json:"driver,omitempty"`
+	RiskEventID         *uint            `json:"risk_event_id"`
+	RiskEvent           *RiskEvent       `json:"risk_event,omitempty"`
+	Type                string           `json:"type" gorm:"size:30"`                                                 // crash
+	Severity            string           `json:"severity" gorm:"size:20;default:critical"`                            // low, medium, high, critical
+	Status              string           `json:"status" gorm:"size:20;default:open;index:idx_incidents_fleet_status"` // open, acknowledged, dispatched, resolved, closed
+	OccurredAt          time.Time        `json:"occurred_at"`
+	Latitude            *float64         `json:"latitude"`
+	Longitude           *float64         `json:"longitude"`
+	Description         string           `json:"description"`
+	Telemetry           string           `json:"telemetry" gorm:"type:json"`
+	TelemetryCapturedAt *time.Time       `json:"telemetry_captured_at"`
+	AcknowledgedAt      *time.Time       `json:"acknowledged_at"`
+	DispatchedAt        *time.Time       `json:"dispatched_at"`
+	ResolvedAt          *time.Time       `json:"resolved_at"`
+	ClosedAt            *time.Time       `json:"closed_at"`
+	Updates             []IncidentUpdate `json:"updates,omitempty"`
+	CreatedAt           time.Time        `json:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at"`
+}
+
+// IncidentUpdate records a change of an incident's response status. UserID is
+// nil for changes made by the system.
+type IncidentUpdate struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	IncidentID uint      `json:"incident_id" gorm:"index"`
+	Status     string    `json:"status" gorm:"size:20"`
+	Note       string    `json:"note" gorm:"type:text"`
+	UserID     *uint     `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Geofence is a named zone of a fleet: a circle of RadiusMeters around its
+// center, or a polygon whose vertices are stored in Polygon as a JSON array of
+// {"latitude", "longitude"} objects. SpeedLimit (mph) applies inside the zone.
+// Entering a Restricted zone is unauthorized for every vehicle whose class is
+// not listed in AllowedVehicleClasses.
+type Geofence struct {
+	ID                    uint      `json:"id" gorm:"primaryKey"`
+	FleetID               uint      `json:"fleet_id" gorm:"index"`
+	Fleet                 Fleet     `json:"fleet"`
+	Name                  string    `json:"name" gorm:"size:100"`
+	Category              string    `json:"category" gorm:"size:30;default:other"` // depot, customer_site, school_zone, other
+	Shape                 string    `json:"shape" gorm:"size:10"`                  // circle, polygon
+	CenterLatitude        *float64  `json:"center_latitude"`
+	CenterLongitude       *float64  `json:"center_longitude"`
+	RadiusMeters          *float64  `json:"radius_meters"`
+	Polygon               string    `json:"polygon" gorm:"type:text"`
+	SpeedLimit            *float64  `json:"speed_limit"`
+	Restricted            bool      `json:"restricted"`
+	AllowedVehicleClasses string    `json:"allowed_vehicle_classes" gorm:"size:255"` // comma separated
+	Status                string    `json:"status" gorm:"size:20;default:active"`    // active, inactive
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// GeofenceEvent records a vehicle entering or leaving a geofence. Exits carry
+// how long the vehicle stayed inside.
+type GeofenceEvent struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	GeofenceID   uint      `json:"geofence_id" gorm:"index"`
+	Geofence     Geofence  `json:"geofence"`
+	VehicleID    uint      `json:"vehicle_id" gorm:"index:idx_geofence_events_vehicle_time"`
+	Vehicle      Vehicle   `json:"vehicle"`
+	DriverID     *uint     `json:"driver_id"`
+	Driver       *Driver   `json:"driver,omitempty"`
+	Type         string    `json:"type" gorm:"size:10"` // enter, exit
+	Timestamp    time.Time `json:"timestamp" gorm:"index:idx_geofence_events_vehicle_time"`
+	Latitude     *float64  `json:"latitude"`
+	Longitude    *float64  `json:"longitude"`
+	DwellSeconds *int      `json:"dwell_seconds"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PlannedRoute is a route dispatch expects vehicles to follow: Path is a JSON
+// array of {"latitude", "longitude"} waypoints in driving order, and vehicles
+// are on route while within half of CorridorWidthMeters of it. Leaving the
+// corridor for longer than DeviationSeconds is a route deviation.
+type PlannedRoute struct {
+	ID                  uint      `json:"id" gorm:"primaryKey"`
+	FleetID             uint      `json:"fleet_id" gorm:"index"`
+	Fleet               Fleet     `json:"fleet"`
+	Name                string    `json:"name" gorm:"size:100"`
+	Path                string    `json:"path" gorm:"type:text"`
+	CorridorWidthMeters float64   `json:"corridor_width_meters"`
+	DeviationSeconds    int       `json:"deviation_seconds" gorm:"default:120"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// RouteAssignment attaches a planned route to a vehicle from StartTime until
+// EndTime
+type RouteAssignment struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	RouteID   uint         `json:"route_id" gorm:"index"`
+	Route     PlannedRoute `json:"route" gorm:"foreignKey:RouteID"`
+	VehicleID uint         `json:"vehicle_id" gorm:"index:idx_route_assignments_vehicle_window"`
+	Vehicle   Vehicle      `json:"vehicle"`
+	StartTime time.Time    `json:"start_time" gorm:"index:idx_route_assignments_vehicle_window"`
+	EndTime   time.Time    `json:"end_time" gorm:"index"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// DriverBaseline is a driver's usual behaviour, learned from their trips for
+// anomaly detection. Stats is a JSON object of per-feature exponentially
+// weighted means and variances; LastTripID is the last trip folded in.
+type DriverBaseline struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DriverID   uint      `json:"driver_id" gorm:"uniqueIndex"`
+	Driver     Driver    `json:"driver"`
+	FleetID    uint      `json:"fleet_id" gorm:"index"`
+	Trips      int       `json:"trips"`
+	LastTripID uint      `json:"last_trip_id"`
+	Stats      string    `json:"stats" gorm:"type:json"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 // User represents system users with authentication
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -169,28 +411,86 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
-// Session represents user sessions for tracking logins
+// Session represents user sessions for tracking logins.
+// Each refresh token is stored as its own session row. Rotating a token marks
+// the row rotated and creates its successor in the same family, so a rotated
+// token presented again identifies a stolen family that must be revoked.
 type Session struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id"`
-	User      User      `json:"user"`
-	Token     string    `json:"token" gorm:"uniqueIndex;size:255"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	User      User       `json:"user"`
+	FamilyID  string     `json:"family_id" gorm:"index;size:64"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64"` // SHA-256 of the refresh token
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 // Migrate runs the database migrations
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&Fleet{},
 		&Driver{},
 		&Vehicle{},
 		&TelemetryEvent{},
+		&TelemetryLease{},
 		&RiskEvent{},
 		&Alert{},
 		&DriverScore{},
+		&DriverScoreSnapshot{},
+		&Trip{},
+		&VehicleAssignment{},
+		&RiskPolicy{},
+		&BackfillJob{},
+		&Incident{},
+		&IncidentUpdate{},
+		&Geofence{},
+		&GeofenceEvent{},
+		&PlannedRoute{},
+		&RouteAssignment{},
+		&DriverBaseline{},
 		&User{},
 		&Session{},
 	)
+	if err != nil {
+		return err
+	}
+	return seedVehicleAssignments(db)
+}
+
+// seedVehicleAssignments opens an assignment for each vehicle whose driver was
+// set before assignment history was kept, so that its events keep their
+// driver. It starts when the vehicle's last assignment ended or, without one,
+// when the vehicle was created.
+func seedVehicleAssignments(db *gorm.DB) error {
+	var vehicles []Vehicle
+	if err := db.Select("id", "driver_id", "created_at").
+		Where("driver_id IS NOT NULL").
+		Where("NOT EXISTS (SELECT 1 FROM vehicle_assignments WHERE vehicle_assignments.vehicle_id = vehicles.id AND vehicle_assignments.end_time IS NULL)").
+		Find(&vehicles).Error; err != nil {
+		return err
+	}
+
+	for _, vehicle := range vehicles {
+		start := vehicle.CreatedAt
+		var last []VehicleAssignment
+		if err := db.Where("vehicle_id = ? AND end_time IS NOT NULL", vehicle.ID).
+			Order("end_time DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		if len(last) > 0 && last[0].EndTime.After(start) {
+			start = *last[0].EndTime
+		}
+
+		if err := db.Omit("Vehicle", "Driver").Create(&VehicleAssignment{
+			VehicleID: vehicle.ID,
+			DriverID:  *vehicle.DriverID,
+			StartTime: start,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
\ No newline at end of file
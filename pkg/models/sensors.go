@@ -0,0 +1,44 @@
+package models
+
+// In-cab sensor telemetry event types. Their readings travel in the event's
+// Data as the matching *Data struct below.
+const (
+	EventPhoneUse          = "phone_use"
+	EventSeatbeltUnbuckled = "seatbelt_unbuckled"
+	EventCameraDistraction = "camera_distraction"
+	EventFollowingDistance = "following_distance"
+)
+
+// PhoneUseData is reported when the driver's phone is used while the ignition is on
+type PhoneUseData struct {
+	Usage           string  `json:"usage"` // handheld_call, texting, browsing, hands_free
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// SeatbeltData is reported when an occupied seat's belt is unbuckled
+type SeatbeltData struct {
+	Seat            string  `json:"seat"` // driver, passenger
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// CameraDistractionData is reported by a driver-facing camera
+type CameraDistractionData struct {
+	Behavior        string  `json:"behavior"` // looking_away, eyes_closed, drowsiness, eating, smoking
+	DurationSeconds float64 `json:"duration_seconds"`
+	Confidence      float64 `json:"confidence"` // 0-1
+}
+
+// FollowingDistanceData is reported by a forward-facing camera or radar while
+// the vehicle follows another too closely
+type FollowingDistanceData struct {
+	HeadwaySeconds  float64  `json:"headway_seconds"` // time to reach the lead vehicle's position
+	DistanceMeters  *float64 `json:"distance_meters,omitempty"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+// Allowed values of the sensor readings' enumerated fields
+var (
+	PhoneUsages     = []string{"handheld_call", "texting", "browsing", "hands_free"}
+	Seats           = []string{"driver", "passenger"}
+	CameraBehaviors = []string{"looking_away", "eyes_closed", "drowsiness", "eating", "smoking"}
+)
@@ -0,0 +1,138 @@
+package processing
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Claimer hands out unprocessed telemetry to one of several risk-engine
+// replicas. Work is leased per vehicle rather than per event, so that all of a
+// vehicle's events are analyzed by the replica holding its in-memory episode
+// and trip state. Leases are renewed on every claim; a replica that stops
+// claiming loses its vehicles to the others once the TTL runs out.
+type Claimer struct {
+	db    *gorm.DB
+	owner string
+	ttl   time.Duration
+}
+
+// NewClaimer returns a claimer leasing vehicles to owner, which must be unique
+// per replica
+func NewClaimer(db *gorm.DB, owner string, ttl time.Duration) *Claimer {
+	return &Claimer{db: db, owner: owner, ttl: ttl}
+}
+
+// Owner identifies the replica holding the claimer's leases
+func (c *Claimer) Owner() string {
+	return c.owner
+}
+
+// Claim renews the replica's leases, leases up to maxVehicles more vehicles
+// with telemetry created after since that no other replica holds, and returns
+// up to limit unprocessed events of the leased vehicles in timestamp order
+func (c *Claimer) Claim(ctx context.Context, since time.Time, maxVehicles, limit int) ([]models.TelemetryEvent, error) {
+	db := c.db.WithContext(ctx)
+	now := time.Now()
+	expires := now.Add(c.ttl)
+
+	if err := db.Model(&models.TelemetryLease{}).
+		Where("owner = ? AND expires_at > ?", c.owner, now).
+		Update("expires_at", expires).Error; err != nil {
+		return nil, err
+	}
+
+	var pending []uint
+	err := db.Model(&models.TelemetryEvent{}).
+		Where("processed_at IS NULL AND created_at > ?", since).
+		Where("vehicle_id NOT IN (?)", db.Model(&models.TelemetryLease{}).
+			Select("vehicle_id").
+			Where("expires_at > ?", now)).
+		Group("vehicle_id").
+		Order("MIN(id)").
+		Limit(maxVehicles).
+		Pluck("vehicle_id", &pending).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) > 0 {
+		leases := make([]models.TelemetryLease, len(pending))
+		for i, vehicleID := range pending {
+			leases[i] = models.TelemetryLease{VehicleID: vehicleID, Owner: c.owner, ExpiresAt: expires}
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&leases).Error; err != nil {
+			return nil, err
+		}
+
+		// Take over expired leases; the expiry check keeps two replicas from
+		// taking over the same one
+		if err := db.Model(&models.TelemetryLease{}).
+			Where("vehicle_id IN ? AND expires_at <= ?", pending, now).
+			Updates(map[string]interface{}{"owner": c.owner, "expires_at": expires}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var events []models.TelemetryEvent
+	err = db.Where("processed_at IS NULL AND created_at > ?", since).
+		Where("vehicle_id IN (?)", db.Model(&models.TelemetryLease{}).
+			Select("vehicle_id").
+			Where("owner = ? AND expires_at > ?", c.owner, now)).
+		Order("timestamp ASC, id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// Leased returns the vehicles the replica holds an unexpired lease on
+func (c *Claimer) Leased(ctx context.Context) ([]uint, error) {
+	var vehicles []uint
+	err := c.db.WithContext(ctx).Model(&models.TelemetryLease{}).
+		Where("owner = ? AND expires_at > ?", c.owner, time.Now()).
+		Pluck("vehicle_id", &vehicles).Error
+	return vehicles, err
+}
+
+// Release gives up the replica's leases, letting others pick up its vehicles
+// without waiting for the TTL
+func (c *Claimer) Release(ctx context.Context) error {
+	return c.db.WithContext(ctx).Where("owner = ?", c.owner).Delete(&models.TelemetryLease{}).Error
+}
+
+// Commit marks the event processed and runs write in the same transaction, so
+// that the results of processing an event are stored exactly once. It returns
+// false, writing nothing, if the event had already been processed, for example
+// by a replica that took over an expired lease.
+func Commit(ctx context.Context, db *gorm.DB, event *models.TelemetryEvent, write func(tx *gorm.DB) error) (bool, error) {
+	committed := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Marking first locks the row until the transaction ends
+		result := tx.Model(&models.TelemetryEvent{}).
+			Where("id = ? AND processed_at IS NULL", event.ID).
+			Update("processed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := write(tx); err != nil {
+			return err
+		}
+		event.ProcessedAt = &now
+		committed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return committed, nil
+}
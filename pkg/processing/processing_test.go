@@ -0,0 +1,194 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// setupDB opens a file database, since concurrent workers need separate
+// connections to the same data
+func setupDB(t *testing.T, vehicles, eventsPerVehicle int) (*gorm.DB, models.Fleet) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "telemetry.db") + "?_busy_timeout=10000&_txlock=immediate&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+
+	start := time.Now().Add(-10 * time.Minute)
+	for v := 0; v < vehicles; v++ {
+		vehicle := models.Vehicle{FleetID: fleet.ID, VIN: fmt.Sprintf("VIN%d", v), Make: "Ford", Model: "Transit", Year: 2022}
+		require.NoError(t, db.Omit("Fleet").Create(&vehicle).Error)
+
+		events := make([]models.TelemetryEvent, eventsPerVehicle)
+		for i := range events {
+			events[i] = models.TelemetryEvent{VehicleID: vehicle.ID, EventType: "location", Timestamp: start.Add(time.Duration(i) * time.Second), Data: "{}"}
+		}
+		require.NoError(t, db.Omit("Vehicle").Create(&events).Error)
+	}
+	return db, fleet
+}
+
+// flagEvent stores a risk event and an alert for every telemetry event
+func flagEvent(fleetID uint, event *models.TelemetryEvent) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		risk := models.RiskEvent{VehicleID: event.VehicleID, EventType: "speeding", Severity: "high", Timestamp: event.Timestamp}
+		if err := tx.Omit("Vehicle", "Driver").Create(&risk).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Fleet", "Vehicle", "Driver", "RiskEvent").Create(&models.Alert{
+			FleetID: fleetID, VehicleID: &event.VehicleID, RiskEventID: &risk.ID, Type: "risk", Priority: "high", Status: "unread",
+		}).Error
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}, conditions ...interface{}) int64 {
+	var n int64
+	query := db.Model(model)
+	if len(conditions) > 0 {
+		query = query.Where(conditions[0], conditions[1:]...)
+	}
+	require.NoError(t, query.Count(&n).Error)
+	return n
+}
+
+func TestConcurrentWorkersProcessEachEventOnce(t *testing.T) {
+	const vehicles, eventsPerVehicle, workers = 8, 40, 4
+	db, fleet := setupDB(t, vehicles, eventsPerVehicle)
+	since := time.Now().Add(-time.Hour)
+
+	var mu sync.Mutex
+	owners := make(map[uint]map[string]bool)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(claimer *Claimer) {
+			defer wg.Done()
+			for {
+				events, err := claimer.Claim(t.Context(), since, 3, 25)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(events) == 0 {
+					return
+				}
+				for i := range events {
+					event := &events[i]
+					committed, err := Commit(t.Context(), db, event, flagEvent(fleet.ID, event))
+					if err != nil {
+						errs <- err
+						return
+					}
+					if committed {
+						mu.Lock()
+						if owners[event.VehicleID] == nil {
+							owners[event.VehicleID] = make(map[string]bool)
+						}
+						owners[event.VehicleID][claimer.Owner()] = true
+						mu.Unlock()
+					}
+				}
+			}
+		}(NewClaimer(db, fmt.Sprintf("worker-%d", w), time.Minute))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	total := int64(vehicles * eventsPerVehicle)
+	assert.Zero(t, count(t, db, &models.TelemetryEvent{}, "processed_at IS NULL"))
+	assert.Equal(t, total, count(t, db, &models.RiskEvent{}))
+	assert.Equal(t, total, count(t, db, &models.Alert{}))
+
+	require.Len(t, owners, vehicles)
+	for vehicleID, processedBy := range owners {
+		assert.Len(t, processedBy, 1, "vehicle %d was split between workers", vehicleID)
+	}
+}
+
+func TestCommitIsExactlyOnce(t *testing.T) {
+	db, fleet := setupDB(t, 1, 2)
+
+	var events []models.TelemetryEvent
+	require.NoError(t, db.Order("id").Find(&events).Error)
+
+	committed, err := Commit(t.Context(), db, &events[0], flagEvent(fleet.ID, &events[0]))
+	require.NoError(t, err)
+	assert.True(t, committed)
+	assert.NotNil(t, events[0].ProcessedAt)
+
+	// A second replica with a stale copy of the event writes nothing
+	stale := events[0]
+	stale.ProcessedAt = nil
+	committed, err = Commit(t.Context(), db, &stale, flagEvent(fleet.ID, &stale))
+	require.NoError(t, err)
+	assert.False(t, committed)
+	assert.Equal(t, int64(1), count(t, db, &models.RiskEvent{}))
+
+	// A failed write leaves the event unprocessed and nothing stored
+	_, err = Commit(t.Context(), db, &events[1], func(tx *gorm.DB) error {
+		if err := flagEvent(fleet.ID, &events[1])(tx); err != nil {
+			return err
+		}
+		return errors.New("publish failed")
+	})
+	require.Error(t, err)
+	assert.Equal(t, int64(1), count(t, db, &models.TelemetryEvent{}, "processed_at IS NULL"))
+	assert.Equal(t, int64(1), count(t, db, &models.RiskEvent{}))
+	assert.Equal(t, int64(1), count(t, db, &models.Alert{}))
+}
+
+func TestLeasesMoveBetweenReplicas(t *testing.T) {
+	db, _ := setupDB(t, 1, 5)
+	since := time.Now().Add(-time.Hour)
+
+	first := NewClaimer(db, "first", 50*time.Millisecond)
+	second := NewClaimer(db, "second", time.Minute)
+
+	events, err := first.Claim(t.Context(), since, 10, 100)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+
+	events, err = second.Claim(t.Context(), since, 10, 100)
+	require.NoError(t, err)
+	assert.Empty(t, events, "the vehicle is leased to the first replica")
+
+	leased, err := first.Leased(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, leased, 1)
+	leased, err = second.Leased(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, leased)
+
+	// The first replica stops renewing
+	time.Sleep(60 * time.Millisecond)
+	leased, err = first.Leased(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, leased, "the lease has expired")
+	events, err = second.Claim(t.Context(), since, 10, 100)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+
+	require.NoError(t, second.Release(t.Context()))
+	events, err = first.Claim(t.Context(), since, 10, 100)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+}
@@ -0,0 +1,136 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Redis channels consumed by the websocket hub and GraphQL subscriptions
+const (
+	RiskEventsChannel     = "risk_events"
+	AlertsChannel         = "alerts"
+	VehicleUpdatesChannel = "vehicle_updates"
+	DriverUpdatesChannel  = "driver_updates"
+	IncidentsChannel      = "incidents"
+	GeofenceEventsChannel = "geofence_events"
+)
+
+// Message types carried in the envelope
+const (
+	TypeRiskEvent     = "risk_event"
+	TypeAlert         = "alert"
+	TypeVehicleUpdate = "vehicle_update"
+	TypeDriverUpdate  = "driver_update"
+	TypeIncident      = "incident"
+	TypeGeofenceEvent = "geofence_event"
+)
+
+// Message is the envelope broadcast on every real-time channel
+type Message struct {
+	Type      string      `json:"type"`
+	FleetID   string      `json:"fleet_id,omitempty"`
+	VehicleID string      `json:"vehicle_id,omitempty"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// NewMessage builds an envelope for the given fleet and vehicle; zero IDs are omitted
+func NewMessage(messageType string, fleetID, vehicleID uint, data interface{}) Message {
+	msg := Message{
+		Type:      messageType,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+	if fleetID != 0 {
+		msg.FleetID = fmt.Sprintf("%d", fleetID)
+	}
+	if vehicleID != 0 {
+		msg.VehicleID = fmt.Sprintf("%d", vehicleID)
+	}
+	return msg
+}
+
+// Publisher emits real-time events to Redis. A Publisher without a Redis
+// client is valid and silently drops every event, so services keep working
+// when Redis is unavailable.
+type Publisher struct {
+	redis *redis.Client
+}
+
+// New creates a publisher; client may be nil to disable publishing
+func New(client *redis.Client) *Publisher {
+	return &Publisher{redis: client}
+}
+
+// Enabled reports whether events are actually being sent to Redis
+func (p *Publisher) Enabled() bool {
+	return p != nil && p.redis != nil
+}
+
+// Publish encodes msg and sends it on channel
+func (p *Publisher) Publish(ctx context.Context, channel string, msg Message) error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", msg.Type, err)
+	}
+
+	if err := p.redis.Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+
+	return nil
+}
+
+// PublishRiskEvent announces a newly stored risk event to the vehicle's fleet
+func (p *Publisher) PublishRiskEvent(ctx context.Context, event *models.RiskEvent, fleetID uint) error {
+	return p.Publish(ctx, RiskEventsChannel, NewMessage(TypeRiskEvent, fleetID, event.VehicleID, event))
+}
+
+// PublishAlert announces a newly stored alert to its fleet
+func (p *Publisher) PublishAlert(ctx context.Context, alert *models.Alert) error {
+	var vehicleID uint
+	if alert.VehicleID != nil {
+		vehicleID = *alert.VehicleID
+	}
+	return p.Publish(ctx, AlertsChannel, NewMessage(TypeAlert, alert.FleetID, vehicleID, alert))
+}
+
+// PublishVehicleUpdate announces the latest telemetry received from a vehicle
+func (p *Publisher) PublishVehicleUpdate(ctx context.Context, event *models.TelemetryEvent, fleetID uint) error {
+	return p.Publish(ctx, VehicleUpdatesChannel, NewMessage(TypeVehicleUpdate, fleetID, event.VehicleID, event))
+}
+
+// PublishDriverUpdate announces a recalculated driver score
+func (p *Publisher) PublishDriverUpdate(ctx context.Context, score *models.DriverScore, fleetID uint) error {
+	return p.Publish(ctx, DriverUpdatesChannel, NewMessage(TypeDriverUpdate, fleetID, 0, score))
+}
+
+// PublishIncident announces a new or updated incident to its fleet
+func (p *Publisher) PublishIncident(ctx context.Context, incident *models.Incident) error {
+	return p.Publish(ctx, IncidentsChannel, NewMessage(TypeIncident, incident.FleetID, incident.VehicleID, incident))
+}
+
+// PublishGeofenceEvent announces a vehicle entering or leaving a geofence. The
+// event's Geofence must be populated to resolve the fleet.
+func (p *Publisher) PublishGeofenceEvent(ctx context.Context, event *models.GeofenceEvent) error {
+	return p.Publish(ctx, GeofenceEventsChannel, NewMessage(TypeGeofenceEvent, event.Geofence.FleetID, event.VehicleID, event))
+}
+
+// LogError logs a failed publish without interrupting the caller; real-time
+// delivery is best effort and the data is already persisted
+func LogError(err error, fields logrus.Fields) {
+	if err != nil {
+		logrus.WithError(err).WithFields(fields).Warn("Failed to publish real-time event")
+	}
+}
@@ -0,0 +1,47 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func TestNewMessage(t *testing.T) {
+	msg := NewMessage(TypeRiskEvent, 3, 42, map[string]string{"event_type": "speeding"})
+
+	assert.Equal(t, TypeRiskEvent, msg.Type)
+	assert.Equal(t, "3", msg.FleetID)
+	assert.Equal(t, "42", msg.VehicleID)
+	assert.False(t, msg.Timestamp.IsZero())
+
+	// Zero IDs are left out of the envelope
+	payload, err := json.Marshal(NewMessage(TypeDriverUpdate, 3, 0, nil))
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &decoded))
+	assert.Equal(t, "3", decoded["fleet_id"])
+	assert.NotContains(t, decoded, "vehicle_id")
+}
+
+func TestPublisherWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+
+	// A publisher without a client drops events instead of failing
+	p := New(nil)
+	assert.False(t, p.Enabled())
+	assert.NoError(t, p.PublishRiskEvent(ctx, &models.RiskEvent{VehicleID: 1}, 1))
+	assert.NoError(t, p.PublishAlert(ctx, &models.Alert{FleetID: 1}))
+	assert.NoError(t, p.PublishVehicleUpdate(ctx, &models.TelemetryEvent{VehicleID: 1}, 1))
+	assert.NoError(t, p.PublishDriverUpdate(ctx, &models.DriverScore{DriverID: 1}, 1))
+
+	// A nil publisher behaves the same way
+	var nilPublisher *Publisher
+	assert.False(t, nilPublisher.Enabled())
+	assert.NoError(t, nilPublisher.Publish(ctx, AlertsChannel, Message{Type: TypeAlert}))
+}
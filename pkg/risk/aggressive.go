@@ -0,0 +1,188 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// AggressiveDrivingDetector is a composite rule: it raises AGGRESSIVE_DRIVING
+// when a driver (or a vehicle without one) racks up MinEvents of the listed
+// event types, spanning at least MinTypes of them, within a rolling window.
+// The events that made up a composite do not count towards the next one.
+type AggressiveDrivingDetector struct {
+	EventTypes    []string `json:"event_types"`
+	WindowMinutes float64  `json:"window_minutes"`
+	MinEvents     int      `json:"min_events"`
+	MinTypes      int      `json:"min_types"`
+	Severity      string   `json:"severity"`
+	RiskScore     float64  `json:"risk_score"`
+
+	state *State
+}
+
+func newAggressiveDrivingDetector(params json.RawMessage) (Detector, error) {
+	d := &AggressiveDrivingDetector{
+		EventTypes:    []string{RuleSpeeding, RuleHarshBraking, RuleRapidAcceleration, RuleHarshCornering, EventTailgating},
+		WindowMinutes: 10,
+		MinEvents:     3,
+		MinTypes:      2,
+		Severity:      "high",
+		RiskScore:     80.0,
+		state:         NewState(),
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if len(d.EventTypes) == 0 {
+		return nil, errors.New("at least one event type is required")
+	}
+	for _, eventType := range d.EventTypes {
+		if eventType == RuleAggressiveDriving {
+			return nil, errors.New("event_types cannot include aggressive_driving itself")
+		}
+	}
+	if d.WindowMinutes <= 0 {
+		return nil, errors.New("window_minutes must be positive")
+	}
+	if d.MinEvents < 2 {
+		return nil, errors.New("min_events must be at least 2")
+	}
+	if d.MinTypes < 1 || d.MinTypes > d.MinEvents {
+		return nil, errors.New("min_types must be between 1 and min_events")
+	}
+	if !validSeverities[d.Severity] {
+		return nil, fmt.Errorf("unknown severity %q", d.Severity)
+	}
+	if d.RiskScore < 0 || d.RiskScore > 100 {
+		return nil, errors.New("risk_score must be between 0 and 100")
+	}
+	return d, nil
+}
+
+func (d *AggressiveDrivingDetector) Name() string { return RuleAggressiveDriving }
+
+func (d *AggressiveDrivingDetector) Bind(state *State) { d.state = state }
+
+// Detect finds nothing on its own; see Combine
+func (d *AggressiveDrivingDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	return nil
+}
+
+func (d *AggressiveDrivingDetector) Combine(event *models.TelemetryEvent, risks []models.RiskEvent) []models.RiskEvent {
+	var counted []models.RiskEvent
+	for _, risk := range risks {
+		if d.counts(risk.EventType) {
+			counted = append(counted, risk)
+		}
+	}
+	if len(counted) == 0 {
+		return nil
+	}
+
+	rule, key := driverKey(RuleAggressiveDriving, event)
+	window, _ := d.state.Get(rule, key).(*aggressiveWindow)
+	if window == nil {
+		window = &aggressiveWindow{}
+		d.state.Put(rule, key, window)
+	}
+	window.length = d.window()
+	window.add(event.Timestamp, counted)
+
+	byType := window.byType()
+	if len(window.entries) < d.MinEvents || len(byType) < d.MinTypes {
+		return nil
+	}
+
+	types := make([]string, 0, len(byType))
+	for eventType := range byType {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	data, err := json.Marshal(map[string]interface{}{
+		"events":         len(window.entries),
+		"by_type":        byType,
+		"window_start":   window.entries[0].at,
+		"window_minutes": d.WindowMinutes,
+	})
+	if err != nil {
+		return nil
+	}
+	risk := newRiskEvent(event, RuleAggressiveDriving, d.Severity, d.RiskScore,
+		fmt.Sprintf("Aggressive driving: %d risky maneuvers (%s) within %.0f minutes",
+			len(window.entries), strings.Join(types, ", "), d.WindowMinutes),
+		string(data))
+	risk.DriverID = event.DriverID
+	window.entries = nil
+	return []models.RiskEvent{risk}
+}
+
+func (d *AggressiveDrivingDetector) counts(eventType string) bool {
+	for _, counted := range d.EventTypes {
+		if counted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *AggressiveDrivingDetector) window() time.Duration {
+	return minutes(d.WindowMinutes)
+}
+
+// aggressiveWindow is the recent counted risk events of one driver (or vehicle)
+type aggressiveWindow struct {
+	length  time.Duration
+	last    time.Time
+	entries []aggressiveEntry // oldest first
+}
+
+type aggressiveEntry struct {
+	at        time.Time
+	eventType string
+}
+
+// add records risks and forgets entries that fell out of the window ending at now
+func (w *aggressiveWindow) add(now time.Time, risks []models.RiskEvent) {
+	if now.After(w.last) {
+		w.last = now
+	}
+	for _, risk := range risks {
+		w.entries = append(w.entries, aggressiveEntry{at: risk.Timestamp, eventType: risk.EventType})
+	}
+	// Episode events such as speeding are reported when they end, stamped
+	// with their start, so entries do not arrive in order
+	sort.SliceStable(w.entries, func(i, j int) bool { return w.entries[i].at.Before(w.entries[j].at) })
+
+	cutoff := w.last.Add(-w.length)
+	kept := w.entries[:0]
+	for _, entry := range w.entries {
+		if entry.at.After(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	w.entries = kept
+}
+
+func (w *aggressiveWindow) byType() map[string]int {
+	counts := make(map[string]int)
+	for _, entry := range w.entries {
+		counts[entry.eventType]++
+	}
+	return counts
+}
+
+func (w *aggressiveWindow) Expired(now time.Time) bool {
+	return now.Sub(w.last) > w.length
+}
+
+// Close reports nothing: composites are raised as they happen
+func (w *aggressiveWindow) Close() []models.RiskEvent {
+	return nil
+}
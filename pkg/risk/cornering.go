@@ -0,0 +1,240 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+const (
+	gravity               = 9.80665 // m/s²
+	metersPerSecondPerMph = 0.44704
+	// minBearingMiles is how far a vehicle must move between two positions for
+	// the bearing between them to be a usable heading (about 5 m)
+	minBearingMiles = 0.003
+)
+
+// HarshCorneringDetector estimates lateral acceleration from the heading change
+// between consecutive samples: a vehicle turning at ω rad/s at speed v pulls
+// v·ω of lateral acceleration. Headings come from the "heading" reading in the
+// event data or, without one, from the bearing between consecutive positions.
+// A turn opens when lateral acceleration exceeds the threshold (g) and becomes
+// one RiskEvent, graded by its peak, once it drops back.
+type HarshCorneringDetector struct {
+	thresholdRule
+	MinSpeed            float64 `json:"min_speed"` // mph
+	MaxSampleGapSeconds float64 `json:"max_sample_gap_seconds"`
+
+	state *State
+}
+
+func newHarshCorneringDetector(params json.RawMessage) (Detector, error) {
+	d := &HarshCorneringDetector{
+		thresholdRule: thresholdRule{Threshold: 0.4, Bands: []Band{
+			{Above: 1.0, Severity: "medium", RiskScore: 55.0},
+			{Above: 1.5, Severity: "high", RiskScore: 75.0},
+		}},
+		MinSpeed:            10,
+		MaxSampleGapSeconds: 5,
+		state:               NewState(),
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if d.Threshold <= 0 {
+		return nil, errors.New("threshold must be positive")
+	}
+	if d.MinSpeed < 0 {
+		return nil, errors.New("min_speed must not be negative")
+	}
+	if d.MaxSampleGapSeconds <= 0 {
+		return nil, errors.New("max_sample_gap_seconds must be positive")
+	}
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *HarshCorneringDetector) Name() string { return RuleHarshCornering }
+
+func (d *HarshCorneringDetector) Bind(state *State) { d.state = state }
+
+func (d *HarshCorneringDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	if event.Speed == nil {
+		return nil
+	}
+
+	var risks []models.RiskEvent
+	track, _ := d.state.Get(RuleHarshCornering, event.VehicleID).(*corneringTrack)
+	if track == nil {
+		track = &corneringTrack{vehicleID: event.VehicleID}
+		d.state.Put(RuleHarshCornering, event.VehicleID, track)
+	} else if !event.Timestamp.After(track.last) {
+		// Late or duplicate sample; the heading has already moved on
+		return nil
+	} else if event.Timestamp.Sub(track.last) > d.maxGap() {
+		// Too far apart to tell how the vehicle turned in between
+		risks = append(risks, track.Close()...)
+		track.headingAt = time.Time{}
+	}
+	track.detector = d
+
+	if heading, ok := track.headingOf(event); ok {
+		if !track.headingAt.IsZero() && event.Timestamp.Sub(track.headingAt) <= d.maxGap() {
+			seconds := event.Timestamp.Sub(track.headingAt).Seconds()
+			change := geo.HeadingChange(track.heading, heading)
+			speed := (track.speed + *event.Speed) / 2
+
+			lateralG := 0.0
+			if speed >= d.MinSpeed {
+				lateralG = math.Abs(speed*metersPerSecondPerMph*change*math.Pi/180/seconds) / gravity
+			}
+
+			if lateralG > d.Threshold {
+				if track.turn == nil {
+					track.turn = &corneringTurn{start: event.Timestamp, latitude: event.Latitude, longitude: event.Longitude}
+				}
+				track.turn.extend(event.Timestamp, lateralG, change, speed)
+			} else {
+				risks = append(risks, track.Close()...)
+			}
+		}
+		track.heading, track.headingAt = heading, event.Timestamp
+	}
+
+	track.last = event.Timestamp
+	track.speed = *event.Speed
+	if event.Latitude != nil && event.Longitude != nil {
+		track.latitude, track.longitude = event.Latitude, event.Longitude
+	}
+	return risks
+}
+
+func (d *HarshCorneringDetector) maxGap() time.Duration {
+	return time.Duration(d.MaxSampleGapSeconds * float64(time.Second))
+}
+
+// corneringTrack is the last known heading of one vehicle and the harsh turn
+// it is in, if any
+type corneringTrack struct {
+	// detector is the one that last saw the vehicle, whose parameters apply
+	// even if the policy was reloaded since the turn started
+	detector  *HarshCorneringDetector
+	vehicleID uint
+
+	last      time.Time
+	speed     float64
+	latitude  *float64
+	longitude *float64
+
+	heading   float64
+	headingAt time.Time // zero until a heading is known
+
+	turn *corneringTurn
+}
+
+// headingOf returns the event's heading: the reported one, or the bearing from
+// the previous position if the vehicle moved far enough to measure it
+func (t *corneringTrack) headingOf(event *models.TelemetryEvent) (float64, bool) {
+	if event.Data != "" {
+		var data struct {
+			Heading *float64 `json:"heading"`
+		}
+		if json.Unmarshal([]byte(event.Data), &data) == nil && data.Heading != nil {
+			return *data.Heading, true
+		}
+	}
+
+	if event.Latitude == nil || event.Longitude == nil || t.latitude == nil || t.longitude == nil {
+		return 0, false
+	}
+	if geo.DistanceMiles(*t.latitude, *t.longitude, *event.Latitude, *event.Longitude) < minBearingMiles {
+		return 0, false
+	}
+	return geo.BearingDegrees(*t.latitude, *t.longitude, *event.Latitude, *event.Longitude), true
+}
+
+func (t *corneringTrack) Expired(now time.Time) bool {
+	return t.detector == nil || now.Sub(t.last) > t.detector.maxGap()
+}
+
+// Close reports the open turn, if any
+func (t *corneringTrack) Close() []models.RiskEvent {
+	turn := t.turn
+	t.turn = nil
+	if turn == nil || t.detector == nil {
+		return nil
+	}
+	d := t.detector
+	band, ok := d.classify(turn.peakG)
+	if !ok {
+		return nil
+	}
+
+	direction := "right"
+	if turn.headingChange < 0 {
+		direction = "left"
+	}
+	data, err := json.Marshal(struct {
+		Start         time.Time `json:"start"`
+		End           time.Time `json:"end"`
+		PeakLateralG  float64   `json:"peak_lateral_g"`
+		HeadingChange float64   `json:"heading_change"`
+		Direction     string    `json:"direction"`
+		PeakSpeed     float64   `json:"peak_speed"`
+		Threshold     float64   `json:"threshold"`
+	}{
+		Start:         turn.start,
+		End:           turn.end,
+		PeakLateralG:  math.Round(turn.peakG*100) / 100,
+		HeadingChange: math.Round(math.Abs(turn.headingChange)*10) / 10,
+		Direction:     direction,
+		PeakSpeed:     turn.peakSpeed,
+		Threshold:     d.Threshold,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return []models.RiskEvent{{
+		VehicleID: t.vehicleID,
+		EventType: RuleHarshCornering,
+		Severity:  band.Severity,
+		RiskScore: band.RiskScore,
+		Timestamp: turn.start,
+		Latitude:  turn.latitude,
+		Longitude: turn.longitude,
+		Description: fmt.Sprintf("Harsh %s turn: %.2f g lateral through %.0f degrees at %.0f mph",
+			direction, turn.peakG, math.Abs(turn.headingChange), turn.peakSpeed),
+		Data: string(data),
+	}}
+}
+
+// corneringTurn is a run of samples above the lateral acceleration threshold
+type corneringTurn struct {
+	start     time.Time
+	latitude  *float64
+	longitude *float64
+
+	end           time.Time
+	peakG         float64
+	headingChange float64 // signed, degrees
+	peakSpeed     float64
+}
+
+func (t *corneringTurn) extend(at time.Time, lateralG, change, speed float64) {
+	t.end = at
+	t.headingChange += change
+	if lateralG > t.peakG {
+		t.peakG = lateralG
+	}
+	if speed > t.peakSpeed {
+		t.peakSpeed = speed
+	}
+}
@@ -0,0 +1,167 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+type turnData struct {
+	PeakLateralG  float64 `json:"peak_lateral_g"`
+	HeadingChange float64 `json:"heading_change"`
+	Direction     string  `json:"direction"`
+}
+
+func corneringAnalyzer(t *testing.T) *Analyzer {
+	analyzer, err := NewAnalyzerFromConfig(Config{Rules: []RuleConfig{{Name: RuleHarshCornering}}})
+	require.NoError(t, err)
+	return analyzer
+}
+
+// withHeadings returns one sample per second at speed, reporting the headings
+func withHeadings(start time.Time, speed float64, headings ...float64) []*models.TelemetryEvent {
+	samples := drive(1, start, speed, len(headings))
+	for i, sample := range samples {
+		sample.Data = fmt.Sprintf(`{"heading": %g}`, headings[i])
+	}
+	return samples
+}
+
+func TestHarshCorneringFromReportedHeading(t *testing.T) {
+	analyzer := corneringAnalyzer(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// A right-angle turn in three seconds at 30 mph pulls about 0.72 g
+	risks := analyzeAll(analyzer, withHeadings(start, 30, 0, 0, 30, 60, 90, 90, 90))
+	require.Len(t, risks, 1)
+
+	risk := risks[0]
+	assert.Equal(t, RuleHarshCornering, risk.EventType)
+	assert.Equal(t, "high", risk.Severity)
+	assert.Equal(t, start.Add(2*time.Second), risk.Timestamp)
+
+	var data turnData
+	require.NoError(t, json.Unmarshal([]byte(risk.Data), &data))
+	assert.InDelta(t, 0.72, data.PeakLateralG, 0.01)
+	assert.Equal(t, 90.0, data.HeadingChange)
+	assert.Equal(t, "right", data.Direction)
+
+	// The same turn taken gently, or crawling through a car park, is fine
+	analyzer = corneringAnalyzer(t)
+	assert.Empty(t, analyzeAll(analyzer, withHeadings(start, 30, 0, 5, 10, 15, 20, 25, 30)))
+	assert.Empty(t, analyzeAll(analyzer, withHeadings(start.Add(time.Minute), 5, 0, 90, 180, 270)))
+}
+
+func TestHarshCorneringFromPositions(t *testing.T) {
+	analyzer := corneringAnalyzer(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// North for two seconds, then a sharp left to the west at 30 mph
+	positions := [][2]float64{
+		{40, -100}, {40.00012, -100}, {40.00024, -100},
+		{40.00024, -100.000157}, {40.00024, -100.000314}, {40.00024, -100.000471},
+	}
+	samples := drive(1, start, 30, len(positions))
+	for i, sample := range samples {
+		sample.Latitude, sample.Longitude = &positions[i][0], &positions[i][1]
+	}
+
+	risks := analyzeAll(analyzer, samples)
+	require.Len(t, risks, 1)
+
+	var data turnData
+	require.NoError(t, json.Unmarshal([]byte(risks[0].Data), &data))
+	assert.Equal(t, "left", data.Direction)
+	assert.InDelta(t, 90, data.HeadingChange, 0.5)
+	assert.Equal(t, "high", risks[0].Severity)
+}
+
+func TestHarshCorneringTurnClosesOnFlush(t *testing.T) {
+	analyzer := corneringAnalyzer(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The vehicle stops reporting halfway through a 0.48 g turn
+	assert.Empty(t, analyzeAll(analyzer, withHeadings(start, 30, 0, 20, 40)))
+
+	risks := analyzer.Flush(start.Add(time.Minute))
+	require.Len(t, risks, 1)
+	assert.Equal(t, "medium", risks[0].Severity)
+	assert.Zero(t, openEpisodes(analyzer.state, RuleHarshCornering))
+}
+
+func TestAggressiveDrivingCombinesManeuvers(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	driverID := uint(9)
+
+	maneuver := func(vehicleID uint, at time.Duration, acceleration float64) []models.RiskEvent {
+		sample := sampleAt(vehicleID, start.Add(at), 40)
+		sample.Acceleration = &acceleration
+		sample.DriverID = &driverID
+		return analyzer.Analyze(sample)
+	}
+
+	assert.Len(t, maneuver(1, 0, -7), 1)
+	assert.Len(t, maneuver(1, 2*time.Minute, 5), 1)
+	// The third maneuver, in another vehicle, still counts against the driver
+	risks := maneuver(2, 4*time.Minute, -7)
+	require.Len(t, risks, 2)
+
+	composite := risks[1]
+	assert.Equal(t, RuleAggressiveDriving, composite.EventType)
+	assert.Equal(t, "high", composite.Severity)
+	assert.Equal(t, driverID, *composite.DriverID)
+	assert.Equal(t, start.Add(4*time.Minute), composite.Timestamp)
+
+	var data struct {
+		Events int            `json:"events"`
+		ByType map[string]int `json:"by_type"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(composite.Data), &data))
+	assert.Equal(t, 3, data.Events)
+	assert.Equal(t, map[string]int{RuleHarshBraking: 2, RuleRapidAcceleration: 1}, data.ByType)
+
+	// Those maneuvers are spent; two more are not enough for another one
+	assert.Len(t, maneuver(1, 5*time.Minute, -7), 1)
+	assert.Len(t, maneuver(1, 6*time.Minute, 5), 1)
+}
+
+func TestAggressiveDrivingNeedsManeuversCloseTogether(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var risks []models.RiskEvent
+	for i, acceleration := range []float64{-7, 5, -7, 5} {
+		sample := sampleAt(1, start.Add(time.Duration(i)*6*time.Minute), 40)
+		sample.Acceleration = &acceleration
+		risks = append(risks, analyzer.Analyze(sample)...)
+	}
+	assert.Len(t, risks, 4, "never three maneuvers within ten minutes")
+
+	// Repeating one kind of maneuver is not enough on its own
+	for i := 0; i < 3; i++ {
+		risks = append(risks, analyzer.Analyze(telemetry(40, -7))...)
+	}
+	assert.Len(t, risks, 7)
+}
+
+func TestAggressiveDrivingParams(t *testing.T) {
+	for _, params := range []string{
+		`{"event_types": []}`,
+		`{"event_types": ["aggressive_driving"]}`,
+		`{"min_events": 1}`,
+		`{"min_types": 4}`,
+		`{"severity": "extreme"}`,
+	} {
+		_, err := Build(Config{Rules: []RuleConfig{{Name: RuleAggressiveDriving, Params: json.RawMessage(params)}}})
+		assert.Error(t, err, params)
+	}
+}
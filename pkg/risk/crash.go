@@ -0,0 +1,217 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// How a crash was confirmed, reported in its data
+const (
+	CrashConfirmedStopped  = "stopped"
+	CrashConfirmedNoSignal = "no_signal"
+)
+
+// CrashDetector looks for an impact, an acceleration reading of at least
+// ImpactThreshold (m/s²) in either direction, followed by the vehicle standing
+// still. A sample at or below StoppedSpeed (mph) within ConfirmSeconds of the
+// impact confirms the crash. A vehicle that goes silent after the impact
+// counts as a crash too, since a crash can take the telematics unit with it;
+// one that keeps driving does not. Silence is judged by telemetry time: the
+// vehicle's next sample coming in after ConfirmSeconds, or a flush at a time
+// by which every sample received has been analyzed.
+//
+// The impact sample itself is the crash detector's: the analyzer does not
+// also report it as harsh braking or rapid acceleration.
+type CrashDetector struct {
+	ImpactThreshold float64 `json:"impact_threshold"`
+	StoppedSpeed    float64 `json:"stopped_speed"`
+	ConfirmSeconds  float64 `json:"confirm_seconds"`
+
+	state *State
+}
+
+func newCrashDetector(params json.RawMessage) (Detector, error) {
+	d := &CrashDetector{
+		ImpactThreshold: 3 * gravity,
+		StoppedSpeed:    2,
+		ConfirmSeconds:  15,
+		state:           NewState(),
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if d.ImpactThreshold <= 0 {
+		return nil, errors.New("impact_threshold must be positive")
+	}
+	if d.StoppedSpeed < 0 {
+		return nil, errors.New("stopped_speed must not be negative")
+	}
+	if d.ConfirmSeconds <= 0 {
+		return nil, errors.New("confirm_seconds must be positive")
+	}
+	return d, nil
+}
+
+func (d *CrashDetector) Name() string { return RuleCrash }
+
+func (d *CrashDetector) Bind(state *State) { d.state = state }
+
+// impact reports whether event is a reading the detector takes for an impact
+func (d *CrashDetector) impact(event *models.TelemetryEvent) bool {
+	return event.Acceleration != nil && math.Abs(*event.Acceleration) >= d.ImpactThreshold
+}
+
+func (d *CrashDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	impact := d.impact(event)
+
+	var risks []models.RiskEvent
+	pending, _ := d.state.Get(RuleCrash, event.VehicleID).(*pendingCrash)
+	if pending != nil {
+		if event.Timestamp.Before(pending.at) {
+			return nil
+		}
+		if event.Timestamp.Sub(pending.at) > pending.confirmWindow() {
+			d.state.Delete(RuleCrash, event.VehicleID)
+			risks = append(risks, pending.Close()...)
+			pending = nil
+		}
+	}
+
+	if pending == nil {
+		if impact {
+			d.state.Put(RuleCrash, event.VehicleID, newPendingCrash(d, event))
+		}
+		return risks
+	}
+
+	pending.detector = d
+	if impact && math.Abs(*event.Acceleration) > math.Abs(pending.peak) {
+		// A secondary impact, such as a vehicle spinning into a barrier
+		pending.peak = *event.Acceleration
+	}
+	if event.Speed == nil || event.Timestamp.Equal(pending.at) {
+		return risks
+	}
+	if *event.Speed <= d.StoppedSpeed {
+		d.state.Delete(RuleCrash, event.VehicleID)
+		return append(risks, pending.crash(CrashConfirmedStopped, event.Timestamp))
+	}
+	pending.moving = true
+	return risks
+}
+
+// withoutImpactAcceleration drops the harsh braking and rapid acceleration
+// events found in an impact sample, so that a crash is not counted towards
+// aggressive driving as well
+func withoutImpactAcceleration(detectors []Detector, event *models.TelemetryEvent, risks []models.RiskEvent) []models.RiskEvent {
+	impact := false
+	for _, detector := range detectors {
+		if crash, ok := detector.(*CrashDetector); ok && crash.impact(event) {
+			impact = true
+			break
+		}
+	}
+	if !impact {
+		return risks
+	}
+
+	kept := risks[:0]
+	for _, found := range risks {
+		if found.EventType != RuleHarshBraking && found.EventType != RuleRapidAcceleration {
+			kept = append(kept, found)
+		}
+	}
+	return kept
+}
+
+// pendingCrash is an impact waiting to be confirmed
+type pendingCrash struct {
+	detector  *CrashDetector
+	vehicleID uint
+	driverID  *uint
+
+	at        time.Time
+	latitude  *float64
+	longitude *float64
+	speed     *float64 // at impact
+	peak      float64  // m/s², signed
+
+	// moving is set once a later sample shows the vehicle still driving
+	moving bool
+}
+
+func newPendingCrash(d *CrashDetector, event *models.TelemetryEvent) *pendingCrash {
+	return &pendingCrash{
+		detector:  d,
+		vehicleID: event.VehicleID,
+		driverID:  event.DriverID,
+		at:        event.Timestamp,
+		latitude:  event.Latitude,
+		longitude: event.Longitude,
+		speed:     event.Speed,
+		peak:      *event.Acceleration,
+	}
+}
+
+func (p *pendingCrash) confirmWindow() time.Duration {
+	return time.Duration(p.detector.ConfirmSeconds * float64(time.Second))
+}
+
+// Expired reports whether the vehicle stayed silent through the confirmation
+// window, now being a time by which its telemetry has all been analyzed
+func (p *pendingCrash) Expired(now time.Time) bool {
+	return now.Sub(p.at) > p.confirmWindow()
+}
+
+// Close reports the impact as a crash if nothing was heard from the vehicle
+// after it
+func (p *pendingCrash) Close() []models.RiskEvent {
+	if p.moving {
+		return nil
+	}
+	return []models.RiskEvent{p.crash(CrashConfirmedNoSignal, p.at)}
+}
+
+func (p *pendingCrash) crash(confirmedBy string, confirmedAt time.Time) models.RiskEvent {
+	data, _ := json.Marshal(struct {
+		PeakAcceleration float64   `json:"peak_acceleration"`
+		PeakG            float64   `json:"peak_g"`
+		SpeedAtImpact    *float64  `json:"speed_at_impact"`
+		ConfirmedBy      string    `json:"confirmed_by"`
+		ConfirmedAt      time.Time `json:"confirmed_at"`
+	}{
+		PeakAcceleration: p.peak,
+		PeakG:            math.Round(math.Abs(p.peak)/gravity*10) / 10,
+		SpeedAtImpact:    p.speed,
+		ConfirmedBy:      confirmedBy,
+		ConfirmedAt:      confirmedAt,
+	})
+
+	description := fmt.Sprintf("Possible crash: %.1f g impact", math.Abs(p.peak)/gravity)
+	if p.speed != nil {
+		description += fmt.Sprintf(" at %.0f mph", *p.speed)
+	}
+	if confirmedBy == CrashConfirmedStopped {
+		description += ", vehicle stopped"
+	} else {
+		description += ", vehicle stopped reporting"
+	}
+
+	return models.RiskEvent{
+		VehicleID:   p.vehicleID,
+		DriverID:    p.driverID,
+		EventType:   RuleCrash,
+		Severity:    "critical",
+		RiskScore:   100,
+		Timestamp:   p.at,
+		Latitude:    p.latitude,
+		Longitude:   p.longitude,
+		Description: description,
+		Data:        string(data),
+	}
+}
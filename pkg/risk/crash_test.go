@@ -0,0 +1,105 @@
+package risk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func newCrashAnalyzer(t *testing.T) *Analyzer {
+	analyzer, err := NewAnalyzerFromConfig(Config{Rules: []RuleConfig{{Name: RuleCrash}}})
+	require.NoError(t, err)
+	return analyzer
+}
+
+func impactAt(vehicleID uint, timestamp time.Time, speed, acceleration float64) *models.TelemetryEvent {
+	sample := sampleAt(vehicleID, timestamp, speed)
+	sample.Acceleration = &acceleration
+	return sample
+}
+
+func crashConfirmation(t *testing.T, risk models.RiskEvent) string {
+	var data struct {
+		ConfirmedBy string `json:"confirmed_by"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(risk.Data), &data))
+	return data.ConfirmedBy
+}
+
+func TestImpactFollowedByStopIsACrash(t *testing.T) {
+	analyzer := newCrashAnalyzer(t)
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	driverID := uint(7)
+
+	samples := drive(1, start, 45, 5)
+	impact := impactAt(1, start.Add(5*time.Second), 42, -45)
+	impact.DriverID = &driverID
+	samples = append(samples, impact, sampleAt(1, start.Add(8*time.Second), 0))
+
+	risks := analyzeAll(analyzer, samples)
+	require.Len(t, risks, 1)
+	crash := risks[0]
+	assert.Equal(t, RuleCrash, crash.EventType)
+	assert.Equal(t, "critical", crash.Severity)
+	assert.Equal(t, 100.0, crash.RiskScore)
+	assert.Equal(t, impact.Timestamp, crash.Timestamp)
+	assert.Equal(t, &driverID, crash.DriverID)
+	assert.Equal(t, CrashConfirmedStopped, crashConfirmation(t, crash))
+}
+
+func TestImpactIsNotAlsoHarshBraking(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	samples := drive(1, start, 45, 5)
+	samples = append(samples, impactAt(1, start.Add(5*time.Second), 42, -45), impactAt(1, start.Add(6*time.Second), 20, -8))
+	samples = append(samples, sampleAt(1, start.Add(8*time.Second), 0))
+
+	risks := analyzeAll(analyzer, samples)
+	require.Len(t, risks, 2)
+	assert.Equal(t, RuleHarshBraking, risks[0].EventType, "braking after the impact still counts")
+	assert.Equal(t, start.Add(6*time.Second), risks[0].Timestamp)
+	assert.Equal(t, RuleCrash, risks[1].EventType)
+}
+
+func TestImpactWhileStillDrivingIsNotACrash(t *testing.T) {
+	analyzer := newCrashAnalyzer(t)
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	// A pothole: the vehicle carries on at speed
+	samples := []*models.TelemetryEvent{impactAt(1, start, 40, 32)}
+	samples = append(samples, drive(1, start.Add(time.Second), 40, 30)...)
+
+	assert.Empty(t, analyzeAll(analyzer, samples))
+	assert.Empty(t, analyzer.Flush(start.Add(5*time.Minute)))
+}
+
+func TestImpactFollowedBySilenceIsACrash(t *testing.T) {
+	analyzer := newCrashAnalyzer(t)
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, analyzer.Analyze(impactAt(1, start, 55, 60)))
+	assert.Empty(t, analyzer.Flush(start.Add(10*time.Second)), "still within the confirmation window")
+
+	risks := analyzer.Flush(start.Add(time.Minute))
+	require.Len(t, risks, 1)
+	assert.Equal(t, CrashConfirmedNoSignal, crashConfirmation(t, risks[0]))
+
+	// The vehicle's next sample shows the gap in telemetry time, however
+	// late it is analyzed
+	assert.Empty(t, analyzer.Analyze(impactAt(2, start, 55, 60)))
+	risks = analyzer.Analyze(sampleAt(2, start.Add(time.Minute), 0))
+	require.Len(t, risks, 1)
+	assert.Equal(t, CrashConfirmedNoSignal, crashConfirmation(t, risks[0]))
+}
+
+func TestCrashParamsAreValidated(t *testing.T) {
+	_, err := NewAnalyzerFromConfig(Config{Rules: []RuleConfig{{Name: RuleCrash, Params: json.RawMessage(`{"confirm_seconds": 0}`)}}})
+	assert.Error(t, err)
+}
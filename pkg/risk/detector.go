@@ -0,0 +1,264 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Detector inspects a single telemetry event and reports the risky behavior
+// it finds. Each rule (speeding, harsh braking, ...) is its own Detector.
+type Detector interface {
+	// Name is the registry key the detector was built from
+	Name() string
+	Detect(event *models.TelemetryEvent) []models.RiskEvent
+}
+
+// CompositeDetector derives risk events from what the other detectors found in
+// the same telemetry event. The analyzer runs composites after every other
+// detector, passing Combine their risk events; Detect is not called.
+type CompositeDetector interface {
+	Detector
+	Combine(event *models.TelemetryEvent, risks []models.RiskEvent) []models.RiskEvent
+}
+
+// Factory builds a detector from its JSON parameters. params is empty when the
+// configuration does not override anything, in which case defaults apply.
+type Factory func(params json.RawMessage) (Detector, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a detector available to configuration under name. It is
+// meant to be called from init and panics on duplicate names.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("risk: detector %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of all registered detectors, sorted
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RuleConfig enables and parameterizes one registered detector
+type RuleConfig struct {
+	Name    string          `json:"name"`
+	Enabled *bool           `json:"enabled,omitempty"` // defaults to true
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsEnabled reports whether the rule should run
+func (r RuleConfig) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// Config lists the rules the analyzer runs, in order
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// Merge layers override on top of c. Rules are matched by name: an override may
+// disable a rule, and its params replace the base params key by key, so a
+// policy that only sets a threshold keeps the base severity bands. Rules that
+// only appear in override are appended.
+func (c Config) Merge(override Config) (Config, error) {
+	merged := Config{Rules: append([]RuleConfig(nil), c.Rules...)}
+
+	for _, rule := range override.Rules {
+		i := merged.index(rule.Name)
+		if i < 0 {
+			merged.Rules = append(merged.Rules, rule)
+			continue
+		}
+
+		if rule.Enabled != nil {
+			merged.Rules[i].Enabled = rule.Enabled
+		}
+		params, err := mergeParams(merged.Rules[i].Params, rule.Params)
+		if err != nil {
+			return Config{}, fmt.Errorf("risk rule %q: %w", rule.Name, err)
+		}
+		merged.Rules[i].Params = params
+	}
+
+	return merged, nil
+}
+
+func (c Config) index(name string) int {
+	for i, rule := range c.Rules {
+		if rule.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeParams overlays the top-level keys of override onto base
+func mergeParams(base, override json.RawMessage) (json.RawMessage, error) {
+	if len(override) == 0 {
+		return base, nil
+	}
+	if len(base) == 0 {
+		return override, nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(base, &fields); err != nil {
+		return nil, fmt.Errorf("params must be a JSON object: %w", err)
+	}
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal(override, &overrides); err != nil {
+		return nil, fmt.Errorf("params must be a JSON object: %w", err)
+	}
+	for key, value := range overrides {
+		fields[key] = value
+	}
+
+	return json.Marshal(fields)
+}
+
+// LoadConfig reads a rule configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read risk rules: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse risk rules %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Build instantiates the enabled detectors of cfg
+func Build(cfg Config) ([]Detector, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	detectors := make([]Detector, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		factory, ok := registry[rule.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown risk rule %q", rule.Name)
+		}
+		if !rule.IsEnabled() {
+			continue
+		}
+
+		detector, err := factory(rule.Params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters for risk rule %q: %w", rule.Name, err)
+		}
+		detectors = append(detectors, detector)
+	}
+
+	return detectors, nil
+}
+
+// decodeParams overlays params onto dst, which holds the detector's defaults
+func decodeParams(params json.RawMessage, dst interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	return json.Unmarshal(params, dst)
+}
+
+// Analyzer runs every configured detector against telemetry events
+type Analyzer struct {
+	detectors []Detector
+	state     *State
+	// scoring is nil unless a Scorer rescores the risk events
+	scoring *modelScoring
+}
+
+func NewAnalyzer(detectors ...Detector) *Analyzer {
+	return newAnalyzer(NewState(), detectors)
+}
+
+// newAnalyzer binds the stateful detectors to state, which may be shared with
+// other analyzers
+func newAnalyzer(state *State, detectors []Detector) *Analyzer {
+	for _, detector := range detectors {
+		if stateful, ok := detector.(StatefulDetector); ok {
+			stateful.Bind(state)
+		}
+	}
+	return &Analyzer{detectors: detectors, state: state}
+}
+
+// NewAnalyzerFromConfig builds the detectors in cfg and wraps them in an Analyzer
+func NewAnalyzerFromConfig(cfg Config) (*Analyzer, error) {
+	return newAnalyzerFromConfig(cfg, NewState())
+}
+
+func newAnalyzerFromConfig(cfg Config, state *State) (*Analyzer, error) {
+	detectors, err := Build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newAnalyzer(state, detectors), nil
+}
+
+// Detectors returns the detectors the analyzer runs
+func (a *Analyzer) Detectors() []Detector {
+	return a.detectors
+}
+
+// Analyze returns the risk events every detector found in event
+func (a *Analyzer) Analyze(event *models.TelemetryEvent) []models.RiskEvent {
+	var risks []models.RiskEvent
+	var composites []CompositeDetector
+	for _, detector := range a.detectors {
+		if composite, ok := detector.(CompositeDetector); ok {
+			composites = append(composites, composite)
+			continue
+		}
+		risks = append(risks, detector.Detect(event)...)
+	}
+	risks = withoutImpactAcceleration(a.detectors, event, risks)
+	for _, composite := range composites {
+		risks = append(risks, composite.Combine(event, risks)...)
+	}
+	if a.scoring != nil {
+		a.scoring.observe(event)
+		a.scoring.rescore(risks)
+	}
+	return risks
+}
+
+// Flush closes the episodes of vehicles that stopped reporting; see State.Flush
+func (a *Analyzer) Flush(now time.Time) []models.RiskEvent {
+	return flush(a.state, a.scoring, now)
+}
+
+func flush(state *State, scoring *modelScoring, now time.Time) []models.RiskEvent {
+	risks := state.Flush(now)
+	if scoring != nil {
+		scoring.rescore(risks)
+		scoring.flush(now)
+	}
+	return risks
+}
@@ -0,0 +1,130 @@
+package risk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func telemetry(speed, acceleration float64) *models.TelemetryEvent {
+	return &models.TelemetryEvent{
+		VehicleID:    7,
+		Timestamp:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Speed:        &speed,
+		Acceleration: &acceleration,
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	require.Len(t, analyzer.Detectors(), 11)
+
+	tests := []struct {
+		name         string
+		speed, accel float64
+		wantType     string
+		wantSeverity string
+		wantScore    float64
+	}{
+		{"within limits", 60, 1, "", "", 0},
+		// A single sample is not sustained speeding
+		{"speeding sample", 125, 0, "", "", 0},
+		{"rapid acceleration", 50, 4.5, RuleRapidAcceleration, "medium", 60},
+		{"harsh braking", 50, -7, RuleHarshBraking, "medium", 65},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risks := analyzer.Analyze(telemetry(tt.speed, tt.accel))
+			if tt.wantType == "" {
+				assert.Empty(t, risks)
+				return
+			}
+			require.Len(t, risks, 1)
+			assert.Equal(t, tt.wantType, risks[0].EventType)
+			assert.Equal(t, tt.wantSeverity, risks[0].Severity)
+			assert.Equal(t, tt.wantScore, risks[0].RiskScore)
+			assert.Equal(t, uint(7), risks[0].VehicleID)
+			assert.True(t, json.Valid([]byte(risks[0].Data)))
+		})
+	}
+}
+
+func TestBuildAppliesConfig(t *testing.T) {
+	disabled := false
+	cfg := Config{Rules: []RuleConfig{
+		{Name: RuleRapidAcceleration, Params: json.RawMessage(`{"threshold": 2}`)},
+		{Name: RuleHarshBraking, Enabled: &disabled},
+	}}
+
+	analyzer, err := NewAnalyzerFromConfig(cfg)
+	require.NoError(t, err)
+	require.Len(t, analyzer.Detectors(), 1)
+
+	assert.Empty(t, analyzer.Analyze(telemetry(50, -9)))
+
+	// Overridden threshold, with the bands keeping their defaults
+	risks := analyzer.Analyze(telemetry(50, 3))
+	require.Len(t, risks, 1)
+	assert.Equal(t, RuleRapidAcceleration, risks[0].EventType)
+	assert.Equal(t, "medium", risks[0].Severity)
+	assert.JSONEq(t, `{"acceleration": 3.0, "threshold": 2.0}`, risks[0].Data)
+}
+
+func TestBuildRejectsInvalidConfig(t *testing.T) {
+	_, err := Build(Config{Rules: []RuleConfig{{Name: "teleportation"}}})
+	assert.ErrorContains(t, err, "unknown risk rule")
+
+	_, err = Build(Config{Rules: []RuleConfig{{Name: RuleHarshBraking, Params: json.RawMessage(`{"threshold": 6}`)}}})
+	assert.ErrorContains(t, err, "threshold must be negative")
+
+	_, err = Build(Config{Rules: []RuleConfig{{Name: RuleSpeeding, Params: json.RawMessage(`{"threshold": "fast"}`)}}})
+	assert.Error(t, err)
+}
+
+type stubDetector struct{}
+
+func (stubDetector) Name() string { return "stub" }
+
+func (stubDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	return []models.RiskEvent{{VehicleID: event.VehicleID, EventType: "stub"}}
+}
+
+func TestRegisterCustomDetector(t *testing.T) {
+	Register("stub", func(json.RawMessage) (Detector, error) { return stubDetector{}, nil })
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "stub")
+		registryMu.Unlock()
+	})
+
+	assert.Contains(t, Registered(), "stub")
+	assert.Panics(t, func() { Register("stub", nil) })
+
+	analyzer, err := NewAnalyzerFromConfig(Config{Rules: []RuleConfig{{Name: "stub"}}})
+	require.NoError(t, err)
+	risks := analyzer.Analyze(telemetry(0, 0))
+	require.Len(t, risks, 1)
+	assert.Equal(t, "stub", risks[0].EventType)
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"name": "speeding", "enabled": false}]}`), 0o600))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, cfg.Rules, 1)
+	assert.False(t, cfg.Rules[0].IsEnabled())
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
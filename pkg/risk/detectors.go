@@ -0,0 +1,251 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Built-in rule names
+const (
+	RuleSpeeding          = "speeding"
+	RuleHarshBraking      = "harsh_braking"
+	RuleRapidAcceleration = "rapid_acceleration"
+	RuleFatigue           = "fatigue"
+	RuleHarshCornering    = "harsh_cornering"
+	RuleAggressiveDriving = "aggressive_driving"
+	RulePhoneUse          = "phone_use"
+	RuleCameraDistraction = "camera_distraction"
+	RuleSeatbelt          = "seatbelt"
+	RuleFollowingDistance = "following_distance"
+	RuleCrash             = "crash"
+)
+
+// Risk event types raised by rules that are not named after them
+const (
+	EventDistractedDriving = "distracted_driving"
+	EventSeatbeltViolation = "seatbelt_violation"
+	EventTailgating        = "tailgating"
+)
+
+// EventTypes returns the risk event types the built-in rules raise. Risk
+// events of other types, such as geofence, route and anomaly events, are
+// raised outside the rules.
+func EventTypes() []string {
+	return []string{
+		RuleSpeeding, RuleHarshBraking, RuleRapidAcceleration, RuleFatigue, RuleHarshCornering,
+		RuleAggressiveDriving, RuleCrash, EventDistractedDriving, EventSeatbeltViolation, EventTailgating,
+	}
+}
+
+func init() {
+	Register(RuleSpeeding, newSpeedingDetector)
+	Register(RuleHarshBraking, newHarshBrakingDetector)
+	Register(RuleRapidAcceleration, newRapidAccelerationDetector)
+	Register(RuleFatigue, newFatigueDetector)
+	Register(RuleHarshCornering, newHarshCorneringDetector)
+	Register(RuleAggressiveDriving, newAggressiveDrivingDetector)
+	Register(RulePhoneUse, newPhoneUseDetector)
+	Register(RuleCameraDistraction, newCameraDistractionDetector)
+	Register(RuleSeatbelt, newSeatbeltDetector)
+	Register(RuleFollowingDistance, newFollowingDistanceDetector)
+	Register(RuleCrash, newCrashDetector)
+}
+
+// DefaultConfig enables the built-in rules with their default parameters
+func DefaultConfig() Config {
+	return Config{Rules: []RuleConfig{
+		{Name: RuleSpeeding},
+		{Name: RuleRapidAcceleration},
+		{Name: RuleHarshBraking},
+		{Name: RuleFatigue},
+		{Name: RuleHarshCornering},
+		{Name: RulePhoneUse},
+		{Name: RuleCameraDistraction},
+		{Name: RuleSeatbelt},
+		{Name: RuleFollowingDistance},
+		{Name: RuleCrash},
+		{Name: RuleAggressiveDriving},
+	}}
+}
+
+// RaisesAlert reports whether risk events of the given severity alert the fleet
+func RaisesAlert(severity string) bool {
+	return severity == "high" || severity == "critical"
+}
+
+// BaseConfig is the configuration the risk engine runs before fleet policies
+// are layered on top: the JSON rule file in RISK_RULES_FILE or, without one,
+// the built-in rules with the legacy threshold variables
+func BaseConfig() (Config, error) {
+	if path := os.Getenv("RISK_RULES_FILE"); path != "" {
+		return LoadConfig(path)
+	}
+
+	cfg := DefaultConfig()
+	thresholds := map[string]float64{
+		RuleSpeeding:          envFloat("SPEED_THRESHOLD", 80.0),   // mph
+		RuleRapidAcceleration: envFloat("ACCEL_THRESHOLD", 4.0),    // m/s²
+		RuleHarshBraking:      envFloat("BRAKING_THRESHOLD", -6.0), // m/s²
+	}
+	for i := range cfg.Rules {
+		if threshold, ok := thresholds[cfg.Rules[i].Name]; ok {
+			cfg.Rules[i].Params = json.RawMessage(fmt.Sprintf(`{"threshold": %g}`, threshold))
+		}
+	}
+	return cfg, nil
+}
+
+func envFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// Band maps how far a reading exceeds its threshold onto a severity. Above is a
+// multiple of the threshold: with a threshold of 80 mph, a band above 1.3
+// applies from 104 mph.
+type Band struct {
+	Above     float64 `json:"above"`
+	Severity  string  `json:"severity"`
+	RiskScore float64 `json:"risk_score"`
+}
+
+// thresholdRule is the shared parameter set of detectors that compare a single
+// reading against a threshold and grade the excess with severity bands
+type thresholdRule struct {
+	Threshold float64 `json:"threshold"`
+	Bands     []Band  `json:"bands"`
+}
+
+// validate checks the bands are usable; bands must be sorted by Above
+func (r *thresholdRule) validate() error {
+	if len(r.Bands) == 0 {
+		return errors.New("at least one severity band is required")
+	}
+	for i, band := range r.Bands {
+		if band.Above <= 0 {
+			return fmt.Errorf("band %d: above must be positive", i)
+		}
+		if i > 0 && band.Above <= r.Bands[i-1].Above {
+			return fmt.Errorf("band %d: bands must be sorted by above", i)
+		}
+		if !validSeverities[band.Severity] {
+			return fmt.Errorf("band %d: unknown severity %q", i, band.Severity)
+		}
+		if band.RiskScore < 0 || band.RiskScore > 100 {
+			return fmt.Errorf("band %d: risk_score must be between 0 and 100", i)
+		}
+	}
+	return nil
+}
+
+// classify returns the highest band the reading exceeds. Dividing by the
+// threshold makes negative thresholds (braking) grade the same way.
+func (r *thresholdRule) classify(value float64) (Band, bool) {
+	ratio := value / r.Threshold
+	for i := len(r.Bands) - 1; i >= 0; i-- {
+		if ratio > r.Bands[i].Above {
+			return r.Bands[i], true
+		}
+	}
+	return Band{}, false
+}
+
+var validSeverities = map[string]bool{"low": true, "medium": true, "high": true, "critical": true}
+
+// HarshBrakingDetector flags deceleration below a negative threshold (m/s²)
+type HarshBrakingDetector struct {
+	thresholdRule
+}
+
+func newHarshBrakingDetector(params json.RawMessage) (Detector, error) {
+	d := &HarshBrakingDetector{thresholdRule{Threshold: -6.0, Bands: []Band{
+		{Above: 1.0, Severity: "medium", RiskScore: 65.0},
+	}}}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if d.Threshold >= 0 {
+		return nil, errors.New("threshold must be negative")
+	}
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *HarshBrakingDetector) Name() string { return RuleHarshBraking }
+
+func (d *HarshBrakingDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	if event.Acceleration == nil {
+		return nil
+	}
+	band, ok := d.classify(*event.Acceleration)
+	if !ok {
+		return nil
+	}
+
+	return []models.RiskEvent{newRiskEvent(event, RuleHarshBraking, band.Severity, band.RiskScore,
+		fmt.Sprintf("Harsh braking detected: %.1f m/s²", *event.Acceleration),
+		fmt.Sprintf(`{"acceleration": %.1f, "threshold": %.1f}`, *event.Acceleration, d.Threshold))}
+}
+
+// RapidAccelerationDetector flags acceleration above a threshold (m/s²)
+type RapidAccelerationDetector struct {
+	thresholdRule
+}
+
+func newRapidAccelerationDetector(params json.RawMessage) (Detector, error) {
+	d := &RapidAccelerationDetector{thresholdRule{Threshold: 4.0, Bands: []Band{
+		{Above: 1.0, Severity: "medium", RiskScore: 60.0},
+	}}}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if d.Threshold <= 0 {
+		return nil, errors.New("threshold must be positive")
+	}
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *RapidAccelerationDetector) Name() string { return RuleRapidAcceleration }
+
+func (d *RapidAccelerationDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	if event.Acceleration == nil {
+		return nil
+	}
+	band, ok := d.classify(*event.Acceleration)
+	if !ok {
+		return nil
+	}
+
+	return []models.RiskEvent{newRiskEvent(event, RuleRapidAcceleration, band.Severity, band.RiskScore,
+		fmt.Sprintf("Harsh acceleration detected: %.1f m/s²", *event.Acceleration),
+		fmt.Sprintf(`{"acceleration": %.1f, "threshold": %.1f}`, *event.Acceleration, d.Threshold))}
+}
+
+// newRiskEvent fills in the fields every detector copies from the telemetry event
+func newRiskEvent(event *models.TelemetryEvent, eventType, severity string, riskScore float64, description, data string) models.RiskEvent {
+	return models.RiskEvent{
+		VehicleID:   event.VehicleID,
+		EventType:   eventType,
+		Severity:    severity,
+		RiskScore:   riskScore,
+		Timestamp:   event.Timestamp,
+		Latitude:    event.Latitude,
+		Longitude:   event.Longitude,
+		Description: description,
+		Data:        data,
+	}
+}
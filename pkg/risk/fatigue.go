@@ -0,0 +1,235 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Reasons a fatigue event was raised, reported in its data
+const (
+	FatigueContinuousDriving = "continuous_driving"
+	FatigueDailyDriving      = "daily_driving"
+	FatigueOnDuty            = "on_duty"
+)
+
+// FatigueDetector tracks hours of service per driver, or per vehicle for
+// telemetry without an assigned driver. Time between two moving samples no
+// more than MaxSampleGapMinutes apart counts as driving; anything else is a
+// break. A break of at least MinBreakMinutes resets continuous driving, and
+// one of at least DailyRestHours starts a new duty day. Each limit raises one
+// FATIGUE event per stint or duty day.
+type FatigueDetector struct {
+	MaxContinuousDrivingHours float64 `json:"max_continuous_driving_hours"`
+	MaxDailyDrivingHours      float64 `json:"max_daily_driving_hours"`
+	MaxOnDutyHours            float64 `json:"max_on_duty_hours"`
+	MinBreakMinutes           float64 `json:"min_break_minutes"`
+	DailyRestHours            float64 `json:"daily_rest_hours"`
+	MovingSpeed               float64 `json:"moving_speed"` // mph
+	MaxSampleGapMinutes       float64 `json:"max_sample_gap_minutes"`
+
+	state *State
+}
+
+func newFatigueDetector(params json.RawMessage) (Detector, error) {
+	d := &FatigueDetector{
+		MaxContinuousDrivingHours: 4.5,
+		MaxDailyDrivingHours:      11,
+		MaxOnDutyHours:            14,
+		MinBreakMinutes:           45,
+		DailyRestHours:            10,
+		MovingSpeed:               3,
+		MaxSampleGapMinutes:       5,
+		state:                     NewState(),
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if d.MaxContinuousDrivingHours <= 0 || d.MaxDailyDrivingHours <= 0 || d.MaxOnDutyHours <= 0 {
+		return nil, errors.New("driving and on-duty limits must be positive")
+	}
+	if d.MinBreakMinutes <= 0 || d.DailyRestHours <= 0 || d.MaxSampleGapMinutes <= 0 {
+		return nil, errors.New("min_break_minutes, daily_rest_hours and max_sample_gap_minutes must be positive")
+	}
+	if d.DailyRestHours*60 < d.MinBreakMinutes {
+		return nil, errors.New("daily_rest_hours must be at least min_break_minutes")
+	}
+	return d, nil
+}
+
+func (d *FatigueDetector) Name() string { return RuleFatigue }
+
+func (d *FatigueDetector) Bind(state *State) { d.state = state }
+
+func (d *FatigueDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	if event.Speed == nil {
+		return nil
+	}
+
+	rule, key := driverKey(RuleFatigue, event)
+
+	shift, _ := d.state.Get(rule, key).(*fatigueShift)
+	if shift == nil {
+		shift = &fatigueShift{dailyRest: hours(d.DailyRestHours)}
+		d.state.Put(rule, key, shift)
+	} else if event.Timestamp.Before(shift.last) {
+		// Late sample; the driver's time has already moved on
+		return nil
+	}
+
+	moving := *event.Speed > d.MovingSpeed
+	if !moving {
+		shift.last = event.Timestamp
+		return nil
+	}
+
+	if shift.lastMoving.IsZero() {
+		shift.startDay(event.Timestamp)
+	} else {
+		since := event.Timestamp.Sub(shift.lastMoving)
+		switch {
+		case since >= hours(d.DailyRestHours):
+			shift.startDay(event.Timestamp)
+		case since > minutes(d.MaxSampleGapMinutes) || shift.last.After(shift.lastMoving):
+			// Stopped, or not reporting: a break, but only a long enough
+			// one lets the driver start a fresh stint
+			if since >= minutes(d.MinBreakMinutes) {
+				shift.startStint(event.Timestamp)
+			}
+		default:
+			shift.continuous += since
+			shift.daily += since
+		}
+	}
+	shift.last = event.Timestamp
+	shift.lastMoving = event.Timestamp
+
+	var risks []models.RiskEvent
+	if !shift.continuousReported && shift.continuous > hours(d.MaxContinuousDrivingHours) {
+		shift.continuousReported = true
+		risks = append(risks, d.riskEvent(event, shift, FatigueContinuousDriving, "high", 80.0, shift.continuous, d.MaxContinuousDrivingHours,
+			fmt.Sprintf("Driving for %.1f hours without a %.0f minute break", shift.continuous.Hours(), d.MinBreakMinutes)))
+	}
+	if !shift.dailyReported && shift.daily > hours(d.MaxDailyDrivingHours) {
+		shift.dailyReported = true
+		risks = append(risks, d.riskEvent(event, shift, FatigueDailyDriving, "high", 85.0, shift.daily, d.MaxDailyDrivingHours,
+			fmt.Sprintf("%.1f hours of driving since the last daily rest", shift.daily.Hours())))
+	}
+	onDuty := event.Timestamp.Sub(shift.dutyStart)
+	if !shift.onDutyReported && onDuty > hours(d.MaxOnDutyHours) {
+		shift.onDutyReported = true
+		risks = append(risks, d.riskEvent(event, shift, FatigueOnDuty, "medium", 60.0, onDuty, d.MaxOnDutyHours,
+			fmt.Sprintf("Still driving %.1f hours into the duty day", onDuty.Hours())))
+	}
+	return risks
+}
+
+func (d *FatigueDetector) riskEvent(event *models.TelemetryEvent, shift *fatigueShift, reason, severity string, riskScore float64, elapsed time.Duration, limitHours float64, description string) models.RiskEvent {
+	data, _ := json.Marshal(map[string]interface{}{
+		"reason":      reason,
+		"hours":       math.Round(elapsed.Hours()*100) / 100,
+		"limit_hours": limitHours,
+		"stint_start": shift.stintStart,
+		"duty_start":  shift.dutyStart,
+	})
+	risk := newRiskEvent(event, RuleFatigue, severity, riskScore, description, string(data))
+	risk.DriverID = event.DriverID
+	return risk
+}
+
+// seed rebuilds the shift of a driver who is not tracked yet from their
+// trips, oldest first, counting the time of each trip not spent idling as
+// driving. Limits the trips went over count as reported already.
+func (d *FatigueDetector) seed(driverID uint, trips []models.Trip) {
+	rule := RuleFatigue + driverSuffix
+	if d.state.Get(rule, driverID) != nil || len(trips) == 0 {
+		return
+	}
+
+	shift := &fatigueShift{dailyRest: hours(d.DailyRestHours)}
+	for _, trip := range trips {
+		since := trip.StartTime.Sub(shift.lastMoving)
+		switch {
+		case shift.lastMoving.IsZero() || since >= hours(d.DailyRestHours):
+			shift.startDay(trip.StartTime)
+		case since >= minutes(d.MinBreakMinutes):
+			shift.startStint(trip.StartTime)
+		}
+		if driving := trip.EndTime.Sub(trip.StartTime) - time.Duration(trip.IdleSeconds)*time.Second; driving > 0 {
+			shift.continuous += driving
+			shift.daily += driving
+		}
+		if trip.EndTime.After(shift.lastMoving) {
+			shift.last = trip.EndTime
+			shift.lastMoving = trip.EndTime
+		}
+	}
+	shift.continuousReported = shift.continuous > hours(d.MaxContinuousDrivingHours)
+	shift.dailyReported = shift.daily > hours(d.MaxDailyDrivingHours)
+	shift.onDutyReported = shift.lastMoving.Sub(shift.dutyStart) > hours(d.MaxOnDutyHours)
+	d.state.Put(rule, driverID, shift)
+}
+
+// lastSample returns the time of the last sample counted in the driver's
+// shift, or the zero time if they are not tracked
+func (d *FatigueDetector) lastSample(driverID uint) time.Time {
+	if shift, ok := d.state.Get(RuleFatigue+driverSuffix, driverID).(*fatigueShift); ok {
+		return shift.last
+	}
+	return time.Time{}
+}
+
+// fatigueShift is the driving time of one driver (or vehicle) since their last
+// qualifying break and daily rest
+type fatigueShift struct {
+	dailyRest time.Duration
+
+	last       time.Time // last sample
+	lastMoving time.Time // last sample while driving
+
+	stintStart time.Time
+	dutyStart  time.Time
+	continuous time.Duration
+	daily      time.Duration
+
+	continuousReported bool
+	dailyReported      bool
+	onDutyReported     bool
+}
+
+func (s *fatigueShift) startStint(at time.Time) {
+	s.stintStart = at
+	s.continuous = 0
+	s.continuousReported = false
+}
+
+func (s *fatigueShift) startDay(at time.Time) {
+	s.startStint(at)
+	s.dutyStart = at
+	s.daily = 0
+	s.dailyReported = false
+	s.onDutyReported = false
+}
+
+// Expired once the driver has rested for a full day; the next drive starts
+// from scratch anyway
+func (s *fatigueShift) Expired(now time.Time) bool {
+	return now.Sub(s.lastMoving) >= s.dailyRest
+}
+
+// Close reports nothing: limits are reported as they are crossed
+func (s *fatigueShift) Close() []models.RiskEvent {
+	return nil
+}
+
+func hours(h float64) time.Duration {
+	return time.Duration(h * float64(time.Hour))
+}
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
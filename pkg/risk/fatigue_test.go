@@ -0,0 +1,149 @@
+package risk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// shift feeds one sample per minute to a fatigue-only analyzer
+type shift struct {
+	t        *testing.T
+	analyzer *Analyzer
+	clock    time.Time
+	driverID *uint
+	risks    []models.RiskEvent
+}
+
+func newShift(t *testing.T, driverID *uint) *shift {
+	analyzer, err := NewAnalyzerFromConfig(Config{Rules: []RuleConfig{{Name: RuleFatigue}}})
+	require.NoError(t, err)
+	return &shift{t: t, analyzer: analyzer, clock: time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC), driverID: driverID}
+}
+
+func (s *shift) run(vehicleID uint, speed float64, duration time.Duration) {
+	for end := s.clock.Add(duration); s.clock.Before(end); s.clock = s.clock.Add(time.Minute) {
+		sample := sampleAt(vehicleID, s.clock, speed)
+		sample.DriverID = s.driverID
+		s.risks = append(s.risks, s.analyzer.Analyze(sample)...)
+	}
+}
+
+func (s *shift) drive(duration time.Duration) { s.run(1, 50, duration) }
+
+func (s *shift) rest(duration time.Duration) { s.run(1, 0, duration) }
+
+func fatigueReasons(risks []models.RiskEvent) []string {
+	var reasons []string
+	for _, risk := range risks {
+		var data struct {
+			Reason string `json:"reason"`
+		}
+		_ = json.Unmarshal([]byte(risk.Data), &data)
+		reasons = append(reasons, data.Reason)
+	}
+	return reasons
+}
+
+func TestContinuousDrivingRaisesFatigue(t *testing.T) {
+	s := newShift(t, nil)
+	s.drive(4*time.Hour + 40*time.Minute)
+
+	require.Len(t, s.risks, 1)
+	risk := s.risks[0]
+	assert.Equal(t, RuleFatigue, risk.EventType)
+	assert.Equal(t, "high", risk.Severity)
+	assert.Equal(t, []string{FatigueContinuousDriving}, fatigueReasons(s.risks))
+	// Raised on the first sample past 4.5 hours
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 31, 0, 0, time.UTC), risk.Timestamp)
+}
+
+func TestShortBreaksDoNotResetContinuousDriving(t *testing.T) {
+	s := newShift(t, nil)
+	s.drive(3 * time.Hour)
+	s.rest(20 * time.Minute)
+	s.drive(2 * time.Hour)
+	assert.Equal(t, []string{FatigueContinuousDriving}, fatigueReasons(s.risks))
+
+	rested := newShift(t, nil)
+	rested.drive(3 * time.Hour)
+	rested.rest(50 * time.Minute)
+	rested.drive(2 * time.Hour)
+	assert.Empty(t, rested.risks)
+}
+
+func TestDailyDrivingAndOnDutyLimits(t *testing.T) {
+	s := newShift(t, nil)
+	for i := 0; i < 3; i++ {
+		s.drive(4 * time.Hour)
+		s.rest(time.Hour)
+	}
+	// 12 hours of driving across 14 hours on duty
+	s.drive(time.Hour + 30*time.Minute)
+	assert.Equal(t, []string{FatigueDailyDriving, FatigueOnDuty}, fatigueReasons(s.risks))
+
+	// A full night's rest starts a new day
+	s.rest(10 * time.Hour)
+	s.risks = nil
+	s.drive(4 * time.Hour)
+	assert.Empty(t, s.risks)
+}
+
+func TestFatigueFollowsTheDriverAcrossVehicles(t *testing.T) {
+	driverID := uint(42)
+	s := newShift(t, &driverID)
+
+	s.run(1, 50, 3*time.Hour)
+	s.clock = s.clock.Add(2 * time.Minute)
+	s.run(2, 50, 2*time.Hour)
+
+	require.Len(t, s.risks, 1)
+	assert.Equal(t, uint(2), s.risks[0].VehicleID)
+	require.NotNil(t, s.risks[0].DriverID)
+	assert.Equal(t, driverID, *s.risks[0].DriverID)
+}
+
+func TestRestoreFatigueFromTripsAndTelemetry(t *testing.T) {
+	store, db := setupPolicyStore(t)
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	driver := models.Driver{FleetID: fleet.ID, EmployeeID: "E1", FirstName: "Sam", LastName: "Lee", Email: "sam@example.com"}
+	require.NoError(t, db.Omit("Fleet").Create(&driver).Error)
+	vehicle := models.Vehicle{FleetID: fleet.ID, VIN: "VIN1", Make: "Ford", Model: "Transit", Year: 2022}
+	require.NoError(t, db.Omit("Fleet", "Driver").Create(&vehicle).Error)
+	require.NoError(t, db.Omit("Vehicle", "Driver").Create(&models.VehicleAssignment{
+		VehicleID: vehicle.ID, DriverID: driver.ID, StartTime: day.Add(5 * time.Hour)}).Error)
+
+	// 3h50 of driving with a 10 minute stop, which is too short a break
+	for _, trip := range [][2]time.Duration{{6 * time.Hour, 8 * time.Hour}, {8*time.Hour + 10*time.Minute, 10 * time.Hour}} {
+		require.NoError(t, db.Omit("Vehicle", "Driver").Create(&models.Trip{
+			VehicleID: vehicle.ID, DriverID: &driver.ID, StartTime: day.Add(trip[0]), EndTime: day.Add(trip[1])}).Error)
+	}
+	// and the trip in progress, analyzed for half an hour before the restart
+	processed := day.Add(11 * time.Hour)
+	for minute := 1; minute <= 30; minute++ {
+		sample := sampleAt(vehicle.ID, day.Add(10*time.Hour+time.Duration(minute)*time.Minute), 50)
+		sample.ProcessedAt = &processed
+		require.NoError(t, db.Omit("Vehicle").Create(sample).Error)
+	}
+
+	require.NoError(t, store.RestoreFatigue(t.Context(), day.Add(10*time.Hour+31*time.Minute)))
+
+	var risks []models.RiskEvent
+	for minute := 31; minute <= 45; minute++ {
+		sample := sampleAt(vehicle.ID, day.Add(10*time.Hour+time.Duration(minute)*time.Minute), 50)
+		sample.DriverID = &driver.ID
+		risks = append(risks, store.AnalyzerFor(fleet.ID, "").Analyze(sample)...)
+	}
+	require.Len(t, risks, 1)
+	assert.Equal(t, RuleFatigue, risks[0].EventType)
+	assert.Equal(t, []string{FatigueContinuousDriving}, fatigueReasons(risks))
+	assert.Equal(t, day.Add(10*time.Hour+41*time.Minute), risks[0].Timestamp)
+}
@@ -0,0 +1,178 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Scorer estimates how likely a risk event is to matter, as a 0-100
+// probability, from features of the event and of the vehicle's last
+// WindowDuration of telemetry:
+//
+//   - rule_score: the score the rule gave the event, as a fraction
+//   - event_<type>: 1 for the event's type, e.g. event_harsh_braking
+//   - window_samples: the number of telemetry events in the window
+//   - speed_mean, speed_max, speed_std: speed in mph
+//   - accel_max, decel_max: the strongest acceleration and deceleration, both
+//     positive, in m/s²
+//   - accel_std: the standard deviation of acceleration
+//
+// Speed and acceleration features are left out of windows without readings.
+// Implementations must be safe for concurrent use.
+//
+// Only the risk events of the rules are scored, i.e. the types in EventTypes.
+// Geofence, route and anomaly events are raised outside the analyzers and keep
+// their own scores, without a ModelVersion.
+type Scorer interface {
+	// Version identifies the model; it is recorded on every risk event scored
+	Version() string
+	Score(features map[string]float64) float64
+}
+
+// Model types a model file may hold
+const (
+	ModelLogisticRegression   = "logistic_regression"
+	ModelGradientBoostedTrees = "gradient_boosted_trees"
+)
+
+// Model is a trained scorer exported as JSON. A logistic regression sums
+// Intercept and each feature times its coefficient; gradient-boosted trees sum
+// BaseScore and the leaf each tree reaches. Either sum is a log-odds turned
+// into a probability. Features missing from the input count as 0.
+type Model struct {
+	ModelVersion string `json:"version"`
+	Type         string `json:"type"`
+
+	Intercept    float64            `json:"intercept,omitempty"`
+	Coefficients map[string]float64 `json:"coefficients,omitempty"`
+
+	BaseScore float64 `json:"base_score,omitempty"`
+	Trees     []Tree  `json:"trees,omitempty"`
+}
+
+// Tree is a regression tree stored as a flat list of nodes, the root first
+type Tree struct {
+	Nodes []Node `json:"nodes"`
+}
+
+// Node is a leaf if Leaf is set. Otherwise samples whose Feature is below
+// Threshold go to the node at index Left, and the others to Right.
+type Node struct {
+	Feature   string   `json:"feature,omitempty"`
+	Threshold float64  `json:"threshold,omitempty"`
+	Left      int      `json:"left,omitempty"`
+	Right     int      `json:"right,omitempty"`
+	Leaf      *float64 `json:"leaf,omitempty"`
+}
+
+// LoadModel reads and validates a model file
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read risk model: %w", err)
+	}
+	var model Model
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse risk model %s: %w", path, err)
+	}
+	if err := model.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid risk model %s: %w", path, err)
+	}
+	return &model, nil
+}
+
+// BaseScorer loads the model in RISK_MODEL_FILE, or returns nil without one.
+// Setting the file is what turns rescoring on.
+func BaseScorer() (Scorer, error) {
+	path := os.Getenv("RISK_MODEL_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return LoadModel(path)
+}
+
+// Validate checks that the model can be evaluated
+func (m *Model) Validate() error {
+	if m.ModelVersion == "" {
+		return errors.New("a model needs a version")
+	}
+	if len(m.ModelVersion) > 100 {
+		return errors.New("the model version must be at most 100 characters")
+	}
+
+	switch m.Type {
+	case ModelLogisticRegression:
+		if len(m.Coefficients) == 0 {
+			return errors.New("a logistic regression needs coefficients")
+		}
+	case ModelGradientBoostedTrees:
+		if len(m.Trees) == 0 {
+			return errors.New("gradient-boosted trees need at least one tree")
+		}
+		for i, tree := range m.Trees {
+			if err := tree.validate(); err != nil {
+				return fmt.Errorf("tree %d: %w", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown model type %q", m.Type)
+	}
+	return nil
+}
+
+// Children must come after their parent, which rules out cycles
+func (t Tree) validate() error {
+	if len(t.Nodes) == 0 {
+		return errors.New("a tree needs at least one node")
+	}
+	for i, node := range t.Nodes {
+		if node.Leaf != nil {
+			continue
+		}
+		if node.Feature == "" {
+			return fmt.Errorf("node %d is neither a leaf nor a split", i)
+		}
+		for _, child := range []int{node.Left, node.Right} {
+			if child <= i || child >= len(t.Nodes) {
+				return fmt.Errorf("node %d has a child %d out of order", i, child)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Model) Version() string {
+	return m.ModelVersion
+}
+
+// Score returns the model's probability scaled to 0-100
+func (m *Model) Score(features map[string]float64) float64 {
+	var logOdds float64
+	if m.Type == ModelLogisticRegression {
+		logOdds = m.Intercept
+		for feature, coefficient := range m.Coefficients {
+			logOdds += coefficient * features[feature]
+		}
+	} else {
+		logOdds = m.BaseScore
+		for _, tree := range m.Trees {
+			logOdds += tree.predict(features)
+		}
+	}
+	return 100 / (1 + math.Exp(-logOdds))
+}
+
+func (t Tree) predict(features map[string]float64) float64 {
+	node := t.Nodes[0]
+	for node.Leaf == nil {
+		if features[node.Feature] < node.Threshold {
+			node = t.Nodes[node.Left]
+		} else {
+			node = t.Nodes[node.Right]
+		}
+	}
+	return *node.Leaf
+}
@@ -0,0 +1,142 @@
+package risk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+const logisticModel = `{
+	"version": "lr-2024-01",
+	"type": "logistic_regression",
+	"intercept": -4,
+	"coefficients": {"rule_score": 3, "decel_max": 0.2, "event_harsh_braking": 0.5}
+}`
+
+const treeModel = `{
+	"version": "gbt-2024-02",
+	"type": "gradient_boosted_trees",
+	"base_score": -1,
+	"trees": [
+		{"nodes": [
+			{"feature": "speed_max", "threshold": 70, "left": 1, "right": 2},
+			{"leaf": -0.5},
+			{"feature": "decel_max", "threshold": 6, "left": 3, "right": 4},
+			{"leaf": 0.5},
+			{"leaf": 2}
+		]},
+		{"nodes": [{"leaf": 0.5}]}
+	]
+}`
+
+func loadModel(t *testing.T, model string) *Model {
+	path := filepath.Join(t.TempDir(), "model.json")
+	require.NoError(t, os.WriteFile(path, []byte(model), 0o600))
+	loaded, err := LoadModel(path)
+	require.NoError(t, err)
+	return loaded
+}
+
+func TestLogisticRegressionScore(t *testing.T) {
+	model := loadModel(t, logisticModel)
+	assert.Equal(t, "lr-2024-01", model.Version())
+
+	// -4 + 3*0.65 + 0.2*7 + 0.5 = -0.15
+	score := model.Score(map[string]float64{"rule_score": 0.65, "decel_max": 7, "event_harsh_braking": 1, "unused": 9})
+	assert.InDelta(t, 46.257, score, 0.001)
+	assert.InDelta(t, 1.799, model.Score(nil), 0.001, "missing features count as 0")
+}
+
+func TestGradientBoostedTreesScore(t *testing.T) {
+	model := loadModel(t, treeModel)
+
+	// -1 - 0.5 + 0.5
+	assert.InDelta(t, 26.894, model.Score(map[string]float64{"speed_max": 50}), 0.001)
+	// -1 + 0.5 + 0.5
+	assert.InDelta(t, 50, model.Score(map[string]float64{"speed_max": 80, "decel_max": 3}), 0.001)
+	// -1 + 2 + 0.5
+	assert.InDelta(t, 81.757, model.Score(map[string]float64{"speed_max": 80, "decel_max": 6}), 0.001)
+}
+
+func TestLoadModelRejectsInvalidModels(t *testing.T) {
+	tests := map[string]string{
+		"no version":      `{"type": "logistic_regression", "coefficients": {"rule_score": 1}}`,
+		"unknown type":    `{"version": "1", "type": "neural_network"}`,
+		"no coefficients": `{"version": "1", "type": "logistic_regression"}`,
+		"no trees":        `{"version": "1", "type": "gradient_boosted_trees"}`,
+		"empty tree":      `{"version": "1", "type": "gradient_boosted_trees", "trees": [{"nodes": []}]}`,
+		"cycle":           `{"version": "1", "type": "gradient_boosted_trees", "trees": [{"nodes": [{"feature": "speed_max", "left": 0, "right": 1}, {"leaf": 1}]}]}`,
+		"missing child":   `{"version": "1", "type": "gradient_boosted_trees", "trees": [{"nodes": [{"feature": "speed_max", "left": 1, "right": 2}, {"leaf": 1}]}]}`,
+		"not json":        `version: 1`,
+	}
+	for name, model := range tests {
+		path := filepath.Join(t.TempDir(), "model.json")
+		require.NoError(t, os.WriteFile(path, []byte(model), 0o600))
+		_, err := LoadModel(path)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestAnalyzerRescoresRiskEventsWithModel(t *testing.T) {
+	store, _ := setupPolicyStore(t)
+	store.SetScorer(loadModel(t, logisticModel))
+	analyzer := store.AnalyzerFor(1, "")
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int, speed, acceleration float64) *models.TelemetryEvent {
+		event := telemetry(speed, acceleration)
+		event.Timestamp = start.Add(time.Duration(seconds) * time.Second)
+		return event
+	}
+	// An earlier hard stop that has left the window, then a calm minute
+	assert.Empty(t, analyzer.Analyze(at(0, 50, -5.5)))
+	for i := 1; i <= 60; i++ {
+		assert.Empty(t, analyzer.Analyze(at(120+i, 50, -1)))
+	}
+
+	risks := analyzer.Analyze(at(200, 48, -7))
+	require.Len(t, risks, 1)
+	risk := risks[0]
+	assert.Equal(t, RuleHarshBraking, risk.EventType)
+	assert.Equal(t, "medium", risk.Severity, "severity still comes from the rule")
+	assert.Equal(t, "lr-2024-01", risk.ModelVersion)
+	// -4 + 3*0.65 + 0.2*7 + 0.5
+	assert.Equal(t, 46.3, risk.RiskScore)
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(risk.Data), &data))
+	assert.Equal(t, 65.0, data["rule_risk_score"])
+	assert.Contains(t, data, "acceleration", "the rule's data is kept")
+
+	unscored, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	risks = unscored.Analyze(at(300, 48, -7))
+	require.Len(t, risks, 1)
+	assert.Empty(t, risks[0].ModelVersion)
+	assert.Equal(t, 65.0, risks[0].RiskScore)
+}
+
+func TestWindowFeatures(t *testing.T) {
+	speed := func(v float64) *float64 { return &v }
+	features := windowFeatures([]sample{
+		{speed: speed(40), acceleration: speed(1)},
+		{speed: speed(60), acceleration: speed(-3)},
+		{speed: nil, acceleration: speed(2)},
+	})
+	assert.Equal(t, 3.0, features["window_samples"])
+	assert.Equal(t, 50.0, features["speed_mean"])
+	assert.Equal(t, 10.0, features["speed_std"])
+	assert.Equal(t, 60.0, features["speed_max"])
+	assert.Equal(t, 2.0, features["accel_max"])
+	assert.Equal(t, 3.0, features["decel_max"])
+
+	features = windowFeatures(nil)
+	assert.Equal(t, map[string]float64{"window_samples": 0}, features)
+}
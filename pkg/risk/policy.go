@@ -0,0 +1,367 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// PolicyStore resolves the analyzer for a vehicle from the risk policies of its
+// fleet and vehicle class. Refresh reloads the policies when they change, so
+// edits take effect without restarting the engine.
+type PolicyStore struct {
+	db       *gorm.DB
+	base     Config
+	state    *State
+	fallback *Analyzer
+	scoring  *modelScoring
+
+	// overlay holds unsaved rules layered on top of a fleet's policies; see
+	// Candidate
+	overlay map[uint]Config
+
+	mu          sync.RWMutex
+	fingerprint string
+	analyzers   map[policyKey]*Analyzer
+}
+
+type policyKey struct {
+	fleetID      uint
+	vehicleClass string
+}
+
+// NewPolicyStore creates a store layering policies on top of base, which also
+// serves vehicles whose fleet has no policy
+func NewPolicyStore(db *gorm.DB, base Config) (*PolicyStore, error) {
+	// Every analyzer shares one State, so a vehicle keeps its open episodes
+	// when its policy is edited or replaced
+	state := NewState()
+	fallback, err := newAnalyzerFromConfig(base, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolicyStore{
+		db:        db,
+		base:      base,
+		state:     state,
+		fallback:  fallback,
+		analyzers: make(map[policyKey]*Analyzer),
+	}, nil
+}
+
+// Candidate returns a separate store that analyzes fleetID with rules layered
+// on top of the fleet's current policies, for trying out a change without
+// saving it. Other fleets are analyzed as they are now. The candidate has its
+// own episode state and does not reload policies.
+func (s *PolicyStore) Candidate(ctx context.Context, fleetID uint, rules Config) (*PolicyStore, error) {
+	if err := ValidatePolicy(rules); err != nil {
+		return nil, err
+	}
+
+	candidate, err := NewPolicyStore(s.db, s.base)
+	if err != nil {
+		return nil, err
+	}
+	candidate.overlay = map[uint]Config{fleetID: rules}
+	if s.scoring != nil {
+		candidate.SetScorer(s.scoring.scorer)
+	}
+
+	var policies []models.RiskPolicy
+	if err := s.db.WithContext(ctx).Order("id").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	// The overlay is applied through the fleet policy, so the fleet needs one
+	hasFleetPolicy := false
+	for _, policy := range policies {
+		if policy.FleetID == fleetID && policy.VehicleClass == "" {
+			hasFleetPolicy = true
+		}
+	}
+	if !hasFleetPolicy {
+		policies = append(policies, models.RiskPolicy{FleetID: fleetID})
+	}
+
+	candidate.analyzers = candidate.build(policies)
+	if _, ok := candidate.analyzers[policyKey{fleetID: fleetID}]; !ok {
+		return nil, fmt.Errorf("risk policy of fleet %d is invalid", fleetID)
+	}
+	return candidate, nil
+}
+
+// SetScorer has every analyzer of the store rescore the risk events its rules
+// raise with scorer. Call it before analyzing any telemetry.
+func (s *PolicyStore) SetScorer(scorer Scorer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scoring = newModelScoring(scorer)
+	s.fallback.scoring = s.scoring
+	for _, analyzer := range s.analyzers {
+		analyzer.scoring = s.scoring
+	}
+}
+
+// Default returns the analyzer used for fleets without a policy
+func (s *PolicyStore) Default() *Analyzer {
+	return s.fallback
+}
+
+// AnalyzerFor returns the analyzer of the most specific policy that applies:
+// the vehicle class policy, then the fleet policy, then the defaults
+func (s *PolicyStore) AnalyzerFor(fleetID uint, vehicleClass string) *Analyzer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if vehicleClass != "" {
+		if analyzer, ok := s.analyzers[policyKey{fleetID, vehicleClass}]; ok {
+			return analyzer
+		}
+	}
+	if analyzer, ok := s.analyzers[policyKey{fleetID: fleetID}]; ok {
+		return analyzer
+	}
+	return s.fallback
+}
+
+// Flush closes the episodes of vehicles that stopped reporting; see State.Flush
+func (s *PolicyStore) Flush(now time.Time) []models.RiskEvent {
+	return flush(s.state, s.scoring, now)
+}
+
+// Forget drops the vehicle's open episodes and telemetry window, and the
+// episodes following driverID if set; see State.Forget
+func (s *PolicyStore) Forget(vehicleID uint, driverID *uint) {
+	s.state.Forget(vehicleID, driverID)
+	if s.scoring != nil {
+		s.scoring.forget(vehicleID)
+	}
+}
+
+// fatigueLookback is how far back RestoreFatigue looks for driving time: a
+// duty day and the daily rest before it, with room to spare
+const fatigueLookback = 48 * time.Hour
+
+// RestoreFatigue rebuilds the hours of service of drivers from their stored
+// trips, then from the processed telemetry of the vehicles they are assigned
+// to since, which covers the trip in progress. Without it a restart would
+// reset the hours of every driver on duty. Call it after Refresh and before
+// analyzing any telemetry.
+func (s *PolicyStore) RestoreFatigue(ctx context.Context, now time.Time) error {
+	db := s.db.WithContext(ctx)
+	since := now.Add(-fatigueLookback)
+	vehicleColumns := func(tx *gorm.DB) *gorm.DB { return tx.Select("id", "fleet_id", "vehicle_class") }
+
+	var trips []models.Trip
+	if err := db.Preload("Vehicle", vehicleColumns).
+		Where("driver_id IS NOT NULL AND end_time >= ?", since).
+		Order("driver_id, start_time, id").
+		Find(&trips).Error; err != nil {
+		return err
+	}
+	for start := 0; start < len(trips); {
+		end := start + 1
+		for end < len(trips) && *trips[end].DriverID == *trips[start].DriverID {
+			end++
+		}
+		last := trips[end-1]
+		if fatigue := s.fatigueFor(last.Vehicle); fatigue != nil {
+			fatigue.seed(*last.DriverID, trips[start:end])
+		}
+		start = end
+	}
+
+	var assignments []models.VehicleAssignment
+	if err := db.Preload("Vehicle", vehicleColumns).Where("end_time IS NULL").Find(&assignments).Error; err != nil {
+		return err
+	}
+	for _, assignment := range assignments {
+		fatigue := s.fatigueFor(assignment.Vehicle)
+		if fatigue == nil {
+			continue
+		}
+		from := since
+		for _, t := range []time.Time{assignment.StartTime, fatigue.lastSample(assignment.DriverID)} {
+			if t.After(from) {
+				from = t
+			}
+		}
+		if err := s.replayFatigue(db, fatigue, assignment.VehicleID, assignment.DriverID, from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayFatigue feeds the vehicle's processed telemetry after from to the
+// driver's shift. The limits it goes over were reported when the telemetry
+// was analyzed, so the risk events are dropped.
+func (s *PolicyStore) replayFatigue(db *gorm.DB, fatigue *FatigueDetector, vehicleID, driverID uint, from time.Time) error {
+	rows, err := db.Model(&models.TelemetryEvent{}).
+		Select("id", "vehicle_id", "timestamp", "speed").
+		Where("vehicle_id = ? AND timestamp > ? AND processed_at IS NOT NULL AND speed IS NOT NULL", vehicleID, from).
+		Order("timestamp, id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.TelemetryEvent
+		if err := db.ScanRows(rows, &event); err != nil {
+			return err
+		}
+		event.DriverID = &driverID
+		fatigue.Detect(&event)
+	}
+	return rows.Err()
+}
+
+// fatigueFor returns the fatigue detector the vehicle is analyzed with, or
+// nil if its policy does not check fatigue
+func (s *PolicyStore) fatigueFor(vehicle models.Vehicle) *FatigueDetector {
+	for _, detector := range s.AnalyzerFor(vehicle.FleetID, vehicle.VehicleClass).Detectors() {
+		if fatigue, ok := detector.(*FatigueDetector); ok {
+			return fatigue
+		}
+	}
+	return nil
+}
+
+// Refresh reloads the policies if any was created, updated or deleted since
+// the last load, and reports whether it did. Invalid policies are logged and
+// skipped so that one bad edit cannot stop detection for every fleet.
+func (s *PolicyStore) Refresh(ctx context.Context) (bool, error) {
+	var policies []models.RiskPolicy
+	if err := s.db.WithContext(ctx).Order("id").Find(&policies).Error; err != nil {
+		return false, err
+	}
+
+	fingerprint := policyFingerprint(policies)
+	s.mu.RLock()
+	unchanged := fingerprint == s.fingerprint
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	analyzers := s.build(policies)
+
+	s.mu.Lock()
+	s.analyzers = analyzers
+	s.fingerprint = fingerprint
+	s.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"policies": len(policies),
+		"loaded":   len(analyzers),
+	}).Info("Risk policies reloaded")
+	return true, nil
+}
+
+func (s *PolicyStore) build(policies []models.RiskPolicy) map[policyKey]*Analyzer {
+	fleetRules := make(map[uint]Config)
+	for _, policy := range policies {
+		if policy.VehicleClass != "" {
+			continue
+		}
+		rules, err := ParsePolicyRules(policy.Rules)
+		if err != nil {
+			logPolicyError(policy, err)
+			continue
+		}
+		fleetRules[policy.FleetID] = rules
+	}
+
+	analyzers := make(map[policyKey]*Analyzer, len(policies))
+	for _, policy := range policies {
+		analyzer, err := s.buildPolicy(policy, fleetRules[policy.FleetID])
+		if err != nil {
+			logPolicyError(policy, err)
+			continue
+		}
+		analyzers[policyKey{policy.FleetID, policy.VehicleClass}] = analyzer
+	}
+	return analyzers
+}
+
+func (s *PolicyStore) buildPolicy(policy models.RiskPolicy, fleetRules Config) (*Analyzer, error) {
+	cfg, err := s.base.Merge(fleetRules)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.VehicleClass != "" {
+		classRules, err := ParsePolicyRules(policy.Rules)
+		if err != nil {
+			return nil, err
+		}
+		if cfg, err = cfg.Merge(classRules); err != nil {
+			return nil, err
+		}
+	}
+
+	if overlay, ok := s.overlay[policy.FleetID]; ok {
+		if cfg, err = cfg.Merge(overlay); err != nil {
+			return nil, err
+		}
+	}
+
+	analyzer, err := newAnalyzerFromConfig(cfg, s.state)
+	if err != nil {
+		return nil, err
+	}
+	analyzer.scoring = s.scoring
+	return analyzer, nil
+}
+
+// ParsePolicyRules decodes the rules stored on a RiskPolicy
+func ParsePolicyRules(rules string) (Config, error) {
+	var cfg Config
+	if strings.TrimSpace(rules) == "" {
+		return cfg, nil
+	}
+	if err := json.Unmarshal([]byte(rules), &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse risk policy: %w", err)
+	}
+	return cfg, nil
+}
+
+// ValidatePolicy checks that rules name registered detectors with valid
+// parameters once layered on top of the built-in defaults
+func ValidatePolicy(rules Config) error {
+	cfg, err := DefaultConfig().Merge(rules)
+	if err != nil {
+		return err
+	}
+	_, err = Build(cfg)
+	return err
+}
+
+// policyFingerprint changes whenever a policy is added, removed or updated
+func policyFingerprint(policies []models.RiskPolicy) string {
+	var b strings.Builder
+	for _, policy := range policies {
+		fmt.Fprintf(&b, "%d:%d;", policy.ID, policy.UpdatedAt.UnixNano())
+	}
+	return b.String()
+}
+
+func logPolicyError(policy models.RiskPolicy, err error) {
+	logrus.WithError(err).WithFields(logrus.Fields{
+		"policy_id":     policy.ID,
+		"fleet_id":      policy.FleetID,
+		"vehicle_class": policy.VehicleClass,
+	}).Error("Ignoring invalid risk policy")
+}
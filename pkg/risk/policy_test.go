@@ -0,0 +1,196 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func setupPolicyStore(t *testing.T) (*PolicyStore, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	store, err := NewPolicyStore(db, DefaultConfig())
+	require.NoError(t, err)
+	return store, db
+}
+
+func savePolicy(t *testing.T, db *gorm.DB, fleetID uint, vehicleClass, rules string) *models.RiskPolicy {
+	policy := &models.RiskPolicy{FleetID: fleetID, VehicleClass: vehicleClass, Rules: rules}
+	require.NoError(t, db.Omit("Fleet").Create(policy).Error)
+	return policy
+}
+
+// severityAt drives a one-minute speeding episode through analyzer and returns
+// the severity it was reported with, if any
+func severityAt(analyzer *Analyzer, speed float64) string {
+	var risks []models.RiskEvent
+	for _, sample := range drive(1, time.Now(), speed, 60) {
+		risks = append(risks, analyzer.Analyze(sample)...)
+	}
+	risks = append(risks, analyzer.Analyze(sampleAt(1, time.Now().Add(time.Minute), 30))...)
+
+	for _, event := range risks {
+		if event.EventType == RuleSpeeding {
+			return event.Severity
+		}
+	}
+	return ""
+}
+
+func TestConfigMerge(t *testing.T) {
+	disabled := false
+	base := Config{Rules: []RuleConfig{
+		{Name: RuleSpeeding, Params: json.RawMessage(`{"threshold": 80, "bands": [{"above": 1, "severity": "low", "risk_score": 10}]}`)},
+		{Name: RuleHarshBraking},
+	}}
+
+	merged, err := base.Merge(Config{Rules: []RuleConfig{
+		{Name: RuleSpeeding, Params: json.RawMessage(`{"threshold": 60}`)},
+		{Name: RuleHarshBraking, Enabled: &disabled},
+		{Name: RuleRapidAcceleration},
+	}})
+	require.NoError(t, err)
+	require.Len(t, merged.Rules, 3)
+
+	// Params are merged key by key, so the base bands survive
+	assert.JSONEq(t, `{"threshold": 60, "bands": [{"above": 1, "severity": "low", "risk_score": 10}]}`, string(merged.Rules[0].Params))
+	assert.False(t, merged.Rules[1].IsEnabled())
+	assert.Equal(t, RuleRapidAcceleration, merged.Rules[2].Name)
+
+	// The base is left untouched
+	assert.True(t, base.Rules[1].IsEnabled())
+	assert.JSONEq(t, `{"threshold": 80, "bands": [{"above": 1, "severity": "low", "risk_score": 10}]}`, string(base.Rules[0].Params))
+
+	_, err = base.Merge(Config{Rules: []RuleConfig{{Name: RuleSpeeding, Params: json.RawMessage(`[1]`)}}})
+	assert.Error(t, err)
+}
+
+func TestSeverityBands(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(Config{Rules: []RuleConfig{{
+		Name: RuleSpeeding,
+		Params: json.RawMessage(`{"threshold": 60, "bands": [
+			{"above": 1.0, "severity": "low", "risk_score": 20},
+			{"above": 1.2, "severity": "critical", "risk_score": 95}
+		]}`),
+	}}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "", severityAt(analyzer, 60))
+	assert.Equal(t, "low", severityAt(analyzer, 65))
+	assert.Equal(t, "critical", severityAt(analyzer, 80))
+
+	invalid := []string{
+		`{"bands": []}`,
+		`{"bands": [{"above": 1.5, "severity": "high", "risk_score": 50}, {"above": 1.2, "severity": "low", "risk_score": 10}]}`,
+		`{"bands": [{"above": 1, "severity": "extreme", "risk_score": 50}]}`,
+		`{"bands": [{"above": 1, "severity": "high", "risk_score": 150}]}`,
+	}
+	for _, params := range invalid {
+		err := ValidatePolicy(Config{Rules: []RuleConfig{{Name: RuleSpeeding, Params: json.RawMessage(params)}}})
+		assert.Error(t, err, params)
+	}
+}
+
+func TestPolicyStoreLayersPolicies(t *testing.T) {
+	store, db := setupPolicyStore(t)
+
+	savePolicy(t, db, 1, "", `{"rules": [{"name": "speeding", "params": {"threshold": 60}}]}`)
+	savePolicy(t, db, 1, "heavy_truck", `{"rules": [{"name": "speeding", "params": {"bands": [{"above": 1, "severity": "critical", "risk_score": 90}]}}]}`)
+	savePolicy(t, db, 2, "", `{"rules": [{"name": "speeding", "params": {"threshold": -1}}]}`)
+
+	reloaded, err := store.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	// Fleet policy lowers the threshold for every vehicle in fleet 1
+	assert.Equal(t, "medium", severityAt(store.AnalyzerFor(1, "sedan"), 65))
+	// The class policy keeps the fleet threshold and replaces the bands
+	assert.Equal(t, "critical", severityAt(store.AnalyzerFor(1, "heavy_truck"), 65))
+	// Other fleets use the defaults
+	assert.Equal(t, "", severityAt(store.AnalyzerFor(3, ""), 65))
+	// An invalid policy falls back to the defaults instead of failing
+	assert.Equal(t, "", severityAt(store.AnalyzerFor(2, ""), 65))
+	assert.Equal(t, "medium", severityAt(store.AnalyzerFor(2, ""), 85))
+}
+
+func TestPolicyStoreHotReload(t *testing.T) {
+	store, db := setupPolicyStore(t)
+	ctx := context.Background()
+
+	reloaded, err := store.Refresh(ctx)
+	require.NoError(t, err)
+	assert.False(t, reloaded, "nothing to load")
+
+	policy := savePolicy(t, db, 1, "", `{"rules": [{"name": "speeding", "params": {"threshold": 60}}]}`)
+	reloaded, err = store.Refresh(ctx)
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "medium", severityAt(store.AnalyzerFor(1, ""), 65))
+
+	reloaded, err = store.Refresh(ctx)
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged policies are not rebuilt")
+
+	// Editing the policy is picked up by the next refresh
+	require.NoError(t, db.Model(policy).Updates(map[string]interface{}{
+		"rules":      `{"rules": [{"name": "speeding", "enabled": false}]}`,
+		"updated_at": time.Now().Add(time.Second),
+	}).Error)
+	reloaded, err = store.Refresh(ctx)
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "", severityAt(store.AnalyzerFor(1, ""), 200))
+
+	// Deleting it restores the defaults
+	require.NoError(t, db.Delete(policy).Error)
+	_, err = store.Refresh(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "critical", severityAt(store.AnalyzerFor(1, ""), 200))
+}
+
+// closedEpisode reports a single risk event for its vehicle when flushed
+type closedEpisode struct{ vehicleID uint }
+
+func (e closedEpisode) Expired(time.Time) bool { return true }
+
+func (e closedEpisode) Close() []models.RiskEvent {
+	return []models.RiskEvent{{VehicleID: e.vehicleID}}
+}
+
+func TestForgetDropsEpisodesWithoutReportingThem(t *testing.T) {
+	store, _ := setupPolicyStore(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	analyzeAll(store.AnalyzerFor(1, ""), drive(1, start, 90, 60))
+	analyzeAll(store.AnalyzerFor(1, ""), drive(2, start, 90, 60))
+
+	store.Forget(1, nil)
+	risks := store.Flush(start.Add(time.Hour))
+	require.Len(t, risks, 1)
+	assert.Equal(t, uint(2), risks[0].VehicleID)
+
+	// Episodes following a driver go with them, but fatigue shifts stay
+	driverID := uint(1)
+	state := NewState()
+	state.Put(RuleAggressiveDriving, 1, closedEpisode{vehicleID: 1})
+	state.Put(RuleAggressiveDriving+driverSuffix, driverID, closedEpisode{vehicleID: 2})
+	state.Put(RuleAggressiveDriving+driverSuffix, 2, closedEpisode{vehicleID: 3})
+	state.Put(RuleFatigue, 1, closedEpisode{vehicleID: 4})
+	state.Put(RuleFatigue+driverSuffix, driverID, closedEpisode{vehicleID: 5})
+	state.Forget(1, &driverID)
+	risks = state.Flush(start)
+	vehicles := make([]uint, len(risks))
+	for i, risk := range risks {
+		vehicles[i] = risk.VehicleID
+	}
+	assert.ElementsMatch(t, []uint{3, 4, 5}, vehicles)
+}
@@ -0,0 +1,298 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Grade is the severity and risk score a sensor reading is reported with
+type Grade struct {
+	Severity  string  `json:"severity"`
+	RiskScore float64 `json:"risk_score"`
+}
+
+func (g Grade) validate(name string) error {
+	if !validSeverities[g.Severity] {
+		return fmt.Errorf("%s: unknown severity %q", name, g.Severity)
+	}
+	if g.RiskScore < 0 || g.RiskScore > 100 {
+		return fmt.Errorf("%s: risk_score must be between 0 and 100", name)
+	}
+	return nil
+}
+
+// sensorRule is the shared parameter set of detectors for in-cab sensor
+// events. The devices report an occurrence once it is over, with its duration,
+// so these detectors are stateless. Readings taken while the vehicle is known
+// to be slower than MinSpeed (mph) or that lasted less than MinDurationSeconds
+// are ignored.
+type sensorRule struct {
+	MinSpeed           float64 `json:"min_speed"`
+	MinDurationSeconds float64 `json:"min_duration_seconds"`
+}
+
+func (r *sensorRule) validate() error {
+	if r.MinSpeed < 0 {
+		return errors.New("min_speed must not be negative")
+	}
+	if r.MinDurationSeconds < 0 {
+		return errors.New("min_duration_seconds must not be negative")
+	}
+	return nil
+}
+
+// reading decodes the event's sensor reading into dst if the event is of
+// eventType and the vehicle was moving fast enough
+func (r *sensorRule) reading(event *models.TelemetryEvent, eventType string, dst interface{}) bool {
+	if event.EventType != eventType || event.Data == "" {
+		return false
+	}
+	if event.Speed != nil && *event.Speed < r.MinSpeed {
+		return false
+	}
+	return json.Unmarshal([]byte(event.Data), dst) == nil
+}
+
+// validateGrades checks a grade table and that it grades something
+func validateGrades(grades map[string]Grade, field string) error {
+	if len(grades) == 0 {
+		return fmt.Errorf("%s must grade at least one value", field)
+	}
+	for name, grade := range grades {
+		if err := grade.validate(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sensorData is the risk event data of sensor detectors: the reading and the
+// speed it was taken at
+func sensorData(event *models.TelemetryEvent, reading interface{}) string {
+	data, _ := json.Marshal(struct {
+		Reading interface{} `json:"reading"`
+		Speed   *float64    `json:"speed,omitempty"`
+	}{reading, event.Speed})
+	return string(data)
+}
+
+// PhoneUseDetector raises DISTRACTED_DRIVING for phone_use events, graded by
+// how the phone was used. Usages without a grade, such as hands_free by
+// default, are allowed. Grades in params are added to the defaults.
+type PhoneUseDetector struct {
+	sensorRule
+	Usages map[string]Grade `json:"usages"`
+}
+
+func newPhoneUseDetector(params json.RawMessage) (Detector, error) {
+	d := &PhoneUseDetector{
+		sensorRule: sensorRule{MinSpeed: 5, MinDurationSeconds: 2},
+		Usages: map[string]Grade{
+			"handheld_call": {Severity: "high", RiskScore: 75},
+			"texting":       {Severity: "critical", RiskScore: 90},
+			"browsing":      {Severity: "critical", RiskScore: 90},
+		},
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if err := d.sensorRule.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateGrades(d.Usages, "usages"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *PhoneUseDetector) Name() string { return RulePhoneUse }
+
+func (d *PhoneUseDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	var reading models.PhoneUseData
+	if !d.reading(event, models.EventPhoneUse, &reading) || reading.DurationSeconds < d.MinDurationSeconds {
+		return nil
+	}
+	grade, ok := d.Usages[reading.Usage]
+	if !ok {
+		return nil
+	}
+
+	return []models.RiskEvent{newRiskEvent(event, EventDistractedDriving, grade.Severity, grade.RiskScore,
+		fmt.Sprintf("Phone use while driving: %s for %.0f seconds", reading.Usage, reading.DurationSeconds),
+		sensorData(event, reading))}
+}
+
+// CameraDistractionDetector raises DISTRACTED_DRIVING for behaviors seen by a
+// driver-facing camera with at least MinConfidence
+type CameraDistractionDetector struct {
+	sensorRule
+	MinConfidence float64          `json:"min_confidence"`
+	Behaviors     map[string]Grade `json:"behaviors"`
+}
+
+func newCameraDistractionDetector(params json.RawMessage) (Detector, error) {
+	d := &CameraDistractionDetector{
+		sensorRule:    sensorRule{MinSpeed: 5, MinDurationSeconds: 2},
+		MinConfidence: 0.7,
+		Behaviors: map[string]Grade{
+			"looking_away": {Severity: "high", RiskScore: 70},
+			"eyes_closed":  {Severity: "critical", RiskScore: 95},
+			"drowsiness":   {Severity: "high", RiskScore: 80},
+			"eating":       {Severity: "medium", RiskScore: 45},
+			"smoking":      {Severity: "low", RiskScore: 30},
+		},
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if err := d.sensorRule.validate(); err != nil {
+		return nil, err
+	}
+	if d.MinConfidence < 0 || d.MinConfidence > 1 {
+		return nil, errors.New("min_confidence must be between 0 and 1")
+	}
+	if err := validateGrades(d.Behaviors, "behaviors"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *CameraDistractionDetector) Name() string { return RuleCameraDistraction }
+
+func (d *CameraDistractionDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	var reading models.CameraDistractionData
+	if !d.reading(event, models.EventCameraDistraction, &reading) ||
+		reading.DurationSeconds < d.MinDurationSeconds || reading.Confidence < d.MinConfidence {
+		return nil
+	}
+	grade, ok := d.Behaviors[reading.Behavior]
+	if !ok {
+		return nil
+	}
+
+	return []models.RiskEvent{newRiskEvent(event, EventDistractedDriving, grade.Severity, grade.RiskScore,
+		fmt.Sprintf("Driver distraction detected by camera: %s for %.0f seconds", reading.Behavior, reading.DurationSeconds),
+		sensorData(event, reading))}
+}
+
+// SeatbeltDetector raises SEATBELT_VIOLATION for seatbelt_unbuckled events,
+// graded by seat
+type SeatbeltDetector struct {
+	sensorRule
+	Seats map[string]Grade `json:"seats"`
+}
+
+func newSeatbeltDetector(params json.RawMessage) (Detector, error) {
+	d := &SeatbeltDetector{
+		sensorRule: sensorRule{MinSpeed: 5, MinDurationSeconds: 10},
+		Seats: map[string]Grade{
+			"driver":    {Severity: "medium", RiskScore: 55},
+			"passenger": {Severity: "low", RiskScore: 30},
+		},
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if err := d.sensorRule.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateGrades(d.Seats, "seats"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *SeatbeltDetector) Name() string { return RuleSeatbelt }
+
+func (d *SeatbeltDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	var reading models.SeatbeltData
+	if !d.reading(event, models.EventSeatbeltUnbuckled, &reading) || reading.DurationSeconds < d.MinDurationSeconds {
+		return nil
+	}
+	grade, ok := d.Seats[reading.Seat]
+	if !ok {
+		return nil
+	}
+
+	return []models.RiskEvent{newRiskEvent(event, EventSeatbeltViolation, grade.Severity, grade.RiskScore,
+		fmt.Sprintf("%s seatbelt unbuckled while driving for %.0f seconds", reading.Seat, reading.DurationSeconds),
+		sensorData(event, reading))}
+}
+
+// HeadwayBand grades following distances shorter than Below seconds
+type HeadwayBand struct {
+	Below     float64 `json:"below"`
+	Severity  string  `json:"severity"`
+	RiskScore float64 `json:"risk_score"`
+}
+
+// FollowingDistanceDetector raises TAILGATING for following_distance events
+// whose headway falls in one of its bands, sorted by descending Below. Close
+// following in slow traffic is expected, hence the higher default MinSpeed.
+type FollowingDistanceDetector struct {
+	sensorRule
+	Bands []HeadwayBand `json:"bands"`
+}
+
+func newFollowingDistanceDetector(params json.RawMessage) (Detector, error) {
+	d := &FollowingDistanceDetector{
+		sensorRule: sensorRule{MinSpeed: 30, MinDurationSeconds: 3},
+		Bands: []HeadwayBand{
+			{Below: 1.0, Severity: "medium", RiskScore: 55},
+			{Below: 0.6, Severity: "high", RiskScore: 75},
+		},
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if err := d.sensorRule.validate(); err != nil {
+		return nil, err
+	}
+	if len(d.Bands) == 0 {
+		return nil, errors.New("at least one headway band is required")
+	}
+	for i, band := range d.Bands {
+		if band.Below <= 0 {
+			return nil, fmt.Errorf("band %d: below must be positive", i)
+		}
+		if i > 0 && band.Below >= d.Bands[i-1].Below {
+			return nil, fmt.Errorf("band %d: bands must be sorted by descending below", i)
+		}
+		if err := (Grade{band.Severity, band.RiskScore}).validate(fmt.Sprintf("band %d", i)); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d *FollowingDistanceDetector) Name() string { return RuleFollowingDistance }
+
+func (d *FollowingDistanceDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	var reading models.FollowingDistanceData
+	if !d.reading(event, models.EventFollowingDistance, &reading) ||
+		reading.DurationSeconds < d.MinDurationSeconds || reading.HeadwaySeconds <= 0 {
+		return nil
+	}
+	// Without a speed the reading cannot be told apart from a traffic queue
+	if event.Speed == nil {
+		return nil
+	}
+
+	var band *HeadwayBand
+	for i := range d.Bands {
+		if reading.HeadwaySeconds < d.Bands[i].Below {
+			band = &d.Bands[i]
+		}
+	}
+	if band == nil {
+		return nil
+	}
+
+	return []models.RiskEvent{newRiskEvent(event, EventTailgating, band.Severity, band.RiskScore,
+		fmt.Sprintf("Following too closely: %.1f second headway at %.0f mph for %.0f seconds",
+			reading.HeadwaySeconds, *event.Speed, reading.DurationSeconds),
+		sensorData(event, reading))}
+}
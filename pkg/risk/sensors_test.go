@@ -0,0 +1,84 @@
+package risk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func sensorEvent(eventType string, speed float64, data string) *models.TelemetryEvent {
+	return &models.TelemetryEvent{
+		VehicleID: 3,
+		EventType: eventType,
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Speed:     &speed,
+		Data:      data,
+	}
+}
+
+func TestSensorDetectors(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		event        *models.TelemetryEvent
+		wantType     string
+		wantSeverity string
+	}{
+		{"texting", sensorEvent(models.EventPhoneUse, 45, `{"usage": "texting", "duration_seconds": 8}`), EventDistractedDriving, "critical"},
+		{"handheld call", sensorEvent(models.EventPhoneUse, 45, `{"usage": "handheld_call", "duration_seconds": 60}`), EventDistractedDriving, "high"},
+		{"hands free call", sensorEvent(models.EventPhoneUse, 45, `{"usage": "hands_free", "duration_seconds": 60}`), "", ""},
+		{"texting while parked", sensorEvent(models.EventPhoneUse, 0, `{"usage": "texting", "duration_seconds": 60}`), "", ""},
+		{"glance at phone", sensorEvent(models.EventPhoneUse, 45, `{"usage": "texting", "duration_seconds": 1}`), "", ""},
+		{"eyes closed", sensorEvent(models.EventCameraDistraction, 60, `{"behavior": "eyes_closed", "duration_seconds": 3, "confidence": 0.9}`), EventDistractedDriving, "critical"},
+		{"uncertain camera", sensorEvent(models.EventCameraDistraction, 60, `{"behavior": "eyes_closed", "duration_seconds": 3, "confidence": 0.4}`), "", ""},
+		{"driver unbuckled", sensorEvent(models.EventSeatbeltUnbuckled, 30, `{"seat": "driver", "duration_seconds": 120}`), EventSeatbeltViolation, "medium"},
+		{"passenger unbuckled", sensorEvent(models.EventSeatbeltUnbuckled, 30, `{"seat": "passenger", "duration_seconds": 120}`), EventSeatbeltViolation, "low"},
+		{"tailgating", sensorEvent(models.EventFollowingDistance, 65, `{"headway_seconds": 0.8, "duration_seconds": 10}`), EventTailgating, "medium"},
+		{"dangerous tailgating", sensorEvent(models.EventFollowingDistance, 65, `{"headway_seconds": 0.4, "duration_seconds": 10}`), EventTailgating, "high"},
+		{"safe headway", sensorEvent(models.EventFollowingDistance, 65, `{"headway_seconds": 2.5, "duration_seconds": 10}`), "", ""},
+		{"queueing traffic", sensorEvent(models.EventFollowingDistance, 12, `{"headway_seconds": 0.4, "duration_seconds": 10}`), "", ""},
+		{"malformed reading", sensorEvent(models.EventPhoneUse, 45, `not json`), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risks := analyzer.Analyze(tt.event)
+			if tt.wantType == "" {
+				assert.Empty(t, risks)
+				return
+			}
+			require.Len(t, risks, 1)
+			assert.Equal(t, tt.wantType, risks[0].EventType)
+			assert.Equal(t, tt.wantSeverity, risks[0].Severity)
+
+			var data struct {
+				Reading json.RawMessage `json:"reading"`
+				Speed   float64         `json:"speed"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(risks[0].Data), &data))
+			assert.NotEmpty(t, data.Reading)
+			assert.Equal(t, *tt.event.Speed, data.Speed)
+		})
+	}
+}
+
+func TestSensorDetectorParams(t *testing.T) {
+	invalid := []RuleConfig{
+		{Name: RulePhoneUse, Params: json.RawMessage(`{"usages": {"browsing": {"severity": "high", "risk_score": 150}}}`)},
+		{Name: RulePhoneUse, Params: json.RawMessage(`{"usages": {"texting": {"severity": "dire", "risk_score": 90}}}`)},
+		{Name: RuleCameraDistraction, Params: json.RawMessage(`{"min_confidence": 1.5}`)},
+		{Name: RuleSeatbelt, Params: json.RawMessage(`{"min_speed": -1}`)},
+		{Name: RuleFollowingDistance, Params: json.RawMessage(`{"bands": [{"below": 0.5, "severity": "high", "risk_score": 75}, {"below": 1.0, "severity": "medium", "risk_score": 55}]}`)},
+	}
+	for _, rule := range invalid {
+		_, err := Build(Config{Rules: []RuleConfig{rule}})
+		assert.Error(t, err, string(rule.Params))
+	}
+}
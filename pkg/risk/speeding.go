@@ -0,0 +1,204 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// SpeedingDetector reports sustained speeding rather than individual samples.
+// An episode opens when a vehicle exceeds the threshold (mph) and closes when
+// its speed drops back or it stops reporting for MaxGapSeconds. Episodes that
+// lasted at least MinDurationSeconds become one RiskEvent, graded by the
+// severity band of their peak speed.
+type SpeedingDetector struct {
+	thresholdRule
+	MinDurationSeconds float64 `json:"min_duration_seconds"`
+	MaxGapSeconds      float64 `json:"max_gap_seconds"`
+
+	state *State
+}
+
+func newSpeedingDetector(params json.RawMessage) (Detector, error) {
+	d := &SpeedingDetector{
+		thresholdRule: thresholdRule{Threshold: 80.0, Bands: []Band{
+			{Above: 1.0, Severity: "medium", RiskScore: 50.0},
+			{Above: 1.3, Severity: "high", RiskScore: 75.0},
+			{Above: 1.5, Severity: "critical", RiskScore: 90.0},
+		}},
+		MinDurationSeconds: 10,
+		MaxGapSeconds:      60,
+		state:              NewState(),
+	}
+	if err := decodeParams(params, d); err != nil {
+		return nil, err
+	}
+	if d.Threshold <= 0 {
+		return nil, errors.New("threshold must be positive")
+	}
+	if d.MinDurationSeconds < 0 {
+		return nil, errors.New("min_duration_seconds must not be negative")
+	}
+	if d.MaxGapSeconds <= 0 {
+		return nil, errors.New("max_gap_seconds must be positive")
+	}
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *SpeedingDetector) Name() string { return RuleSpeeding }
+
+func (d *SpeedingDetector) Bind(state *State) { d.state = state }
+
+func (d *SpeedingDetector) Detect(event *models.TelemetryEvent) []models.RiskEvent {
+	if event.Speed == nil {
+		return nil
+	}
+
+	var risks []models.RiskEvent
+	episode, _ := d.state.Get(RuleSpeeding, event.VehicleID).(*speedingEpisode)
+	if episode != nil {
+		if event.Timestamp.Before(episode.last) {
+			// Late sample for a stretch that has already moved on
+			return nil
+		}
+		if event.Timestamp.Sub(episode.last) > d.maxGap() {
+			// The vehicle went silent, so the episode ended at its last sample
+			d.state.Delete(RuleSpeeding, event.VehicleID)
+			risks = append(risks, episode.Close()...)
+			episode = nil
+		}
+	}
+
+	if *event.Speed > d.Threshold {
+		if episode == nil {
+			d.state.Put(RuleSpeeding, event.VehicleID, newSpeedingEpisode(d, event))
+		} else {
+			episode.extend(d, event)
+		}
+		return risks
+	}
+
+	if episode != nil {
+		// The episode runs until the sample that dropped back below the threshold
+		episode.extend(d, event)
+		d.state.Delete(RuleSpeeding, event.VehicleID)
+		risks = append(risks, episode.Close()...)
+	}
+	return risks
+}
+
+func (d *SpeedingDetector) maxGap() time.Duration {
+	return time.Duration(d.MaxGapSeconds * float64(time.Second))
+}
+
+// speedingEpisode is an open run of speeding samples for one vehicle
+type speedingEpisode struct {
+	// detector is the one that last saw the vehicle, whose parameters apply
+	// even if the policy was reloaded since the episode opened
+	detector  *SpeedingDetector
+	vehicleID uint
+
+	start     time.Time
+	latitude  *float64
+	longitude *float64
+
+	last          time.Time
+	lastLatitude  *float64
+	lastLongitude *float64
+	lastSpeed     float64
+
+	peakSpeed     float64
+	distanceMiles float64
+}
+
+func newSpeedingEpisode(d *SpeedingDetector, event *models.TelemetryEvent) *speedingEpisode {
+	return &speedingEpisode{
+		detector:      d,
+		vehicleID:     event.VehicleID,
+		start:         event.Timestamp,
+		latitude:      event.Latitude,
+		longitude:     event.Longitude,
+		last:          event.Timestamp,
+		lastLatitude:  event.Latitude,
+		lastLongitude: event.Longitude,
+		lastSpeed:     *event.Speed,
+		peakSpeed:     *event.Speed,
+	}
+}
+
+// extend adds the stretch driven since the previous sample
+func (e *speedingEpisode) extend(d *SpeedingDetector, event *models.TelemetryEvent) {
+	if event.Latitude != nil && event.Longitude != nil && e.lastLatitude != nil && e.lastLongitude != nil {
+		e.distanceMiles += geo.DistanceMiles(*e.lastLatitude, *e.lastLongitude, *event.Latitude, *event.Longitude)
+	} else {
+		// Without positions, estimate from the average speed over the interval
+		e.distanceMiles += (e.lastSpeed + *event.Speed) / 2 * event.Timestamp.Sub(e.last).Hours()
+	}
+
+	e.detector = d
+	e.last = event.Timestamp
+	e.lastSpeed = *event.Speed
+	if event.Latitude != nil && event.Longitude != nil {
+		e.lastLatitude = event.Latitude
+		e.lastLongitude = event.Longitude
+	}
+	if *event.Speed > e.peakSpeed {
+		e.peakSpeed = *event.Speed
+	}
+}
+
+func (e *speedingEpisode) Expired(now time.Time) bool {
+	return now.Sub(e.last) > e.detector.maxGap()
+}
+
+func (e *speedingEpisode) Close() []models.RiskEvent {
+	d := e.detector
+	duration := e.last.Sub(e.start)
+	if duration.Seconds() < d.MinDurationSeconds {
+		return nil
+	}
+	band, ok := d.classify(e.peakSpeed)
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(struct {
+		Start           time.Time `json:"start"`
+		End             time.Time `json:"end"`
+		DurationSeconds float64   `json:"duration_seconds"`
+		PeakSpeed       float64   `json:"peak_speed"`
+		DistanceMiles   float64   `json:"distance_miles"`
+		Threshold       float64   `json:"threshold"`
+	}{
+		Start:           e.start,
+		End:             e.last,
+		DurationSeconds: duration.Seconds(),
+		PeakSpeed:       e.peakSpeed,
+		DistanceMiles:   math.Round(e.distanceMiles*100) / 100,
+		Threshold:       d.Threshold,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return []models.RiskEvent{{
+		VehicleID: e.vehicleID,
+		EventType: RuleSpeeding,
+		Severity:  band.Severity,
+		RiskScore: band.RiskScore,
+		Timestamp: e.start,
+		Latitude:  e.latitude,
+		Longitude: e.longitude,
+		Description: fmt.Sprintf("Vehicle exceeded speed limit for %s, peaking at %.1f mph over %.2f miles",
+			duration.Round(time.Second), e.peakSpeed, e.distanceMiles),
+		Data: string(data),
+	}}
+}
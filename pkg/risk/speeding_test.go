@@ -0,0 +1,165 @@
+package risk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+func sampleAt(vehicleID uint, timestamp time.Time, speed float64) *models.TelemetryEvent {
+	return &models.TelemetryEvent{VehicleID: vehicleID, Timestamp: timestamp, Speed: &speed}
+}
+
+// drive returns one sample per second at a constant speed
+func drive(vehicleID uint, start time.Time, speed float64, seconds int) []*models.TelemetryEvent {
+	samples := make([]*models.TelemetryEvent, 0, seconds)
+	for i := 0; i < seconds; i++ {
+		samples = append(samples, sampleAt(vehicleID, start.Add(time.Duration(i)*time.Second), speed))
+	}
+	return samples
+}
+
+func analyzeAll(analyzer *Analyzer, samples []*models.TelemetryEvent) []models.RiskEvent {
+	var risks []models.RiskEvent
+	for _, sample := range samples {
+		risks = append(risks, analyzer.Analyze(sample)...)
+	}
+	return risks
+}
+
+type episodeData struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	PeakSpeed       float64   `json:"peak_speed"`
+	DistanceMiles   float64   `json:"distance_miles"`
+	Threshold       float64   `json:"threshold"`
+}
+
+func TestSustainedSpeedingIsOneEpisode(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Three minutes of highway speeding sampled every second, peaking once
+	samples := drive(1, start, 90, 181)
+	samples[90].Speed = floatPtr(110)
+	samples = append(samples, sampleAt(1, start.Add(181*time.Second), 60))
+
+	risks := analyzeAll(analyzer, samples)
+	require.Len(t, risks, 1)
+
+	risk := risks[0]
+	assert.Equal(t, RuleSpeeding, risk.EventType)
+	assert.Equal(t, "high", risk.Severity)
+	assert.Equal(t, 75.0, risk.RiskScore)
+	assert.Equal(t, start, risk.Timestamp)
+
+	var data episodeData
+	require.NoError(t, json.Unmarshal([]byte(risk.Data), &data))
+	assert.Equal(t, start, data.Start)
+	assert.Equal(t, start.Add(181*time.Second), data.End)
+	assert.Equal(t, 181.0, data.DurationSeconds)
+	assert.Equal(t, 110.0, data.PeakSpeed)
+	// Estimated from speeds: 181 seconds at roughly 90 mph
+	assert.InDelta(t, 4.53, data.DistanceMiles, 0.01)
+	assert.Zero(t, openEpisodes(analyzer.state, RuleSpeeding))
+}
+
+func TestShortSpeedingSpikeIsIgnored(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	samples := append(drive(1, start, 130, 5), sampleAt(1, start.Add(5*time.Second), 50))
+	assert.Empty(t, analyzeAll(analyzer, samples))
+	assert.Zero(t, openEpisodes(analyzer.state, RuleSpeeding))
+}
+
+func TestSpeedingEpisodeClosesWhenVehicleGoesSilent(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Heading north at 0.001 degrees per second
+	samples := drive(1, start, 95, 31)
+	for i, sample := range samples {
+		lat, lon := 40.0+float64(i)*0.001, -100.0
+		sample.Latitude, sample.Longitude = &lat, &lon
+	}
+	assert.Empty(t, analyzeAll(analyzer, samples))
+
+	last := start.Add(30 * time.Second)
+	assert.Empty(t, analyzer.Flush(last.Add(30*time.Second)), "still within the gap")
+
+	risks := analyzer.Flush(last.Add(2 * time.Minute))
+	require.Len(t, risks, 1)
+	assert.Equal(t, "medium", risks[0].Severity)
+	assert.Equal(t, 40.0, *risks[0].Latitude)
+
+	var data episodeData
+	require.NoError(t, json.Unmarshal([]byte(risks[0].Data), &data))
+	assert.Equal(t, last, data.End)
+	// Measured from positions: 0.03 degrees of latitude
+	assert.InDelta(t, 2.07, data.DistanceMiles, 0.01)
+}
+
+func TestSpeedingEpisodeEndsAtReportingGap(t *testing.T) {
+	analyzer, err := NewAnalyzerFromConfig(DefaultConfig())
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first := drive(1, start, 95, 30)
+	second := drive(1, start.Add(10*time.Minute), 95, 30)
+
+	risks := analyzeAll(analyzer, append(first, second...))
+	require.Len(t, risks, 1, "the first episode closes when the second opens")
+	assert.Equal(t, start, risks[0].Timestamp)
+	assert.Equal(t, 1, openEpisodes(analyzer.state, RuleSpeeding))
+
+	// Vehicles are tracked independently
+	assert.Empty(t, analyzeAll(analyzer, drive(2, start, 95, 30)))
+	assert.Equal(t, 2, openEpisodes(analyzer.state, RuleSpeeding))
+}
+
+func TestSpeedingEpisodeSurvivesPolicyReload(t *testing.T) {
+	store, db := setupPolicyStore(t)
+	start := time.Now().Add(-time.Hour)
+
+	// The episode opens under the defaults...
+	assert.Empty(t, analyzeAll(store.AnalyzerFor(1, ""), drive(1, start, 90, 30)))
+
+	// ...and a stricter policy arrives mid-episode
+	savePolicy(t, db, 1, "", `{"rules": [{"name": "speeding", "params": {"bands": [{"above": 1, "severity": "critical", "risk_score": 95}]}}]}`)
+	_, err := store.Refresh(t.Context())
+	require.NoError(t, err)
+
+	samples := append(drive(1, start.Add(30*time.Second), 90, 30), sampleAt(1, start.Add(time.Minute), 40))
+	risks := analyzeAll(store.AnalyzerFor(1, ""), samples)
+	require.Len(t, risks, 1)
+	assert.Equal(t, start, risks[0].Timestamp)
+	assert.Equal(t, "critical", risks[0].Severity)
+}
+
+// openEpisodes counts the vehicles a rule is tracking
+func openEpisodes(state *State, rule string) int {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	open := 0
+	for key := range state.episodes {
+		if key.rule == rule {
+			open++
+		}
+	}
+	return open
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,125 @@
+package risk
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// StatefulDetector follows each vehicle across events, e.g. to turn a run of
+// samples into a single episode. Its per-vehicle state is kept in the State it
+// is bound to rather than in the detector, so that open episodes survive
+// policy reloads and a vehicle is never tracked twice.
+type StatefulDetector interface {
+	Detector
+	Bind(state *State)
+}
+
+// Episode is the state a stateful detector keeps for one vehicle
+type Episode interface {
+	// Expired reports whether the vehicle has been silent for too long at now
+	Expired(now time.Time) bool
+	// Close ends the episode and returns the risk events it amounts to
+	Close() []models.RiskEvent
+}
+
+type stateKey struct {
+	rule      string
+	vehicleID uint
+}
+
+// driverKey keys a detector's state by the event's driver, so that it follows
+// them across vehicles, or by vehicle for telemetry without a driver. Driver
+// state is stored under its own rule name to keep the two ID spaces apart.
+func driverKey(rule string, event *models.TelemetryEvent) (string, uint) {
+	if event.DriverID != nil {
+		return rule + driverSuffix, *event.DriverID
+	}
+	return rule, event.VehicleID
+}
+
+const driverSuffix = ":driver"
+
+// State holds the open episodes of every stateful detector
+type State struct {
+	mu       sync.Mutex
+	episodes map[stateKey]Episode
+}
+
+func NewState() *State {
+	return &State{episodes: make(map[stateKey]Episode)}
+}
+
+// Get returns the vehicle's open episode for rule, or nil
+func (s *State) Get(rule string, vehicleID uint) Episode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.episodes[stateKey{rule, vehicleID}]
+}
+
+// Put stores the vehicle's open episode for rule
+func (s *State) Put(rule string, vehicleID uint, episode Episode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.episodes[stateKey{rule, vehicleID}] = episode
+}
+
+// Delete forgets the vehicle's episode for rule
+func (s *State) Delete(rule string, vehicleID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.episodes, stateKey{rule, vehicleID})
+}
+
+// Forget drops the vehicle's open episodes, and those following driverID if
+// set, without reporting them. Fatigue shifts are kept: analyzing a sample
+// again adds no driving time, while dropping a shift would reset the driver's
+// hours of service.
+func (s *State) Forget(vehicleID uint, driverID *uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.episodes {
+		if strings.TrimSuffix(key.rule, driverSuffix) == RuleFatigue {
+			continue
+		}
+		byDriver := strings.HasSuffix(key.rule, driverSuffix)
+		if (!byDriver && key.vehicleID == vehicleID) || (byDriver && driverID != nil && key.vehicleID == *driverID) {
+			delete(s.episodes, key)
+		}
+	}
+}
+
+// Len returns the number of open episodes
+func (s *State) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.episodes)
+}
+
+// Flush closes the episodes of vehicles that stopped reporting, such as a
+// vehicle switched off while speeding, and returns the resulting risk events.
+// now must be a time by which every sample received has been analyzed, or
+// samples still waiting to be analyzed pass for silence.
+func (s *State) Flush(now time.Time) []models.RiskEvent {
+	s.mu.Lock()
+	var expired []Episode
+	for key, episode := range s.episodes {
+		if episode.Expired(now) {
+			expired = append(expired, episode)
+			delete(s.episodes, key)
+		}
+	}
+	s.mu.Unlock()
+
+	var risks []models.RiskEvent
+	for _, episode := range expired {
+		risks = append(risks, episode.Close()...)
+	}
+	sort.Slice(risks, func(i, j int) bool {
+		return risks[i].Timestamp.Before(risks[j].Timestamp)
+	})
+	return risks
+}
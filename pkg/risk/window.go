@@ -0,0 +1,158 @@
+package risk
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// WindowDuration is how much of a vehicle's recent telemetry a Scorer sees
+const WindowDuration = 2 * time.Minute
+
+// maxWindowSamples bounds the window of vehicles reporting very often
+const maxWindowSamples = 1200
+
+// sample is what a window keeps of a telemetry event
+type sample struct {
+	at           time.Time
+	speed        *float64
+	acceleration *float64
+}
+
+// windowFeatures engineers the features of a vehicle's telemetry window,
+// oldest sample first; see Scorer
+func windowFeatures(samples []sample) map[string]float64 {
+	features := map[string]float64{"window_samples": float64(len(samples))}
+
+	var speeds, accelerations []float64
+	for _, s := range samples {
+		if s.speed != nil {
+			speeds = append(speeds, *s.speed)
+		}
+		if s.acceleration != nil {
+			accelerations = append(accelerations, *s.acceleration)
+		}
+	}
+
+	if len(speeds) > 0 {
+		mean, std := meanStd(speeds)
+		features["speed_mean"] = mean
+		features["speed_std"] = std
+		features["speed_max"] = maxOf(speeds)
+	}
+	if len(accelerations) > 0 {
+		_, std := meanStd(accelerations)
+		features["accel_std"] = std
+		features["accel_max"] = math.Max(0, maxOf(accelerations))
+		decelerations := make([]float64, len(accelerations))
+		for i, a := range accelerations {
+			decelerations[i] = -a
+		}
+		features["decel_max"] = math.Max(0, maxOf(decelerations))
+	}
+	return features
+}
+
+func meanStd(values []float64) (float64, float64) {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+func maxOf(values []float64) float64 {
+	highest := values[0]
+	for _, v := range values[1:] {
+		highest = math.Max(highest, v)
+	}
+	return highest
+}
+
+// modelScoring rescores the risk events the rules raise with a Scorer. It is
+// shared by every analyzer of a PolicyStore, like State, so a vehicle keeps
+// its window when its policy changes.
+type modelScoring struct {
+	scorer Scorer
+
+	mu      sync.Mutex
+	windows map[uint][]sample
+}
+
+func newModelScoring(scorer Scorer) *modelScoring {
+	return &modelScoring{scorer: scorer, windows: make(map[uint][]sample)}
+}
+
+// observe adds the event to its vehicle's window, dropping samples older than
+// WindowDuration. Late events are ignored.
+func (m *modelScoring) observe(event *models.TelemetryEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	window := m.windows[event.VehicleID]
+	if n := len(window); n > 0 && event.Timestamp.Before(window[n-1].at) {
+		return
+	}
+	window = append(window, sample{at: event.Timestamp, speed: event.Speed, acceleration: event.Acceleration})
+
+	start := 0
+	cutoff := event.Timestamp.Add(-WindowDuration)
+	for start < len(window) && window[start].at.Before(cutoff) {
+		start++
+	}
+	start = max(start, len(window)-maxWindowSamples)
+	m.windows[event.VehicleID] = window[start:]
+}
+
+// rescore replaces the rule risk scores with the model's, keeping the rule's
+// in the event data as rule_risk_score, and records the model version
+func (m *modelScoring) rescore(risks []models.RiskEvent) {
+	for i := range risks {
+		r := &risks[i]
+
+		m.mu.Lock()
+		features := windowFeatures(m.windows[r.VehicleID])
+		m.mu.Unlock()
+		features["rule_score"] = r.RiskScore / 100
+		features["event_"+r.EventType] = 1
+
+		data := make(map[string]interface{})
+		if r.Data != "" {
+			_ = json.Unmarshal([]byte(r.Data), &data)
+		}
+		data["rule_risk_score"] = r.RiskScore
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			continue
+		}
+
+		r.Data = string(encoded)
+		r.RiskScore = math.Round(m.scorer.Score(features)*10) / 10
+		r.ModelVersion = m.scorer.Version()
+	}
+}
+
+func (m *modelScoring) forget(vehicleID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.windows, vehicleID)
+}
+
+// flush forgets the windows of vehicles silent for WindowDuration
+func (m *modelScoring) flush(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for vehicleID, window := range m.windows {
+		if len(window) == 0 || now.Sub(window[len(window)-1].at) > WindowDuration {
+			delete(m.windows, vehicleID)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Why a route deviation ended
+const (
+	ResolvedRejoined   = "rejoined"
+	ResolvedRouteEnded = "route_ended"
+	ResolvedExpired    = "expired"
+)
+
+// DeviationTTL is how long a deviation is kept for a vehicle that stopped
+// reporting its position
+const DeviationTTL = time.Hour
+
+// Result is what the monitor made of one telemetry event
+type Result struct {
+	Risks       []models.RiskEvent
+	Resolutions []Resolution
+}
+
+// Resolution ends a raised route deviation, either because the vehicle came
+// back into the corridor, because its route assignment ended or because it
+// stopped reporting its position. See Resolve.
+type Resolution struct {
+	// DeviationID is the ID of the deviation's risk event, or 0 if it was
+	// never stored
+	DeviationID       uint
+	VehicleID         uint
+	DriverID          *uint
+	RouteID           uint
+	AssignmentID      uint
+	RouteName         string
+	LeftAt            time.Time
+	ResolvedAt        time.Time
+	Latitude          *float64
+	Longitude         *float64
+	Reason            string
+	MaxDistanceMeters float64
+}
+
+// Monitor compares vehicle positions with the planned routes assigned to them.
+// A vehicle that stays outside its route's corridor for the route's deviation
+// time raises ROUTE_DEVIATION, stamped with when it left; coming back resolves
+// it. Events must be observed in timestamp order per vehicle; late events are
+// ignored. Refresh reloads the assignments when they change, and Restore
+// reopens the deviations raised before a restart.
+type Monitor struct {
+	db *gorm.DB
+	// lookback is how far back assignments are loaded, matching how old the
+	// telemetry being processed may be
+	lookback time.Duration
+
+	mu          sync.Mutex
+	fingerprint string
+	plans       map[uint][]*plan // by vehicle, in start order
+	deviations  map[uint]*deviation
+}
+
+// deviation is a vehicle's time outside its route's corridor
+type deviation struct {
+	plan        *plan
+	riskEventID uint
+	driverID    *uint
+	leftAt      time.Time
+	latitude    float64
+	longitude   float64
+	last        time.Time
+	maxDistance float64
+	raised      bool
+}
+
+func NewMonitor(db *gorm.DB, lookback time.Duration) *Monitor {
+	return &Monitor{
+		db:         db,
+		lookback:   lookback,
+		plans:      make(map[uint][]*plan),
+		deviations: make(map[uint]*deviation),
+	}
+}
+
+// Raised records the IDs of the stored route deviation risk events among
+// risks, which the resolutions of the deviations refer to
+func (m *Monitor) Raised(risks []models.RiskEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range risks {
+		if event.EventType != EventRouteDeviation || event.ID == 0 {
+			continue
+		}
+		if dev := m.deviations[event.VehicleID]; dev != nil && dev.raised && dev.leftAt.Equal(event.Timestamp) {
+			dev.riskEventID = event.ID
+		}
+	}
+}
+
+// Restore reopens the stored route deviations that were not resolved yet and
+// whose route assignment is being monitored. Call it after Refresh.
+func (m *Monitor) Restore(ctx context.Context) error {
+	return m.restore(ctx, nil)
+}
+
+// Reset drops the vehicle's deviation in progress and reopens its stored
+// deviation, if one was raised and not resolved
+func (m *Monitor) Reset(ctx context.Context, vehicleID uint) error {
+	m.mu.Lock()
+	delete(m.deviations, vehicleID)
+	m.mu.Unlock()
+	return m.restore(ctx, &vehicleID)
+}
+
+// deviationData is what restoring needs from the data of route deviation and
+// resolution risk events
+type deviationData struct {
+	RouteAssignmentID uint    `json:"route_assignment_id"`
+	DistanceMeters    float64 `json:"distance_meters"`
+	OffRouteSeconds   float64 `json:"off_route_seconds"`
+	RouteDeviationID  uint    `json:"route_deviation_id"`
+}
+
+// restore reopens the unresolved deviations of the vehicle, or of every
+// vehicle with a route assignment if vehicleID is nil
+func (m *Monitor) restore(ctx context.Context, vehicleID *uint) error {
+	m.mu.Lock()
+	var vehicles []uint
+	var since time.Time
+	for id, plans := range m.plans {
+		if vehicleID != nil && id != *vehicleID {
+			continue
+		}
+		vehicles = append(vehicles, id)
+		if since.IsZero() || plans[0].start.Before(since) {
+			since = plans[0].start
+		}
+	}
+	m.mu.Unlock()
+	if len(vehicles) == 0 {
+		return nil
+	}
+
+	var events []models.RiskEvent
+	if err := m.db.WithContext(ctx).
+		Where("vehicle_id IN ? AND event_type IN ? AND timestamp >= ?",
+			vehicles, []string{EventRouteDeviation, EventRouteDeviationResolved}, since).
+		Order("id").
+		Find(&events).Error; err != nil {
+		return err
+	}
+
+	resolved := make(map[uint]bool)
+	for _, event := range events {
+		var data deviationData
+		if event.EventType == EventRouteDeviationResolved && json.Unmarshal([]byte(event.Data), &data) == nil {
+			resolved[data.RouteDeviationID] = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range events {
+		var data deviationData
+		if event.EventType != EventRouteDeviation || resolved[event.ID] || m.deviations[event.VehicleID] != nil ||
+			json.Unmarshal([]byte(event.Data), &data) != nil {
+			continue
+		}
+		var current *plan
+		for _, p := range m.plans[event.VehicleID] {
+			if p.assignmentID == data.RouteAssignmentID {
+				current = p
+			}
+		}
+		if current == nil || event.Latitude == nil || event.Longitude == nil {
+			continue
+		}
+		m.deviations[event.VehicleID] = &deviation{
+			plan:        current,
+			riskEventID: event.ID,
+			driverID:    event.DriverID,
+			leftAt:      event.Timestamp,
+			latitude:    *event.Latitude,
+			longitude:   *event.Longitude,
+			last:        event.Timestamp.Add(time.Duration(data.OffRouteSeconds * float64(time.Second))),
+			maxDistance: data.DistanceMeters,
+			raised:      true,
+		}
+	}
+	return nil
+}
+
+// Assignments returns the number of route assignments being monitored
+func (m *Monitor) Assignments() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, plans := range m.plans {
+		count += len(plans)
+	}
+	return count
+}
+
+// Refresh reloads the route assignments that end after now minus the
+// lookback if any of them or their routes changed, and reports whether it
+// did. Invalid routes are logged and skipped.
+func (m *Monitor) Refresh(ctx context.Context, now time.Time) (bool, error) {
+	var assignments []models.RouteAssignment
+	if err := m.db.WithContext(ctx).Preload("Route").
+		Where("end_time >= ?", now.Add(-m.lookback)).
+		Order("id").
+		Find(&assignments).Error; err != nil {
+		return false, err
+	}
+
+	fingerprint := assignmentFingerprint(assignments)
+	m.mu.Lock()
+	unchanged := fingerprint == m.fingerprint
+	m.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	plans := make(map[uint][]*plan)
+	for _, assignment := range assignments {
+		p, err := compile(assignment)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"route_assignment_id": assignment.ID,
+				"route_id":            assignment.RouteID,
+			}).Error("Ignoring invalid route assignment")
+			continue
+		}
+		plans[assignment.VehicleID] = append(plans[assignment.VehicleID], p)
+	}
+	for _, vehiclePlans := range plans {
+		sort.Slice(vehiclePlans, func(i, j int) bool { return vehiclePlans[i].start.Before(vehiclePlans[j].start) })
+	}
+
+	m.mu.Lock()
+	m.plans = plans
+	m.fingerprint = fingerprint
+	m.mu.Unlock()
+
+	logrus.WithField("assignments", len(assignments)).Info("Route assignments reloaded")
+	return true, nil
+}
+
+// Observe feeds one event to the monitor. Events without a position are
+// ignored.
+func (m *Monitor) Observe(event *models.TelemetryEvent) Result {
+	var result Result
+	if event.Latitude == nil || event.Longitude == nil {
+		return result
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dev := m.deviations[event.VehicleID]
+	if dev != nil && event.Timestamp.Before(dev.last) {
+		return result
+	}
+	current := m.planAt(event.VehicleID, event.Timestamp)
+
+	// The assignment ended, or the next one began, while the vehicle was off route
+	if dev != nil && (current == nil || current.assignmentID != dev.plan.assignmentID) {
+		if dev.raised {
+			result.Resolutions = append(result.Resolutions, dev.resolve(event.VehicleID, event, ResolvedRouteEnded))
+		}
+		delete(m.deviations, event.VehicleID)
+		dev = nil
+	}
+	if current == nil {
+		return result
+	}
+
+	distance := geo.DistanceToPathMeters(*event.Latitude, *event.Longitude, current.path)
+	if distance <= current.halfWidth {
+		if dev != nil {
+			if dev.raised {
+				result.Resolutions = append(result.Resolutions, dev.resolve(event.VehicleID, event, ResolvedRejoined))
+			}
+			delete(m.deviations, event.VehicleID)
+		}
+		return result
+	}
+
+	if dev == nil {
+		dev = &deviation{
+			plan:      current,
+			driverID:  event.DriverID,
+			leftAt:    event.Timestamp,
+			latitude:  *event.Latitude,
+			longitude: *event.Longitude,
+		}
+		m.deviations[event.VehicleID] = dev
+	}
+	dev.plan = current
+	dev.last = event.Timestamp
+	dev.maxDistance = math.Max(dev.maxDistance, distance)
+
+	if !dev.raised && event.Timestamp.Sub(dev.leftAt) >= current.deviation {
+		dev.raised = true
+		result.Risks = append(result.Risks, dev.risk(event.VehicleID, event.Timestamp))
+	}
+	return result
+}
+
+// Flush ends the deviations of vehicles whose route assignment ended or that
+// have not reported a position for DeviationTTL at now, and returns the
+// resolutions of those that were raised
+func (m *Monitor) Flush(now time.Time) []Resolution {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var resolutions []Resolution
+	for vehicleID, dev := range m.deviations {
+		var resolution Resolution
+		switch {
+		case !now.Before(dev.plan.end):
+			resolution = dev.resolve(vehicleID, nil, ResolvedRouteEnded)
+			resolution.ResolvedAt = dev.plan.end
+		case now.Sub(dev.last) > DeviationTTL:
+			resolution = dev.resolve(vehicleID, nil, ResolvedExpired)
+		default:
+			continue
+		}
+		if dev.raised {
+			resolutions = append(resolutions, resolution)
+		}
+		delete(m.deviations, vehicleID)
+	}
+	sort.Slice(resolutions, func(i, j int) bool { return resolutions[i].VehicleID < resolutions[j].VehicleID })
+	return resolutions
+}
+
+// planAt returns the vehicle's route assignment covering the time, or nil
+func (m *Monitor) planAt(vehicleID uint, at time.Time) *plan {
+	for _, p := range m.plans[vehicleID] {
+		if p.covers(at) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (d *deviation) risk(vehicleID uint, now time.Time) models.RiskEvent {
+	data, _ := json.Marshal(map[string]interface{}{
+		"route_id":              d.plan.routeID,
+		"route_assignment_id":   d.plan.assignmentID,
+		"route_name":            d.plan.routeName,
+		"corridor_width_meters": d.plan.halfWidth * 2,
+		"distance_meters":       math.Round(d.maxDistance),
+		"off_route_seconds":     now.Sub(d.leftAt).Seconds(),
+	})
+	latitude, longitude := d.latitude, d.longitude
+	return models.RiskEvent{
+		VehicleID: vehicleID,
+		DriverID:  d.driverID,
+		EventType: EventRouteDeviation,
+		Severity:  "medium",
+		RiskScore: 50,
+		Timestamp: d.leftAt,
+		Latitude:  &latitude,
+		Longitude: &longitude,
+		Description: fmt.Sprintf("Off planned route %s for over %.0f minutes, up to %.0f m from it",
+			d.plan.routeName, d.plan.deviation.Minutes(), d.maxDistance),
+		Data: string(data),
+	}
+}
+
+// resolve ends the deviation at the sample back on route, or at the last
+// sample off route without one
+func (d *deviation) resolve(vehicleID uint, event *models.TelemetryEvent, reason string) Resolution {
+	resolution := Resolution{
+		DeviationID:       d.riskEventID,
+		VehicleID:         vehicleID,
+		DriverID:          d.driverID,
+		RouteID:           d.plan.routeID,
+		AssignmentID:      d.plan.assignmentID,
+		RouteName:         d.plan.routeName,
+		LeftAt:            d.leftAt,
+		ResolvedAt:        d.last,
+		Reason:            reason,
+		MaxDistanceMeters: d.maxDistance,
+	}
+	if event != nil {
+		resolution.ResolvedAt = event.Timestamp
+		resolution.Latitude, resolution.Longitude = event.Latitude, event.Longitude
+	}
+	return resolution
+}
+
+// Resolve stores the ROUTE_DEVIATION_RESOLVED risk event that ends a route
+// deviation, referring to the deviation's risk event by its ID; the deviation
+// itself is left as it was raised. It returns nil if the deviation was never
+// stored. Run it inside the transaction that stores the event being processed.
+func Resolve(tx *gorm.DB, resolution Resolution) (*models.RiskEvent, error) {
+	if resolution.DeviationID == 0 {
+		return nil, nil
+	}
+
+	offRoute := resolution.ResolvedAt.Sub(resolution.LeftAt)
+	data, err := json.Marshal(map[string]interface{}{
+		"route_deviation_id":  resolution.DeviationID,
+		"route_id":            resolution.RouteID,
+		"route_assignment_id": resolution.AssignmentID,
+		"route_name":          resolution.RouteName,
+		"left_at":             resolution.LeftAt,
+		"resolution":          resolution.Reason,
+		"off_route_seconds":   offRoute.Seconds(),
+		"distance_meters":     math.Round(resolution.MaxDistanceMeters),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Off planned route %s for %.0f minutes, up to %.0f m from it; ",
+		resolution.RouteName, offRoute.Minutes(), resolution.MaxDistanceMeters)
+	switch resolution.Reason {
+	case ResolvedRejoined:
+		description += "rejoined the route"
+	case ResolvedRouteEnded:
+		description += "the route assignment ended"
+	default:
+		description += "stopped reporting its position"
+	}
+
+	event := &models.RiskEvent{
+		VehicleID:   resolution.VehicleID,
+		DriverID:    resolution.DriverID,
+		EventType:   EventRouteDeviationResolved,
+		Severity:    "low",
+		Timestamp:   resolution.ResolvedAt,
+		Latitude:    resolution.Latitude,
+		Longitude:   resolution.Longitude,
+		Description: description,
+		Data:        string(data),
+		Status:      "resolved",
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// assignmentFingerprint changes whenever an assignment or its route is added,
+// removed or updated
+func assignmentFingerprint(assignments []models.RouteAssignment) string {
+	var b strings.Builder
+	for _, a := range assignments {
+		fmt.Fprintf(&b, "%d:%d:%d;", a.ID, a.UpdatedAt.UnixNano(), a.Route.UpdatedAt.UnixNano())
+	}
+	return b.String()
+}
@@ -0,0 +1,244 @@
+package route
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+func setupDB(t *testing.T) (*gorm.DB, models.Fleet) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	return db, fleet
+}
+
+// plannedRoute runs east along latitude 41 for about 8 km with a 200 m
+// corridor and a 2 minute deviation time
+func plannedRoute(fleetID uint) models.PlannedRoute {
+	path, _ := json.Marshal([]geo.Point{{Latitude: 41, Longitude: -87}, {Latitude: 41, Longitude: -86.9}})
+	return models.PlannedRoute{FleetID: fleetID, Name: "Morning run", Path: string(path),
+		CorridorWidthMeters: 200, DeviationSeconds: 120}
+}
+
+// newMonitor assigns the route to vehicle 1 for the first hour after start
+func newMonitor(t *testing.T, db *gorm.DB, r models.PlannedRoute) (*Monitor, models.RouteAssignment) {
+	require.NoError(t, db.Omit("Fleet").Create(&r).Error)
+	assignment := models.RouteAssignment{RouteID: r.ID, VehicleID: 1, StartTime: start, EndTime: start.Add(time.Hour)}
+	require.NoError(t, db.Omit("Route", "Vehicle").Create(&assignment).Error)
+
+	monitor := NewMonitor(db, time.Hour)
+	_, err := monitor.Refresh(t.Context(), start)
+	require.NoError(t, err)
+	return monitor, assignment
+}
+
+// at is a sample of vehicle 1 seconds after start, lat thousandths of a degree
+// north of the route (about 111 m each)
+func at(seconds int, lat, lng float64) *models.TelemetryEvent {
+	latitude, longitude := 41+lat/1000, lng
+	return &models.TelemetryEvent{VehicleID: 1, Timestamp: start.Add(time.Duration(seconds) * time.Second),
+		Latitude: &latitude, Longitude: &longitude}
+}
+
+func observeAll(m *Monitor, samples ...*models.TelemetryEvent) Result {
+	var all Result
+	for _, sample := range samples {
+		result := m.Observe(sample)
+		all.Risks = append(all.Risks, result.Risks...)
+		all.Resolutions = append(all.Resolutions, result.Resolutions...)
+	}
+	return all
+}
+
+func TestMonitorRaisesDeviationAfterDeviationTime(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor, assignment := newMonitor(t, db, plannedRoute(fleet.ID))
+	assert.Equal(t, 1, monitor.Assignments())
+
+	result := observeAll(monitor,
+		at(0, 0, -86.99),
+		at(30, 0.5, -86.98), // about 55 m off the line, inside the corridor
+		at(60, 3, -86.97),
+		at(120, 4, -86.97),
+	)
+	assert.Empty(t, result.Risks, "not off route for long enough yet")
+
+	result = observeAll(monitor, at(180, 3, -86.96), at(240, 5, -86.95))
+	require.Len(t, result.Risks, 1)
+	risk := result.Risks[0]
+	assert.Equal(t, EventRouteDeviation, risk.EventType)
+	assert.Equal(t, "medium", risk.Severity)
+	assert.Equal(t, start.Add(60*time.Second), risk.Timestamp, "stamped with when the vehicle left")
+	assert.InDelta(t, 41.003, *risk.Latitude, 1e-9)
+	assert.Contains(t, risk.Description, "Morning run")
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(risk.Data), &data))
+	assert.Equal(t, float64(assignment.ID), data["route_assignment_id"])
+	assert.InDelta(t, 445, data["distance_meters"], 5)
+}
+
+func TestMonitorResolvesDeviationOnRejoin(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor, _ := newMonitor(t, db, plannedRoute(fleet.ID))
+
+	result := observeAll(monitor, at(0, 3, -86.99), at(150, 3, -86.98))
+	require.Len(t, result.Risks, 1)
+	risk := result.Risks[0]
+	require.NoError(t, db.Create(&risk).Error)
+	monitor.Raised([]models.RiskEvent{risk})
+
+	result = observeAll(monitor, at(200, 2, -86.97), at(300, 0, -86.96), at(400, 3, -86.95))
+	assert.Empty(t, result.Risks, "a new deviation starts the clock again")
+	require.Len(t, result.Resolutions, 1)
+	resolution := result.Resolutions[0]
+	assert.Equal(t, ResolvedRejoined, resolution.Reason)
+	assert.Equal(t, risk.ID, resolution.DeviationID)
+	assert.Equal(t, start, resolution.LeftAt)
+	assert.Equal(t, start.Add(300*time.Second), resolution.ResolvedAt)
+
+	resolved, err := Resolve(db, resolution)
+	require.NoError(t, err)
+	require.NotNil(t, resolved)
+	assert.NotEqual(t, risk.ID, resolved.ID)
+	assert.Equal(t, EventRouteDeviationResolved, resolved.EventType)
+	assert.Equal(t, resolution.ResolvedAt, resolved.Timestamp)
+	assert.Contains(t, resolved.Description, "rejoined the route")
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resolved.Data), &data))
+	assert.Equal(t, float64(risk.ID), data["route_deviation_id"])
+	assert.Equal(t, float64(300), data["off_route_seconds"])
+	assert.Equal(t, ResolvedRejoined, data["resolution"])
+
+	var stored models.RiskEvent
+	require.NoError(t, db.First(&stored, risk.ID).Error)
+	assert.Equal(t, risk.Description, stored.Description, "the deviation is left as it was raised")
+	assert.Equal(t, risk.Data, stored.Data)
+
+	unstored, err := Resolve(db, Resolution{VehicleID: 1, Reason: ResolvedRejoined})
+	require.NoError(t, err)
+	assert.Nil(t, unstored, "the deviation was never stored")
+}
+
+func TestMonitorRestoresUnresolvedDeviations(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor, _ := newMonitor(t, db, plannedRoute(fleet.ID))
+
+	result := observeAll(monitor, at(0, 3, -86.99), at(150, 3, -86.98))
+	require.Len(t, result.Risks, 1)
+	risk := result.Risks[0]
+	require.NoError(t, db.Create(&risk).Error)
+
+	// A restarted engine picks the deviation up again
+	restarted := NewMonitor(db, time.Hour)
+	_, err := restarted.Refresh(t.Context(), start)
+	require.NoError(t, err)
+	require.NoError(t, restarted.Restore(t.Context()))
+
+	result = observeAll(restarted, at(200, 3, -86.97))
+	assert.Empty(t, result.Risks, "the deviation was raised already")
+	result = observeAll(restarted, at(300, 0, -86.96))
+	require.Len(t, result.Resolutions, 1)
+	assert.Equal(t, risk.ID, result.Resolutions[0].DeviationID)
+	assert.Equal(t, start, result.Resolutions[0].LeftAt)
+	_, err = Resolve(db, result.Resolutions[0])
+	require.NoError(t, err)
+
+	// Resolved deviations stay closed
+	require.NoError(t, restarted.Reset(t.Context(), 1))
+	assert.Empty(t, observeAll(restarted, at(400, 0, -86.95)).Resolutions)
+}
+
+func TestMonitorFlushEndsSilentDeviations(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor, assignment := newMonitor(t, db, plannedRoute(fleet.ID))
+	require.NoError(t, db.Model(&assignment).Update("end_time", start.Add(3*time.Hour)).Error)
+	_, err := monitor.Refresh(t.Context(), start)
+	require.NoError(t, err)
+
+	result := observeAll(monitor, at(0, 3, -86.99), at(150, 3, -86.98))
+	require.Len(t, result.Risks, 1)
+
+	assert.Empty(t, monitor.Flush(start.Add(30*time.Minute)))
+	resolutions := monitor.Flush(start.Add(150*time.Second + DeviationTTL + time.Second))
+	require.Len(t, resolutions, 1)
+	assert.Equal(t, ResolvedExpired, resolutions[0].Reason)
+	assert.Equal(t, start.Add(150*time.Second), resolutions[0].ResolvedAt, "resolved at the last sample off route")
+
+	// The route ends before the vehicle is silent for long enough
+	result = observeAll(monitor, at(10000, 3, -86.99), at(10150, 3, -86.98))
+	require.Len(t, result.Risks, 1)
+	resolutions = monitor.Flush(start.Add(3*time.Hour + time.Minute))
+	require.Len(t, resolutions, 1)
+	assert.Equal(t, ResolvedRouteEnded, resolutions[0].Reason)
+	assert.Equal(t, start.Add(3*time.Hour), resolutions[0].ResolvedAt)
+	assert.Empty(t, monitor.Flush(start.Add(5*time.Hour)))
+}
+
+func TestMonitorResolvesDeviationWhenAssignmentEnds(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor, _ := newMonitor(t, db, plannedRoute(fleet.ID))
+
+	result := observeAll(monitor, at(3000, 3, -86.99), at(3300, 3, -86.98))
+	require.Len(t, result.Risks, 1)
+
+	result = observeAll(monitor, at(3700, 3, -86.97))
+	require.Len(t, result.Resolutions, 1)
+	assert.Equal(t, ResolvedRouteEnded, result.Resolutions[0].Reason)
+
+	result = observeAll(monitor, at(4000, 3, -86.96), at(5000, 3, -86.95))
+	assert.Empty(t, result.Risks, "no route assigned")
+	assert.Empty(t, result.Resolutions)
+}
+
+func TestMonitorIgnoresShortDeviationsAndOtherVehicles(t *testing.T) {
+	db, fleet := setupDB(t)
+	monitor, _ := newMonitor(t, db, plannedRoute(fleet.ID))
+
+	result := observeAll(monitor, at(0, 3, -86.99), at(60, 3, -86.98), at(90, 0, -86.97))
+	assert.Empty(t, result.Risks)
+	assert.Empty(t, result.Resolutions)
+
+	other := at(0, 10, -86.99)
+	other.VehicleID = 2
+	later := at(600, 10, -86.98)
+	later.VehicleID = 2
+	result = observeAll(monitor, other, later)
+	assert.Empty(t, result.Risks)
+}
+
+func TestValidate(t *testing.T) {
+	valid := plannedRoute(1)
+	assert.NoError(t, Validate(valid))
+
+	tests := map[string]func(r *models.PlannedRoute){
+		"one waypoint": func(r *models.PlannedRoute) { r.Path = `[{"latitude":41,"longitude":-87}]` },
+		"bad waypoint": func(r *models.PlannedRoute) {
+			r.Path = `[{"latitude":41,"longitude":-87},{"latitude":95,"longitude":0}]`
+		},
+		"not json":        func(r *models.PlannedRoute) { r.Path = "41,-87 41,-86" },
+		"narrow corridor": func(r *models.PlannedRoute) { r.CorridorWidthMeters = 5 },
+		"wide corridor":   func(r *models.PlannedRoute) { r.CorridorWidthMeters = MaxCorridorWidthMeters + 1 },
+		"no deviation":    func(r *models.PlannedRoute) { r.DeviationSeconds = 0 },
+		"long deviation":  func(r *models.PlannedRoute) { r.DeviationSeconds = MaxDeviationSeconds + 1 },
+	}
+	for name, mutate := range tests {
+		r := valid
+		mutate(&r)
+		assert.Error(t, Validate(r), name)
+	}
+}
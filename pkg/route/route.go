@@ -0,0 +1,95 @@
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Risk event types raised for vehicles that leave their planned route, and
+// once they are back on it or their route ends
+const (
+	EventRouteDeviation         = "route_deviation"
+	EventRouteDeviationResolved = "route_deviation_resolved"
+)
+
+// Limits on planned routes
+const (
+	MaxWaypoints           = 5000
+	MinCorridorWidthMeters = 10
+	MaxCorridorWidthMeters = 5000
+	MaxDeviationSeconds    = 24 * 60 * 60
+)
+
+// ParsePath decodes and validates the stored waypoints of a route
+func ParsePath(path string) ([]geo.Point, error) {
+	var waypoints []geo.Point
+	if err := json.Unmarshal([]byte(path), &waypoints); err != nil {
+		return nil, errors.New("path must be a JSON array of points")
+	}
+	if len(waypoints) < 2 {
+		return nil, errors.New("a route needs at least 2 waypoints")
+	}
+	if len(waypoints) > MaxWaypoints {
+		return nil, fmt.Errorf("a route may have at most %d waypoints", MaxWaypoints)
+	}
+	for i, waypoint := range waypoints {
+		if !waypoint.Valid() {
+			return nil, fmt.Errorf("waypoint %d: latitude must be between -90 and 90 and longitude between -180 and 180", i)
+		}
+	}
+	return waypoints, nil
+}
+
+// Validate checks a planned route the way the Monitor will load it
+func Validate(r models.PlannedRoute) error {
+	if _, err := ParsePath(r.Path); err != nil {
+		return err
+	}
+	if r.CorridorWidthMeters < MinCorridorWidthMeters || r.CorridorWidthMeters > MaxCorridorWidthMeters {
+		return fmt.Errorf("corridor width must be between %d and %d meters", MinCorridorWidthMeters, MaxCorridorWidthMeters)
+	}
+	if r.DeviationSeconds <= 0 || r.DeviationSeconds > MaxDeviationSeconds {
+		return fmt.Errorf("deviation time must be between 1 and %d seconds", MaxDeviationSeconds)
+	}
+	return nil
+}
+
+// plan is a route assignment prepared for checking positions against
+type plan struct {
+	assignmentID uint
+	routeID      uint
+	routeName    string
+	path         []geo.Point
+	halfWidth    float64 // meters
+	deviation    time.Duration
+	start, end   time.Time
+}
+
+func compile(assignment models.RouteAssignment) (*plan, error) {
+	if err := Validate(assignment.Route); err != nil {
+		return nil, err
+	}
+	if !assignment.EndTime.After(assignment.StartTime) {
+		return nil, errors.New("assignment ends before it starts")
+	}
+	path, _ := ParsePath(assignment.Route.Path)
+	return &plan{
+		assignmentID: assignment.ID,
+		routeID:      assignment.RouteID,
+		routeName:    assignment.Route.Name,
+		path:         path,
+		halfWidth:    assignment.Route.CorridorWidthMeters / 2,
+		deviation:    time.Duration(assignment.Route.DeviationSeconds) * time.Second,
+		start:        assignment.StartTime,
+		end:          assignment.EndTime,
+	}, nil
+}
+
+func (p *plan) covers(at time.Time) bool {
+	return !at.Before(p.start) && at.Before(p.end)
+}
@@ -0,0 +1,114 @@
+package scoring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Config is the driver safety scoring model. Each risk event weighs its
+// severity's weight, scaled by its RiskScore and halved every HalfLifeDays
+// before the end of the scoring window; the safety score loses
+// PenaltyPer100Miles for every unit of weight per 100 miles driven.
+type Config struct {
+	// SeverityWeights weighs each severity; severities not listed weigh 1
+	SeverityWeights map[string]float64 `json:"severity_weights"`
+
+	// RiskScoreWeight is how far an event's RiskScore moves its weight: from
+	// 1-RiskScoreWeight at 0 through 1 at 50 to 1+RiskScoreWeight at 100
+	RiskScoreWeight float64 `json:"risk_score_weight"`
+
+	// HalfLifeDays is the age at which an event weighs half as much as a new
+	// one; 0 turns decay off
+	HalfLifeDays float64 `json:"half_life_days"`
+
+	PenaltyPer100Miles float64 `json:"penalty_per_100_miles"`
+
+	// MinExposureMiles is the least mileage a driver is scored on, so that a
+	// single event in a quiet period does not wipe out the safety score
+	MinExposureMiles float64 `json:"min_exposure_miles"`
+}
+
+// DefaultConfig is the scoring model the risk engine runs without a scoring
+// file: a medium event from today costs SafetyPenaltyPer100Miles per 100 miles
+func DefaultConfig() Config {
+	return Config{
+		SeverityWeights: map[string]float64{
+			"low":      0.5,
+			"medium":   1,
+			"high":     2,
+			"critical": 4,
+		},
+		RiskScoreWeight:    0.5,
+		HalfLifeDays:       14,
+		PenaltyPer100Miles: SafetyPenaltyPer100Miles,
+		MinExposureMiles:   MinExposureMiles,
+	}
+}
+
+// LoadConfig reads a scoring model from a JSON file. Settings left out keep
+// their defaults, and so do the weights of severities left out.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read driver scoring model: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse driver scoring model %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid driver scoring model %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// BaseConfig is the scoring model in DRIVER_SCORING_FILE, or the default one
+// without it
+func BaseConfig() (Config, error) {
+	if path := os.Getenv("DRIVER_SCORING_FILE"); path != "" {
+		return LoadConfig(path)
+	}
+	return DefaultConfig(), nil
+}
+
+// Validate checks that the model scores every history
+func (c Config) Validate() error {
+	for severity, weight := range c.SeverityWeights {
+		if weight < 0 {
+			return fmt.Errorf("the weight of %s events must not be negative", severity)
+		}
+	}
+	if c.RiskScoreWeight < 0 || c.RiskScoreWeight > 1 {
+		return errors.New("risk_score_weight must be between 0 and 1")
+	}
+	if c.HalfLifeDays < 0 {
+		return errors.New("half_life_days must not be negative")
+	}
+	if c.PenaltyPer100Miles < 0 {
+		return errors.New("penalty_per_100_miles must not be negative")
+	}
+	if c.MinExposureMiles <= 0 {
+		return errors.New("min_exposure_miles must be positive")
+	}
+	return nil
+}
+
+// Weight is how much the event counts against a driver scored at at
+func (c Config) Weight(event Event, at time.Time) float64 {
+	weight, ok := c.SeverityWeights[event.Severity]
+	if !ok {
+		weight = 1
+	}
+
+	weight *= 1 + c.RiskScoreWeight*(math.Max(0, math.Min(100, event.RiskScore))-50)/50
+
+	if age := at.Sub(event.Timestamp).Hours() / 24; c.HalfLifeDays > 0 && age > 0 {
+		weight *= math.Pow(0.5, age/c.HalfLifeDays)
+	}
+	return weight
+}
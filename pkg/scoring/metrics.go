@@ -0,0 +1,56 @@
+package scoring
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/route"
+)
+
+// DriverMetrics aggregates the driver's trips and risk events in [from, to).
+// The events resolving route deviations only mark their end and are left out.
+func DriverMetrics(ctx context.Context, db *gorm.DB, driverID uint, from, to time.Time) (Metrics, error) {
+	var m Metrics
+	db = db.WithContext(ctx)
+
+	err := db.Model(&models.Trip{}).
+		Select(`COALESCE(SUM(distance_miles), 0) AS miles,
+			COUNT(*) AS trips,
+			COALESCE(SUM(duration_seconds), 0) AS driving_seconds,
+			COALESCE(SUM(idle_seconds), 0) AS idle_seconds,
+			COALESCE(SUM(fuel_used), 0) AS fuel_used,
+			COALESCE(SUM(CASE WHEN fuel_used IS NOT NULL THEN distance_miles ELSE 0 END), 0) AS fuel_miles`).
+		Where("driver_id = ? AND start_time >= ? AND start_time < ?", driverID, from, to).
+		Scan(&m).Error
+	if err != nil {
+		return m, err
+	}
+
+	err = db.Model(&models.RiskEvent{}).
+		Select("event_type, severity, risk_score, timestamp").
+		Where("driver_id = ? AND timestamp >= ? AND timestamp < ?", driverID, from, to).
+		Where("event_type <> ?", route.EventRouteDeviationResolved).
+		Order("timestamp, id").
+		Scan(&m.Events).Error
+	m.RiskEvents = len(m.Events)
+
+	return m, err
+}
+
+// FleetFuelPer100Miles is the fleet's average fuel use in [from, to), or 0 if
+// none of its trips reported fuel levels
+func FleetFuelPer100Miles(ctx context.Context, db *gorm.DB, fleetID uint, from, to time.Time) (float64, error) {
+	var totals Metrics
+	err := db.WithContext(ctx).Model(&models.Trip{}).
+		Select("COALESCE(SUM(trips.fuel_used), 0) AS fuel_used, COALESCE(SUM(trips.distance_miles), 0) AS fuel_miles").
+		Joins("JOIN vehicles ON vehicles.id = trips.vehicle_id").
+		Where("vehicles.fleet_id = ? AND trips.fuel_used IS NOT NULL AND trips.start_time >= ? AND trips.start_time < ?", fleetID, from, to).
+		Scan(&totals).Error
+	if err != nil {
+		return 0, err
+	}
+	return totals.FuelPer100Miles(), nil
+}
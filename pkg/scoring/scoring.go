@@ -0,0 +1,164 @@
+package scoring
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// MinExposureMiles is the default least mileage a driver is scored on
+	MinExposureMiles = 100.0
+
+	// SafetyPenaltyPer100Miles is the default deduction for each medium risk
+	// event per 100 miles
+	SafetyPenaltyPer100Miles = 10.0
+
+	// maxIdlePenalty and maxFuelPenalty bound the efficiency deductions
+	maxIdlePenalty = 40.0
+	maxFuelPenalty = 40.0
+)
+
+// Metrics are a driver's activity over a scoring window
+type Metrics struct {
+	Miles          float64
+	Trips          int
+	DrivingSeconds int
+	IdleSeconds    int
+	// FuelUsed is the fuel level consumed over FuelMiles, the miles of the
+	// trips that reported fuel levels
+	FuelUsed   float64
+	FuelMiles  float64
+	RiskEvents int
+	// Events are the RiskEvents, oldest first
+	Events []Event `gorm:"-"`
+}
+
+// Event is a risk event as the scoring model sees it
+type Event struct {
+	EventType string
+	Severity  string
+	RiskScore float64
+	Timestamp time.Time
+}
+
+// Deduction is the points risk events of one type and severity took off the
+// safety score
+type Deduction struct {
+	EventType string  `json:"event_type"`
+	Severity  string  `json:"severity"`
+	Events    int     `json:"events"`
+	Points    float64 `json:"points"`
+}
+
+// RiskEventsPer100Miles normalizes the event count by exposure, unweighted
+func (m Metrics) RiskEventsPer100Miles() float64 {
+	return float64(m.RiskEvents) / math.Max(m.Miles, MinExposureMiles) * 100
+}
+
+// IdleRatio is the share of driving time spent stationary with the engine on
+func (m Metrics) IdleRatio() float64 {
+	if m.DrivingSeconds == 0 {
+		return 0
+	}
+	return float64(m.IdleSeconds) / float64(m.DrivingSeconds)
+}
+
+// FuelPer100Miles is the fuel level consumed per 100 miles, or 0 without data
+func (m Metrics) FuelPer100Miles() float64 {
+	if m.FuelMiles == 0 {
+		return 0
+	}
+	return m.FuelUsed / m.FuelMiles * 100
+}
+
+// Scores are 0-100, higher is better
+type Scores struct {
+	Overall    float64
+	Safety     float64
+	Efficiency float64
+	// Breakdown explains the safety score, largest deduction first
+	Breakdown []Deduction
+}
+
+// Compute scores a driver at the end of the scoring window with the model in
+// config. fleetFuelPer100Miles is the fleet's average fuel use, the baseline
+// for fuel efficiency since tank sizes are unknown; pass 0 when the fleet has
+// no fuel data.
+func Compute(m Metrics, fleetFuelPer100Miles float64, at time.Time, config Config) Scores {
+	weights := make([]float64, len(m.Events))
+	total := 0.0
+	for i, event := range m.Events {
+		weights[i] = config.Weight(event, at)
+		total += weights[i]
+	}
+	safety := clamp(100 - total/math.Max(m.Miles, config.MinExposureMiles)*100*config.PenaltyPer100Miles)
+
+	idlePenalty := math.Min(maxIdlePenalty, m.IdleRatio()*100)
+
+	fuelPenalty := 0.0
+	if driverFuel := m.FuelPer100Miles(); driverFuel > 0 && fleetFuelPer100Miles > 0 {
+		// Half a point for every percent above the fleet average
+		excess := (driverFuel/fleetFuelPer100Miles - 1) * 100
+		fuelPenalty = math.Min(maxFuelPenalty, math.Max(0, excess/2))
+	}
+
+	efficiency := clamp(100 - idlePenalty - fuelPenalty)
+
+	return Scores{
+		Overall:    round((safety + efficiency) / 2),
+		Safety:     round(safety),
+		Efficiency: round(efficiency),
+		Breakdown:  breakdown(m.Events, weights, total, 100-safety),
+	}
+}
+
+// breakdown shares the safety deduction out between the event types and
+// severities by the weight of their events. When the score bottoms out at 0
+// each deduction is scaled down so that they still add up to the points lost.
+func breakdown(events []Event, weights []float64, total, deducted float64) []Deduction {
+	if len(events) == 0 {
+		return nil
+	}
+
+	type key struct{ eventType, severity string }
+	indexes := make(map[key]int)
+	groupWeights := make([]float64, 0)
+	deductions := make([]Deduction, 0)
+	for i, event := range events {
+		k := key{event.EventType, event.Severity}
+		j, ok := indexes[k]
+		if !ok {
+			j = len(deductions)
+			indexes[k] = j
+			deductions = append(deductions, Deduction{EventType: event.EventType, Severity: event.Severity})
+			groupWeights = append(groupWeights, 0)
+		}
+		deductions[j].Events++
+		groupWeights[j] += weights[i]
+	}
+	for j := range deductions {
+		if total > 0 {
+			deductions[j].Points = math.Round(deducted*groupWeights[j]/total*100) / 100
+		}
+	}
+
+	sort.SliceStable(deductions, func(i, j int) bool {
+		if deductions[i].Points != deductions[j].Points {
+			return deductions[i].Points > deductions[j].Points
+		}
+		if deductions[i].EventType != deductions[j].EventType {
+			return deductions[i].EventType < deductions[j].EventType
+		}
+		return deductions[i].Severity < deductions[j].Severity
+	})
+	return deductions
+}
+
+func clamp(score float64) float64 {
+	return math.Max(0, math.Min(100, score))
+}
+
+func round(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,282 @@
+package scoring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+var scoredAt = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+// event is a risk event daysAgo days before scoredAt
+func event(eventType, severity string, riskScore, daysAgo float64) Event {
+	age := time.Duration(daysAgo * 24 * float64(time.Hour))
+	return Event{EventType: eventType, Severity: severity, RiskScore: riskScore, Timestamp: scoredAt.Add(-age)}
+}
+
+// mediumEvents are n events that weigh 1 under the default model
+func mediumEvents(n int) Metrics {
+	m := Metrics{RiskEvents: n}
+	for i := 0; i < n; i++ {
+		m.Events = append(m.Events, event("harsh_braking", "medium", 50, 0))
+	}
+	return m
+}
+
+func compute(m Metrics) Scores {
+	return Compute(m, 0, scoredAt, DefaultConfig())
+}
+
+func TestSafetyIsNormalizedByMileage(t *testing.T) {
+	// The same five events over very different distances
+	local, longHaul := mediumEvents(5), mediumEvents(5)
+	local.Miles, longHaul.Miles = 200, 5000
+
+	assert.Equal(t, 75.0, compute(local).Safety)
+	assert.Equal(t, 99.0, compute(longHaul).Safety)
+
+	// Below the exposure floor a driver is scored as if they drove 100 miles
+	quiet := mediumEvents(1)
+	quiet.Miles = 3
+	assert.Equal(t, 90.0, compute(quiet).Safety)
+
+	crowded := mediumEvents(50)
+	crowded.Miles = 100
+	assert.Equal(t, 0.0, compute(crowded).Safety)
+}
+
+func TestEventWeights(t *testing.T) {
+	config := DefaultConfig()
+
+	// A critical 120 mph event against a low-severity blip
+	assert.Equal(t, 6.0, config.Weight(event("speeding", "critical", 100, 0), scoredAt))
+	assert.Equal(t, 0.3, config.Weight(event("speeding", "low", 10, 0), scoredAt))
+	assert.Equal(t, 1.0, config.Weight(event("speeding", "unknown", 50, 0), scoredAt), "unknown severities weigh 1")
+
+	// Halved every two weeks
+	assert.InDelta(t, 0.5, config.Weight(event("speeding", "medium", 50, 14), scoredAt), 1e-9)
+	assert.InDelta(t, 0.238, config.Weight(event("speeding", "medium", 50, 29), scoredAt), 0.001)
+
+	config.HalfLifeDays = 0
+	config.RiskScoreWeight = 0
+	assert.Equal(t, 4.0, config.Weight(event("speeding", "critical", 100, 29), scoredAt))
+}
+
+// TestFixtureHistories pins the default model's scores
+func TestFixtureHistories(t *testing.T) {
+	tests := []struct {
+		name   string
+		miles  float64
+		events []Event
+		safety float64
+	}{
+		{"clean", 500, nil, 100},
+		{"critical speeding today", 500, []Event{event("speeding", "critical", 100, 0)}, 88},
+		{"low-severity blip today", 500, []Event{event("speeding", "low", 10, 0)}, 99.4},
+		{"medium event today", 500, []Event{event("harsh_braking", "medium", 50, 0)}, 98},
+		{"medium event 29 days ago", 500, []Event{event("harsh_braking", "medium", 50, 29)}, 99.5},
+		{"old blips", 300, []Event{
+			event("harsh_braking", "low", 20, 28),
+			event("harsh_braking", "low", 20, 28),
+			event("harsh_braking", "low", 20, 28),
+		}, 99.1},
+		{"mixed", 800, []Event{
+			event("speeding", "high", 80, 7),
+			event("harsh_braking", "low", 50, 14),
+			event("phone_use", "medium", 50, 0),
+		}, 96.1},
+		{"reckless short haul", 50, []Event{
+			event("speeding", "critical", 95, 1),
+			event("speeding", "critical", 90, 2),
+			event("phone_use", "high", 70, 0),
+		}, 0},
+	}
+	for _, tt := range tests {
+		scores := compute(Metrics{Miles: tt.miles, RiskEvents: len(tt.events), Events: tt.events})
+		assert.Equal(t, tt.safety, scores.Safety, tt.name)
+	}
+}
+
+func TestSafetyBreakdown(t *testing.T) {
+	scores := compute(Metrics{Miles: 800, RiskEvents: 4, Events: []Event{
+		event("speeding", "high", 80, 7),
+		event("harsh_braking", "low", 50, 14),
+		event("phone_use", "medium", 50, 0),
+		event("phone_use", "medium", 50, 0),
+	}})
+	assert.Equal(t, 94.9, scores.Safety)
+	assert.Equal(t, []Deduction{
+		{EventType: "phone_use", Severity: "medium", Events: 2, Points: 2.5},
+		{EventType: "speeding", Severity: "high", Events: 1, Points: 2.3},
+		{EventType: "harsh_braking", Severity: "low", Events: 1, Points: 0.31},
+	}, scores.Breakdown)
+
+	// A score that bottoms out only loses 100 points
+	floored := mediumEvents(15)
+	floored.Miles = 100
+	for i := 0; i < 5; i++ {
+		floored.Events = append(floored.Events, event("speeding", "high", 50, 0))
+	}
+	floored.RiskEvents = len(floored.Events)
+	scores = compute(floored)
+	assert.Equal(t, 0.0, scores.Safety)
+	assert.Equal(t, 60.0, scores.Breakdown[0].Points)
+	assert.Equal(t, 40.0, scores.Breakdown[1].Points)
+
+	assert.Nil(t, compute(Metrics{Miles: 100}).Breakdown)
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"half_life_days": 7, "severity_weights": {"low": 0, "critical": 10}}`), 0o600))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, config.HalfLifeDays)
+	assert.Equal(t, map[string]float64{"low": 0, "medium": 1, "high": 2, "critical": 10}, config.SeverityWeights)
+	assert.Equal(t, DefaultConfig().PenaltyPer100Miles, config.PenaltyPer100Miles, "left out settings keep their defaults")
+
+	for _, invalid := range []string{
+		`{"risk_score_weight": 1.5}`,
+		`{"half_life_days": -1}`,
+		`{"min_exposure_miles": 0}`,
+		`{"severity_weights": {"high": -2}}`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+		_, err := LoadConfig(path)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestEfficiencyPenalties(t *testing.T) {
+	clean := compute(Metrics{Miles: 500, DrivingSeconds: 36000})
+	assert.Equal(t, Scores{Overall: 100, Safety: 100, Efficiency: 100}, clean)
+
+	// A quarter of the time idling
+	idle := compute(Metrics{Miles: 500, DrivingSeconds: 36000, IdleSeconds: 9000})
+	assert.Equal(t, 75.0, idle.Efficiency)
+	assert.Equal(t, 87.5, idle.Overall)
+
+	// 30% more fuel per mile than the fleet
+	thirsty := Compute(Metrics{Miles: 500, DrivingSeconds: 36000, FuelUsed: 26, FuelMiles: 100}, 20, scoredAt, DefaultConfig())
+	assert.Equal(t, 85.0, thirsty.Efficiency)
+
+	// Better than the fleet is not a bonus, and no fleet baseline means no penalty
+	assert.Equal(t, 100.0, Compute(Metrics{Miles: 500, FuelUsed: 10, FuelMiles: 100}, 20, scoredAt, DefaultConfig()).Efficiency)
+	assert.Equal(t, 100.0, compute(Metrics{Miles: 500, FuelUsed: 50, FuelMiles: 100}).Efficiency)
+
+	// Both penalties are capped
+	worst := Compute(Metrics{Miles: 500, DrivingSeconds: 100, IdleSeconds: 100, FuelUsed: 100, FuelMiles: 100}, 10, scoredAt, DefaultConfig())
+	assert.Equal(t, 20.0, worst.Efficiency)
+}
+
+func TestDriverMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	vehicle := models.Vehicle{FleetID: fleet.ID, VIN: "VIN1", Make: "Ford", Model: "F-150", Year: 2022}
+	require.NoError(t, db.Omit("Fleet").Create(&vehicle).Error)
+	driver := models.Driver{FleetID: fleet.ID, EmployeeID: "E1", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"}
+	require.NoError(t, db.Omit("Fleet").Create(&driver).Error)
+
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -30)
+	fuel := func(value float64) *float64 { return &value }
+
+	trips := []models.Trip{
+		{StartTime: to.AddDate(0, 0, -2), DistanceMiles: 120, DurationSeconds: 7200, IdleSeconds: 600, FuelUsed: fuel(12)},
+		{StartTime: to.AddDate(0, 0, -1), DistanceMiles: 80, DurationSeconds: 3600, IdleSeconds: 300},
+		// Outside the window
+		{StartTime: from.Add(-time.Hour), DistanceMiles: 500, DurationSeconds: 36000},
+	}
+	for i := range trips {
+		trips[i].VehicleID = vehicle.ID
+		trips[i].DriverID = &driver.ID
+		trips[i].EndTime = trips[i].StartTime.Add(time.Duration(trips[i].DurationSeconds) * time.Second)
+		require.NoError(t, db.Omit("Vehicle", "Driver").Create(&trips[i]).Error)
+	}
+	// Another driver's trip in the same fleet
+	require.NoError(t, db.Omit("Vehicle", "Driver").Create(&models.Trip{
+		VehicleID: vehicle.ID, StartTime: to.AddDate(0, 0, -3), DistanceMiles: 100, FuelUsed: fuel(8),
+	}).Error)
+
+	for _, timestamp := range []time.Time{to.AddDate(0, 0, -2), to.AddDate(0, 0, -40)} {
+		require.NoError(t, db.Omit("Vehicle", "Driver").Create(&models.RiskEvent{
+			VehicleID: vehicle.ID, DriverID: &driver.ID, EventType: "harsh_braking", Severity: "medium", Timestamp: timestamp,
+		}).Error)
+	}
+
+	metrics, err := DriverMetrics(t.Context(), db, driver.ID, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, Metrics{
+		Miles:          200,
+		Trips:          2,
+		DrivingSeconds: 10800,
+		IdleSeconds:    900,
+		FuelUsed:       12,
+		FuelMiles:      120,
+		RiskEvents:     1,
+		Events:         []Event{{EventType: "harsh_braking", Severity: "medium", Timestamp: to.AddDate(0, 0, -2)}},
+	}, metrics)
+	assert.Equal(t, 0.5, metrics.RiskEventsPer100Miles())
+
+	fleetFuel, err := FleetFuelPer100Miles(t.Context(), db, fleet.ID, from, to)
+	require.NoError(t, err)
+	assert.InDelta(t, 20.0/220*100, fleetFuel, 0.001)
+}
+
+func TestThinSnapshots(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	var drivers []uint
+	for _, employeeID := range []string{"E1", "E2"} {
+		driver := models.Driver{FleetID: fleet.ID, EmployeeID: employeeID, FirstName: "Ada", LastName: "Lovelace", Email: employeeID + "@example.com"}
+		require.NoError(t, db.Omit("Fleet").Create(&driver).Error)
+		drivers = append(drivers, driver.ID)
+	}
+
+	// Both drivers are scored every 10 minutes from 09:00 to 11:50
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	for run := range 18 {
+		for _, driverID := range drivers {
+			require.NoError(t, db.Omit("Driver").Create(&models.DriverScoreSnapshot{
+				DriverID: driverID, ScoredAt: start.Add(time.Duration(run) * 10 * time.Minute),
+			}).Error)
+		}
+	}
+
+	// The hour being scored when the window ends is left alone
+	deleted, err := ThinSnapshots(t.Context(), db, start, start.Add(2*time.Hour+30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), deleted)
+
+	for _, driverID := range drivers {
+		var scoredAt []time.Time
+		require.NoError(t, db.Model(&models.DriverScoreSnapshot{}).
+			Where("driver_id = ?", driverID).Order("scored_at").Pluck("scored_at", &scoredAt).Error)
+		require.Len(t, scoredAt, 8)
+		assert.True(t, scoredAt[0].Equal(start.Add(50*time.Minute)))
+		assert.True(t, scoredAt[1].Equal(start.Add(110*time.Minute)))
+		assert.True(t, scoredAt[2].Equal(start.Add(2*time.Hour)))
+	}
+
+	// Thinning again finds nothing left to delete
+	deleted, err = ThinSnapshots(t.Context(), db, start, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
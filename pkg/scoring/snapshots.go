@@ -0,0 +1,54 @@
+package scoring
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// SnapshotRetention is how long every driver score snapshot is kept; older
+// ones are thinned out to the last of each hour
+const SnapshotRetention = 31 * 24 * time.Hour
+
+// thinBatch bounds the snapshots deleted by one statement
+const thinBatch = 500
+
+// ThinSnapshots deletes the driver score snapshots scored in [from, to) that
+// are not the last of their driver's hour, and returns how many it deleted.
+// from and to are truncated to the hour, so no hour is thinned in part.
+func ThinSnapshots(ctx context.Context, db *gorm.DB, from, to time.Time) (int64, error) {
+	db = db.WithContext(ctx)
+	from, to = from.Truncate(time.Hour), to.Truncate(time.Hour)
+
+	var snapshots []models.DriverScoreSnapshot
+	if err := db.Select("id", "driver_id", "scored_at").
+		Where("scored_at >= ? AND scored_at < ?", from, to).
+		Order("driver_id, scored_at, id").
+		Find(&snapshots).Error; err != nil {
+		return 0, err
+	}
+
+	stale := make([]uint, 0)
+	for i, snapshot := range snapshots {
+		if i+1 < len(snapshots) {
+			next := snapshots[i+1]
+			if next.DriverID == snapshot.DriverID && next.ScoredAt.Truncate(time.Hour).Equal(snapshot.ScoredAt.Truncate(time.Hour)) {
+				stale = append(stale, snapshot.ID)
+			}
+		}
+	}
+
+	var deleted int64
+	for start := 0; start < len(stale); start += thinBatch {
+		end := min(start+thinBatch, len(stale))
+		result := db.Where("id IN ?", stale[start:end]).Delete(&models.DriverScoreSnapshot{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
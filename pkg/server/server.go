@@ -13,6 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/auth"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/config"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/database"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
@@ -56,6 +57,11 @@ func NewBaseServer(serviceName string) (*BaseServer, error) {
 		return nil, err
 	}
 
+	// Restrict queries made on behalf of authenticated users to their fleets
+	if err := auth.RegisterTenantScope(db); err != nil {
+		return nil, err
+	}
+
 	// Setup Gin router with common middleware
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery())
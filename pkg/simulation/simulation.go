@@ -0,0 +1,145 @@
+package simulation
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/assignment"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+)
+
+// batchSize is how many telemetry events are read at a time
+const batchSize = 5000
+
+// Counts are what one rule configuration reported over the window
+type Counts struct {
+	RiskEvents  int
+	Alerts      int
+	ByEventType map[string]int
+	BySeverity  map[string]int
+	// ByDriver is keyed by driver ID, with 0 counting events on vehicles
+	// nobody was assigned to
+	ByDriver map[uint]int
+}
+
+func newCounts() Counts {
+	return Counts{
+		ByEventType: make(map[string]int),
+		BySeverity:  make(map[string]int),
+		ByDriver:    make(map[uint]int),
+	}
+}
+
+func (c *Counts) add(event models.RiskEvent) {
+	c.RiskEvents++
+	if risk.RaisesAlert(event.Severity) {
+		c.Alerts++
+	}
+	c.ByEventType[event.EventType]++
+	c.BySeverity[event.Severity]++
+	driverID := uint(0)
+	if event.DriverID != nil {
+		driverID = *event.DriverID
+	}
+	c.ByDriver[driverID]++
+}
+
+// Result compares the current configuration with a candidate
+type Result struct {
+	FleetID         uint
+	From            time.Time
+	To              time.Time
+	TelemetryEvents int
+	Current         Counts
+	Candidate       Counts
+}
+
+// Run replays the fleet's stored telemetry in [from, to) through the current
+// and candidate policy stores, which should both be fresh, and counts what
+// each would have reported. Nothing is written.
+func Run(ctx context.Context, db *gorm.DB, current, candidate *risk.PolicyStore, fleetID uint, from, to time.Time) (*Result, error) {
+	db = db.WithContext(ctx)
+	result := &Result{
+		FleetID:   fleetID,
+		From:      from,
+		To:        to,
+		Current:   newCounts(),
+		Candidate: newCounts(),
+	}
+
+	var vehicles []models.Vehicle
+	if err := db.Select("id", "vehicle_class").Where("fleet_id = ?", fleetID).Find(&vehicles).Error; err != nil {
+		return nil, err
+	}
+
+	drivers := assignment.NewResolver(db, time.Hour)
+
+	// Episodes still open at the end of a vehicle's stream are closed with it
+	flushAt := to.Add(24 * time.Hour)
+
+	for _, vehicle := range vehicles {
+		var cursor *models.TelemetryEvent
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			query := db.Where("vehicle_id = ? AND timestamp >= ? AND timestamp < ?", vehicle.ID, from, to)
+			if cursor != nil {
+				query = query.Where("timestamp > ? OR (timestamp = ? AND id > ?)", cursor.Timestamp, cursor.Timestamp, cursor.ID)
+			}
+			var events []models.TelemetryEvent
+			if err := query.Order("timestamp ASC, id ASC").Limit(batchSize).Find(&events).Error; err != nil {
+				return nil, err
+			}
+
+			for i := range events {
+				event := &events[i]
+				driverID, err := drivers.DriverAt(ctx, event.VehicleID, event.Timestamp)
+				if err != nil {
+					return nil, err
+				}
+				event.DriverID = driverID
+				if err := count(ctx, drivers, &result.Current, current.AnalyzerFor(fleetID, vehicle.VehicleClass).Analyze(event)); err != nil {
+					return nil, err
+				}
+				if err := count(ctx, drivers, &result.Candidate, candidate.AnalyzerFor(fleetID, vehicle.VehicleClass).Analyze(event)); err != nil {
+					return nil, err
+				}
+			}
+			result.TelemetryEvents += len(events)
+
+			if len(events) < batchSize {
+				break
+			}
+			cursor = &events[len(events)-1]
+		}
+
+		if err := count(ctx, drivers, &result.Current, current.Flush(flushAt)); err != nil {
+			return nil, err
+		}
+		if err := count(ctx, drivers, &result.Candidate, candidate.Flush(flushAt)); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// count attributes the risk events to drivers and adds them to counts
+func count(ctx context.Context, drivers *assignment.Resolver, counts *Counts, risks []models.RiskEvent) error {
+	for _, event := range risks {
+		if event.DriverID == nil {
+			driverID, err := drivers.DriverAt(ctx, event.VehicleID, event.Timestamp)
+			if err != nil {
+				return err
+			}
+			event.DriverID = driverID
+		}
+		counts.add(event)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/assignment"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+)
+
+func TestSimulationComparesCandidateWithCurrentRules(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	fleet := models.Fleet{Name: "Fleet", CompanyName: "Co"}
+	require.NoError(t, db.Create(&fleet).Error)
+	vehicle := models.Vehicle{FleetID: fleet.ID, VIN: "VIN1", Make: "Ford", Model: "Transit", Year: 2022}
+	require.NoError(t, db.Omit("Fleet").Create(&vehicle).Error)
+	driver := models.Driver{FleetID: fleet.ID, EmployeeID: "E1", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"}
+	require.NoError(t, db.Omit("Fleet").Create(&driver).Error)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, assignment.Assign(db, vehicle.ID, driver.ID, start.Add(-time.Hour)))
+
+	// A minute at 95 mph, then a hard stop at -7 m/s²
+	var events []models.TelemetryEvent
+	for second := 0; second <= 60; second++ {
+		speed := 95.0
+		if second == 60 {
+			speed = 40
+		}
+		events = append(events, models.TelemetryEvent{VehicleID: vehicle.ID, EventType: "speed", Timestamp: start.Add(time.Duration(second) * time.Second), Speed: &speed, Data: "{}"})
+	}
+	braking := -7.0
+	events = append(events, models.TelemetryEvent{VehicleID: vehicle.ID, EventType: "acceleration", Timestamp: start.Add(2 * time.Minute), Acceleration: &braking, Data: "{}"})
+	require.NoError(t, db.Omit("Vehicle").Create(&events).Error)
+
+	current, err := risk.NewPolicyStore(db, risk.DefaultConfig())
+	require.NoError(t, err)
+	_, err = current.Refresh(t.Context())
+	require.NoError(t, err)
+
+	rules, err := risk.ParsePolicyRules(`{"rules": [
+		{"name": "speeding", "params": {"threshold": 70}},
+		{"name": "harsh_braking", "params": {"threshold": -8}}
+	]}`)
+	require.NoError(t, err)
+	candidate, err := current.Candidate(t.Context(), fleet.ID, rules)
+	require.NoError(t, err)
+
+	result, err := Run(t.Context(), db, current, candidate, fleet.ID, start.Add(-time.Hour), start.Add(time.Hour))
+	require.NoError(t, err)
+
+	assert.Equal(t, 62, result.TelemetryEvents)
+
+	assert.Equal(t, 2, result.Current.RiskEvents)
+	assert.Equal(t, 0, result.Current.Alerts)
+	assert.Equal(t, map[string]int{risk.RuleSpeeding: 1, risk.RuleHarshBraking: 1}, result.Current.ByEventType)
+	assert.Equal(t, map[string]int{"medium": 2}, result.Current.BySeverity)
+	assert.Equal(t, map[uint]int{driver.ID: 2}, result.Current.ByDriver)
+
+	// 95 mph is more than 30% over the lower threshold, and the stop is
+	// within the stricter braking threshold
+	assert.Equal(t, 1, result.Candidate.RiskEvents)
+	assert.Equal(t, 1, result.Candidate.Alerts)
+	assert.Equal(t, map[string]int{risk.RuleSpeeding: 1}, result.Candidate.ByEventType)
+	assert.Equal(t, map[string]int{"high": 1}, result.Candidate.BySeverity)
+
+	// Nothing was written
+	var stored int64
+	require.NoError(t, db.Model(&models.RiskEvent{}).Count(&stored).Error)
+	assert.Zero(t, stored)
+	require.NoError(t, db.Model(&models.TelemetryEvent{}).Where("processed_at IS NOT NULL").Count(&stored).Error)
+	assert.Zero(t, stored)
+}
+
+func TestCandidateOnlyChangesItsFleet(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+
+	current, err := risk.NewPolicyStore(db, risk.DefaultConfig())
+	require.NoError(t, err)
+
+	rules, err := risk.ParsePolicyRules(`{"rules": [{"name": "speeding", "enabled": false}]}`)
+	require.NoError(t, err)
+	candidate, err := current.Candidate(t.Context(), 1, rules)
+	require.NoError(t, err)
+
+	assert.Len(t, candidate.AnalyzerFor(1, "").Detectors(), len(risk.DefaultConfig().Rules)-1)
+	assert.Len(t, candidate.AnalyzerFor(2, "").Detectors(), len(risk.DefaultConfig().Rules))
+
+	_, err = current.Candidate(t.Context(), 1, risk.Config{Rules: []risk.RuleConfig{{Name: "teleporting"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+package trip
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// Reasons a trip ended
+const (
+	EndIgnitionOff = "ignition_off"
+	EndStationary  = "stationary"
+	EndGap         = "gap"
+)
+
+// Config tunes how telemetry is cut into trips
+type Config struct {
+	// StationarySpeed is the speed (mph) at or below which a vehicle counts as stopped
+	StationarySpeed float64
+	// StationaryTimeout ends a trip once the vehicle has been stopped this long
+	StationaryTimeout time.Duration
+	// MaxGap ends a trip when the vehicle stops reporting for this long
+	MaxGap time.Duration
+	// MinDistanceMiles drops trips that barely moved, such as idling in a yard
+	MinDistanceMiles float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		StationarySpeed:   3.0,
+		StationaryTimeout: 5 * time.Minute,
+		MaxGap:            10 * time.Minute,
+		MinDistanceMiles:  0.1,
+	}
+}
+
+// Segmenter builds trips from per-vehicle telemetry streams. Events must be
+// observed in timestamp order per vehicle; late events are ignored. A trip
+// opens when the vehicle starts moving or its ignition turns on, and ends when
+// the ignition turns off, the vehicle stays stationary for StationaryTimeout,
+// or it stops reporting for MaxGap.
+type Segmenter struct {
+	config Config
+
+	mu       sync.Mutex
+	vehicles map[uint]*vehicleState
+}
+
+type vehicleState struct {
+	trip     *models.Trip
+	ignition *bool
+
+	last          time.Time
+	lastLatitude  *float64
+	lastLongitude *float64
+	lastMoving    bool
+	lastFuel      *float64
+
+	// Where and when the vehicle came to a stop, while it is stopped
+	stoppedAt        *time.Time
+	stoppedLatitude  *float64
+	stoppedLongitude *float64
+
+	// Idle time of the trip so far, and of the current stop. The current stop
+	// only counts once the vehicle moves on or is switched off, since a trip
+	// ended by the stationary timeout ends where the stop began.
+	idle        time.Duration
+	idlePending time.Duration
+	fuelUsed    *float64
+}
+
+func NewSegmenter(config Config) *Segmenter {
+	return &Segmenter{
+		config:   config,
+		vehicles: make(map[uint]*vehicleState),
+	}
+}
+
+// Observe feeds one event to the segmenter and returns the trips it completed
+func (s *Segmenter) Observe(event *models.TelemetryEvent) []models.Trip {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.vehicles[event.VehicleID]
+	if !ok {
+		state = &vehicleState{}
+		s.vehicles[event.VehicleID] = state
+	} else if event.Timestamp.Before(state.last) {
+		return nil
+	}
+
+	var completed []models.Trip
+	if state.trip != nil && event.Timestamp.Sub(state.last) > s.config.MaxGap {
+		completed = s.finishSilent(state, completed)
+	}
+
+	data := readData(event)
+	ignition, known := data.ignitionState()
+	turnedOn := known && ignition && (state.ignition == nil || !*state.ignition)
+	if known {
+		state.ignition = &ignition
+	}
+	moving := event.Speed != nil && *event.Speed > s.config.StationarySpeed
+
+	if state.trip == nil && (moving || turnedOn) {
+		state.trip = &models.Trip{
+			VehicleID:      event.VehicleID,
+			StartTime:      event.Timestamp,
+			StartLatitude:  event.Latitude,
+			StartLongitude: event.Longitude,
+		}
+		state.stoppedAt = nil
+		state.lastLatitude, state.lastLongitude = nil, nil
+		state.lastFuel, state.fuelUsed = nil, nil
+		state.idle, state.idlePending = 0, 0
+	}
+
+	if state.trip != nil {
+		s.extend(state, event, data, moving)
+
+		switch {
+		case known && !ignition:
+			completed = s.finish(state, event.Timestamp, state.lastLatitude, state.lastLongitude, EndIgnitionOff, completed)
+		case state.stoppedAt != nil && event.Timestamp.Sub(*state.stoppedAt) >= s.config.StationaryTimeout:
+			completed = s.finish(state, *state.stoppedAt, state.stoppedLatitude, state.stoppedLongitude, EndStationary, completed)
+		}
+	}
+
+	state.last = event.Timestamp
+	state.lastMoving = moving
+	return completed
+}
+
+// Flush ends the trips of vehicles that have not reported within MaxGap of now
+func (s *Segmenter) Flush(now time.Time) []models.Trip {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var completed []models.Trip
+	for _, state := range s.vehicles {
+		if state.trip == nil || now.Sub(state.last) <= s.config.MaxGap {
+			continue
+		}
+		completed = s.finishSilent(state, completed)
+	}
+	return completed
+}
+
+// Forget drops the vehicle's trip in progress without completing it
+func (s *Segmenter) Forget(vehicleID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.vehicles, vehicleID)
+}
+
+// OpenTrips returns the number of trips in progress
+func (s *Segmenter) OpenTrips() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open := 0
+	for _, state := range s.vehicles {
+		if state.trip != nil {
+			open++
+		}
+	}
+	return open
+}
+
+// extend adds the event to the open trip
+func (s *Segmenter) extend(state *vehicleState, event *models.TelemetryEvent, data telemetryData, moving bool) {
+	trip := state.trip
+
+	// Stationary since the previous sample with the engine running
+	engineOn := state.ignition == nil || *state.ignition
+	if !moving && !state.lastMoving && state.stoppedAt != nil && engineOn {
+		state.idlePending += event.Timestamp.Sub(state.last)
+	}
+
+	// Fuel burned is the sum of level drops; rises are refuelling
+	if data.FuelLevel != nil {
+		if state.lastFuel != nil {
+			used := 0.0
+			if state.fuelUsed != nil {
+				used = *state.fuelUsed
+			}
+			used += math.Max(0, *state.lastFuel-*data.FuelLevel)
+			state.fuelUsed = &used
+		}
+		state.lastFuel = data.FuelLevel
+	}
+
+	if event.Speed != nil && *event.Speed > trip.MaxSpeed {
+		trip.MaxSpeed = *event.Speed
+	}
+
+	if event.Latitude != nil && event.Longitude != nil {
+		// GPS drift while parked is not distance driven
+		if state.lastLatitude != nil && state.lastLongitude != nil && (moving || state.lastMoving) {
+			trip.DistanceMiles += geo.DistanceMiles(*state.lastLatitude, *state.lastLongitude, *event.Latitude, *event.Longitude)
+		}
+		state.lastLatitude, state.lastLongitude = event.Latitude, event.Longitude
+	}
+
+	if moving {
+		state.stoppedAt = nil
+		state.idle += state.idlePending
+		state.idlePending = 0
+	} else if event.Speed != nil && state.stoppedAt == nil {
+		stoppedAt := event.Timestamp
+		state.stoppedAt = &stoppedAt
+		state.stoppedLatitude, state.stoppedLongitude = state.lastLatitude, state.lastLongitude
+	}
+}
+
+// finishSilent closes the trip of a vehicle that stopped reporting. If it was
+// stopped at the time, the trip ended where it stopped.
+func (s *Segmenter) finishSilent(state *vehicleState, completed []models.Trip) []models.Trip {
+	if state.stoppedAt != nil {
+		return s.finish(state, *state.stoppedAt, state.stoppedLatitude, state.stoppedLongitude, EndStationary, completed)
+	}
+	return s.finish(state, state.last, state.lastLatitude, state.lastLongitude, EndGap, completed)
+}
+
+// finish closes the open trip, keeping it if the vehicle actually went somewhere
+func (s *Segmenter) finish(state *vehicleState, end time.Time, latitude, longitude *float64, reason string, completed []models.Trip) []models.Trip {
+	trip := state.trip
+	state.trip = nil
+	state.stoppedAt = nil
+
+	if trip.DistanceMiles < s.config.MinDistanceMiles {
+		return completed
+	}
+
+	if reason != EndStationary {
+		state.idle += state.idlePending
+	}
+	trip.IdleSeconds = int(state.idle.Seconds())
+	trip.FuelUsed = state.fuelUsed
+
+	trip.EndTime = end
+	trip.EndLatitude = latitude
+	trip.EndLongitude = longitude
+	trip.EndReason = reason
+	trip.DurationSeconds = int(end.Sub(trip.StartTime).Seconds())
+	trip.DistanceMiles = math.Round(trip.DistanceMiles*100) / 100
+	return append(completed, *trip)
+}
+
+// IgnitionState reads the ignition from an event, either from an
+// ignition_on/ignition_off event type or from the ignition or engine_status
+// fields of its Data. known is false when the event does not say.
+func IgnitionState(event *models.TelemetryEvent) (on bool, known bool) {
+	return readData(event).ignitionState()
+}
+
+// telemetryData holds the trip-related fields of an event's Data JSON
+type telemetryData struct {
+	eventType    string
+	Ignition     *bool    `json:"ignition"`
+	EngineStatus *string  `json:"engine_status"`
+	FuelLevel    *float64 `json:"fuel_level"` // percent of tank
+}
+
+func readData(event *models.TelemetryEvent) telemetryData {
+	var data telemetryData
+	if event.Data != "" && json.Unmarshal([]byte(event.Data), &data) != nil {
+		data = telemetryData{}
+	}
+	data.eventType = event.EventType
+	return data
+}
+
+func (d telemetryData) ignitionState() (on bool, known bool) {
+	switch d.eventType {
+	case "ignition_on":
+		return true, true
+	case "ignition_off":
+		return false, true
+	}
+
+	if d.Ignition != nil {
+		return *d.Ignition, true
+	}
+	if d.EngineStatus != nil {
+		switch strings.ToLower(*d.EngineStatus) {
+		case "on", "running", "idle", "idling":
+			return true, true
+		case "off":
+			return false, true
+		}
+	}
+	return false, false
+}
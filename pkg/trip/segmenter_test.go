@@ -0,0 +1,173 @@
+package trip
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+func sample(offset time.Duration, speed, latitude float64, data string) *models.TelemetryEvent {
+	longitude := -100.0
+	return &models.TelemetryEvent{
+		VehicleID: 1,
+		EventType: "location",
+		Timestamp: start.Add(offset),
+		Speed:     &speed,
+		Latitude:  &latitude,
+		Longitude: &longitude,
+		Data:      data,
+	}
+}
+
+// driveNorth returns one sample per minute, moving 0.01 degrees of latitude
+// (about 0.69 miles) each minute
+func driveNorth(from time.Duration, latitude float64, minutes int) []*models.TelemetryEvent {
+	var samples []*models.TelemetryEvent
+	for i := 0; i < minutes; i++ {
+		samples = append(samples, sample(from+time.Duration(i)*time.Minute, 45, latitude+float64(i)*0.01, `{"engine_status":"on"}`))
+	}
+	return samples
+}
+
+func observeAll(s *Segmenter, events []*models.TelemetryEvent) []models.Trip {
+	var trips []models.Trip
+	for _, event := range events {
+		trips = append(trips, s.Observe(event)...)
+	}
+	return trips
+}
+
+func TestTripEndsAtIgnitionOff(t *testing.T) {
+	s := NewSegmenter(DefaultConfig())
+
+	events := []*models.TelemetryEvent{sample(0, 0, 40, `{"engine_status":"on"}`)}
+	events = append(events, driveNorth(time.Minute, 40, 11)...)
+	events[5].Speed = floatPtr(62)
+	events = append(events, sample(12*time.Minute, 0, 40.1, `{"engine_status":"off"}`))
+
+	trips := observeAll(s, events)
+	require.Len(t, trips, 1)
+
+	trip := trips[0]
+	assert.Equal(t, EndIgnitionOff, trip.EndReason)
+	assert.Equal(t, start, trip.StartTime)
+	assert.Equal(t, start.Add(12*time.Minute), trip.EndTime)
+	assert.Equal(t, 720, trip.DurationSeconds)
+	assert.Equal(t, 62.0, trip.MaxSpeed)
+	assert.Equal(t, 40.0, *trip.StartLatitude)
+	assert.Equal(t, 40.1, *trip.EndLatitude)
+	// 0.1 degrees of latitude
+	assert.InDelta(t, 6.91, trip.DistanceMiles, 0.01)
+	assert.Zero(t, s.OpenTrips())
+}
+
+func TestTripEndsWhenStationary(t *testing.T) {
+	s := NewSegmenter(DefaultConfig())
+
+	events := driveNorth(0, 40, 10)
+	// Parked with the engine running from minute 10 to 16
+	for i := 10; i <= 16; i++ {
+		events = append(events, sample(time.Duration(i)*time.Minute, 0, 40.09, `{"engine_status":"on"}`))
+	}
+	trips := observeAll(s, events)
+	require.Len(t, trips, 1)
+	assert.Equal(t, EndStationary, trips[0].EndReason)
+	assert.Equal(t, start.Add(10*time.Minute), trips[0].EndTime, "the trip ends when the vehicle stopped")
+	assert.InDelta(t, 6.22, trips[0].DistanceMiles, 0.01)
+
+	// Pulling away opens the next trip
+	assert.Empty(t, observeAll(s, driveNorth(17*time.Minute, 40.09, 3)))
+	assert.Equal(t, 1, s.OpenTrips())
+}
+
+func TestTripEndsAtReportingGap(t *testing.T) {
+	s := NewSegmenter(DefaultConfig())
+
+	assert.Empty(t, observeAll(s, driveNorth(0, 40, 5)))
+
+	// Half an hour of silence, then the vehicle reports again
+	trips := observeAll(s, driveNorth(35*time.Minute, 41, 5))
+	require.Len(t, trips, 1)
+	assert.Equal(t, EndGap, trips[0].EndReason)
+	assert.Equal(t, start.Add(4*time.Minute), trips[0].EndTime)
+	assert.InDelta(t, 2.76, trips[0].DistanceMiles, 0.01, "the gap is not counted as distance")
+
+	// Flush closes the second trip once the vehicle has been silent long enough
+	assert.Empty(t, s.Flush(start.Add(45*time.Minute)))
+	trips = s.Flush(start.Add(time.Hour))
+	require.Len(t, trips, 1)
+	assert.Equal(t, start.Add(35*time.Minute), trips[0].StartTime)
+}
+
+func TestTripIdleTimeAndFuel(t *testing.T) {
+	s := NewSegmenter(DefaultConfig())
+
+	var events []*models.TelemetryEvent
+	for minute := 0; minute <= 14; minute++ {
+		speed := 40.0
+		if minute >= 5 && minute <= 8 {
+			speed = 0 // waiting in traffic with the engine running
+		}
+		fuel := 80 - 0.5*float64(minute)
+		if minute >= 7 {
+			fuel = 100 - 0.5*float64(minute-7) // topped up while waiting
+		}
+		data := fmt.Sprintf(`{"engine_status":"on","fuel_level":%.1f}`, fuel)
+		if minute == 14 {
+			speed, data = 0, fmt.Sprintf(`{"engine_status":"off","fuel_level":%.1f}`, fuel)
+		}
+		events = append(events, sample(time.Duration(minute)*time.Minute, speed, 40+float64(minute)*0.01, data))
+	}
+
+	trips := observeAll(s, events)
+	require.Len(t, trips, 1)
+	assert.Equal(t, 180, trips[0].IdleSeconds)
+	require.NotNil(t, trips[0].FuelUsed)
+	assert.InDelta(t, 6.5, *trips[0].FuelUsed, 0.001, "refuelling is not negative consumption")
+}
+
+func TestIdlingIsNotATrip(t *testing.T) {
+	s := NewSegmenter(DefaultConfig())
+
+	events := []*models.TelemetryEvent{sample(0, 0, 40, `{"ignition":true}`)}
+	for i := 1; i <= 10; i++ {
+		// GPS drift while parked
+		events = append(events, sample(time.Duration(i)*time.Minute, 0, 40+float64(i%2)*0.0005, `{"ignition":true}`))
+	}
+	events = append(events, sample(11*time.Minute, 0, 40, `{"ignition":false}`))
+
+	assert.Empty(t, observeAll(s, events))
+	assert.Zero(t, s.OpenTrips())
+}
+
+func TestIgnitionState(t *testing.T) {
+	tests := []struct {
+		eventType, data string
+		on, known       bool
+	}{
+		{"ignition_on", "", true, true},
+		{"ignition_off", `{"engine_status":"on"}`, false, true},
+		{"location", `{"ignition":true}`, true, true},
+		{"location", `{"engine_status":"idle"}`, true, true},
+		{"location", `{"engine_status":"OFF"}`, false, true},
+		{"location", `{"fuel_level":50}`, false, false},
+		{"location", `not json`, false, false},
+	}
+
+	for _, tt := range tests {
+		on, known := IgnitionState(&models.TelemetryEvent{EventType: tt.eventType, Data: tt.data})
+		assert.Equal(t, tt.on, on, tt.data)
+		assert.Equal(t, tt.known, known, tt.data)
+	}
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,6 +9,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
 )
 
 // ValidationError represents a validation error
@@ -55,6 +59,19 @@ func ValidateVehicleID() gin.HandlerFunc {
 	}
 }
 
+var validEventTypes = map[string]bool{
+	"location":                    true,
+	"speed":                       true,
+	"acceleration":                true,
+	"harsh_braking":               true,
+	"engine_status":               true,
+	"fuel_level":                  true,
+	models.EventPhoneUse:          true,
+	models.EventSeatbeltUnbuckled: true,
+	models.EventCameraDistraction: true,
+	models.EventFollowingDistance: true,
+}
+
 // ValidateTelemetryPayload validates telemetry payload data
 func ValidateTelemetryPayload() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -66,9 +83,11 @@ func ValidateTelemetryPayload() gin.HandlerFunc {
 			Longitude    *float64  `json:"longitude"`
 			Speed        *float64  `json:"speed"`
 			Acceleration *float64  `json:"acceleration"`
+			Data         string    `json:"data"`
 		}
 
-		if err := c.ShouldBindJSON(&payload); err != nil {
+		// The body is cached so that later handlers can bind it again
+		if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "validation_failed",
 				"message": "Invalid JSON payload: " + err.Error(),
@@ -88,21 +107,16 @@ func ValidateTelemetryPayload() gin.HandlerFunc {
 		}
 
 		// Validate event type
-		validEventTypes := map[string]bool{
-			"location":           true,
-			"speed":              true,
-			"acceleration":       true,
-			"harsh_braking":      true,
-			"engine_status":      true,
-			"fuel_level":         true,
-		}
 		if !validEventTypes[payload.EventType] {
 			errors = append(errors, ValidationError{
 				Field:   "event_type",
-				Message: "event_type must be one of: location, speed, acceleration, harsh_braking, engine_status, fuel_level",
+				Message: "event_type must be one of: location, speed, acceleration, harsh_braking, engine_status, fuel_level, phone_use, seatbelt_unbuckled, camera_distraction, following_distance",
 			})
 		}
 
+		// Validate in-cab sensor readings
+		errors = append(errors, ValidateEventData(payload.EventType, payload.Data)...)
+
 		// Validate timestamp is not too far in the future
 		if payload.Timestamp.After(time.Now().Add(5 * time.Minute)) {
 			errors = append(errors, ValidationError{
@@ -142,10 +156,10 @@ func ValidateTelemetryPayload() gin.HandlerFunc {
 
 		// Validate acceleration if provided
 		if payload.Acceleration != nil {
-			if *payload.Acceleration < -20 || *payload.Acceleration > 20 { // Reasonable g-force limits
+			if *payload.Acceleration < -200 || *payload.Acceleration > 200 { // About 20 g, enough to record crash impacts
 				errors = append(errors, ValidationError{
 					Field:   "acceleration",
-					Message: "acceleration must be between -20 and 20 m/s²",
+					Message: "acceleration must be between -200 and 200 m/s²",
 				})
 			}
 		}
@@ -173,7 +187,7 @@ func RequireCoordinates() gin.HandlerFunc {
 			Longitude *float64 `json:"longitude"`
 		}
 
-		if err := c.ShouldBindJSON(&payload); err != nil {
+		if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
 			c.Next() // Let other validation handle JSON errors
 			return
 		}
@@ -191,4 +205,79 @@ func RequireCoordinates() gin.HandlerFunc {
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// ValidateEventData checks that an in-cab sensor event carries its reading in
+// data, the JSON form of the event type's models.*Data struct. Other event
+// types are not checked.
+func ValidateEventData(eventType, data string) ValidationErrors {
+	var reading interface{}
+	switch eventType {
+	case models.EventPhoneUse:
+		reading = &models.PhoneUseData{}
+	case models.EventSeatbeltUnbuckled:
+		reading = &models.SeatbeltData{}
+	case models.EventCameraDistraction:
+		reading = &models.CameraDistractionData{}
+	case models.EventFollowingDistance:
+		reading = &models.FollowingDistanceData{}
+	default:
+		return nil
+	}
+
+	if data == "" {
+		return ValidationErrors{{Field: "data", Message: eventType + " events must include their reading in data"}}
+	}
+	if err := json.Unmarshal([]byte(data), reading); err != nil {
+		return ValidationErrors{{Field: "data", Message: "data must be a JSON object: " + err.Error()}}
+	}
+
+	var errors ValidationErrors
+	invalid := func(field, message string) {
+		errors = append(errors, ValidationError{Field: "data." + field, Message: message})
+	}
+	duration := func(seconds float64) {
+		if seconds < 0 || seconds > 86400 {
+			invalid("duration_seconds", "duration_seconds must be between 0 and 86400")
+		}
+	}
+
+	switch reading := reading.(type) {
+	case *models.PhoneUseData:
+		if !oneOf(reading.Usage, models.PhoneUsages) {
+			invalid("usage", "usage must be one of: "+strings.Join(models.PhoneUsages, ", "))
+		}
+		duration(reading.DurationSeconds)
+	case *models.SeatbeltData:
+		if !oneOf(reading.Seat, models.Seats) {
+			invalid("seat", "seat must be one of: "+strings.Join(models.Seats, ", "))
+		}
+		duration(reading.DurationSeconds)
+	case *models.CameraDistractionData:
+		if !oneOf(reading.Behavior, models.CameraBehaviors) {
+			invalid("behavior", "behavior must be one of: "+strings.Join(models.CameraBehaviors, ", "))
+		}
+		if reading.Confidence < 0 || reading.Confidence > 1 {
+			invalid("confidence", "confidence must be between 0 and 1")
+		}
+		duration(reading.DurationSeconds)
+	case *models.FollowingDistanceData:
+		if reading.HeadwaySeconds <= 0 || reading.HeadwaySeconds > 60 {
+			invalid("headway_seconds", "headway_seconds must be greater than 0 and at most 60")
+		}
+		if reading.DistanceMeters != nil && *reading.DistanceMeters < 0 {
+			invalid("distance_meters", "distance_meters must not be negative")
+		}
+		duration(reading.DurationSeconds)
+	}
+	return errors
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -196,7 +197,7 @@ func TestValidateTelemetryPayload(t *testing.T) {
 				"vehicle_id":   1,
 				"event_type":   "acceleration",
 				"timestamp":    time.Now().Format(time.RFC3339),
-				"acceleration": 25.0,
+				"acceleration": 250.0,
 			},
 			expectedStatus: http.StatusBadRequest,
 			shouldAbort:    true,
@@ -230,6 +231,73 @@ func TestValidateTelemetryPayload(t *testing.T) {
 	}
 }
 
+func TestValidateEventData(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		data      string
+		wantField string
+	}{
+		{"non-sensor event", "location", "", ""},
+		{"phone use", "phone_use", `{"usage": "texting", "duration_seconds": 12}`, ""},
+		{"phone use without reading", "phone_use", "", "data"},
+		{"phone use with unknown usage", "phone_use", `{"usage": "gaming", "duration_seconds": 12}`, "data.usage"},
+		{"seatbelt", "seatbelt_unbuckled", `{"seat": "driver", "duration_seconds": 30}`, ""},
+		{"seatbelt with unknown seat", "seatbelt_unbuckled", `{"seat": "roof", "duration_seconds": 30}`, "data.seat"},
+		{"camera", "camera_distraction", `{"behavior": "looking_away", "duration_seconds": 3, "confidence": 0.8}`, ""},
+		{"camera with bad confidence", "camera_distraction", `{"behavior": "looking_away", "duration_seconds": 3, "confidence": 80}`, "data.confidence"},
+		{"following distance", "following_distance", `{"headway_seconds": 0.9, "distance_meters": 25, "duration_seconds": 5}`, ""},
+		{"following distance without headway", "following_distance", `{"duration_seconds": 5}`, "data.headway_seconds"},
+		{"negative duration", "following_distance", `{"headway_seconds": 0.9, "duration_seconds": -5}`, "data.duration_seconds"},
+		{"malformed reading", "phone_use", `{"usage": 3}`, "data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := ValidateEventData(tt.eventType, tt.data)
+			if tt.wantField == "" {
+				assert.Empty(t, errors)
+				return
+			}
+			if assert.NotEmpty(t, errors) {
+				assert.Equal(t, tt.wantField, errors[0].Field)
+			}
+		})
+	}
+}
+
+func TestValidatedPayloadCanBeBoundAgain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.POST("/test", ValidateTelemetryPayload(), RequireCoordinates(), func(c *gin.Context) {
+		var payload struct {
+			VehicleID uint   `json:"vehicle_id"`
+			Data      string `json:"data"`
+		}
+		if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, payload)
+	})
+
+	jsonPayload, _ := json.Marshal(map[string]interface{}{
+		"vehicle_id": 4,
+		"event_type": "phone_use",
+		"timestamp":  time.Now().Format(time.RFC3339),
+		"speed":      40.0,
+		"data":       `{"usage": "texting", "duration_seconds": 6}`,
+	})
+	req, _ := http.NewRequest("POST", "/test", bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"vehicle_id":4`)
+}
+
 func TestRequireCoordinates(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
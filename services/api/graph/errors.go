@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+)
+
+// ErrorPresenter maps application errors onto GraphQL errors so that clients
+// receive the same error codes and context as the REST endpoints
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		return gqlErr
+	}
+
+	logEntry := logrus.WithFields(logrus.Fields{
+		"error_code": appErr.Code,
+		"context":    appErr.Context,
+	})
+	if appErr.Internal != nil {
+		logEntry = logEntry.WithError(appErr.Internal)
+	}
+	if appErr.HTTPStatus >= 500 {
+		logEntry.Error(appErr.Message)
+	} else {
+		logEntry.Debug(appErr.Message)
+	}
+
+	gqlErr.Message = appErr.Message
+	gqlErr.Extensions = map[string]interface{}{
+		"code":   appErr.Code,
+		"status": appErr.HTTPStatus,
+	}
+	if len(appErr.Context) > 0 {
+		gqlErr.Extensions["context"] = appErr.Context
+	}
+
+	return gqlErr
+}
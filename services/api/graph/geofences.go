@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"encoding/json"
+	"strings"
+
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geofence"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+)
+
+// This file will not be regenerated automatically.
+//
+// It holds the helpers behind the geofence resolvers in schema.resolvers.go.
+
+// geofenceFromInput builds the stored form of a geofence and validates its
+// shape the way the risk engine will compile it
+func geofenceFromInput(fleetID uint, input model.GeofenceInput) (models.Geofence, error) {
+	g := models.Geofence{
+		FleetID:    fleetID,
+		Name:       strings.TrimSpace(input.Name),
+		Category:   geofence.CategoryOther,
+		Shape:      fromEnum(input.Shape.String()),
+		SpeedLimit: input.SpeedLimit,
+		Status:     geofence.StatusActive,
+	}
+	if g.Name == "" {
+		return g, apperrors.ValidationError("name", "name is required")
+	}
+	if len(g.Name) > 100 {
+		return g, apperrors.ValidationError("name", "name must be at most 100 characters")
+	}
+	if input.Category != nil {
+		g.Category = fromEnum(input.Category.String())
+	}
+	if input.Status != nil {
+		g.Status = fromEnum(input.Status.String())
+	}
+	if input.Restricted != nil {
+		g.Restricted = *input.Restricted
+	}
+
+	classes := make([]string, 0, len(input.AllowedVehicleClasses))
+	for _, class := range input.AllowedVehicleClasses {
+		normalized, err := normalizeVehicleClass(&class)
+		if err != nil {
+			return g, apperrors.ValidationError("allowedVehicleClasses", "vehicle classes must be at most 50 characters")
+		}
+		if strings.Contains(normalized, ",") {
+			return g, apperrors.ValidationError("allowedVehicleClasses", "vehicle classes cannot contain commas")
+		}
+		if normalized != "" {
+			classes = append(classes, normalized)
+		}
+	}
+	g.AllowedVehicleClasses = strings.Join(classes, ",")
+	if len(g.AllowedVehicleClasses) > 255 {
+		return g, apperrors.ValidationError("allowedVehicleClasses", "too many vehicle classes")
+	}
+
+	switch g.Shape {
+	case geofence.ShapeCircle:
+		if input.Center != nil {
+			g.CenterLatitude = &input.Center.Latitude
+			g.CenterLongitude = &input.Center.Longitude
+		}
+		g.RadiusMeters = input.RadiusMeters
+	case geofence.ShapePolygon:
+		vertices := make([]geo.Point, 0, len(input.Polygon))
+		for _, vertex := range input.Polygon {
+			vertices = append(vertices, geo.Point{Latitude: vertex.Latitude, Longitude: vertex.Longitude})
+		}
+		encoded, err := json.Marshal(vertices)
+		if err != nil {
+			return g, apperrors.ValidationError("polygon", "polygon could not be encoded")
+		}
+		g.Polygon = string(encoded)
+	}
+
+	if _, err := geofence.Compile(g); err != nil {
+		return g, apperrors.ValidationError("shape", err.Error())
+	}
+	return g, nil
+}
+
+// geofenceUpdates lists the columns written when a geofence is replaced
+var geofenceUpdates = []string{
+	"name", "category", "shape", "center_latitude", "center_longitude", "radius_meters", "polygon",
+	"speed_limit", "restricted", "allowed_vehicle_classes", "status",
+}
+
+// formatPolygon renders the stored vertices of a polygon fence
+func formatPolygon(g *models.Geofence) []*model.Location {
+	if g.Shape != geofence.ShapePolygon {
+		return nil
+	}
+	var vertices []geo.Point
+	if err := json.Unmarshal([]byte(g.Polygon), &vertices); err != nil {
+		return nil
+	}
+	locations := make([]*model.Location, len(vertices))
+	for i, vertex := range vertices {
+		locations[i] = &model.Location{Latitude: vertex.Latitude, Longitude: vertex.Longitude}
+	}
+	return locations
+}
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/auth"
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+)
+
+// This file will not be regenerated automatically.
+//
+// It holds the conversions shared by the resolvers in schema.resolvers.go.
+
+// formatID renders a database primary key as a GraphQL ID
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// formatOptionalID renders a nullable foreign key as a nullable GraphQL ID
+func formatOptionalID(id *uint) *string {
+	if id == nil {
+		return nil
+	}
+	formatted := formatID(*id)
+	return &formatted
+}
+
+// parseID converts a GraphQL ID argument into a database primary key
+func parseID(field, id string) (uint, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(id), 10, 32)
+	if err != nil || value == 0 {
+		return 0, apperrors.ValidationError(field, field+" must be a valid positive integer")
+	}
+	return uint(value), nil
+}
+
+// parseOptionalID converts a nullable GraphQL ID argument into a database primary key
+func parseOptionalID(field string, id *string) (*uint, error) {
+	if id == nil {
+		return nil, nil
+	}
+	value, err := parseID(field, *id)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// formatTime renders timestamps in RFC 3339, which is what the frontend parses
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// formatOptionalTime renders a nullable timestamp
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := formatTime(*t)
+	return &formatted
+}
+
+// parseOptionalTime converts a nullable RFC 3339 argument into a timestamp
+func parseOptionalTime(field string, value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(*value))
+	if err != nil {
+		return nil, apperrors.ValidationError(field, field+" must be an RFC 3339 timestamp")
+	}
+	return &parsed, nil
+}
+
+// formatLocation renders a pair of nullable coordinates as a location
+func formatLocation(latitude, longitude *float64) *model.Location {
+	if latitude == nil || longitude == nil {
+		return nil
+	}
+	return &model.Location{
+		Latitude:  *latitude,
+		Longitude: *longitude,
+	}
+}
+
+// toEnum converts a stored value such as "harsh_braking" into its GraphQL enum form
+func toEnum(value string) string {
+	return strings.ToUpper(value)
+}
+
+// fromEnum converts a GraphQL enum value back into its stored form
+func fromEnum(value string) string {
+	return strings.ToLower(value)
+}
+
+// lookupError maps a failed single-record lookup onto an application error
+func lookupError(resource string, id uint, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.NotFoundError(resource, id)
+	}
+	return apperrors.WrapDatabaseError("fetch_"+resource, err, map[string]interface{}{
+		"id": id,
+	})
+}
+
+// buildVehicleData assembles the live view of a vehicle from its latest telemetry event
+func buildVehicleData(vehicle *models.Vehicle, event *models.TelemetryEvent) *model.VehicleData {
+	data := &model.VehicleData{
+		Vehicle:    vehicle,
+		LastUpdate: formatTime(vehicle.UpdatedAt),
+	}
+	if event == nil {
+		return data
+	}
+
+	data.Speed = event.Speed
+	data.LastUpdate = formatTime(event.Timestamp)
+	if event.Latitude != nil && event.Longitude != nil {
+		data.Location = &model.Location{
+			Latitude:  *event.Latitude,
+			Longitude: *event.Longitude,
+		}
+	}
+
+	// Engine, fuel and heading readings travel in the event-specific JSON payload
+	var extra struct {
+		EngineStatus *string  `json:"engine_status"`
+		FuelLevel    *float64 `json:"fuel_level"`
+		Heading      *float64 `json:"heading"`
+	}
+	if event.Data != "" && json.Unmarshal([]byte(event.Data), &extra) == nil {
+		data.EngineStatus = extra.EngineStatus
+		data.FuelLevel = extra.FuelLevel
+		data.Heading = extra.Heading
+	}
+
+	return data
+}
+
+// latestTelemetry returns the most recent telemetry event for a vehicle, or nil
+// if it has not reported yet. withLocation restricts the search to events that
+// carry GPS coordinates.
+func (r *Resolver) latestTelemetry(ctx context.Context, vehicleID uint, withLocation bool) (*models.TelemetryEvent, error) {
+	query := r.DB.WithContext(ctx).Where("vehicle_id = ?", vehicleID)
+	if withLocation {
+		query = query.Where("latitude IS NOT NULL AND longitude IS NOT NULL")
+	}
+
+	var event models.TelemetryEvent
+	err := query.Order("timestamp desc").First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("fetch_latest_telemetry", err)
+	}
+	return &event, nil
+}
+
+// setAlertStatus moves an alert to the given status and returns it with its associations
+func (r *Resolver) setAlertStatus(ctx context.Context, id, status string) (*models.Alert, error) {
+	alertID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var alert models.Alert
+	if err := db.First(&alert, alertID).Error; err != nil {
+		return nil, lookupError("alert", alertID, err)
+	}
+
+	if err := db.Model(&alert).Update("status", status).Error; err != nil {
+		return nil, apperrors.DatabaseError("update_alert_status", err)
+	}
+
+	if err := db.Preload("Fleet").Preload("Vehicle").Preload("Driver").Preload("RiskEvent").
+		First(&alert, alertID).Error; err != nil {
+		return nil, lookupError("alert", alertID, err)
+	}
+	return &alert, nil
+}
+
+// authorizeFleet rejects requests naming a fleet outside the caller's claims.
+// The tenant scope already filters the rows; this reports the violation instead
+// of returning an empty result.
+func authorizeFleet(ctx context.Context, fleetID uint) error {
+	claims, ok := auth.GetUserFromContext(ctx)
+	if ok && !claims.CanAccessFleet(fleetID) {
+		appErr := apperrors.FleetAccessDeniedError("authorize_fleet", apperrors.ErrFleetAccessDenied)
+		appErr.Context["fleet_id"] = fleetID
+		return appErr
+	}
+	return nil
+}
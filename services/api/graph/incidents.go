@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/auth"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+)
+
+// This file will not be regenerated automatically.
+//
+// It holds the helpers behind the incident resolvers in schema.resolvers.go.
+
+// withIncidentAssociations preloads what the Incident type exposes, with the
+// status history in the order it happened
+func withIncidentAssociations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Vehicle").Preload("Driver").Preload("RiskEvent").
+		Preload("Updates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		})
+}
+
+// currentUserID returns the ID of the signed-in user, or nil when the request
+// carries no claims
+func currentUserID(ctx context.Context) *uint {
+	claims, ok := auth.GetUserFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	id, err := strconv.ParseUint(claims.UserID, 10, 32)
+	if err != nil {
+		return nil
+	}
+	userID := uint(id)
+	return &userID
+}
+
+// loadIncident fetches an incident with its associations
+func (r *Resolver) loadIncident(ctx context.Context, id uint) (*models.Incident, error) {
+	var incident models.Incident
+	if err := withIncidentAssociations(r.DB.WithContext(ctx)).First(&incident, id).Error; err != nil {
+		return nil, lookupError("incident", id, err)
+	}
+	return &incident, nil
+}
@@ -28,12 +28,34 @@ type CreateFleetInput struct {
 }
 
 type CreateVehicleInput struct {
-	Vin          string `json:"vin"`
-	Make         string `json:"make"`
-	Model        string `json:"model"`
-	Year         int    `json:"year"`
-	LicensePlate string `json:"licensePlate"`
-	FleetID      string `json:"fleetId"`
+	Vin          string  `json:"vin"`
+	Make         string  `json:"make"`
+	Model        string  `json:"model"`
+	Year         int     `json:"year"`
+	LicensePlate string  `json:"licensePlate"`
+	VehicleClass *string `json:"vehicleClass,omitempty"`
+	FleetID      string  `json:"fleetId"`
+}
+
+type DriverSimulationCount struct {
+	DriverID  *string        `json:"driverId,omitempty"`
+	Driver    *models.Driver `json:"driver,omitempty"`
+	Current   int            `json:"current"`
+	Candidate int            `json:"candidate"`
+}
+
+type GeofenceInput struct {
+	FleetID               string            `json:"fleetId"`
+	Name                  string            `json:"name"`
+	Category              *GeofenceCategory `json:"category,omitempty"`
+	Shape                 GeofenceShape     `json:"shape"`
+	Center                *LocationInput    `json:"center,omitempty"`
+	RadiusMeters          *float64          `json:"radiusMeters,omitempty"`
+	Polygon               []*LocationInput  `json:"polygon,omitempty"`
+	SpeedLimit            *float64          `json:"speedLimit,omitempty"`
+	Restricted            *bool             `json:"restricted,omitempty"`
+	AllowedVehicleClasses []string          `json:"allowedVehicleClasses,omitempty"`
+	Status                *GeofenceStatus   `json:"status,omitempty"`
 }
 
 type Location struct {
@@ -42,12 +64,82 @@ type Location struct {
 	Address   *string `json:"address,omitempty"`
 }
 
+type LocationInput struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
 type Mutation struct {
 }
 
+type PlannedRouteInput struct {
+	FleetID             string           `json:"fleetId"`
+	Name                string           `json:"name"`
+	Path                []*LocationInput `json:"path"`
+	CorridorWidthMeters float64          `json:"corridorWidthMeters"`
+	DeviationSeconds    *int             `json:"deviationSeconds,omitempty"`
+}
+
 type Query struct {
 }
 
+type RiskPolicyInput struct {
+	FleetID      string           `json:"fleetId"`
+	VehicleClass *string          `json:"vehicleClass,omitempty"`
+	Rules        []*RiskRuleInput `json:"rules"`
+}
+
+type RiskRule struct {
+	Name      string          `json:"name"`
+	Enabled   bool            `json:"enabled"`
+	Threshold *float64        `json:"threshold,omitempty"`
+	Bands     []*SeverityBand `json:"bands,omitempty"`
+	Params    *string         `json:"params,omitempty"`
+}
+
+type RiskRuleInput struct {
+	Name      string               `json:"name"`
+	Enabled   *bool                `json:"enabled,omitempty"`
+	Threshold *float64             `json:"threshold,omitempty"`
+	Bands     []*SeverityBandInput `json:"bands,omitempty"`
+	Params    *string              `json:"params,omitempty"`
+}
+
+type RiskSimulation struct {
+	FleetID         string                   `json:"fleetId"`
+	From            string                   `json:"from"`
+	To              string                   `json:"to"`
+	TelemetryEvents int                      `json:"telemetryEvents"`
+	Current         *RiskSimulationTotals    `json:"current"`
+	Candidate       *RiskSimulationTotals    `json:"candidate"`
+	ByEventType     []*RiskSimulationCount   `json:"byEventType"`
+	BySeverity      []*RiskSimulationCount   `json:"bySeverity"`
+	ByDriver        []*DriverSimulationCount `json:"byDriver"`
+}
+
+type RiskSimulationCount struct {
+	Key       string `json:"key"`
+	Current   int    `json:"current"`
+	Candidate int    `json:"candidate"`
+}
+
+type RiskSimulationTotals struct {
+	RiskEvents int `json:"riskEvents"`
+	Alerts     int `json:"alerts"`
+}
+
+type SeverityBand struct {
+	Above     float64      `json:"above"`
+	Severity  RiskSeverity `json:"severity"`
+	RiskScore float64      `json:"riskScore"`
+}
+
+type SeverityBandInput struct {
+	Above     float64      `json:"above"`
+	Severity  RiskSeverity `json:"severity"`
+	RiskScore float64      `json:"riskScore"`
+}
+
 type Subscription struct {
 }
 
@@ -72,6 +164,7 @@ type UpdateVehicleInput struct {
 	Model        *string        `json:"model,omitempty"`
 	Year         *int           `json:"year,omitempty"`
 	LicensePlate *string        `json:"licensePlate,omitempty"`
+	VehicleClass *string        `json:"vehicleClass,omitempty"`
 	Status       *VehicleStatus `json:"status,omitempty"`
 }
 
@@ -205,19 +298,21 @@ type AlertType string
 
 const (
 	AlertTypeRisk        AlertType = "RISK"
+	AlertTypeIncident    AlertType = "INCIDENT"
 	AlertTypeMaintenance AlertType = "MAINTENANCE"
 	AlertTypeSystem      AlertType = "SYSTEM"
 )
 
 var AllAlertType = []AlertType{
 	AlertTypeRisk,
+	AlertTypeIncident,
 	AlertTypeMaintenance,
 	AlertTypeSystem,
 }
 
 func (e AlertType) IsValid() bool {
 	switch e {
-	case AlertTypeRisk, AlertTypeMaintenance, AlertTypeSystem:
+	case AlertTypeRisk, AlertTypeIncident, AlertTypeMaintenance, AlertTypeSystem:
 		return true
 	}
 	return false
@@ -315,6 +410,291 @@ func (e DriverStatus) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type GeofenceCategory string
+
+const (
+	GeofenceCategoryDepot        GeofenceCategory = "DEPOT"
+	GeofenceCategoryCustomerSite GeofenceCategory = "CUSTOMER_SITE"
+	GeofenceCategorySchoolZone   GeofenceCategory = "SCHOOL_ZONE"
+	GeofenceCategoryOther        GeofenceCategory = "OTHER"
+)
+
+var AllGeofenceCategory = []GeofenceCategory{
+	GeofenceCategoryDepot,
+	GeofenceCategoryCustomerSite,
+	GeofenceCategorySchoolZone,
+	GeofenceCategoryOther,
+}
+
+func (e GeofenceCategory) IsValid() bool {
+	switch e {
+	case GeofenceCategoryDepot, GeofenceCategoryCustomerSite, GeofenceCategorySchoolZone, GeofenceCategoryOther:
+		return true
+	}
+	return false
+}
+
+func (e GeofenceCategory) String() string {
+	return string(e)
+}
+
+func (e *GeofenceCategory) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GeofenceCategory(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GeofenceCategory", str)
+	}
+	return nil
+}
+
+func (e GeofenceCategory) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *GeofenceCategory) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e GeofenceCategory) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type GeofenceEventType string
+
+const (
+	GeofenceEventTypeEnter GeofenceEventType = "ENTER"
+	GeofenceEventTypeExit  GeofenceEventType = "EXIT"
+)
+
+var AllGeofenceEventType = []GeofenceEventType{
+	GeofenceEventTypeEnter,
+	GeofenceEventTypeExit,
+}
+
+func (e GeofenceEventType) IsValid() bool {
+	switch e {
+	case GeofenceEventTypeEnter, GeofenceEventTypeExit:
+		return true
+	}
+	return false
+}
+
+func (e GeofenceEventType) String() string {
+	return string(e)
+}
+
+func (e *GeofenceEventType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GeofenceEventType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GeofenceEventType", str)
+	}
+	return nil
+}
+
+func (e GeofenceEventType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *GeofenceEventType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e GeofenceEventType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type GeofenceShape string
+
+const (
+	GeofenceShapeCircle  GeofenceShape = "CIRCLE"
+	GeofenceShapePolygon GeofenceShape = "POLYGON"
+)
+
+var AllGeofenceShape = []GeofenceShape{
+	GeofenceShapeCircle,
+	GeofenceShapePolygon,
+}
+
+func (e GeofenceShape) IsValid() bool {
+	switch e {
+	case GeofenceShapeCircle, GeofenceShapePolygon:
+		return true
+	}
+	return false
+}
+
+func (e GeofenceShape) String() string {
+	return string(e)
+}
+
+func (e *GeofenceShape) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GeofenceShape(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GeofenceShape", str)
+	}
+	return nil
+}
+
+func (e GeofenceShape) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *GeofenceShape) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e GeofenceShape) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type GeofenceStatus string
+
+const (
+	GeofenceStatusActive   GeofenceStatus = "ACTIVE"
+	GeofenceStatusInactive GeofenceStatus = "INACTIVE"
+)
+
+var AllGeofenceStatus = []GeofenceStatus{
+	GeofenceStatusActive,
+	GeofenceStatusInactive,
+}
+
+func (e GeofenceStatus) IsValid() bool {
+	switch e {
+	case GeofenceStatusActive, GeofenceStatusInactive:
+		return true
+	}
+	return false
+}
+
+func (e GeofenceStatus) String() string {
+	return string(e)
+}
+
+func (e *GeofenceStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GeofenceStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GeofenceStatus", str)
+	}
+	return nil
+}
+
+func (e GeofenceStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *GeofenceStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e GeofenceStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type IncidentStatus string
+
+const (
+	IncidentStatusOpen         IncidentStatus = "OPEN"
+	IncidentStatusAcknowledged IncidentStatus = "ACKNOWLEDGED"
+	IncidentStatusDispatched   IncidentStatus = "DISPATCHED"
+	IncidentStatusResolved     IncidentStatus = "RESOLVED"
+	IncidentStatusClosed       IncidentStatus = "CLOSED"
+)
+
+var AllIncidentStatus = []IncidentStatus{
+	IncidentStatusOpen,
+	IncidentStatusAcknowledged,
+	IncidentStatusDispatched,
+	IncidentStatusResolved,
+	IncidentStatusClosed,
+}
+
+func (e IncidentStatus) IsValid() bool {
+	switch e {
+	case IncidentStatusOpen, IncidentStatusAcknowledged, IncidentStatusDispatched, IncidentStatusResolved, IncidentStatusClosed:
+		return true
+	}
+	return false
+}
+
+func (e IncidentStatus) String() string {
+	return string(e)
+}
+
+func (e *IncidentStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = IncidentStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid IncidentStatus", str)
+	}
+	return nil
+}
+
+func (e IncidentStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *IncidentStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e IncidentStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type RiskEventStatus string
 
 const (
@@ -375,26 +755,44 @@ func (e RiskEventStatus) MarshalJSON() ([]byte, error) {
 type RiskEventType string
 
 const (
-	RiskEventTypeSpeeding          RiskEventType = "SPEEDING"
-	RiskEventTypeHarshBraking      RiskEventType = "HARSH_BRAKING"
-	RiskEventTypeRapidAcceleration RiskEventType = "RAPID_ACCELERATION"
-	RiskEventTypeFatigue           RiskEventType = "FATIGUE"
-	RiskEventTypeDistractedDriving RiskEventType = "DISTRACTED_DRIVING"
-	RiskEventTypeAggressiveDriving RiskEventType = "AGGRESSIVE_DRIVING"
+	RiskEventTypeSpeeding               RiskEventType = "SPEEDING"
+	RiskEventTypeHarshBraking           RiskEventType = "HARSH_BRAKING"
+	RiskEventTypeRapidAcceleration      RiskEventType = "RAPID_ACCELERATION"
+	RiskEventTypeHarshCornering         RiskEventType = "HARSH_CORNERING"
+	RiskEventTypeFatigue                RiskEventType = "FATIGUE"
+	RiskEventTypeDistractedDriving      RiskEventType = "DISTRACTED_DRIVING"
+	RiskEventTypeAggressiveDriving      RiskEventType = "AGGRESSIVE_DRIVING"
+	RiskEventTypeTailgating             RiskEventType = "TAILGATING"
+	RiskEventTypeSeatbeltViolation      RiskEventType = "SEATBELT_VIOLATION"
+	RiskEventTypeCrash                  RiskEventType = "CRASH"
+	RiskEventTypeZoneSpeeding           RiskEventType = "ZONE_SPEEDING"
+	RiskEventTypeUnauthorizedZone       RiskEventType = "UNAUTHORIZED_ZONE"
+	RiskEventTypeRouteDeviation         RiskEventType = "ROUTE_DEVIATION"
+	RiskEventTypeRouteDeviationResolved RiskEventType = "ROUTE_DEVIATION_RESOLVED"
+	RiskEventTypeBehaviorAnomaly        RiskEventType = "BEHAVIOR_ANOMALY"
 )
 
 var AllRiskEventType = []RiskEventType{
 	RiskEventTypeSpeeding,
 	RiskEventTypeHarshBraking,
 	RiskEventTypeRapidAcceleration,
+	RiskEventTypeHarshCornering,
 	RiskEventTypeFatigue,
 	RiskEventTypeDistractedDriving,
 	RiskEventTypeAggressiveDriving,
+	RiskEventTypeTailgating,
+	RiskEventTypeSeatbeltViolation,
+	RiskEventTypeCrash,
+	RiskEventTypeZoneSpeeding,
+	RiskEventTypeUnauthorizedZone,
+	RiskEventTypeRouteDeviation,
+	RiskEventTypeRouteDeviationResolved,
+	RiskEventTypeBehaviorAnomaly,
 }
 
 func (e RiskEventType) IsValid() bool {
 	switch e {
-	case RiskEventTypeSpeeding, RiskEventTypeHarshBraking, RiskEventTypeRapidAcceleration, RiskEventTypeFatigue, RiskEventTypeDistractedDriving, RiskEventTypeAggressiveDriving:
+	case RiskEventTypeSpeeding, RiskEventTypeHarshBraking, RiskEventTypeRapidAcceleration, RiskEventTypeHarshCornering, RiskEventTypeFatigue, RiskEventTypeDistractedDriving, RiskEventTypeAggressiveDriving, RiskEventTypeTailgating, RiskEventTypeSeatbeltViolation, RiskEventTypeCrash, RiskEventTypeZoneSpeeding, RiskEventTypeUnauthorizedZone, RiskEventTypeRouteDeviation, RiskEventTypeRouteDeviationResolved, RiskEventTypeBehaviorAnomaly:
 		return true
 	}
 	return false
@@ -494,6 +892,65 @@ func (e RiskSeverity) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type ScoreGranularity string
+
+const (
+	ScoreGranularityRun  ScoreGranularity = "RUN"
+	ScoreGranularityHour ScoreGranularity = "HOUR"
+	ScoreGranularityDay  ScoreGranularity = "DAY"
+	ScoreGranularityWeek ScoreGranularity = "WEEK"
+)
+
+var AllScoreGranularity = []ScoreGranularity{
+	ScoreGranularityRun,
+	ScoreGranularityHour,
+	ScoreGranularityDay,
+	ScoreGranularityWeek,
+}
+
+func (e ScoreGranularity) IsValid() bool {
+	switch e {
+	case ScoreGranularityRun, ScoreGranularityHour, ScoreGranularityDay, ScoreGranularityWeek:
+		return true
+	}
+	return false
+}
+
+func (e ScoreGranularity) String() string {
+	return string(e)
+}
+
+func (e *ScoreGranularity) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScoreGranularity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScoreGranularity", str)
+	}
+	return nil
+}
+
+func (e ScoreGranularity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ScoreGranularity) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ScoreGranularity) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type VehicleStatus string
 
 const (
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"gorm.io/gorm"
+	"github.com/go-redis/redis/v8"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/config"
 )
 
@@ -12,4 +13,5 @@ import (
 type Resolver struct{
 	DB     *gorm.DB
 	Config *config.Config
-}
\ No newline at end of file
+	Redis  *redis.Client // nil when Redis is unavailable; subscriptions are disabled
+}
@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+)
+
+// This file will not be regenerated automatically.
+//
+// It converts risk policy rules between their GraphQL shape and the risk
+// engine's rule format, in which threshold and bands are ordinary params.
+
+// normalizeVehicleClass trims and lowercases a vehicle class so that vehicles
+// and policies match regardless of how the class was typed
+func normalizeVehicleClass(class *string) (string, error) {
+	if class == nil {
+		return "", nil
+	}
+	normalized := strings.ToLower(strings.TrimSpace(*class))
+	if len(normalized) > 50 {
+		return "", apperrors.ValidationError("vehicleClass", "vehicleClass must be at most 50 characters")
+	}
+	return normalized, nil
+}
+
+// riskRulesFromInput builds the stored rule configuration of a policy and
+// validates it against the registered detectors
+func riskRulesFromInput(inputs []*model.RiskRuleInput) (risk.Config, error) {
+	var cfg risk.Config
+	seen := make(map[string]bool)
+
+	for _, input := range inputs {
+		if seen[input.Name] {
+			return cfg, apperrors.ValidationError("rules", fmt.Sprintf("rule %q is listed twice", input.Name))
+		}
+		seen[input.Name] = true
+
+		params := make(map[string]interface{})
+		if input.Params != nil && strings.TrimSpace(*input.Params) != "" {
+			if err := json.Unmarshal([]byte(*input.Params), &params); err != nil {
+				return cfg, apperrors.ValidationError("params", "params must be a JSON object")
+			}
+		}
+		if input.Threshold != nil {
+			params["threshold"] = *input.Threshold
+		}
+		if input.Bands != nil {
+			bands := make([]risk.Band, 0, len(input.Bands))
+			for _, band := range input.Bands {
+				bands = append(bands, risk.Band{
+					Above:     band.Above,
+					Severity:  fromEnum(band.Severity.String()),
+					RiskScore: band.RiskScore,
+				})
+			}
+			params["bands"] = bands
+		}
+
+		rule := risk.RuleConfig{Name: input.Name, Enabled: input.Enabled}
+		if len(params) > 0 {
+			raw, err := json.Marshal(params)
+			if err != nil {
+				return cfg, err
+			}
+			rule.Params = raw
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+
+	if err := risk.ValidatePolicy(cfg); err != nil {
+		return cfg, apperrors.ValidationError("rules", err.Error())
+	}
+	return cfg, nil
+}
+
+// riskRulesToModel splits threshold and bands back out of the stored params
+func riskRulesToModel(cfg risk.Config) ([]*model.RiskRule, error) {
+	rules := make([]*model.RiskRule, 0, len(cfg.Rules))
+
+	for _, rule := range cfg.Rules {
+		out := &model.RiskRule{Name: rule.Name, Enabled: rule.IsEnabled()}
+
+		fields := make(map[string]json.RawMessage)
+		if len(rule.Params) > 0 {
+			if err := json.Unmarshal(rule.Params, &fields); err != nil {
+				return nil, fmt.Errorf("risk rule %q: %w", rule.Name, err)
+			}
+		}
+
+		if raw, ok := fields["threshold"]; ok {
+			var threshold float64
+			if err := json.Unmarshal(raw, &threshold); err != nil {
+				return nil, fmt.Errorf("risk rule %q: %w", rule.Name, err)
+			}
+			out.Threshold = &threshold
+			delete(fields, "threshold")
+		}
+
+		if raw, ok := fields["bands"]; ok {
+			var bands []risk.Band
+			if err := json.Unmarshal(raw, &bands); err != nil {
+				return nil, fmt.Errorf("risk rule %q: %w", rule.Name, err)
+			}
+			for _, band := range bands {
+				out.Bands = append(out.Bands, &model.SeverityBand{
+					Above:     band.Above,
+					Severity:  model.RiskSeverity(toEnum(band.Severity)),
+					RiskScore: band.RiskScore,
+				})
+			}
+			delete(fields, "bands")
+		}
+
+		if len(fields) > 0 {
+			raw, err := json.Marshal(fields)
+			if err != nil {
+				return nil, err
+			}
+			params := string(raw)
+			out.Params = &params
+		}
+
+		rules = append(rules, out)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"encoding/json"
+	"strings"
+
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geo"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/route"
+	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+)
+
+// This file will not be regenerated automatically.
+//
+// It holds the helpers behind the planned route resolvers in schema.resolvers.go.
+
+// plannedRouteFromInput builds the stored form of a planned route and
+// validates it the way the risk engine will load it
+func plannedRouteFromInput(fleetID uint, input model.PlannedRouteInput) (models.PlannedRoute, error) {
+	r := models.PlannedRoute{
+		FleetID:             fleetID,
+		Name:                strings.TrimSpace(input.Name),
+		CorridorWidthMeters: input.CorridorWidthMeters,
+		DeviationSeconds:    120,
+	}
+	if r.Name == "" {
+		return r, apperrors.ValidationError("name", "name is required")
+	}
+	if len(r.Name) > 100 {
+		return r, apperrors.ValidationError("name", "name must be at most 100 characters")
+	}
+	if input.DeviationSeconds != nil {
+		r.DeviationSeconds = *input.DeviationSeconds
+	}
+
+	waypoints := make([]geo.Point, 0, len(input.Path))
+	for _, waypoint := range input.Path {
+		waypoints = append(waypoints, geo.Point{Latitude: waypoint.Latitude, Longitude: waypoint.Longitude})
+	}
+	encoded, err := json.Marshal(waypoints)
+	if err != nil {
+		return r, apperrors.ValidationError("path", "path could not be encoded")
+	}
+	r.Path = string(encoded)
+
+	if err := route.Validate(r); err != nil {
+		return r, apperrors.ValidationError("input", err.Error())
+	}
+	return r, nil
+}
+
+// plannedRouteUpdates lists the columns written when a planned route is replaced
+var plannedRouteUpdates = []string{"name", "path", "corridor_width_meters", "deviation_seconds"}
+
+// formatPath renders the stored waypoints of a planned route
+func formatPath(r *models.PlannedRoute) []*model.Location {
+	waypoints, err := route.ParsePath(r.Path)
+	if err != nil {
+		return []*model.Location{}
+	}
+	locations := make([]*model.Location, len(waypoints))
+	for i, waypoint := range waypoints {
+		locations[i] = &model.Location{Latitude: waypoint.Latitude, Longitude: waypoint.Longitude}
+	}
+	return locations
+}
@@ -6,422 +6,2280 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/assignment"
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geofence"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/incident"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/publisher"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/route"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/scoring"
 	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // ID is the resolver for the id field.
 func (r *alertResolver) ID(ctx context.Context, obj *models.Alert) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return formatID(obj.ID), nil
 }
 
 // FleetID is the resolver for the fleetId field.
 func (r *alertResolver) FleetID(ctx context.Context, obj *models.Alert) (string, error) {
-	panic(fmt.Errorf("not implemented: FleetID - fleetId"))
+	return formatID(obj.FleetID), nil
 }
 
 // VehicleID is the resolver for the vehicleId field.
 func (r *alertResolver) VehicleID(ctx context.Context, obj *models.Alert) (*string, error) {
-	panic(fmt.Errorf("not implemented: VehicleID - vehicleId"))
+	return formatOptionalID(obj.VehicleID), nil
 }
 
 // DriverID is the resolver for the driverId field.
 func (r *alertResolver) DriverID(ctx context.Context, obj *models.Alert) (*string, error) {
-	panic(fmt.Errorf("not implemented: DriverID - driverId"))
+	return formatOptionalID(obj.DriverID), nil
 }
 
 // RiskEventID is the resolver for the riskEventId field.
 func (r *alertResolver) RiskEventID(ctx context.Context, obj *models.Alert) (*string, error) {
-	panic(fmt.Errorf("not implemented: RiskEventID - riskEventId"))
+	return formatOptionalID(obj.RiskEventID), nil
+}
+
+// IncidentID is the resolver for the incidentId field.
+func (r *alertResolver) IncidentID(ctx context.Context, obj *models.Alert) (*string, error) {
+	return formatOptionalID(obj.IncidentID), nil
 }
 
 // Type is the resolver for the type field.
 func (r *alertResolver) Type(ctx context.Context, obj *models.Alert) (model.AlertType, error) {
-	panic(fmt.Errorf("not implemented: Type - type"))
+	alertType := model.AlertType(toEnum(obj.Type))
+	if !alertType.IsValid() {
+		return "", fmt.Errorf("unknown alert type %q", obj.Type)
+	}
+	return alertType, nil
 }
 
 // Priority is the resolver for the priority field.
 func (r *alertResolver) Priority(ctx context.Context, obj *models.Alert) (model.AlertPriority, error) {
-	panic(fmt.Errorf("not implemented: Priority - priority"))
+	priority := model.AlertPriority(toEnum(obj.Priority))
+	if !priority.IsValid() {
+		return "", fmt.Errorf("unknown alert priority %q", obj.Priority)
+	}
+	return priority, nil
 }
 
 // Status is the resolver for the status field.
 func (r *alertResolver) Status(ctx context.Context, obj *models.Alert) (model.AlertStatus, error) {
-	panic(fmt.Errorf("not implemented: Status - status"))
+	status := model.AlertStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown alert status %q", obj.Status)
+	}
+	return status, nil
 }
 
 // CreatedAt is the resolver for the createdAt field.
 func (r *alertResolver) CreatedAt(ctx context.Context, obj *models.Alert) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
+	return formatTime(obj.CreatedAt), nil
 }
 
 // UpdatedAt is the resolver for the updatedAt field.
 func (r *alertResolver) UpdatedAt(ctx context.Context, obj *models.Alert) (string, error) {
-	panic(fmt.Errorf("not implemented: UpdatedAt - updatedAt"))
+	return formatTime(obj.UpdatedAt), nil
 }
 
 // ID is the resolver for the id field.
 func (r *driverResolver) ID(ctx context.Context, obj *models.Driver) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return formatID(obj.ID), nil
 }
 
 // LicenseNumber is the resolver for the licenseNumber field.
 func (r *driverResolver) LicenseNumber(ctx context.Context, obj *models.Driver) (string, error) {
-	panic(fmt.Errorf("not implemented: LicenseNumber - licenseNumber"))
+	return obj.LicenseNum, nil
 }
 
 // FleetID is the resolver for the fleetId field.
 func (r *driverResolver) FleetID(ctx context.Context, obj *models.Driver) (string, error) {
-	panic(fmt.Errorf("not implemented: FleetID - fleetId"))
+	return formatID(obj.FleetID), nil
 }
 
 // Status is the resolver for the status field.
 func (r *driverResolver) Status(ctx context.Context, obj *models.Driver) (model.DriverStatus, error) {
-	panic(fmt.Errorf("not implemented: Status - status"))
+	status := model.DriverStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown driver status %q", obj.Status)
+	}
+	return status, nil
 }
 
 // CurrentVehicle is the resolver for the currentVehicle field.
 func (r *driverResolver) CurrentVehicle(ctx context.Context, obj *models.Driver) (*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: CurrentVehicle - currentVehicle"))
+	var vehicle models.Vehicle
+	err := r.DB.WithContext(ctx).Preload("Fleet").
+		Where("driver_id = ?", obj.ID).
+		First(&vehicle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("fetch_driver_vehicle", err)
+	}
+	return &vehicle, nil
 }
 
 // DriverScore is the resolver for the driverScore field.
 func (r *driverResolver) DriverScore(ctx context.Context, obj *models.Driver) (*models.DriverScore, error) {
-	panic(fmt.Errorf("not implemented: DriverScore - driverScore"))
+	var score models.DriverScore
+	err := r.DB.WithContext(ctx).Where("driver_id = ?", obj.ID).First(&score).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("fetch_driver_score", err)
+	}
+	score.Driver = *obj
+	return &score, nil
 }
 
 // CreatedAt is the resolver for the createdAt field.
 func (r *driverResolver) CreatedAt(ctx context.Context, obj *models.Driver) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
+	return formatTime(obj.CreatedAt), nil
 }
 
 // UpdatedAt is the resolver for the updatedAt field.
 func (r *driverResolver) UpdatedAt(ctx context.Context, obj *models.Driver) (string, error) {
-	panic(fmt.Errorf("not implemented: UpdatedAt - updatedAt"))
+	return formatTime(obj.UpdatedAt), nil
 }
 
 // ID is the resolver for the id field.
 func (r *driverScoreResolver) ID(ctx context.Context, obj *models.DriverScore) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+	return formatID(obj.ID), nil
 }
 
 // DriverID is the resolver for the driverId field.
 func (r *driverScoreResolver) DriverID(ctx context.Context, obj *models.DriverScore) (string, error) {
-	panic(fmt.Errorf("not implemented: DriverID - driverId"))
+	return formatID(obj.DriverID), nil
 }
 
 // LastUpdated is the resolver for the lastUpdated field.
 func (r *driverScoreResolver) LastUpdated(ctx context.Context, obj *models.DriverScore) (string, error) {
-	panic(fmt.Errorf("not implemented: LastUpdated - lastUpdated"))
+	return formatTime(obj.LastUpdated), nil
 }
 
 // CreatedAt is the resolver for the createdAt field.
 func (r *driverScoreResolver) CreatedAt(ctx context.Context, obj *models.DriverScore) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
+	return formatTime(obj.CreatedAt), nil
 }
 
 // UpdatedAt is the resolver for the updatedAt field.
 func (r *driverScoreResolver) UpdatedAt(ctx context.Context, obj *models.DriverScore) (string, error) {
-	panic(fmt.Errorf("not implemented: UpdatedAt - updatedAt"))
+	return formatTime(obj.UpdatedAt), nil
+}
+
+// ID is the resolver for the id field.
+func (r *driverScoreSnapshotResolver) ID(ctx context.Context, obj *models.DriverScoreSnapshot) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// DriverID is the resolver for the driverId field.
+func (r *driverScoreSnapshotResolver) DriverID(ctx context.Context, obj *models.DriverScoreSnapshot) (string, error) {
+	return formatID(obj.DriverID), nil
+}
+
+// Breakdown is the resolver for the breakdown field.
+func (r *driverScoreSnapshotResolver) Breakdown(ctx context.Context, obj *models.DriverScoreSnapshot) ([]*scoring.Deduction, error) {
+	deductions, err := scoreBreakdown(obj)
+	if err != nil {
+		return nil, fmt.Errorf("invalid breakdown on driver score snapshot %d: %w", obj.ID, err)
+	}
+	return deductions, nil
+}
+
+// ScoredAt is the resolver for the scoredAt field.
+func (r *driverScoreSnapshotResolver) ScoredAt(ctx context.Context, obj *models.DriverScoreSnapshot) (string, error) {
+	return formatTime(obj.ScoredAt), nil
 }
 
 // ID is the resolver for the id field.
 func (r *fleetResolver) ID(ctx context.Context, obj *models.Fleet) (string, error) {
-	return fmt.Sprintf("%d", obj.ID), nil
+	return formatID(obj.ID), nil
 }
 
 // Vehicles is the resolver for the vehicles field.
 func (r *fleetResolver) Vehicles(ctx context.Context, obj *models.Fleet) ([]*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: Vehicles - vehicles"))
+	var vehicles []*models.Vehicle
+	if err := r.DB.WithContext(ctx).Preload("Driver").
+		Where("fleet_id = ?", obj.ID).
+		Order("id").
+		Find(&vehicles).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_fleet_vehicles", err)
+	}
+	for _, vehicle := range vehicles {
+		vehicle.Fleet = *obj
+	}
+	return vehicles, nil
 }
 
 // Drivers is the resolver for the drivers field.
 func (r *fleetResolver) Drivers(ctx context.Context, obj *models.Fleet) ([]*models.Driver, error) {
-	panic(fmt.Errorf("not implemented: Drivers - drivers"))
+	var drivers []*models.Driver
+	if err := r.DB.WithContext(ctx).
+		Where("fleet_id = ?", obj.ID).
+		Order("last_name, first_name").
+		Find(&drivers).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_fleet_drivers", err)
+	}
+	for _, driver := range drivers {
+		driver.Fleet = *obj
+	}
+	return drivers, nil
 }
 
 // CreatedAt is the resolver for the createdAt field.
 func (r *fleetResolver) CreatedAt(ctx context.Context, obj *models.Fleet) (string, error) {
-	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+	return formatTime(obj.CreatedAt), nil
 }
 
 // UpdatedAt is the resolver for the updatedAt field.
 func (r *fleetResolver) UpdatedAt(ctx context.Context, obj *models.Fleet) (string, error) {
-	return obj.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+	return formatTime(obj.UpdatedAt), nil
 }
 
-// CreateFleet is the resolver for the createFleet field.
-func (r *mutationResolver) CreateFleet(ctx context.Context, input model.CreateFleetInput) (*models.Fleet, error) {
-	fleet := &models.Fleet{
-		Name:         input.Name,
-		CompanyName:  input.CompanyName,
-		ContactEmail: input.ContactEmail,
-		Status:       "active",
-	}
-
-	if err := r.DB.Create(fleet).Error; err != nil {
-		return nil, fmt.Errorf("failed to create fleet: %w", err)
-	}
-
-	return fleet, nil
+// ID is the resolver for the id field.
+func (r *geofenceResolver) ID(ctx context.Context, obj *models.Geofence) (string, error) {
+	return formatID(obj.ID), nil
 }
 
-// UpdateFleet is the resolver for the updateFleet field.
-func (r *mutationResolver) UpdateFleet(ctx context.Context, id string, input model.UpdateFleetInput) (*models.Fleet, error) {
-	panic(fmt.Errorf("not implemented: UpdateFleet - updateFleet"))
+// FleetID is the resolver for the fleetId field.
+func (r *geofenceResolver) FleetID(ctx context.Context, obj *models.Geofence) (string, error) {
+	return formatID(obj.FleetID), nil
 }
 
-// CreateVehicle is the resolver for the createVehicle field.
-func (r *mutationResolver) CreateVehicle(ctx context.Context, input model.CreateVehicleInput) (*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: CreateVehicle - createVehicle"))
+// Category is the resolver for the category field.
+func (r *geofenceResolver) Category(ctx context.Context, obj *models.Geofence) (model.GeofenceCategory, error) {
+	category := model.GeofenceCategory(toEnum(obj.Category))
+	if !category.IsValid() {
+		return "", fmt.Errorf("unknown geofence category %q", obj.Category)
+	}
+	return category, nil
 }
 
-// UpdateVehicle is the resolver for the updateVehicle field.
-func (r *mutationResolver) UpdateVehicle(ctx context.Context, id string, input model.UpdateVehicleInput) (*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: UpdateVehicle - updateVehicle"))
+// Shape is the resolver for the shape field.
+func (r *geofenceResolver) Shape(ctx context.Context, obj *models.Geofence) (model.GeofenceShape, error) {
+	shape := model.GeofenceShape(toEnum(obj.Shape))
+	if !shape.IsValid() {
+		return "", fmt.Errorf("unknown geofence shape %q", obj.Shape)
+	}
+	return shape, nil
 }
 
-// AssignDriver is the resolver for the assignDriver field.
-func (r *mutationResolver) AssignDriver(ctx context.Context, vehicleID string, driverID string) (*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: AssignDriver - assignDriver"))
+// Center is the resolver for the center field.
+func (r *geofenceResolver) Center(ctx context.Context, obj *models.Geofence) (*model.Location, error) {
+	return formatLocation(obj.CenterLatitude, obj.CenterLongitude), nil
 }
 
-// CreateDriver is the resolver for the createDriver field.
-func (r *mutationResolver) CreateDriver(ctx context.Context, input model.CreateDriverInput) (*models.Driver, error) {
-	panic(fmt.Errorf("not implemented: CreateDriver - createDriver"))
+// Polygon is the resolver for the polygon field.
+func (r *geofenceResolver) Polygon(ctx context.Context, obj *models.Geofence) ([]*model.Location, error) {
+	return formatPolygon(obj), nil
 }
 
-// UpdateDriver is the resolver for the updateDriver field.
-func (r *mutationResolver) UpdateDriver(ctx context.Context, id string, input model.UpdateDriverInput) (*models.Driver, error) {
-	panic(fmt.Errorf("not implemented: UpdateDriver - updateDriver"))
+// AllowedVehicleClasses is the resolver for the allowedVehicleClasses field.
+func (r *geofenceResolver) AllowedVehicleClasses(ctx context.Context, obj *models.Geofence) ([]string, error) {
+	classes := geofence.ParseVehicleClasses(obj.AllowedVehicleClasses)
+	if classes == nil {
+		classes = []string{}
+	}
+	return classes, nil
 }
 
-// AcknowledgeAlert is the resolver for the acknowledgeAlert field.
-func (r *mutationResolver) AcknowledgeAlert(ctx context.Context, id string) (*models.Alert, error) {
-	panic(fmt.Errorf("not implemented: AcknowledgeAlert - acknowledgeAlert"))
+// Status is the resolver for the status field.
+func (r *geofenceResolver) Status(ctx context.Context, obj *models.Geofence) (model.GeofenceStatus, error) {
+	status := model.GeofenceStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown geofence status %q", obj.Status)
+	}
+	return status, nil
 }
 
-// DismissAlert is the resolver for the dismissAlert field.
-func (r *mutationResolver) DismissAlert(ctx context.Context, id string) (*models.Alert, error) {
-	panic(fmt.Errorf("not implemented: DismissAlert - dismissAlert"))
+// CreatedAt is the resolver for the createdAt field.
+func (r *geofenceResolver) CreatedAt(ctx context.Context, obj *models.Geofence) (string, error) {
+	return formatTime(obj.CreatedAt), nil
 }
 
-// Fleets is the resolver for the fleets field.
-func (r *queryResolver) Fleets(ctx context.Context) ([]*models.Fleet, error) {
-	var fleets []*models.Fleet
-	if err := r.DB.Find(&fleets).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch fleets: %w", err)
-	}
-	return fleets, nil
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *geofenceResolver) UpdatedAt(ctx context.Context, obj *models.Geofence) (string, error) {
+	return formatTime(obj.UpdatedAt), nil
 }
 
-// Fleet is the resolver for the fleet field.
-func (r *queryResolver) Fleet(ctx context.Context, id string) (*models.Fleet, error) {
-	var fleet models.Fleet
-	if err := r.DB.First(&fleet, id).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch fleet: %w", err)
-	}
-	return &fleet, nil
+// ID is the resolver for the id field.
+func (r *geofenceEventResolver) ID(ctx context.Context, obj *models.GeofenceEvent) (string, error) {
+	return formatID(obj.ID), nil
 }
 
-// Vehicles is the resolver for the vehicles field.
-func (r *queryResolver) Vehicles(ctx context.Context, fleetID *string) ([]*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: Vehicles - vehicles"))
+// GeofenceID is the resolver for the geofenceId field.
+func (r *geofenceEventResolver) GeofenceID(ctx context.Context, obj *models.GeofenceEvent) (string, error) {
+	return formatID(obj.GeofenceID), nil
 }
 
-// Vehicle is the resolver for the vehicle field.
-func (r *queryResolver) Vehicle(ctx context.Context, id string) (*models.Vehicle, error) {
-	panic(fmt.Errorf("not implemented: Vehicle - vehicle"))
+// VehicleID is the resolver for the vehicleId field.
+func (r *geofenceEventResolver) VehicleID(ctx context.Context, obj *models.GeofenceEvent) (string, error) {
+	return formatID(obj.VehicleID), nil
 }
 
-// Drivers is the resolver for the drivers field.
-func (r *queryResolver) Drivers(ctx context.Context, fleetID *string) ([]*models.Driver, error) {
-	panic(fmt.Errorf("not implemented: Drivers - drivers"))
+// DriverID is the resolver for the driverId field.
+func (r *geofenceEventResolver) DriverID(ctx context.Context, obj *models.GeofenceEvent) (*string, error) {
+	return formatOptionalID(obj.DriverID), nil
 }
 
-// Driver is the resolver for the driver field.
-func (r *queryResolver) Driver(ctx context.Context, id string) (*models.Driver, error) {
-	panic(fmt.Errorf("not implemented: Driver - driver"))
+// Type is the resolver for the type field.
+func (r *geofenceEventResolver) Type(ctx context.Context, obj *models.GeofenceEvent) (model.GeofenceEventType, error) {
+	eventType := model.GeofenceEventType(toEnum(obj.Type))
+	if !eventType.IsValid() {
+		return "", fmt.Errorf("unknown geofence event type %q", obj.Type)
+	}
+	return eventType, nil
 }
 
-// RiskEvents is the resolver for the riskEvents field.
-func (r *queryResolver) RiskEvents(ctx context.Context, vehicleID *string, driverID *string, limit *int) ([]*models.RiskEvent, error) {
-	panic(fmt.Errorf("not implemented: RiskEvents - riskEvents"))
+// Timestamp is the resolver for the timestamp field.
+func (r *geofenceEventResolver) Timestamp(ctx context.Context, obj *models.GeofenceEvent) (string, error) {
+	return formatTime(obj.Timestamp), nil
 }
 
-// Alerts is the resolver for the alerts field.
-func (r *queryResolver) Alerts(ctx context.Context, fleetID string, status *model.AlertStatus) ([]*models.Alert, error) {
-	panic(fmt.Errorf("not implemented: Alerts - alerts"))
+// Location is the resolver for the location field.
+func (r *geofenceEventResolver) Location(ctx context.Context, obj *models.GeofenceEvent) (*model.Location, error) {
+	return formatLocation(obj.Latitude, obj.Longitude), nil
 }
 
-// DriverScores is the resolver for the driverScores field.
-func (r *queryResolver) DriverScores(ctx context.Context, fleetID string) ([]*models.DriverScore, error) {
-	panic(fmt.Errorf("not implemented: DriverScores - driverScores"))
+// CreatedAt is the resolver for the createdAt field.
+func (r *geofenceEventResolver) CreatedAt(ctx context.Context, obj *models.GeofenceEvent) (string, error) {
+	return formatTime(obj.CreatedAt), nil
 }
 
-// LiveVehicleData is the resolver for the liveVehicleData field.
-func (r *queryResolver) LiveVehicleData(ctx context.Context, vehicleID string) (*model.VehicleData, error) {
-	panic(fmt.Errorf("not implemented: LiveVehicleData - liveVehicleData"))
+// ID is the resolver for the id field.
+func (r *incidentResolver) ID(ctx context.Context, obj *models.Incident) (string, error) {
+	return formatID(obj.ID), nil
 }
 
-// ID is the resolver for the id field.
-func (r *riskEventResolver) ID(ctx context.Context, obj *models.RiskEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+// FleetID is the resolver for the fleetId field.
+func (r *incidentResolver) FleetID(ctx context.Context, obj *models.Incident) (string, error) {
+	return formatID(obj.FleetID), nil
 }
 
 // VehicleID is the resolver for the vehicleId field.
-func (r *riskEventResolver) VehicleID(ctx context.Context, obj *models.RiskEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: VehicleID - vehicleId"))
+func (r *incidentResolver) VehicleID(ctx context.Context, obj *models.Incident) (string, error) {
+	return formatID(obj.VehicleID), nil
 }
 
 // DriverID is the resolver for the driverId field.
-func (r *riskEventResolver) DriverID(ctx context.Context, obj *models.RiskEvent) (*string, error) {
-	panic(fmt.Errorf("not implemented: DriverID - driverId"))
+func (r *incidentResolver) DriverID(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalID(obj.DriverID), nil
 }
 
-// EventType is the resolver for the eventType field.
-func (r *riskEventResolver) EventType(ctx context.Context, obj *models.RiskEvent) (model.RiskEventType, error) {
-	panic(fmt.Errorf("not implemented: EventType - eventType"))
+// RiskEventID is the resolver for the riskEventId field.
+func (r *incidentResolver) RiskEventID(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalID(obj.RiskEventID), nil
 }
 
 // Severity is the resolver for the severity field.
-func (r *riskEventResolver) Severity(ctx context.Context, obj *models.RiskEvent) (model.RiskSeverity, error) {
-	panic(fmt.Errorf("not implemented: Severity - severity"))
-}
-
-// Timestamp is the resolver for the timestamp field.
-func (r *riskEventResolver) Timestamp(ctx context.Context, obj *models.RiskEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: Timestamp - timestamp"))
+func (r *incidentResolver) Severity(ctx context.Context, obj *models.Incident) (model.RiskSeverity, error) {
+	severity := model.RiskSeverity(toEnum(obj.Severity))
+	if !severity.IsValid() {
+		return "", fmt.Errorf("unknown incident severity %q", obj.Severity)
+	}
+	return severity, nil
 }
 
 // Status is the resolver for the status field.
-func (r *riskEventResolver) Status(ctx context.Context, obj *models.RiskEvent) (model.RiskEventStatus, error) {
-	panic(fmt.Errorf("not implemented: Status - status"))
-}
-
-// CreatedAt is the resolver for the createdAt field.
-func (r *riskEventResolver) CreatedAt(ctx context.Context, obj *models.RiskEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
+func (r *incidentResolver) Status(ctx context.Context, obj *models.Incident) (model.IncidentStatus, error) {
+	status := model.IncidentStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown incident status %q", obj.Status)
+	}
+	return status, nil
 }
 
-// UpdatedAt is the resolver for the updatedAt field.
-func (r *riskEventResolver) UpdatedAt(ctx context.Context, obj *models.RiskEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: UpdatedAt - updatedAt"))
+// OccurredAt is the resolver for the occurredAt field.
+func (r *incidentResolver) OccurredAt(ctx context.Context, obj *models.Incident) (string, error) {
+	return formatTime(obj.OccurredAt), nil
 }
 
-// VehicleUpdates is the resolver for the vehicleUpdates field.
-func (r *subscriptionResolver) VehicleUpdates(ctx context.Context, vehicleID string) (<-chan *model.VehicleData, error) {
-	panic(fmt.Errorf("not implemented: VehicleUpdates - vehicleUpdates"))
+// Location is the resolver for the location field.
+func (r *incidentResolver) Location(ctx context.Context, obj *models.Incident) (*model.Location, error) {
+	return formatLocation(obj.Latitude, obj.Longitude), nil
 }
 
-// RiskEventNotifications is the resolver for the riskEventNotifications field.
-func (r *subscriptionResolver) RiskEventNotifications(ctx context.Context, fleetID string) (<-chan *models.RiskEvent, error) {
-	panic(fmt.Errorf("not implemented: RiskEventNotifications - riskEventNotifications"))
+// TelemetryCapturedAt is the resolver for the telemetryCapturedAt field.
+func (r *incidentResolver) TelemetryCapturedAt(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalTime(obj.TelemetryCapturedAt), nil
 }
 
-// AlertNotifications is the resolver for the alertNotifications field.
-func (r *subscriptionResolver) AlertNotifications(ctx context.Context, fleetID string) (<-chan *models.Alert, error) {
-	panic(fmt.Errorf("not implemented: AlertNotifications - alertNotifications"))
+// AcknowledgedAt is the resolver for the acknowledgedAt field.
+func (r *incidentResolver) AcknowledgedAt(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalTime(obj.AcknowledgedAt), nil
 }
 
-// ID is the resolver for the id field.
-func (r *telemetryEventResolver) ID(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+// DispatchedAt is the resolver for the dispatchedAt field.
+func (r *incidentResolver) DispatchedAt(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalTime(obj.DispatchedAt), nil
 }
 
-// VehicleID is the resolver for the vehicleId field.
-func (r *telemetryEventResolver) VehicleID(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: VehicleID - vehicleId"))
+// ResolvedAt is the resolver for the resolvedAt field.
+func (r *incidentResolver) ResolvedAt(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalTime(obj.ResolvedAt), nil
 }
 
-// Timestamp is the resolver for the timestamp field.
-func (r *telemetryEventResolver) Timestamp(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: Timestamp - timestamp"))
+// ClosedAt is the resolver for the closedAt field.
+func (r *incidentResolver) ClosedAt(ctx context.Context, obj *models.Incident) (*string, error) {
+	return formatOptionalTime(obj.ClosedAt), nil
 }
 
-// ProcessedAt is the resolver for the processedAt field.
-func (r *telemetryEventResolver) ProcessedAt(ctx context.Context, obj *models.TelemetryEvent) (*string, error) {
-	panic(fmt.Errorf("not implemented: ProcessedAt - processedAt"))
+// CreatedAt is the resolver for the createdAt field.
+func (r *incidentResolver) CreatedAt(ctx context.Context, obj *models.Incident) (string, error) {
+	return formatTime(obj.CreatedAt), nil
 }
 
-// CreatedAt is the resolver for the createdAt field.
-func (r *telemetryEventResolver) CreatedAt(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *incidentResolver) UpdatedAt(ctx context.Context, obj *models.Incident) (string, error) {
+	return formatTime(obj.UpdatedAt), nil
 }
 
 // ID is the resolver for the id field.
-func (r *vehicleResolver) ID(ctx context.Context, obj *models.Vehicle) (string, error) {
-	panic(fmt.Errorf("not implemented: ID - id"))
+func (r *incidentUpdateResolver) ID(ctx context.Context, obj *models.IncidentUpdate) (string, error) {
+	return formatID(obj.ID), nil
 }
 
-// FleetID is the resolver for the fleetId field.
-func (r *vehicleResolver) FleetID(ctx context.Context, obj *models.Vehicle) (string, error) {
-	panic(fmt.Errorf("not implemented: FleetID - fleetId"))
+// Status is the resolver for the status field.
+func (r *incidentUpdateResolver) Status(ctx context.Context, obj *models.IncidentUpdate) (model.IncidentStatus, error) {
+	status := model.IncidentStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown incident status %q", obj.Status)
+	}
+	return status, nil
 }
 
-// DriverID is the resolver for the driverId field.
-func (r *vehicleResolver) DriverID(ctx context.Context, obj *models.Vehicle) (*string, error) {
-	panic(fmt.Errorf("not implemented: DriverID - driverId"))
+// UserID is the resolver for the userId field.
+func (r *incidentUpdateResolver) UserID(ctx context.Context, obj *models.IncidentUpdate) (*string, error) {
+	return formatOptionalID(obj.UserID), nil
 }
 
-// Status is the resolver for the status field.
-func (r *vehicleResolver) Status(ctx context.Context, obj *models.Vehicle) (model.VehicleStatus, error) {
-	panic(fmt.Errorf("not implemented: Status - status"))
+// CreatedAt is the resolver for the createdAt field.
+func (r *incidentUpdateResolver) CreatedAt(ctx context.Context, obj *models.IncidentUpdate) (string, error) {
+	return formatTime(obj.CreatedAt), nil
 }
 
-// CurrentLocation is the resolver for the currentLocation field.
-func (r *vehicleResolver) CurrentLocation(ctx context.Context, obj *models.Vehicle) (*model.Location, error) {
-	panic(fmt.Errorf("not implemented: CurrentLocation - currentLocation"))
-}
+// CreateFleet is the resolver for the createFleet field.
+func (r *mutationResolver) CreateFleet(ctx context.Context, input model.CreateFleetInput) (*models.Fleet, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, apperrors.ValidationError("name", "name is required")
+	}
 
-// LastTelemetry is the resolver for the lastTelemetry field.
-func (r *vehicleResolver) LastTelemetry(ctx context.Context, obj *models.Vehicle) (*models.TelemetryEvent, error) {
-	panic(fmt.Errorf("not implemented: LastTelemetry - lastTelemetry"))
+	fleet := &models.Fleet{
+		Name:         input.Name,
+		CompanyName:  input.CompanyName,
+		ContactEmail: input.ContactEmail,
+		Status:       "active",
+	}
+
+	if err := r.DB.WithContext(ctx).Create(fleet).Error; err != nil {
+		return nil, apperrors.DatabaseError("create_fleet", err)
+	}
+
+	return fleet, nil
 }
 
-// RiskScore is the resolver for the riskScore field.
-func (r *vehicleResolver) RiskScore(ctx context.Context, obj *models.Vehicle) (float64, error) {
-	panic(fmt.Errorf("not implemented: RiskScore - riskScore"))
+// UpdateFleet is the resolver for the updateFleet field.
+func (r *mutationResolver) UpdateFleet(ctx context.Context, id string, input model.UpdateFleetInput) (*models.Fleet, error) {
+	fleetID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fleet models.Fleet
+	if err := db.First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+
+	if input.Name != nil {
+		if strings.TrimSpace(*input.Name) == "" {
+			return nil, apperrors.ValidationError("name", "name cannot be empty")
+		}
+		fleet.Name = *input.Name
+	}
+	if input.CompanyName != nil {
+		fleet.CompanyName = *input.CompanyName
+	}
+	if input.ContactEmail != nil {
+		fleet.ContactEmail = *input.ContactEmail
+	}
+	if input.Status != nil {
+		status := strings.ToLower(*input.Status)
+		if status != "active" && status != "inactive" {
+			return nil, apperrors.ValidationError("status", "status must be one of: active, inactive")
+		}
+		fleet.Status = status
+	}
+
+	if err := db.Save(&fleet).Error; err != nil {
+		return nil, apperrors.DatabaseError("update_fleet", err)
+	}
+
+	return &fleet, nil
 }
 
-// CreatedAt is the resolver for the createdAt field.
-func (r *vehicleResolver) CreatedAt(ctx context.Context, obj *models.Vehicle) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
+// CreateVehicle is the resolver for the createVehicle field.
+func (r *mutationResolver) CreateVehicle(ctx context.Context, input model.CreateVehicleInput) (*models.Vehicle, error) {
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+	if len(input.Vin) != 17 {
+		return nil, apperrors.ValidationError("vin", "vin must be exactly 17 characters")
+	}
+	if input.Year < 1900 || input.Year > time.Now().Year()+1 {
+		return nil, apperrors.ValidationError("year", "year is out of range")
+	}
+	vehicleClass, err := normalizeVehicleClass(input.VehicleClass)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fleet models.Fleet
+	if err := db.First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+
+	vehicle := &models.Vehicle{
+		VIN:          strings.ToUpper(input.Vin),
+		Make:         input.Make,
+		Model:        input.Model,
+		Year:         input.Year,
+		LicensePlate: input.LicensePlate,
+		VehicleClass: vehicleClass,
+		FleetID:      fleet.ID,
+		Status:       "active",
+	}
+
+	if err := db.Omit("Fleet").Create(vehicle).Error; err != nil {
+		return nil, apperrors.DatabaseError("create_vehicle", err)
+	}
+	vehicle.Fleet = fleet
+
+	return vehicle, nil
 }
 
-// UpdatedAt is the resolver for the updatedAt field.
-func (r *vehicleResolver) UpdatedAt(ctx context.Context, obj *models.Vehicle) (string, error) {
-	panic(fmt.Errorf("not implemented: UpdatedAt - updatedAt"))
+// UpdateVehicle is the resolver for the updateVehicle field.
+func (r *mutationResolver) UpdateVehicle(ctx context.Context, id string, input model.UpdateVehicleInput) (*models.Vehicle, error) {
+	vehicleID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var vehicle models.Vehicle
+	if err := db.First(&vehicle, vehicleID).Error; err != nil {
+		return nil, lookupError("vehicle", vehicleID, err)
+	}
+
+	updates := map[string]interface{}{}
+	if input.Make != nil {
+		updates["make"] = *input.Make
+	}
+	if input.Model != nil {
+		updates["model"] = *input.Model
+	}
+	if input.Year != nil {
+		if *input.Year < 1900 || *input.Year > time.Now().Year()+1 {
+			return nil, apperrors.ValidationError("year", "year is out of range")
+		}
+		updates["year"] = *input.Year
+	}
+	if input.LicensePlate != nil {
+		updates["license_plate"] = *input.LicensePlate
+	}
+	if input.VehicleClass != nil {
+		vehicleClass, err := normalizeVehicleClass(input.VehicleClass)
+		if err != nil {
+			return nil, err
+		}
+		updates["vehicle_class"] = vehicleClass
+	}
+	if input.Status != nil {
+		updates["status"] = fromEnum(input.Status.String())
+	}
+
+	if len(updates) > 0 {
+		if err := db.Model(&vehicle).Updates(updates).Error; err != nil {
+			return nil, apperrors.DatabaseError("update_vehicle", err)
+		}
+	}
+
+	if err := db.Preload("Fleet").Preload("Driver").First(&vehicle, vehicleID).Error; err != nil {
+		return nil, lookupError("vehicle", vehicleID, err)
+	}
+
+	return &vehicle, nil
 }
 
-// Alert returns AlertResolver implementation.
-func (r *Resolver) Alert() AlertResolver { return &alertResolver{r} }
+// AssignDriver is the resolver for the assignDriver field.
+func (r *mutationResolver) AssignDriver(ctx context.Context, vehicleID string, driverID string) (*models.Vehicle, error) {
+	vID, err := parseID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	dID, err := parseID("driverId", driverID)
+	if err != nil {
+		return nil, err
+	}
 
-// Driver returns DriverResolver implementation.
-func (r *Resolver) Driver() DriverResolver { return &driverResolver{r} }
+	db := r.DB.WithContext(ctx)
 
-// DriverScore returns DriverScoreResolver implementation.
-func (r *Resolver) DriverScore() DriverScoreResolver { return &driverScoreResolver{r} }
+	var vehicle models.Vehicle
+	if err := db.First(&vehicle, vID).Error; err != nil {
+		return nil, lookupError("vehicle", vID, err)
+	}
 
-// Fleet returns FleetResolver implementation.
-func (r *Resolver) Fleet() FleetResolver { return &fleetResolver{r} }
+	var driver models.Driver
+	if err := db.First(&driver, dID).Error; err != nil {
+		return nil, lookupError("driver", dID, err)
+	}
 
-// Mutation returns MutationResolver implementation.
-func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+	if driver.FleetID != vehicle.FleetID {
+		return nil, apperrors.ValidationError("driverId", "driver belongs to a different fleet than the vehicle")
+	}
+	if driver.Status != "active" {
+		return nil, apperrors.ValidationError("driverId", "only active drivers can be assigned to a vehicle")
+	}
 
-// Query returns QueryResolver implementation.
-func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+	// Record the change in the assignment history so that risk events are
+	// attributed to whoever was driving at the time
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return assignment.Assign(tx, vehicle.ID, driver.ID, time.Now())
+	})
+	if err != nil {
+		return nil, apperrors.DatabaseError("assign_driver", err)
+	}
 
-// RiskEvent returns RiskEventResolver implementation.
-func (r *Resolver) RiskEvent() RiskEventResolver { return &riskEventResolver{r} }
+	if err := db.Preload("Fleet").Preload("Driver").First(&vehicle, vID).Error; err != nil {
+		return nil, lookupError("vehicle", vID, err)
+	}
 
-// Subscription returns SubscriptionResolver implementation.
-func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+	return &vehicle, nil
+}
 
-// TelemetryEvent returns TelemetryEventResolver implementation.
-func (r *Resolver) TelemetryEvent() TelemetryEventResolver { return &telemetryEventResolver{r} }
+// CreateDriver is the resolver for the createDriver field.
+func (r *mutationResolver) CreateDriver(ctx context.Context, input model.CreateDriverInput) (*models.Driver, error) {
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.EmployeeID) == "" {
+		return nil, apperrors.ValidationError("employeeId", "employeeId is required")
+	}
+	if strings.TrimSpace(input.Email) == "" {
+		return nil, apperrors.ValidationError("email", "email is required")
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fleet models.Fleet
+	if err := db.First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+
+	driver := &models.Driver{
+		EmployeeID: input.EmployeeID,
+		FirstName:  input.FirstName,
+		LastName:   input.LastName,
+		Email:      input.Email,
+		Phone:      input.Phone,
+		LicenseNum: input.LicenseNumber,
+		FleetID:    fleet.ID,
+		Status:     "active",
+	}
+
+	if err := db.Omit("Fleet").Create(driver).Error; err != nil {
+		return nil, apperrors.DatabaseError("create_driver", err)
+	}
+	driver.Fleet = fleet
+
+	return driver, nil
+}
+
+// UpdateDriver is the resolver for the updateDriver field.
+func (r *mutationResolver) UpdateDriver(ctx context.Context, id string, input model.UpdateDriverInput) (*models.Driver, error) {
+	driverID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var driver models.Driver
+	if err := db.First(&driver, driverID).Error; err != nil {
+		return nil, lookupError("driver", driverID, err)
+	}
+
+	updates := map[string]interface{}{}
+	if input.FirstName != nil {
+		updates["first_name"] = *input.FirstName
+	}
+	if input.LastName != nil {
+		updates["last_name"] = *input.LastName
+	}
+	if input.Email != nil {
+		if strings.TrimSpace(*input.Email) == "" {
+			return nil, apperrors.ValidationError("email", "email cannot be empty")
+		}
+		updates["email"] = *input.Email
+	}
+	if input.Phone != nil {
+		updates["phone"] = *input.Phone
+	}
+	if input.LicenseNumber != nil {
+		updates["license_num"] = *input.LicenseNumber
+	}
+	if input.Status != nil {
+		updates["status"] = fromEnum(input.Status.String())
+	}
+
+	if len(updates) > 0 {
+		if err := db.Model(&driver).Updates(updates).Error; err != nil {
+			return nil, apperrors.DatabaseError("update_driver", err)
+		}
+	}
+
+	if err := db.Preload("Fleet").First(&driver, driverID).Error; err != nil {
+		return nil, lookupError("driver", driverID, err)
+	}
+
+	return &driver, nil
+}
+
+// AcknowledgeAlert is the resolver for the acknowledgeAlert field.
+func (r *mutationResolver) AcknowledgeAlert(ctx context.Context, id string) (*models.Alert, error) {
+	return r.setAlertStatus(ctx, id, "read")
+}
+
+// DismissAlert is the resolver for the dismissAlert field.
+func (r *mutationResolver) DismissAlert(ctx context.Context, id string) (*models.Alert, error) {
+	return r.setAlertStatus(ctx, id, "dismissed")
+}
+
+// UpdateIncidentStatus is the resolver for the updateIncidentStatus field.
+func (r *mutationResolver) UpdateIncidentStatus(ctx context.Context, id string, status model.IncidentStatus, note *string) (*models.Incident, error) {
+	incidentID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var current models.Incident
+	if err := db.First(&current, incidentID).Error; err != nil {
+		return nil, lookupError("incident", incidentID, err)
+	}
+
+	var text string
+	if note != nil {
+		text = strings.TrimSpace(*note)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return incident.Transition(tx, &current, fromEnum(status.String()), text, currentUserID(ctx), time.Now())
+	})
+	var invalid incident.InvalidTransitionError
+	if errors.As(err, &invalid) {
+		return nil, apperrors.ValidationError("status", invalid.Error())
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("update_incident_status", err)
+	}
+
+	updated, err := r.loadIncident(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := publisher.New(r.Redis).PublishIncident(ctx, updated); err != nil {
+		publisher.LogError(err, logrus.Fields{"incident_id": updated.ID})
+	}
+	return updated, nil
+}
+
+// CreateGeofence is the resolver for the createGeofence field.
+func (r *mutationResolver) CreateGeofence(ctx context.Context, input model.GeofenceInput) (*models.Geofence, error) {
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+	fence, err := geofenceFromInput(fleetID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fleet models.Fleet
+	if err := db.First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+
+	// The risk engine notices the new fence on its next pass
+	if err := db.Omit("Fleet").Create(&fence).Error; err != nil {
+		return nil, apperrors.DatabaseError("create_geofence", err)
+	}
+	return &fence, nil
+}
+
+// UpdateGeofence is the resolver for the updateGeofence field.
+func (r *mutationResolver) UpdateGeofence(ctx context.Context, id string, input model.GeofenceInput) (*models.Geofence, error) {
+	geofenceID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var existing models.Geofence
+	if err := db.First(&existing, geofenceID).Error; err != nil {
+		return nil, lookupError("geofence", geofenceID, err)
+	}
+	if existing.FleetID != fleetID {
+		return nil, apperrors.ValidationError("fleetId", "a geofence cannot move to another fleet")
+	}
+
+	fence, err := geofenceFromInput(fleetID, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Model(&existing).Select(geofenceUpdates).Updates(&fence).Error; err != nil {
+		return nil, apperrors.DatabaseError("update_geofence", err)
+	}
+
+	if err := db.First(&existing, geofenceID).Error; err != nil {
+		return nil, lookupError("geofence", geofenceID, err)
+	}
+	return &existing, nil
+}
+
+// DeleteGeofence is the resolver for the deleteGeofence field.
+func (r *mutationResolver) DeleteGeofence(ctx context.Context, id string) (bool, error) {
+	geofenceID, err := parseID("id", id)
+	if err != nil {
+		return false, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fence models.Geofence
+	if err := db.First(&fence, geofenceID).Error; err != nil {
+		return false, lookupError("geofence", geofenceID, err)
+	}
+
+	// Its entry and exit history goes with it; set the fence inactive to keep it
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("geofence_id = ?", fence.ID).Delete(&models.GeofenceEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&fence).Error
+	})
+	if err != nil {
+		return false, apperrors.DatabaseError("delete_geofence", err)
+	}
+
+	return true, nil
+}
+
+// CreatePlannedRoute is the resolver for the createPlannedRoute field.
+func (r *mutationResolver) CreatePlannedRoute(ctx context.Context, input model.PlannedRouteInput) (*models.PlannedRoute, error) {
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+	planned, err := plannedRouteFromInput(fleetID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fleet models.Fleet
+	if err := db.First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+
+	if err := db.Omit("Fleet").Create(&planned).Error; err != nil {
+		return nil, apperrors.DatabaseError("create_planned_route", err)
+	}
+	return &planned, nil
+}
+
+// UpdatePlannedRoute is the resolver for the updatePlannedRoute field.
+func (r *mutationResolver) UpdatePlannedRoute(ctx context.Context, id string, input model.PlannedRouteInput) (*models.PlannedRoute, error) {
+	routeID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var existing models.PlannedRoute
+	if err := db.First(&existing, routeID).Error; err != nil {
+		return nil, lookupError("planned_route", routeID, err)
+	}
+	if existing.FleetID != fleetID {
+		return nil, apperrors.ValidationError("fleetId", "a planned route cannot move to another fleet")
+	}
+
+	planned, err := plannedRouteFromInput(fleetID, input)
+	if err != nil {
+		return nil, err
+	}
+	// Assignments in progress pick up the change on the risk engine's next pass
+	if err := db.Model(&existing).Select(plannedRouteUpdates).Updates(&planned).Error; err != nil {
+		return nil, apperrors.DatabaseError("update_planned_route", err)
+	}
+
+	if err := db.First(&existing, routeID).Error; err != nil {
+		return nil, lookupError("planned_route", routeID, err)
+	}
+	return &existing, nil
+}
+
+// DeletePlannedRoute is the resolver for the deletePlannedRoute field.
+func (r *mutationResolver) DeletePlannedRoute(ctx context.Context, id string) (bool, error) {
+	routeID, err := parseID("id", id)
+	if err != nil {
+		return false, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var planned models.PlannedRoute
+	if err := db.First(&planned, routeID).Error; err != nil {
+		return false, lookupError("planned_route", routeID, err)
+	}
+
+	// Its assignments go with it; route deviations already raised are kept
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("route_id = ?", planned.ID).Delete(&models.RouteAssignment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&planned).Error
+	})
+	if err != nil {
+		return false, apperrors.DatabaseError("delete_planned_route", err)
+	}
+
+	return true, nil
+}
+
+// AssignRoute is the resolver for the assignRoute field.
+func (r *mutationResolver) AssignRoute(ctx context.Context, routeID string, vehicleID string, startTime string, endTime string) (*models.RouteAssignment, error) {
+	rID, err := parseID("routeId", routeID)
+	if err != nil {
+		return nil, err
+	}
+	vID, err := parseID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	start, err := parseOptionalTime("startTime", &startTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseOptionalTime("endTime", &endTime)
+	if err != nil {
+		return nil, err
+	}
+	if !start.Before(*end) {
+		return nil, apperrors.ValidationError("endTime", "endTime must be after startTime")
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var planned models.PlannedRoute
+	if err := db.First(&planned, rID).Error; err != nil {
+		return nil, lookupError("planned_route", rID, err)
+	}
+
+	var vehicle models.Vehicle
+	if err := db.First(&vehicle, vID).Error; err != nil {
+		return nil, lookupError("vehicle", vID, err)
+	}
+	if vehicle.FleetID != planned.FleetID {
+		return nil, apperrors.ValidationError("vehicleId", "vehicle belongs to a different fleet than the route")
+	}
+
+	routeAssignment := models.RouteAssignment{RouteID: planned.ID, VehicleID: vehicle.ID, StartTime: *start, EndTime: *end}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// A vehicle follows one route at a time
+		var overlapping int64
+		if err := tx.Model(&models.RouteAssignment{}).
+			Where("vehicle_id = ? AND start_time < ? AND end_time > ?", vehicle.ID, *end, *start).
+			Count(&overlapping).Error; err != nil {
+			return apperrors.DatabaseError("assign_route", err)
+		}
+		if overlapping > 0 {
+			return apperrors.ValidationError("startTime", "the vehicle already has a route assigned during this time")
+		}
+		if err := tx.Omit("Route", "Vehicle").Create(&routeAssignment).Error; err != nil {
+			return apperrors.DatabaseError("assign_route", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Preload("Route").Preload("Vehicle").First(&routeAssignment, routeAssignment.ID).Error; err != nil {
+		return nil, lookupError("route_assignment", routeAssignment.ID, err)
+	}
+	return &routeAssignment, nil
+}
+
+// UnassignRoute is the resolver for the unassignRoute field.
+func (r *mutationResolver) UnassignRoute(ctx context.Context, id string) (bool, error) {
+	assignmentID, err := parseID("id", id)
+	if err != nil {
+		return false, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var routeAssignment models.RouteAssignment
+	if err := db.First(&routeAssignment, assignmentID).Error; err != nil {
+		return false, lookupError("route_assignment", assignmentID, err)
+	}
+
+	// A deviation in progress is resolved as route_ended on the risk engine's next pass
+	if err := db.Delete(&routeAssignment).Error; err != nil {
+		return false, apperrors.DatabaseError("unassign_route", err)
+	}
+	return true, nil
+}
+
+// UpsertRiskPolicy is the resolver for the upsertRiskPolicy field.
+func (r *mutationResolver) UpsertRiskPolicy(ctx context.Context, input model.RiskPolicyInput) (*models.RiskPolicy, error) {
+	fleetID, err := parseID("fleetId", input.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+	vehicleClass, err := normalizeVehicleClass(input.VehicleClass)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := riskRulesFromInput(input.Rules)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return nil, apperrors.ValidationError("rules", "rules could not be encoded")
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var fleet models.Fleet
+	if err := db.First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+
+	// The risk engine notices the new updated_at and reloads the policy
+	var policy models.RiskPolicy
+	err = db.Where("fleet_id = ? AND vehicle_class = ?", fleetID, vehicleClass).First(&policy).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		policy = models.RiskPolicy{FleetID: fleetID, VehicleClass: vehicleClass, Rules: string(encoded)}
+		if err := db.Omit("Fleet").Create(&policy).Error; err != nil {
+			return nil, apperrors.DatabaseError("create_risk_policy", err)
+		}
+	case err != nil:
+		return nil, apperrors.DatabaseError("fetch_risk_policy", err)
+	default:
+		if err := db.Model(&policy).Update("rules", string(encoded)).Error; err != nil {
+			return nil, apperrors.DatabaseError("update_risk_policy", err)
+		}
+	}
+	policy.Fleet = fleet
+
+	return &policy, nil
+}
+
+// DeleteRiskPolicy is the resolver for the deleteRiskPolicy field.
+func (r *mutationResolver) DeleteRiskPolicy(ctx context.Context, id string) (bool, error) {
+	policyID, err := parseID("id", id)
+	if err != nil {
+		return false, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var policy models.RiskPolicy
+	if err := db.First(&policy, policyID).Error; err != nil {
+		return false, lookupError("risk_policy", policyID, err)
+	}
+	if err := db.Delete(&policy).Error; err != nil {
+		return false, apperrors.DatabaseError("delete_risk_policy", err)
+	}
+
+	return true, nil
+}
+
+// ID is the resolver for the id field.
+func (r *plannedRouteResolver) ID(ctx context.Context, obj *models.PlannedRoute) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// FleetID is the resolver for the fleetId field.
+func (r *plannedRouteResolver) FleetID(ctx context.Context, obj *models.PlannedRoute) (string, error) {
+	return formatID(obj.FleetID), nil
+}
+
+// Path is the resolver for the path field.
+func (r *plannedRouteResolver) Path(ctx context.Context, obj *models.PlannedRoute) ([]*model.Location, error) {
+	return formatPath(obj), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *plannedRouteResolver) CreatedAt(ctx context.Context, obj *models.PlannedRoute) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *plannedRouteResolver) UpdatedAt(ctx context.Context, obj *models.PlannedRoute) (string, error) {
+	return formatTime(obj.UpdatedAt), nil
+}
+
+// Fleets is the resolver for the fleets field.
+func (r *queryResolver) Fleets(ctx context.Context) ([]*models.Fleet, error) {
+	var fleets []*models.Fleet
+	if err := r.DB.WithContext(ctx).Order("name").Find(&fleets).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_fleets", err)
+	}
+	return fleets, nil
+}
+
+// Fleet is the resolver for the fleet field.
+func (r *queryResolver) Fleet(ctx context.Context, id string) (*models.Fleet, error) {
+	fleetID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var fleet models.Fleet
+	if err := r.DB.WithContext(ctx).First(&fleet, fleetID).Error; err != nil {
+		return nil, lookupError("fleet", fleetID, err)
+	}
+	return &fleet, nil
+}
+
+// Vehicles is the resolver for the vehicles field.
+func (r *queryResolver) Vehicles(ctx context.Context, fleetID *string) ([]*models.Vehicle, error) {
+	fID, err := parseOptionalID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if fID != nil {
+		if err := authorizeFleet(ctx, *fID); err != nil {
+			return nil, err
+		}
+	}
+
+	query := r.DB.WithContext(ctx).Preload("Fleet").Preload("Driver").Order("id")
+	if fID != nil {
+		query = query.Where("fleet_id = ?", *fID)
+	}
+
+	var vehicles []*models.Vehicle
+	if err := query.Find(&vehicles).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_vehicles", err)
+	}
+	return vehicles, nil
+}
+
+// Vehicle is the resolver for the vehicle field.
+func (r *queryResolver) Vehicle(ctx context.Context, id string) (*models.Vehicle, error) {
+	vehicleID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicle models.Vehicle
+	if err := r.DB.WithContext(ctx).Preload("Fleet").Preload("Driver").First(&vehicle, vehicleID).Error; err != nil {
+		return nil, lookupError("vehicle", vehicleID, err)
+	}
+	return &vehicle, nil
+}
+
+// Drivers is the resolver for the drivers field.
+func (r *queryResolver) Drivers(ctx context.Context, fleetID *string) ([]*models.Driver, error) {
+	fID, err := parseOptionalID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if fID != nil {
+		if err := authorizeFleet(ctx, *fID); err != nil {
+			return nil, err
+		}
+	}
+
+	query := r.DB.WithContext(ctx).Preload("Fleet").Order("last_name, first_name")
+	if fID != nil {
+		query = query.Where("fleet_id = ?", *fID)
+	}
+
+	var drivers []*models.Driver
+	if err := query.Find(&drivers).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_drivers", err)
+	}
+	return drivers, nil
+}
+
+// Driver is the resolver for the driver field.
+func (r *queryResolver) Driver(ctx context.Context, id string) (*models.Driver, error) {
+	driverID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var driver models.Driver
+	if err := r.DB.WithContext(ctx).Preload("Fleet").First(&driver, driverID).Error; err != nil {
+		return nil, lookupError("driver", driverID, err)
+	}
+	return &driver, nil
+}
+
+// RiskEvents is the resolver for the riskEvents field.
+func (r *queryResolver) RiskEvents(ctx context.Context, vehicleID *string, driverID *string, limit *int) ([]*models.RiskEvent, error) {
+	vID, err := parseOptionalID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	dID, err := parseOptionalID("driverId", driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	max := 50
+	if limit != nil {
+		if *limit < 1 || *limit > 500 {
+			return nil, apperrors.ValidationError("limit", "limit must be between 1 and 500")
+		}
+		max = *limit
+	}
+
+	query := r.DB.WithContext(ctx).Preload("Vehicle").Preload("Driver").
+		Order("timestamp desc").
+		Limit(max)
+	if vID != nil {
+		query = query.Where("vehicle_id = ?", *vID)
+	}
+	if dID != nil {
+		query = query.Where("driver_id = ?", *dID)
+	}
+
+	var events []*models.RiskEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_risk_events", err)
+	}
+	return events, nil
+}
+
+// Alerts is the resolver for the alerts field.
+func (r *queryResolver) Alerts(ctx context.Context, fleetID string, status *model.AlertStatus) ([]*models.Alert, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	query := r.DB.WithContext(ctx).
+		Preload("Fleet").Preload("Vehicle").Preload("Driver").Preload("RiskEvent").
+		Where("fleet_id = ?", fID).
+		Order("created_at desc").
+		Limit(100)
+	if status != nil {
+		query = query.Where("status = ?", fromEnum(status.String()))
+	}
+
+	var alerts []*models.Alert
+	if err := query.Find(&alerts).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_alerts", err)
+	}
+	return alerts, nil
+}
+
+// DriverScores is the resolver for the driverScores field.
+func (r *queryResolver) DriverScores(ctx context.Context, fleetID string) ([]*models.DriverScore, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var scores []*models.DriverScore
+	if err := r.DB.WithContext(ctx).Preload("Driver").
+		Joins("JOIN drivers ON drivers.id = driver_scores.driver_id").
+		Where("drivers.fleet_id = ?", fID).
+		Order("driver_scores.overall_score desc").
+		Find(&scores).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_driver_scores", err)
+	}
+	return scores, nil
+}
+
+// DriverScoreHistory is the resolver for the driverScoreHistory field.
+func (r *queryResolver) DriverScoreHistory(ctx context.Context, driverID string, from string, to string, granularity *model.ScoreGranularity) ([]*models.DriverScoreSnapshot, error) {
+	dID, err := parseID("driverId", driverID)
+	if err != nil {
+		return nil, err
+	}
+	fromTime, err := parseOptionalTime("from", &from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseOptionalTime("to", &to)
+	if err != nil {
+		return nil, err
+	}
+	if !fromTime.Before(*toTime) {
+		return nil, apperrors.ValidationError("to", "to must be after from")
+	}
+	if toTime.Sub(*fromTime) > maxScoreHistory {
+		return nil, apperrors.ValidationError("to", "a score history can cover at most 366 days")
+	}
+	period := model.ScoreGranularityDay
+	if granularity != nil {
+		period = *granularity
+	}
+	if period == model.ScoreGranularityRun && toTime.Sub(*fromTime) > maxScoreRuns {
+		return nil, apperrors.ValidationError("granularity", "every run can be listed for at most 31 days")
+	}
+
+	var driver models.Driver
+	if err := r.DB.WithContext(ctx).Select("id").First(&driver, dID).Error; err != nil {
+		return nil, lookupError("driver", dID, err)
+	}
+
+	query := r.DB.WithContext(ctx).
+		Where("driver_id = ? AND scored_at >= ? AND scored_at < ?", dID, *fromTime, *toTime).
+		Order("scored_at, id")
+	if period != model.ScoreGranularityRun {
+		// Pick the last snapshot of each period from the scoring times alone and
+		// only load those in full
+		var runs []*models.DriverScoreSnapshot
+		if err := query.Session(&gorm.Session{}).Select("id", "scored_at").Find(&runs).Error; err != nil {
+			return nil, apperrors.DatabaseError("fetch_driver_score_history", err)
+		}
+		ids := make([]uint, 0)
+		for _, run := range lastSnapshotPerPeriod(runs, period) {
+			ids = append(ids, run.ID)
+		}
+		if len(ids) == 0 {
+			return []*models.DriverScoreSnapshot{}, nil
+		}
+		query = query.Where("id IN ?", ids)
+	}
+
+	var snapshots []*models.DriverScoreSnapshot
+	if err := query.Find(&snapshots).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_driver_score_history", err)
+	}
+	return snapshots, nil
+}
+
+// Trips is the resolver for the trips field.
+func (r *queryResolver) Trips(ctx context.Context, vehicleID *string, driverID *string, from *string, to *string, limit *int) ([]*models.Trip, error) {
+	vID, err := parseOptionalID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	dID, err := parseOptionalID("driverId", driverID)
+	if err != nil {
+		return nil, err
+	}
+	fromTime, err := parseOptionalTime("from", from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseOptionalTime("to", to)
+	if err != nil {
+		return nil, err
+	}
+
+	max := 50
+	if limit != nil {
+		if *limit < 1 || *limit > 500 {
+			return nil, apperrors.ValidationError("limit", "limit must be between 1 and 500")
+		}
+		max = *limit
+	}
+
+	query := r.DB.WithContext(ctx).Preload("Vehicle").Preload("Driver").
+		Order("start_time desc").
+		Limit(max)
+	if vID != nil {
+		query = query.Where("vehicle_id = ?", *vID)
+	}
+	if dID != nil {
+		query = query.Where("driver_id = ?", *dID)
+	}
+	if fromTime != nil {
+		query = query.Where("end_time >= ?", *fromTime)
+	}
+	if toTime != nil {
+		query = query.Where("start_time < ?", *toTime)
+	}
+
+	var trips []*models.Trip
+	if err := query.Find(&trips).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_trips", err)
+	}
+	return trips, nil
+}
+
+// Incidents is the resolver for the incidents field.
+func (r *queryResolver) Incidents(ctx context.Context, fleetID string, status *model.IncidentStatus) ([]*models.Incident, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	query := withIncidentAssociations(r.DB.WithContext(ctx)).
+		Where("fleet_id = ?", fID).
+		Order("occurred_at desc").
+		Limit(100)
+	if status != nil {
+		query = query.Where("status = ?", fromEnum(status.String()))
+	}
+
+	var incidents []*models.Incident
+	if err := query.Find(&incidents).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_incidents", err)
+	}
+	return incidents, nil
+}
+
+// Incident is the resolver for the incident field.
+func (r *queryResolver) Incident(ctx context.Context, id string) (*models.Incident, error) {
+	incidentID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadIncident(ctx, incidentID)
+}
+
+// Geofences is the resolver for the geofences field.
+func (r *queryResolver) Geofences(ctx context.Context, fleetID string) ([]*models.Geofence, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var fences []*models.Geofence
+	if err := r.DB.WithContext(ctx).Where("fleet_id = ?", fID).Order("name").Find(&fences).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_geofences", err)
+	}
+	return fences, nil
+}
+
+// Geofence is the resolver for the geofence field.
+func (r *queryResolver) Geofence(ctx context.Context, id string) (*models.Geofence, error) {
+	geofenceID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var fence models.Geofence
+	if err := r.DB.WithContext(ctx).First(&fence, geofenceID).Error; err != nil {
+		return nil, lookupError("geofence", geofenceID, err)
+	}
+	return &fence, nil
+}
+
+// GeofenceEvents is the resolver for the geofenceEvents field.
+func (r *queryResolver) GeofenceEvents(ctx context.Context, fleetID string, geofenceID *string, vehicleID *string, from *string, to *string, limit *int) ([]*models.GeofenceEvent, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+	gID, err := parseOptionalID("geofenceId", geofenceID)
+	if err != nil {
+		return nil, err
+	}
+	vID, err := parseOptionalID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	fromTime, err := parseOptionalTime("from", from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseOptionalTime("to", to)
+	if err != nil {
+		return nil, err
+	}
+
+	max := 100
+	if limit != nil {
+		if *limit < 1 || *limit > 500 {
+			return nil, apperrors.ValidationError("limit", "limit must be between 1 and 500")
+		}
+		max = *limit
+	}
+
+	db := r.DB.WithContext(ctx)
+	query := db.Preload("Geofence").Preload("Vehicle").Preload("Driver").
+		Where("geofence_id IN (?)", db.Model(&models.Geofence{}).Select("id").Where("fleet_id = ?", fID)).
+		Order("timestamp desc").
+		Limit(max)
+	if gID != nil {
+		query = query.Where("geofence_id = ?", *gID)
+	}
+	if vID != nil {
+		query = query.Where("vehicle_id = ?", *vID)
+	}
+	if fromTime != nil {
+		query = query.Where("timestamp >= ?", *fromTime)
+	}
+	if toTime != nil {
+		query = query.Where("timestamp < ?", *toTime)
+	}
+
+	var events []*models.GeofenceEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_geofence_events", err)
+	}
+	return events, nil
+}
+
+// PlannedRoutes is the resolver for the plannedRoutes field.
+func (r *queryResolver) PlannedRoutes(ctx context.Context, fleetID string) ([]*models.PlannedRoute, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var routes []*models.PlannedRoute
+	if err := r.DB.WithContext(ctx).Where("fleet_id = ?", fID).Order("name").Find(&routes).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_planned_routes", err)
+	}
+	return routes, nil
+}
+
+// PlannedRoute is the resolver for the plannedRoute field.
+func (r *queryResolver) PlannedRoute(ctx context.Context, id string) (*models.PlannedRoute, error) {
+	routeID, err := parseID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned models.PlannedRoute
+	if err := r.DB.WithContext(ctx).First(&planned, routeID).Error; err != nil {
+		return nil, lookupError("planned_route", routeID, err)
+	}
+	return &planned, nil
+}
+
+// RouteAssignments is the resolver for the routeAssignments field.
+func (r *queryResolver) RouteAssignments(ctx context.Context, fleetID string, routeID *string, vehicleID *string, from *string, to *string, limit *int) ([]*models.RouteAssignment, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+	rID, err := parseOptionalID("routeId", routeID)
+	if err != nil {
+		return nil, err
+	}
+	vID, err := parseOptionalID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	fromTime, err := parseOptionalTime("from", from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseOptionalTime("to", to)
+	if err != nil {
+		return nil, err
+	}
+
+	max := 100
+	if limit != nil {
+		if *limit < 1 || *limit > 500 {
+			return nil, apperrors.ValidationError("limit", "limit must be between 1 and 500")
+		}
+		max = *limit
+	}
+
+	db := r.DB.WithContext(ctx)
+	query := db.Preload("Route").Preload("Vehicle").
+		Where("route_id IN (?)", db.Model(&models.PlannedRoute{}).Select("id").Where("fleet_id = ?", fID)).
+		Order("start_time desc").
+		Limit(max)
+	if rID != nil {
+		query = query.Where("route_id = ?", *rID)
+	}
+	if vID != nil {
+		query = query.Where("vehicle_id = ?", *vID)
+	}
+	// Assignments overlapping the window
+	if fromTime != nil {
+		query = query.Where("end_time > ?", *fromTime)
+	}
+	if toTime != nil {
+		query = query.Where("start_time < ?", *toTime)
+	}
+
+	var assignments []*models.RouteAssignment
+	if err := query.Find(&assignments).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_route_assignments", err)
+	}
+	return assignments, nil
+}
+
+// RiskPolicies is the resolver for the riskPolicies field.
+func (r *queryResolver) RiskPolicies(ctx context.Context, fleetID string) ([]*models.RiskPolicy, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var policies []*models.RiskPolicy
+	if err := r.DB.WithContext(ctx).Preload("Fleet").
+		Where("fleet_id = ?", fID).
+		Order("vehicle_class asc").
+		Find(&policies).Error; err != nil {
+		return nil, apperrors.DatabaseError("fetch_risk_policies", err)
+	}
+	return policies, nil
+}
+
+// SimulateRiskPolicy is the resolver for the simulateRiskPolicy field.
+func (r *queryResolver) SimulateRiskPolicy(ctx context.Context, fleetID string, rules []*model.RiskRuleInput, from string, to string) (*model.RiskSimulation, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+	candidate, err := riskRulesFromInput(rules)
+	if err != nil {
+		return nil, err
+	}
+	fromTime, err := parseOptionalTime("from", &from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseOptionalTime("to", &to)
+	if err != nil {
+		return nil, err
+	}
+	if !fromTime.Before(*toTime) {
+		return nil, apperrors.ValidationError("to", "to must be after from")
+	}
+	if toTime.Sub(*fromTime) > maxSimulationWindow {
+		return nil, apperrors.ValidationError("to", "a simulation can cover at most 31 days")
+	}
+
+	result, err := runSimulation(ctx, r.DB.WithContext(ctx), fID, candidate, *fromTime, *toTime)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		return nil, apperrors.DatabaseError("simulate_risk_policy", err)
+	}
+
+	simulated, err := simulationToModel(ctx, r.DB, result)
+	if err != nil {
+		return nil, apperrors.DatabaseError("simulate_risk_policy", err)
+	}
+	return simulated, nil
+}
+
+// LiveVehicleData is the resolver for the liveVehicleData field.
+func (r *queryResolver) LiveVehicleData(ctx context.Context, vehicleID string) (*model.VehicleData, error) {
+	vID, err := parseID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var vehicle models.Vehicle
+	if err := db.Preload("Fleet").Preload("Driver").First(&vehicle, vID).Error; err != nil {
+		return nil, lookupError("vehicle", vID, err)
+	}
+
+	event, err := r.latestTelemetry(ctx, vehicle.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildVehicleData(&vehicle, event), nil
+}
+
+// ID is the resolver for the id field.
+func (r *riskEventResolver) ID(ctx context.Context, obj *models.RiskEvent) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// VehicleID is the resolver for the vehicleId field.
+func (r *riskEventResolver) VehicleID(ctx context.Context, obj *models.RiskEvent) (string, error) {
+	return formatID(obj.VehicleID), nil
+}
+
+// DriverID is the resolver for the driverId field.
+func (r *riskEventResolver) DriverID(ctx context.Context, obj *models.RiskEvent) (*string, error) {
+	return formatOptionalID(obj.DriverID), nil
+}
+
+// EventType is the resolver for the eventType field.
+func (r *riskEventResolver) EventType(ctx context.Context, obj *models.RiskEvent) (model.RiskEventType, error) {
+	eventType := model.RiskEventType(toEnum(obj.EventType))
+	if !eventType.IsValid() {
+		return "", fmt.Errorf("unknown risk event type %q", obj.EventType)
+	}
+	return eventType, nil
+}
+
+// Severity is the resolver for the severity field.
+func (r *riskEventResolver) Severity(ctx context.Context, obj *models.RiskEvent) (model.RiskSeverity, error) {
+	severity := model.RiskSeverity(toEnum(obj.Severity))
+	if !severity.IsValid() {
+		return "", fmt.Errorf("unknown risk severity %q", obj.Severity)
+	}
+	return severity, nil
+}
+
+// Timestamp is the resolver for the timestamp field.
+func (r *riskEventResolver) Timestamp(ctx context.Context, obj *models.RiskEvent) (string, error) {
+	return formatTime(obj.Timestamp), nil
+}
+
+// Status is the resolver for the status field.
+func (r *riskEventResolver) Status(ctx context.Context, obj *models.RiskEvent) (model.RiskEventStatus, error) {
+	status := model.RiskEventStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown risk event status %q", obj.Status)
+	}
+	return status, nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *riskEventResolver) CreatedAt(ctx context.Context, obj *models.RiskEvent) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *riskEventResolver) UpdatedAt(ctx context.Context, obj *models.RiskEvent) (string, error) {
+	return formatTime(obj.UpdatedAt), nil
+}
+
+// ID is the resolver for the id field.
+func (r *riskPolicyResolver) ID(ctx context.Context, obj *models.RiskPolicy) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// FleetID is the resolver for the fleetId field.
+func (r *riskPolicyResolver) FleetID(ctx context.Context, obj *models.RiskPolicy) (string, error) {
+	return formatID(obj.FleetID), nil
+}
+
+// VehicleClass is the resolver for the vehicleClass field.
+func (r *riskPolicyResolver) VehicleClass(ctx context.Context, obj *models.RiskPolicy) (*string, error) {
+	if obj.VehicleClass == "" {
+		return nil, nil
+	}
+	return &obj.VehicleClass, nil
+}
+
+// Rules is the resolver for the rules field.
+func (r *riskPolicyResolver) Rules(ctx context.Context, obj *models.RiskPolicy) ([]*model.RiskRule, error) {
+	cfg, err := risk.ParsePolicyRules(obj.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return riskRulesToModel(cfg)
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *riskPolicyResolver) CreatedAt(ctx context.Context, obj *models.RiskPolicy) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *riskPolicyResolver) UpdatedAt(ctx context.Context, obj *models.RiskPolicy) (string, error) {
+	return formatTime(obj.UpdatedAt), nil
+}
+
+// ID is the resolver for the id field.
+func (r *routeAssignmentResolver) ID(ctx context.Context, obj *models.RouteAssignment) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// RouteID is the resolver for the routeId field.
+func (r *routeAssignmentResolver) RouteID(ctx context.Context, obj *models.RouteAssignment) (string, error) {
+	return formatID(obj.RouteID), nil
+}
+
+// VehicleID is the resolver for the vehicleId field.
+func (r *routeAssignmentResolver) VehicleID(ctx context.Context, obj *models.RouteAssignment) (string, error) {
+	return formatID(obj.VehicleID), nil
+}
+
+// StartTime is the resolver for the startTime field.
+func (r *routeAssignmentResolver) StartTime(ctx context.Context, obj *models.RouteAssignment) (string, error) {
+	return formatTime(obj.StartTime), nil
+}
+
+// EndTime is the resolver for the endTime field.
+func (r *routeAssignmentResolver) EndTime(ctx context.Context, obj *models.RouteAssignment) (string, error) {
+	return formatTime(obj.EndTime), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *routeAssignmentResolver) CreatedAt(ctx context.Context, obj *models.RouteAssignment) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// EventType is the resolver for the eventType field.
+func (r *scoreDeductionResolver) EventType(ctx context.Context, obj *scoring.Deduction) (model.RiskEventType, error) {
+	eventType := model.RiskEventType(toEnum(obj.EventType))
+	if !eventType.IsValid() {
+		return "", fmt.Errorf("unknown risk event type %q", obj.EventType)
+	}
+	return eventType, nil
+}
+
+// Severity is the resolver for the severity field.
+func (r *scoreDeductionResolver) Severity(ctx context.Context, obj *scoring.Deduction) (model.RiskSeverity, error) {
+	severity := model.RiskSeverity(toEnum(obj.Severity))
+	if !severity.IsValid() {
+		return "", fmt.Errorf("unknown risk severity %q", obj.Severity)
+	}
+	return severity, nil
+}
+
+// VehicleUpdates is the resolver for the vehicleUpdates field.
+func (r *subscriptionResolver) VehicleUpdates(ctx context.Context, vehicleID string) (<-chan *model.VehicleData, error) {
+	vID, err := parseID("vehicleId", vehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicle models.Vehicle
+	if err := r.DB.WithContext(ctx).Preload("Fleet").Preload("Driver").First(&vehicle, vID).Error; err != nil {
+		return nil, lookupError("vehicle", vID, err)
+	}
+
+	return subscribe(ctx, r.Resolver, publisher.VehicleUpdatesChannel, func(msg *eventMessage) (*model.VehicleData, bool) {
+		if msg.VehicleID != vehicleID {
+			return nil, false
+		}
+
+		var event models.TelemetryEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			logrus.WithError(err).Warn("Dropping malformed vehicle update")
+			return nil, false
+		}
+		return buildVehicleData(&vehicle, &event), true
+	})
+}
+
+// RiskEventNotifications is the resolver for the riskEventNotifications field.
+func (r *subscriptionResolver) RiskEventNotifications(ctx context.Context, fleetID string) (<-chan *models.RiskEvent, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var fleet models.Fleet
+	if err := r.DB.WithContext(ctx).First(&fleet, fID).Error; err != nil {
+		return nil, lookupError("fleet", fID, err)
+	}
+
+	return subscribe(ctx, r.Resolver, publisher.RiskEventsChannel, func(msg *eventMessage) (*models.RiskEvent, bool) {
+		if msg.FleetID != fleetID {
+			return nil, false
+		}
+
+		var event models.RiskEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			logrus.WithError(err).Warn("Dropping malformed risk event notification")
+			return nil, false
+		}
+
+		// Reload so the vehicle and driver associations are populated
+		if err := r.DB.WithContext(ctx).Preload("Vehicle").Preload("Driver").First(&event, event.ID).Error; err != nil {
+			logrus.WithError(err).WithField("risk_event_id", event.ID).Warn("Failed to load notified risk event")
+			return nil, false
+		}
+		return &event, true
+	})
+}
+
+// AlertNotifications is the resolver for the alertNotifications field.
+func (r *subscriptionResolver) AlertNotifications(ctx context.Context, fleetID string) (<-chan *models.Alert, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var fleet models.Fleet
+	if err := r.DB.WithContext(ctx).First(&fleet, fID).Error; err != nil {
+		return nil, lookupError("fleet", fID, err)
+	}
+
+	return subscribe(ctx, r.Resolver, publisher.AlertsChannel, func(msg *eventMessage) (*models.Alert, bool) {
+		if msg.FleetID != fleetID {
+			return nil, false
+		}
+
+		var alert models.Alert
+		if err := json.Unmarshal(msg.Data, &alert); err != nil {
+			logrus.WithError(err).Warn("Dropping malformed alert notification")
+			return nil, false
+		}
+
+		// Reload so the fleet, vehicle and driver associations are populated
+		if err := r.DB.WithContext(ctx).Preload("Fleet").Preload("Vehicle").Preload("Driver").Preload("RiskEvent").
+			First(&alert, alert.ID).Error; err != nil {
+			logrus.WithError(err).WithField("alert_id", alert.ID).Warn("Failed to load notified alert")
+			return nil, false
+		}
+		return &alert, true
+	})
+}
+
+// IncidentNotifications is the resolver for the incidentNotifications field.
+func (r *subscriptionResolver) IncidentNotifications(ctx context.Context, fleetID string) (<-chan *models.Incident, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var fleet models.Fleet
+	if err := r.DB.WithContext(ctx).First(&fleet, fID).Error; err != nil {
+		return nil, lookupError("fleet", fID, err)
+	}
+
+	return subscribe(ctx, r.Resolver, publisher.IncidentsChannel, func(msg *eventMessage) (*models.Incident, bool) {
+		if msg.FleetID != fleetID {
+			return nil, false
+		}
+
+		var notified models.Incident
+		if err := json.Unmarshal(msg.Data, &notified); err != nil {
+			logrus.WithError(err).Warn("Dropping malformed incident notification")
+			return nil, false
+		}
+
+		// Reload so the associations and status history are populated
+		loaded, err := r.loadIncident(ctx, notified.ID)
+		if err != nil {
+			logrus.WithError(err).WithField("incident_id", notified.ID).Warn("Failed to load notified incident")
+			return nil, false
+		}
+		return loaded, true
+	})
+}
+
+// GeofenceEventNotifications is the resolver for the geofenceEventNotifications field.
+func (r *subscriptionResolver) GeofenceEventNotifications(ctx context.Context, fleetID string) (<-chan *models.GeofenceEvent, error) {
+	fID, err := parseID("fleetId", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeFleet(ctx, fID); err != nil {
+		return nil, err
+	}
+
+	var fleet models.Fleet
+	if err := r.DB.WithContext(ctx).First(&fleet, fID).Error; err != nil {
+		return nil, lookupError("fleet", fID, err)
+	}
+
+	return subscribe(ctx, r.Resolver, publisher.GeofenceEventsChannel, func(msg *eventMessage) (*models.GeofenceEvent, bool) {
+		if msg.FleetID != fleetID {
+			return nil, false
+		}
+
+		var event models.GeofenceEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			logrus.WithError(err).Warn("Dropping malformed geofence event notification")
+			return nil, false
+		}
+
+		// Reload so the geofence, vehicle and driver associations are populated
+		if err := r.DB.WithContext(ctx).Preload("Geofence").Preload("Vehicle").Preload("Driver").
+			First(&event, event.ID).Error; err != nil {
+			logrus.WithError(err).WithField("geofence_event_id", event.ID).Warn("Failed to load notified geofence event")
+			return nil, false
+		}
+		return &event, true
+	})
+}
+
+// ID is the resolver for the id field.
+func (r *telemetryEventResolver) ID(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// VehicleID is the resolver for the vehicleId field.
+func (r *telemetryEventResolver) VehicleID(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
+	return formatID(obj.VehicleID), nil
+}
+
+// Timestamp is the resolver for the timestamp field.
+func (r *telemetryEventResolver) Timestamp(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
+	return formatTime(obj.Timestamp), nil
+}
+
+// ProcessedAt is the resolver for the processedAt field.
+func (r *telemetryEventResolver) ProcessedAt(ctx context.Context, obj *models.TelemetryEvent) (*string, error) {
+	return formatOptionalTime(obj.ProcessedAt), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *telemetryEventResolver) CreatedAt(ctx context.Context, obj *models.TelemetryEvent) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// ID is the resolver for the id field.
+func (r *tripResolver) ID(ctx context.Context, obj *models.Trip) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// VehicleID is the resolver for the vehicleId field.
+func (r *tripResolver) VehicleID(ctx context.Context, obj *models.Trip) (string, error) {
+	return formatID(obj.VehicleID), nil
+}
+
+// DriverID is the resolver for the driverId field.
+func (r *tripResolver) DriverID(ctx context.Context, obj *models.Trip) (*string, error) {
+	return formatOptionalID(obj.DriverID), nil
+}
+
+// StartTime is the resolver for the startTime field.
+func (r *tripResolver) StartTime(ctx context.Context, obj *models.Trip) (string, error) {
+	return formatTime(obj.StartTime), nil
+}
+
+// EndTime is the resolver for the endTime field.
+func (r *tripResolver) EndTime(ctx context.Context, obj *models.Trip) (string, error) {
+	return formatTime(obj.EndTime), nil
+}
+
+// StartLocation is the resolver for the startLocation field.
+func (r *tripResolver) StartLocation(ctx context.Context, obj *models.Trip) (*model.Location, error) {
+	return formatLocation(obj.StartLatitude, obj.StartLongitude), nil
+}
+
+// EndLocation is the resolver for the endLocation field.
+func (r *tripResolver) EndLocation(ctx context.Context, obj *models.Trip) (*model.Location, error) {
+	return formatLocation(obj.EndLatitude, obj.EndLongitude), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *tripResolver) CreatedAt(ctx context.Context, obj *models.Trip) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// ID is the resolver for the id field.
+func (r *vehicleResolver) ID(ctx context.Context, obj *models.Vehicle) (string, error) {
+	return formatID(obj.ID), nil
+}
+
+// FleetID is the resolver for the fleetId field.
+func (r *vehicleResolver) FleetID(ctx context.Context, obj *models.Vehicle) (string, error) {
+	return formatID(obj.FleetID), nil
+}
+
+// DriverID is the resolver for the driverId field.
+func (r *vehicleResolver) DriverID(ctx context.Context, obj *models.Vehicle) (*string, error) {
+	return formatOptionalID(obj.DriverID), nil
+}
+
+// Status is the resolver for the status field.
+func (r *vehicleResolver) Status(ctx context.Context, obj *models.Vehicle) (model.VehicleStatus, error) {
+	status := model.VehicleStatus(toEnum(obj.Status))
+	if !status.IsValid() {
+		return "", fmt.Errorf("unknown vehicle status %q", obj.Status)
+	}
+	return status, nil
+}
+
+// CurrentLocation is the resolver for the currentLocation field.
+func (r *vehicleResolver) CurrentLocation(ctx context.Context, obj *models.Vehicle) (*model.Location, error) {
+	event, err := r.latestTelemetry(ctx, obj.ID, true)
+	if err != nil || event == nil {
+		return nil, err
+	}
+	return &model.Location{
+		Latitude:  *event.Latitude,
+		Longitude: *event.Longitude,
+	}, nil
+}
+
+// LastTelemetry is the resolver for the lastTelemetry field.
+func (r *vehicleResolver) LastTelemetry(ctx context.Context, obj *models.Vehicle) (*models.TelemetryEvent, error) {
+	event, err := r.latestTelemetry(ctx, obj.ID, false)
+	if err != nil || event == nil {
+		return nil, err
+	}
+	event.Vehicle = *obj
+	return event, nil
+}
+
+// RiskScore is the resolver for the riskScore field.
+func (r *vehicleResolver) RiskScore(ctx context.Context, obj *models.Vehicle) (float64, error) {
+	// Average risk of the vehicle's events over the same 30-day window used for driver scores
+	var score float64
+	if err := r.DB.WithContext(ctx).Model(&models.RiskEvent{}).
+		Select("COALESCE(AVG(risk_score), 0)").
+		Where("vehicle_id = ? AND timestamp > ? AND event_type <> ?", obj.ID, time.Now().AddDate(0, 0, -30), route.EventRouteDeviationResolved).
+		Scan(&score).Error; err != nil {
+		return 0, apperrors.DatabaseError("fetch_vehicle_risk_score", err)
+	}
+	return score, nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *vehicleResolver) CreatedAt(ctx context.Context, obj *models.Vehicle) (string, error) {
+	return formatTime(obj.CreatedAt), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *vehicleResolver) UpdatedAt(ctx context.Context, obj *models.Vehicle) (string, error) {
+	return formatTime(obj.UpdatedAt), nil
+}
+
+// Alert returns AlertResolver implementation.
+func (r *Resolver) Alert() AlertResolver { return &alertResolver{r} }
+
+// Driver returns DriverResolver implementation.
+func (r *Resolver) Driver() DriverResolver { return &driverResolver{r} }
+
+// DriverScore returns DriverScoreResolver implementation.
+func (r *Resolver) DriverScore() DriverScoreResolver { return &driverScoreResolver{r} }
+
+// DriverScoreSnapshot returns DriverScoreSnapshotResolver implementation.
+func (r *Resolver) DriverScoreSnapshot() DriverScoreSnapshotResolver {
+	return &driverScoreSnapshotResolver{r}
+}
+
+// Fleet returns FleetResolver implementation.
+func (r *Resolver) Fleet() FleetResolver { return &fleetResolver{r} }
+
+// Geofence returns GeofenceResolver implementation.
+func (r *Resolver) Geofence() GeofenceResolver { return &geofenceResolver{r} }
+
+// GeofenceEvent returns GeofenceEventResolver implementation.
+func (r *Resolver) GeofenceEvent() GeofenceEventResolver { return &geofenceEventResolver{r} }
+
+// Incident returns IncidentResolver implementation.
+func (r *Resolver) Incident() IncidentResolver { return &incidentResolver{r} }
+
+// IncidentUpdate returns IncidentUpdateResolver implementation.
+func (r *Resolver) IncidentUpdate() IncidentUpdateResolver { return &incidentUpdateResolver{r} }
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// PlannedRoute returns PlannedRouteResolver implementation.
+func (r *Resolver) PlannedRoute() PlannedRouteResolver { return &plannedRouteResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// RiskEvent returns RiskEventResolver implementation.
+func (r *Resolver) RiskEvent() RiskEventResolver { return &riskEventResolver{r} }
+
+// RiskPolicy returns RiskPolicyResolver implementation.
+func (r *Resolver) RiskPolicy() RiskPolicyResolver { return &riskPolicyResolver{r} }
+
+// RouteAssignment returns RouteAssignmentResolver implementation.
+func (r *Resolver) RouteAssignment() RouteAssignmentResolver { return &routeAssignmentResolver{r} }
+
+// ScoreDeduction returns ScoreDeductionResolver implementation.
+func (r *Resolver) ScoreDeduction() ScoreDeductionResolver { return &scoreDeductionResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// TelemetryEvent returns TelemetryEventResolver implementation.
+func (r *Resolver) TelemetryEvent() TelemetryEventResolver { return &telemetryEventResolver{r} }
+
+// Trip returns TripResolver implementation.
+func (r *Resolver) Trip() TripResolver { return &tripResolver{r} }
 
 // Vehicle returns VehicleResolver implementation.
 func (r *Resolver) Vehicle() VehicleResolver { return &vehicleResolver{r} }
@@ -429,10 +2287,20 @@ func (r *Resolver) Vehicle() VehicleResolver { return &vehicleResolver{r} }
 type alertResolver struct{ *Resolver }
 type driverResolver struct{ *Resolver }
 type driverScoreResolver struct{ *Resolver }
+type driverScoreSnapshotResolver struct{ *Resolver }
 type fleetResolver struct{ *Resolver }
+type geofenceResolver struct{ *Resolver }
+type geofenceEventResolver struct{ *Resolver }
+type incidentResolver struct{ *Resolver }
+type incidentUpdateResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type plannedRouteResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type riskEventResolver struct{ *Resolver }
+type riskPolicyResolver struct{ *Resolver }
+type routeAssignmentResolver struct{ *Resolver }
+type scoreDeductionResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
 type telemetryEventResolver struct{ *Resolver }
+type tripResolver struct{ *Resolver }
 type vehicleResolver struct{ *Resolver }
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/scoring"
+	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+)
+
+// This file will not be regenerated automatically.
+//
+// It holds the helpers behind the driver score history resolvers in schema.resolvers.go.
+
+const (
+	// maxScoreHistory bounds the period of a score history request
+	maxScoreHistory = 366 * 24 * time.Hour
+
+	// maxScoreRuns bounds it when every scoring run is returned; the risk
+	// engine scores drivers every 10 minutes, and keeps one run an hour past
+	// scoring.SnapshotRetention
+	maxScoreRuns = 31 * 24 * time.Hour
+)
+
+// scorePeriod is the start of the period of the granularity holding t, in UTC.
+// Weeks start on Monday.
+func scorePeriod(t time.Time, granularity model.ScoreGranularity) time.Time {
+	t = t.UTC()
+	switch granularity {
+	case model.ScoreGranularityHour:
+		return t.Truncate(time.Hour)
+	case model.ScoreGranularityDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case model.ScoreGranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return t
+	}
+}
+
+// lastSnapshotPerPeriod keeps the last of the snapshots, oldest first, in
+// each period of the granularity
+func lastSnapshotPerPeriod(snapshots []*models.DriverScoreSnapshot, granularity model.ScoreGranularity) []*models.DriverScoreSnapshot {
+	if granularity == model.ScoreGranularityRun {
+		return snapshots
+	}
+
+	trend := make([]*models.DriverScoreSnapshot, 0)
+	for _, snapshot := range snapshots {
+		period := scorePeriod(snapshot.ScoredAt, granularity)
+		if n := len(trend); n > 0 && scorePeriod(trend[n-1].ScoredAt, granularity).Equal(period) {
+			trend[n-1] = snapshot
+			continue
+		}
+		trend = append(trend, snapshot)
+	}
+	return trend
+}
+
+// scoreBreakdown decodes a snapshot's breakdown
+func scoreBreakdown(snapshot *models.DriverScoreSnapshot) ([]*scoring.Deduction, error) {
+	deductions := make([]*scoring.Deduction, 0)
+	if snapshot.Breakdown == "" {
+		return deductions, nil
+	}
+	if err := json.Unmarshal([]byte(snapshot.Breakdown), &deductions); err != nil {
+		return nil, err
+	}
+	return deductions, nil
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	apperrors "github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/simulation"
+	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph/model"
+)
+
+// This file will not be regenerated automatically.
+//
+// It runs risk policy simulations and shapes their results for GraphQL.
+
+// maxSimulationWindow bounds how much telemetry one request replays
+const maxSimulationWindow = 31 * 24 * time.Hour
+
+// runSimulation replays the fleet's telemetry through its current policies and
+// through candidate layered on top of them. The base rules are read from the
+// same environment as the risk engine's.
+func runSimulation(ctx context.Context, db *gorm.DB, fleetID uint, candidate risk.Config, from, to time.Time) (*simulation.Result, error) {
+	base, err := risk.BaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := risk.NewPolicyStore(db, base)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := current.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	candidateStore, err := current.Candidate(ctx, fleetID, candidate)
+	if err != nil {
+		return nil, apperrors.ValidationError("rules", err.Error())
+	}
+
+	return simulation.Run(ctx, db, current, candidateStore, fleetID, from, to)
+}
+
+// simulationToModel lays the current and candidate counts side by side
+func simulationToModel(ctx context.Context, db *gorm.DB, result *simulation.Result) (*model.RiskSimulation, error) {
+	out := &model.RiskSimulation{
+		FleetID:         formatID(result.FleetID),
+		From:            formatTime(result.From),
+		To:              formatTime(result.To),
+		TelemetryEvents: result.TelemetryEvents,
+		Current:         &model.RiskSimulationTotals{RiskEvents: result.Current.RiskEvents, Alerts: result.Current.Alerts},
+		Candidate:       &model.RiskSimulationTotals{RiskEvents: result.Candidate.RiskEvents, Alerts: result.Candidate.Alerts},
+		ByEventType:     sideBySide(result.Current.ByEventType, result.Candidate.ByEventType),
+		BySeverity:      sideBySide(result.Current.BySeverity, result.Candidate.BySeverity),
+		ByDriver:        []*model.DriverSimulationCount{},
+	}
+
+	driverIDs := make(map[uint]bool)
+	for id := range result.Current.ByDriver {
+		driverIDs[id] = true
+	}
+	for id := range result.Candidate.ByDriver {
+		driverIDs[id] = true
+	}
+
+	ids := make([]uint, 0, len(driverIDs))
+	for id := range driverIDs {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	drivers := make(map[uint]*models.Driver, len(ids))
+	if len(ids) > 0 {
+		var rows []*models.Driver
+		if err := db.WithContext(ctx).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, driver := range rows {
+			drivers[driver.ID] = driver
+		}
+	}
+
+	// Unassigned last among equal counts
+	ordered := append(ids, 0)
+	sort.Slice(ordered[:len(ids)], func(i, j int) bool { return ordered[i] < ordered[j] })
+	for _, id := range ordered {
+		if !driverIDs[id] {
+			continue
+		}
+		count := &model.DriverSimulationCount{
+			Current:   result.Current.ByDriver[id],
+			Candidate: result.Candidate.ByDriver[id],
+		}
+		if id != 0 {
+			driverID := id
+			count.DriverID = formatOptionalID(&driverID)
+			count.Driver = drivers[id]
+		}
+		out.ByDriver = append(out.ByDriver, count)
+	}
+	// Drivers with the most events under the candidate first
+	sort.SliceStable(out.ByDriver, func(i, j int) bool {
+		a, b := out.ByDriver[i], out.ByDriver[j]
+		if a.Candidate != b.Candidate {
+			return a.Candidate > b.Candidate
+		}
+		return a.Current > b.Current
+	})
+
+	return out, nil
+}
+
+// sideBySide merges two counts keyed by stored values into rows keyed by
+// their enum names
+func sideBySide(current, candidate map[string]int) []*model.RiskSimulationCount {
+	keys := make(map[string]bool)
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range candidate {
+		keys[key] = true
+	}
+
+	counts := make([]*model.RiskSimulationCount, 0, len(keys))
+	for key := range keys {
+		counts = append(counts, &model.RiskSimulationCount{
+			Key:       toEnum(key),
+			Current:   current[key],
+			Candidate: candidate[key],
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Key < counts[j].Key })
+	return counts
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// This file will not be regenerated automatically.
+//
+// It bridges the Redis channels fanned out by the websocket service onto
+// GraphQL subscriptions.
+
+// ErrRealtimeUnavailable is returned when a subscription is requested but the
+// API was started without a Redis connection
+var ErrRealtimeUnavailable = errors.New("real-time updates are unavailable")
+
+// eventMessage mirrors publisher.Message, keeping Data raw so each
+// subscription can decode it into its own type
+type eventMessage struct {
+	Type      string          `json:"type"`
+	FleetID   string          `json:"fleet_id,omitempty"`
+	VehicleID string          `json:"vehicle_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// subscribe listens on a Redis channel until ctx is cancelled and forwards
+// every message accepted by convert to the returned channel. convert returns
+// false to drop messages the subscriber is not interested in.
+func subscribe[T any](ctx context.Context, r *Resolver, channel string, convert func(msg *eventMessage) (*T, bool)) (<-chan *T, error) {
+	if r.Redis == nil {
+		return nil, ErrRealtimeUnavailable
+	}
+
+	pubsub := r.Redis.Subscribe(ctx, channel)
+
+	// Wait for the subscription to be confirmed so connection errors surface to the client
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	out := make(chan *T, 16)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var msg eventMessage
+				if err := json.Unmarshal([]byte(raw.Payload), &msg); err != nil {
+					logrus.WithError(err).WithField("channel", channel).Warn("Dropping malformed real-time message")
+					continue
+				}
+
+				value, ok := convert(&msg)
+				if !ok {
+					continue
+				}
+
+				select {
+				case out <- value:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/auth"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/config"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/database"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/server"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
 	"github.com/Tirrell-C/fleet-risk-intelligence/services/api/graph"
@@ -28,11 +39,14 @@ func main() {
 		jwtSecret = "default-secret-change-in-production"
 		logrus.Warn("Using default JWT secret - change this in production!")
 	}
-	jwtManager := auth.NewJWTManager(jwtSecret, 24*time.Hour)
-	authMiddleware := auth.NewAuthMiddleware(jwtManager)
+	jwtManager := auth.NewJWTManager(jwtSecret, baseServer.Config.Server.AccessTokenTTL)
+
+	// Redis backs subscriptions and caches session revocation checks
+	redisClient := newRedisClient(baseServer.Config)
+	authMiddleware := auth.NewAuthMiddleware(jwtManager, auth.NewSessionStore(baseServer.DB, redisClient))
 
 	// Add GraphQL endpoint
-	setupGraphQL(baseServer, authMiddleware)
+	setupGraphQL(baseServer, authMiddleware, redisClient)
 
 	// Add basic REST endpoints
 	setupRoutes(baseServer, authMiddleware)
@@ -46,21 +60,69 @@ func main() {
 	baseServer.WaitForShutdown()
 }
 
-func setupGraphQL(server *server.BaseServer, authMiddleware *auth.AuthMiddleware) {
-	// Create GraphQL resolver with database access
+func setupGraphQL(server *server.BaseServer, authMiddleware *auth.AuthMiddleware, redisClient *redis.Client) {
+	// Create GraphQL resolver with database and pub/sub access
 	resolver := &graph.Resolver{
 		DB:     server.DB,
 		Config: server.Config,
+		Redis:  redisClient,
 	}
 
 	// Create GraphQL handler
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+
+	// Subscriptions run over graphql-ws; browsers cannot set headers on the
+	// upgrade request, so the bearer token arrives in the connection_init payload
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true // Allow connections from any origin for now, matching the websocket service
+			},
+		},
+		InitFunc: func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+			token := strings.TrimPrefix(payload.Authorization(), "Bearer ")
+			if token == "" {
+				return nil, nil, errors.New("authorization is required")
+			}
+
+			claims, err := authMiddleware.Authenticate(ctx, token)
+			if errors.Is(err, auth.ErrSessionRevoked) {
+				return nil, nil, errors.New("session has been revoked")
+			}
+			if err != nil {
+				return nil, nil, errors.New("invalid or expired token")
+			}
+
+			return auth.ContextWithUser(ctx, claims), nil, nil
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New[string](100),
+	})
+	srv.SetErrorPresenter(graph.ErrorPresenter)
 
 	// Add GraphQL endpoint with authentication
 	server.Router.POST("/graphql", authMiddleware.RequireAuth(), func(c *gin.Context) {
 		srv.ServeHTTP(c.Writer, c.Request)
 	})
 
+	// Websocket upgrades authenticate through the connection_init payload instead
+	server.Router.GET("/graphql", func(c *gin.Context) {
+		if !websocket.IsWebSocketUpgrade(c.Request) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "GET /graphql only accepts websocket subscriptions"})
+			return
+		}
+		srv.ServeHTTP(c.Writer, c.Request)
+	})
+
 	// Add GraphQL playground for development
 	if server.Config.Server.Env == "development" {
 		server.Router.GET("/playground", func(c *gin.Context) {
@@ -72,6 +134,17 @@ func setupGraphQL(server *server.BaseServer, authMiddleware *auth.AuthMiddleware
 	logrus.Info("GraphQL endpoint available at /graphql")
 }
 
+// newRedisClient connects to Redis, returning nil if it is unreachable
+func newRedisClient(cfg *config.Config) *redis.Client {
+	client, err := database.NewRedisClient(cfg.Redis)
+	if err != nil {
+		logrus.WithError(err).Warn("Redis connection failed, GraphQL subscriptions and session caching disabled")
+		return nil
+	}
+
+	return client
+}
+
 func setupRoutes(server *server.BaseServer, authMiddleware *auth.AuthMiddleware) {
 	api := server.Router.Group("/api/v1")
 	api.Use(authMiddleware.RequireAuth())
@@ -82,7 +155,7 @@ func setupRoutes(server *server.BaseServer, authMiddleware *auth.AuthMiddleware)
 
 	// Fleet endpoints
 	api.GET("/fleets", getFleets(server))
-	api.GET("/fleets/:id", getFleet(server))
+	api.GET("/fleets/:id", authMiddleware.RequireFleetAccess("id"), getFleet(server))
 
 	// Driver endpoints
 	api.GET("/drivers", getDrivers(server))
@@ -99,7 +172,7 @@ func setupRoutes(server *server.BaseServer, authMiddleware *auth.AuthMiddleware)
 func getVehicles(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var vehicles []models.Vehicle
-		if err := server.DB.Preload("Fleet").Preload("Driver").Find(&vehicles).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Fleet").Preload("Driver").Find(&vehicles).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicles"})
 			return
 		}
@@ -111,7 +184,7 @@ func getVehicle(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var vehicle models.Vehicle
-		if err := server.DB.Preload("Fleet").Preload("Driver").First(&vehicle, id).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Fleet").Preload("Driver").First(&vehicle, id).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
 			return
 		}
@@ -122,7 +195,7 @@ func getVehicle(server *server.BaseServer) gin.HandlerFunc {
 func getFleets(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var fleets []models.Fleet
-		if err := server.DB.Find(&fleets).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Find(&fleets).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fleets"})
 			return
 		}
@@ -134,7 +207,7 @@ func getFleet(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var fleet models.Fleet
-		if err := server.DB.First(&fleet, id).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).First(&fleet, id).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Fleet not found"})
 			return
 		}
@@ -145,7 +218,7 @@ func getFleet(server *server.BaseServer) gin.HandlerFunc {
 func getDrivers(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var drivers []models.Driver
-		if err := server.DB.Preload("Fleet").Find(&drivers).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Fleet").Find(&drivers).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drivers"})
 			return
 		}
@@ -157,7 +230,7 @@ func getDriver(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var driver models.Driver
-		if err := server.DB.Preload("Fleet").First(&driver, id).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Fleet").First(&driver, id).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
 			return
 		}
@@ -168,7 +241,7 @@ func getDriver(server *server.BaseServer) gin.HandlerFunc {
 func getRiskEvents(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var events []models.RiskEvent
-		if err := server.DB.Preload("Vehicle").Preload("Driver").Order("created_at desc").Limit(100).Find(&events).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Vehicle").Preload("Driver").Order("created_at desc").Limit(100).Find(&events).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch risk events"})
 			return
 		}
@@ -180,7 +253,7 @@ func getVehicleRiskEvents(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		vehicleID := c.Param("id")
 		var events []models.RiskEvent
-		if err := server.DB.Preload("Vehicle").Preload("Driver").Where("vehicle_id = ?", vehicleID).Order("created_at desc").Limit(100).Find(&events).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Vehicle").Preload("Driver").Where("vehicle_id = ?", vehicleID).Order("created_at desc").Limit(100).Find(&events).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicle risk events"})
 			return
 		}
@@ -191,7 +264,7 @@ func getVehicleRiskEvents(server *server.BaseServer) gin.HandlerFunc {
 func getAlerts(server *server.BaseServer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var alerts []models.Alert
-		if err := server.DB.Preload("Fleet").Preload("Vehicle").Preload("Driver").Order("created_at desc").Limit(100).Find(&alerts).Error; err != nil {
+		if err := server.DB.WithContext(c.Request.Context()).Preload("Fleet").Preload("Vehicle").Preload("Driver").Order("created_at desc").Limit(100).Find(&alerts).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
 			return
 		}
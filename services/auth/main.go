@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,13 +11,16 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/auth"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/database"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/server"
 )
 
 type AuthService struct {
-	db         *gorm.DB
-	jwtManager *auth.JWTManager
+	db           *gorm.DB
+	jwtManager   *auth.JWTManager
+	sessions     *auth.SessionManager
+	sessionStore *auth.SessionStore
 }
 
 type LoginRequest struct {
@@ -26,9 +29,15 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token     string      `json:"token"`
-	User      models.User `json:"user"`
-	ExpiresAt time.Time   `json:"expires_at"`
+	Token            string      `json:"token"`
+	RefreshToken     string      `json:"refresh_token"`
+	User             models.User `json:"user"`
+	ExpiresAt        time.Time   `json:"expires_at"`
+	RefreshExpiresAt time.Time   `json:"refresh_expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type RegisterRequest struct {
@@ -53,12 +62,22 @@ func main() {
 		logrus.Warn("Using default JWT secret - change this in production!")
 	}
 
-	jwtManager := auth.NewJWTManager(jwtSecret, 24*time.Hour)
+	// Access tokens are short-lived; clients renew them with the refresh token
+	jwtManager := auth.NewJWTManager(jwtSecret, baseServer.Config.Server.AccessTokenTTL)
+
+	// Redis caches session revocation state shared with the other services
+	redisClient, err := database.NewRedisClient(baseServer.Config.Redis)
+	if err != nil {
+		logrus.WithError(err).Warn("Redis connection failed, session checks will use the database")
+	}
+	sessionStore := auth.NewSessionStore(baseServer.DB, redisClient)
 
 	// Create auth service
 	authService := &AuthService{
-		db:         baseServer.DB,
-		jwtManager: jwtManager,
+		db:           baseServer.DB,
+		jwtManager:   jwtManager,
+		sessions:     auth.NewSessionManager(sessionStore, jwtManager, baseServer.Config.Server.RefreshTokenTTL),
+		sessionStore: sessionStore,
 	}
 
 	// Setup routes
@@ -82,7 +101,7 @@ func setupAuthRoutes(server *server.BaseServer, authService *AuthService) {
 	api.POST("/refresh", authService.refreshToken)
 
 	// Protected routes
-	authMiddleware := auth.NewAuthMiddleware(authService.jwtManager)
+	authMiddleware := auth.NewAuthMiddleware(authService.jwtManager, authService.sessionStore)
 	protected := api.Group("")
 	protected.Use(authMiddleware.RequireAuth())
 	protected.GET("/me", authService.getProfile)
@@ -97,6 +116,9 @@ func setupAuthRoutes(server *server.BaseServer, authService *AuthService) {
 	admin.POST("/users", authService.createUser)
 	admin.PUT("/users/:id", authService.updateUser)
 	admin.DELETE("/users/:id", authService.deleteUser)
+	admin.GET("/users/:id/sessions", authService.listUserSessions)
+	admin.DELETE("/users/:id/sessions", authService.revokeUserSessions)
+	admin.DELETE("/users/:id/sessions/:sessionId", authService.revokeUserSession)
 
 	logrus.Info("Auth endpoints configured")
 }
@@ -119,20 +141,10 @@ func (s *AuthService) login(c *gin.Context) {
 		return
 	}
 
-	// Parse fleet IDs
-	var fleetIDs []string
-	if user.FleetIDs != "" {
-		json.Unmarshal([]byte(user.FleetIDs), &fleetIDs)
-	}
-
-	// Generate JWT token
-	token, err := s.jwtManager.Generate(
-		strconv.Itoa(int(user.ID)),
-		user.Email,
-		user.Role,
-		fleetIDs,
-	)
+	// Start a new session family with its first refresh token
+	tokens, err := s.sessions.StartSession(c.Request.Context(), &user)
 	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to start session")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
@@ -142,19 +154,18 @@ func (s *AuthService) login(c *gin.Context) {
 	user.LastLogin = &now
 	s.db.Save(&user)
 
-	// Create session record
-	session := models.Session{
-		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-	}
-	s.db.Create(&session)
+	c.JSON(http.StatusOK, newLoginResponse(tokens, user))
+}
 
-	c.JSON(http.StatusOK, LoginResponse{
-		Token:     token,
-		User:      user,
-		ExpiresAt: session.ExpiresAt,
-	})
+func newLoginResponse(tokens *auth.TokenPair, user models.User) LoginResponse {
+	user.Password = ""
+	return LoginResponse{
+		Token:            tokens.AccessToken,
+		RefreshToken:     tokens.RefreshToken,
+		User:             user,
+		ExpiresAt:        tokens.ExpiresAt,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
 }
 
 func (s *AuthService) register(c *gin.Context) {
@@ -201,8 +212,27 @@ func (s *AuthService) register(c *gin.Context) {
 }
 
 func (s *AuthService) refreshToken(c *gin.Context) {
-	// Implementation for refresh token
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not implemented yet"})
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, user, err := s.sessions.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; please log in again"})
+		case errors.Is(err, auth.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		default:
+			logrus.WithError(err).Error("Failed to refresh token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(tokens, *user))
 }
 
 func (s *AuthService) getProfile(c *gin.Context) {
@@ -265,11 +295,14 @@ func (s *AuthService) updateProfile(c *gin.Context) {
 }
 
 func (s *AuthService) logout(c *gin.Context) {
-	// Delete session token
-	authHeader := c.GetHeader("Authorization")
-	if authHeader != "" {
-		token := authHeader[7:] // Remove "Bearer " prefix
-		s.db.Where("token = ?", token).Delete(&models.Session{})
+	// Revoke the refresh tokens of the session this access token belongs to
+	claims, exists := auth.GetUserFromContext(c.Request.Context())
+	if exists && claims.SessionID != "" {
+		if err := s.sessions.RevokeFamily(c.Request.Context(), claims.SessionID); err != nil {
+			logrus.WithError(err).Error("Failed to revoke session")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
@@ -340,6 +373,8 @@ func (s *AuthService) updateUser(c *gin.Context) {
 		return
 	}
 
+	previousRole, previousFleetIDs := user.Role, user.FleetIDs
+
 	// Update fields
 	if req.FirstName != "" {
 		user.FirstName = req.FirstName
@@ -365,17 +400,126 @@ func (s *AuthService) updateUser(c *gin.Context) {
 		return
 	}
 
+	// Access tokens embed the role and fleets, so any change to them, or losing
+	// active status, ends the user's current logins
+	if user.Status != "active" || user.Role != previousRole || user.FleetIDs != previousFleetIDs {
+		if _, err := s.sessionStore.RevokeUser(c.Request.Context(), user.ID); err != nil {
+			logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to revoke user sessions")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User updated but sessions could not be revoked"})
+			return
+		}
+	}
+
 	user.Password = ""
 	c.JSON(http.StatusOK, user)
 }
 
 func (s *AuthService) deleteUser(c *gin.Context) {
-	id := c.Param("id")
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
 
-	if err := s.db.Delete(&models.User{}, id).Error; err != nil {
+	// Revoke first so tokens already issued stop working even if cached as active
+	if _, err := s.sessionStore.RevokeUser(c.Request.Context(), userID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to revoke user sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, userID).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// SessionResponse describes an active login without exposing token material
+type SessionResponse struct {
+	ID              uint      `json:"id"`
+	FamilyID        string    `json:"family_id"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+func (s *AuthService) listUserSessions(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.sessionStore.ListActive(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	response := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = SessionResponse{
+			ID:              session.ID,
+			FamilyID:        session.FamilyID,
+			LastRefreshedAt: session.CreatedAt,
+			ExpiresAt:       session.ExpiresAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *AuthService) revokeUserSessions(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := s.sessionStore.RevokeUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions revoked successfully", "revoked": revoked})
+}
+
+func (s *AuthService) revokeUserSession(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("sessionId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	err = s.sessionStore.RevokeSession(c.Request.Context(), userID, uint(sessionID))
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// parseUserID reads the :id parameter, responding with 400 when it is malformed
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
 }
\ No newline at end of file
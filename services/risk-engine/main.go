@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
@@ -13,20 +15,36 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/anomaly"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/assignment"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/config"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/database"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/geofence"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/incident"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/processing"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/publisher"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/route"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/scoring"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/trip"
 )
 
 type RiskEngine struct {
-	db     *gorm.DB
-	config *config.Config
-}
-
-type RiskAnalyzer struct {
-	SpeedThreshold       float64
-	AccelerationThreshold float64
-	BrakingThreshold     float64
+	db        *gorm.DB
+	config    *config.Config
+	publisher *publisher.Publisher
+	segmenter *trip.Segmenter
+	claimer   *processing.Claimer
+	drivers   *assignment.Resolver
+	geofences *geofence.Monitor
+	routes    *route.Monitor
+	scoring   scoring.Config
+	// tracked are the vehicles whose telemetry this replica analyzed, with
+	// their last driver; only touched by the processing loop
+	tracked map[uint]*uint
+	// anomalies is nil unless ML risk scoring is enabled
+	anomalies *anomaly.Detector
 }
 
 func main() {
@@ -53,19 +71,61 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to connect to database")
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			os.Exit(runBackfill(db, os.Args[2:]))
+		case "simulate":
+			os.Exit(runSimulate(db, os.Args[2:]))
+		}
+	}
+
+	// Connect to Redis for real-time notifications; processing continues without it
+	redisClient, err := database.NewRedisClient(cfg.Redis)
+	if err != nil {
+		logrus.WithError(err).Warn("Redis connection failed, real-time notifications disabled")
+	}
+
 	engine := &RiskEngine{
-		db:     db,
-		config: cfg,
+		db:        db,
+		config:    cfg,
+		publisher: publisher.New(redisClient),
+		segmenter: trip.NewSegmenter(trip.DefaultConfig()),
+		claimer:   processing.NewClaimer(db, replicaID(), leaseTTL),
+		drivers:   assignment.NewResolver(db, time.Minute),
+		geofences: geofence.NewMonitor(db, geofence.DefaultConfig()),
+		routes:    route.NewMonitor(db, liveWindow),
+		tracked:   make(map[uint]*uint),
+	}
+
+	if engine.scoring, err = scoring.BaseConfig(); err != nil {
+		logrus.WithError(err).Fatal("Failed to configure driver scoring")
+	}
+
+	if err := loadGeofences(engine.geofences); err != nil {
+		logrus.WithError(err).Fatal("Failed to load geofences")
+	}
+	if _, err := engine.routes.Refresh(context.Background(), time.Now()); err != nil {
+		logrus.WithError(err).Fatal("Failed to load route assignments")
+	}
+	if err := engine.routes.Restore(context.Background()); err != nil {
+		logrus.WithError(err).Fatal("Failed to restore route deviations")
+	}
+	if cfg.Features.EnableMLRiskScoring {
+		engine.anomalies = anomaly.NewDetector(anomaly.DefaultConfig())
+		logrus.Info("Driver behavior anomaly detection enabled")
 	}
 
-	analyzer := &RiskAnalyzer{
-		SpeedThreshold:        getEnvAsFloat("SPEED_THRESHOLD", 80.0),       // mph
-		AccelerationThreshold: getEnvAsFloat("ACCEL_THRESHOLD", 4.0),        // m/s²
-		BrakingThreshold:     getEnvAsFloat("BRAKING_THRESHOLD", -6.0),      // m/s²
+	policies, err := newPolicyStore(db)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to configure risk rules")
+	}
+	if err := policies.RestoreFatigue(context.Background(), time.Now()); err != nil {
+		logrus.WithError(err).Fatal("Failed to restore driver hours of service")
 	}
 
 	// Start background risk processing
-	go engine.startRiskProcessing(analyzer)
+	go engine.startRiskProcessing(policies)
 
 	// Start driver score calculation
 	go engine.startDriverScoreCalculation()
@@ -78,60 +138,435 @@ func main() {
 	<-quit
 
 	logrus.Info("Risk engine shutting down...")
+
+	// Hand this replica's vehicles to the others straight away
+	if err := engine.claimer.Release(context.Background()); err != nil {
+		logrus.WithError(err).Warn("Failed to release telemetry leases")
+	}
+}
+
+// liveWindow is how far back the engine looks for unprocessed telemetry
+const liveWindow = time.Hour
+
+// leaseTTL is how long a replica keeps its vehicles without claiming telemetry;
+// it must comfortably exceed the processing interval
+const leaseTTL = 2 * time.Minute
+
+// replicaID identifies this process among the risk-engine replicas
+func replicaID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "risk-engine"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// newPolicyStore builds the default detectors, attaches the risk model when
+// RISK_MODEL_FILE configures one and loads the fleet risk policies
+func newPolicyStore(db *gorm.DB) (*risk.PolicyStore, error) {
+	cfg, err := risk.BaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := risk.NewPolicyStore(db, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(policies.Default().Detectors()))
+	for _, detector := range policies.Default().Detectors() {
+		names = append(names, detector.Name())
+	}
+	logrus.WithField("rules", names).Info("Risk rules loaded")
+
+	scorer, err := risk.BaseScorer()
+	if err != nil {
+		return nil, err
+	}
+	if scorer != nil {
+		policies.SetScorer(scorer)
+		logrus.WithField("model_version", scorer.Version()).Info("Risk model loaded")
+	}
+
+	if _, err := policies.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// loadGeofences loads the fences and the visits in progress
+func loadGeofences(monitor *geofence.Monitor) error {
+	if _, err := monitor.Refresh(context.Background()); err != nil {
+		return err
+	}
+	if err := monitor.Restore(context.Background()); err != nil {
+		return err
+	}
+	logrus.WithField("geofences", monitor.Fences()).Info("Geofence monitoring configured")
+	return nil
 }
 
 // startRiskProcessing continuously processes telemetry data for risk detection
-func (re *RiskEngine) startRiskProcessing(analyzer *RiskAnalyzer) {
+func (re *RiskEngine) startRiskProcessing(policies *risk.PolicyStore) {
 	ticker := time.NewTicker(30 * time.Second) // Process every 30 seconds
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			re.processUnprocessedTelemetry(analyzer)
+			// Pick up policy edits before each pass
+			if _, err := policies.Refresh(context.Background()); err != nil {
+				logrus.WithError(err).Error("Failed to reload risk policies, keeping the previous ones")
+			}
+			if _, err := re.geofences.Refresh(context.Background()); err != nil {
+				logrus.WithError(err).Error("Failed to reload geofences, keeping the previous ones")
+			}
+			if _, err := re.routes.Refresh(context.Background(), time.Now()); err != nil {
+				logrus.WithError(err).Error("Failed to reload route assignments, keeping the previous ones")
+			}
+			re.processUnprocessedTelemetry(policies)
+			re.completeIncidentCaptures()
+			re.detectAnomalies()
 		}
 	}
 }
 
-// processUnprocessedTelemetry finds and analyzes new telemetry events
-func (re *RiskEngine) processUnprocessedTelemetry(analyzer *RiskAnalyzer) {
-	var events []models.TelemetryEvent
+// completeIncidentCaptures records the minute of telemetry after each new
+// incident once it has had time to arrive
+func (re *RiskEngine) completeIncidentCaptures() {
+	completed, err := incident.CompleteCaptures(context.Background(), re.db, time.Now(), time.Minute)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to capture incident telemetry")
+		return
+	}
+	if completed > 0 {
+		logrus.WithField("count", completed).Info("Captured incident telemetry")
+	}
+}
+
+// detectAnomalies checks completed trips against their drivers' baselines and
+// stores the anomalies found in the same transaction that updates the
+// baselines
+func (re *RiskEngine) detectAnomalies() {
+	if re.anomalies == nil {
+		return
+	}
 
-	// Get unprocessed telemetry events from the last hour
-	result := re.db.Where("processed_at IS NULL AND created_at > ?",
-		time.Now().Add(-1*time.Hour)).
-		Order("timestamp ASC").
-		Limit(1000).
-		Find(&events)
+	var risks []models.RiskEvent
+	var saved results
+	err := re.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if risks, err = re.anomalies.Check(tx, time.Now()); err != nil {
+			return err
+		}
+		saved, err = re.saveResults(tx, risks, nil, nil, nil)
+		return err
+	})
+	if errors.Is(err, anomaly.ErrConflict) {
+		logrus.WithError(err).Debug("Driver baselines updated by another replica, retrying next pass")
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check trips for anomalies")
+		return
+	}
+	if len(risks) > 0 {
+		logrus.WithField("count", len(risks)).Info("Detected anomalous trips")
+		re.notify(risks, saved)
+	}
+}
 
-	if result.Error != nil {
-		logrus.WithError(result.Error).Error("Failed to fetch unprocessed telemetry")
+// processUnprocessedTelemetry analyzes new telemetry of the vehicles leased to
+// this replica. Each event's risk events, alerts and trips are stored in the
+// same transaction that marks it processed, and are only published once that
+// transaction commits. When the transaction fails or the event turns out to be
+// processed already, the vehicle's in-memory state has run ahead of what is
+// stored: it is dropped and the vehicle's remaining events wait for the next
+// pass.
+func (re *RiskEngine) processUnprocessedTelemetry(policies *risk.PolicyStore) {
+	const (
+		batchSize   = 1000
+		maxVehicles = 200
+	)
+	ctx := context.Background()
+
+	// Get unprocessed telemetry events from the live window; older ones are
+	// left to the backfill command. Unless the claim is cut short, every
+	// sample of the leased vehicles received before claimedAt is analyzed by
+	// the end of the pass.
+	claimedAt := time.Now()
+	events, err := re.claimer.Claim(ctx, claimedAt.Add(-liveWindow), maxVehicles, batchSize)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to claim unprocessed telemetry")
 		return
 	}
 
 	logrus.WithField("count", len(events)).Debug("Processing telemetry events")
 
-	for _, event := range events {
-		risks := analyzer.AnalyzeEvent(&event)
+	vehicles := re.loadVehicles(events)
+	skipped := make(map[uint]bool)
+
+	for i := range events {
+		event := &events[i]
+		if skipped[event.VehicleID] {
+			continue
+		}
+		vehicle := vehicles[event.VehicleID]
+		event.DriverID = re.driverAt(event.VehicleID, event.Timestamp)
+		re.tracked[event.VehicleID] = event.DriverID
+		fenced := re.geofences.Observe(event, vehicle)
+		routed := re.routes.Observe(event)
+		risks := policies.AnalyzerFor(vehicle.FleetID, vehicle.VehicleClass).Analyze(event)
+		risks = re.attributeRisks(append(append(risks, fenced.Risks...), routed.Risks...))
+		trips := re.attributeTrips(re.segmenter.Observe(event))
+
+		var saved results
+		committed, err := processing.Commit(ctx, re.db, event, func(tx *gorm.DB) error {
+			var err error
+			saved, err = re.saveResults(tx, risks, trips, fenced.Events, routed.Resolutions)
+			return err
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("telemetry_event_id", event.ID).Error("Failed to store telemetry results")
+		}
+		if !committed {
+			re.forgetVehicle(policies, event.VehicleID)
+			skipped[event.VehicleID] = true
+			continue
+		}
+		re.routes.Raised(risks)
+		re.notify(risks, saved)
+	}
+
+	// Close episodes and trips of vehicles that stopped reporting. Silence is
+	// judged at claimedAt rather than now, as samples received during the
+	// pass are not analyzed yet; while a backlog is being worked through,
+	// nothing is closed.
+	if len(events) < batchSize && len(vehicles) < maxVehicles {
+		if err := re.forgetLostVehicles(policies); err != nil {
+			logrus.WithError(err).Error("Failed to check telemetry leases, not closing episodes and trips")
+			return
+		}
+		risks := re.attributeRisks(append(policies.Flush(claimedAt), re.geofences.Flush(claimedAt)...))
+		trips := re.attributeTrips(re.segmenter.Flush(claimedAt))
+		resolutions := re.routes.Flush(claimedAt)
+		if len(risks) == 0 && len(trips) == 0 && len(resolutions) == 0 {
+			return
+		}
+
+		var saved results
+		err := re.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			saved, err = re.saveResults(tx, risks, trips, nil, resolutions)
+			return err
+		})
+		if err != nil {
+			logrus.WithError(err).Error("Failed to store closed episodes and trips")
+			return
+		}
+		re.notify(risks, saved)
+	}
+}
+
+// forgetVehicle drops the in-memory state this replica keeps for the vehicle,
+// so that its next events are analyzed afresh rather than on top of results
+// that were never stored. Its open episodes and trip in progress are lost,
+// though its driver's hours of service are kept; its visits inside geofences
+// and raised route deviations are restored from the stored events.
+func (re *RiskEngine) forgetVehicle(policies *risk.PolicyStore, vehicleID uint) {
+	policies.Forget(vehicleID, re.tracked[vehicleID])
+	re.segmenter.Forget(vehicleID)
+	if err := re.geofences.Reset(context.Background(), vehicleID); err != nil {
+		logrus.WithError(err).WithField("vehicle_id", vehicleID).Warn("Failed to restore geofence visits")
+	}
+	if err := re.routes.Reset(context.Background(), vehicleID); err != nil {
+		logrus.WithError(err).WithField("vehicle_id", vehicleID).Warn("Failed to restore route deviation")
+	}
+	delete(re.tracked, vehicleID)
+}
+
+// forgetLostVehicles drops the state of vehicles whose lease this replica
+// lost, so that it does not close episodes and trips of vehicles now analyzed
+// by another replica
+func (re *RiskEngine) forgetLostVehicles(policies *risk.PolicyStore) error {
+	leased, err := re.claimer.Leased(context.Background())
+	if err != nil {
+		return err
+	}
+	held := make(map[uint]bool, len(leased))
+	for _, vehicleID := range leased {
+		held[vehicleID] = true
+	}
+	for vehicleID := range re.tracked {
+		if !held[vehicleID] {
+			logrus.WithField("vehicle_id", vehicleID).Info("Telemetry lease lost, dropping vehicle state")
+			re.forgetVehicle(policies, vehicleID)
+		}
+	}
+	return nil
+}
 
-		for _, risk := range risks {
-			if err := re.createRiskEvent(risk); err != nil {
-				logrus.WithError(err).Error("Failed to create risk event")
-				continue
+// attributeRisks stamps risk events with the driver who had the vehicle at
+// the time
+func (re *RiskEngine) attributeRisks(risks []models.RiskEvent) []models.RiskEvent {
+	for i := range risks {
+		if risks[i].DriverID == nil {
+			risks[i].DriverID = re.driverAt(risks[i].VehicleID, risks[i].Timestamp)
+		}
+	}
+	return risks
+}
+
+// attributeTrips stamps trips with the driver who had the vehicle when the
+// trip started
+func (re *RiskEngine) attributeTrips(trips []models.Trip) []models.Trip {
+	for i := range trips {
+		trips[i].DriverID = re.driverAt(trips[i].VehicleID, trips[i].StartTime)
+	}
+	return trips
+}
+
+// results are the alerts and incidents raised while storing risk events, the
+// geofence events stored and the risk events resolving route deviations
+type results struct {
+	alerts         []models.Alert
+	incidents      []models.Incident
+	geofenceEvents []models.GeofenceEvent
+	resolved       []models.RiskEvent
+}
+
+// saveResults stores risk events, alerting on the severe ones, trips,
+// geofence events and the resolutions of route deviations that ended. A crash
+// opens an incident with its own escalated alert. The stored IDs are written
+// back to risks.
+func (re *RiskEngine) saveResults(tx *gorm.DB, risks []models.RiskEvent, trips []models.Trip, geofenceEvents []models.GeofenceEvent, resolutions []route.Resolution) (results, error) {
+	var saved results
+	for i := range risks {
+		event := &risks[i]
+		if err := tx.Create(event).Error; err != nil {
+			return results{}, fmt.Errorf("create risk event: %w", err)
+		}
+
+		if event.EventType == risk.RuleCrash {
+			opened, alert, err := incident.OpenCrash(tx, event)
+			if err != nil {
+				return results{}, fmt.Errorf("open incident: %w", err)
 			}
+			saved.incidents = append(saved.incidents, *opened)
+			saved.alerts = append(saved.alerts, alert)
+			continue
+		}
 
-			// Create alert if risk is high severity
-			if risk.Severity == "high" || risk.Severity == "critical" {
-				if err := re.createAlert(risk); err != nil {
-					logrus.WithError(err).Error("Failed to create alert")
-				}
+		// Create alert if risk is high severity
+		if risk.RaisesAlert(event.Severity) {
+			alert, err := createAlert(tx, event)
+			if err != nil {
+				return results{}, fmt.Errorf("create alert: %w", err)
 			}
+			saved.alerts = append(saved.alerts, alert)
+		}
+	}
+
+	for i := range trips {
+		if err := tx.Create(&trips[i]).Error; err != nil {
+			return results{}, fmt.Errorf("create trip: %w", err)
+		}
+	}
+
+	for i := range geofenceEvents {
+		if err := tx.Omit("Geofence", "Vehicle", "Driver").Create(&geofenceEvents[i]).Error; err != nil {
+			return results{}, fmt.Errorf("create geofence event: %w", err)
+		}
+	}
+	saved.geofenceEvents = geofenceEvents
+
+	for _, resolution := range resolutions {
+		resolved, err := route.Resolve(tx, resolution)
+		if err != nil {
+			return results{}, fmt.Errorf("resolve route deviation: %w", err)
+		}
+		if resolved != nil {
+			saved.resolved = append(saved.resolved, *resolved)
+		}
+	}
+	return saved, nil
+}
+
+// notify announces stored and resolved risk events, alerts, incidents and
+// geofence events to their fleets
+func (re *RiskEngine) notify(risks []models.RiskEvent, saved results) {
+	if !re.publisher.Enabled() {
+		return
+	}
+
+	risks = append(risks, saved.resolved...)
+	for i := range risks {
+		risk := &risks[i]
+		var vehicle models.Vehicle
+		if err := re.db.Select("id", "fleet_id").First(&vehicle, risk.VehicleID).Error; err != nil {
+			logrus.WithError(err).WithField("vehicle_id", risk.VehicleID).Warn("Failed to resolve fleet for risk event notification")
+			continue
+		}
+		publisher.LogError(re.publisher.PublishRiskEvent(context.Background(), risk, vehicle.FleetID),
+			logrus.Fields{"risk_event_id": risk.ID})
+	}
+
+	for i := range saved.alerts {
+		publisher.LogError(re.publisher.PublishAlert(context.Background(), &saved.alerts[i]),
+			logrus.Fields{"alert_id": saved.alerts[i].ID})
+	}
+
+	for i := range saved.incidents {
+		publisher.LogError(re.publisher.PublishIncident(context.Background(), &saved.incidents[i]),
+			logrus.Fields{"incident_id": saved.incidents[i].ID})
+	}
+
+	for i := range saved.geofenceEvents {
+		publisher.LogError(re.publisher.PublishGeofenceEvent(context.Background(), &saved.geofenceEvents[i]),
+			logrus.Fields{"geofence_event_id": saved.geofenceEvents[i].ID})
+	}
+}
+
+// driverAt resolves the driver assigned to the vehicle at the given time from
+// the assignment history
+func (re *RiskEngine) driverAt(vehicleID uint, at time.Time) *uint {
+	driverID, err := re.drivers.DriverAt(context.Background(), vehicleID, at)
+	if err != nil {
+		logrus.WithError(err).WithField("vehicle_id", vehicleID).Warn("Failed to resolve assigned driver")
+	}
+	return driverID
+}
+
+// loadVehicles returns the fleet and class of the vehicles in events, which
+// select the risk policy each event is analyzed with
+func (re *RiskEngine) loadVehicles(events []models.TelemetryEvent) map[uint]models.Vehicle {
+	ids := make([]uint, 0, len(events))
+	seen := make(map[uint]bool)
+	for _, event := range events {
+		if !seen[event.VehicleID] {
+			seen[event.VehicleID] = true
+			ids = append(ids, event.VehicleID)
 		}
+	}
+
+	vehicles := make(map[uint]models.Vehicle, len(ids))
+	if len(ids) == 0 {
+		return vehicles
+	}
+	for _, id := range ids {
+		vehicles[id] = models.Vehicle{ID: id}
+	}
 
-		// Mark as processed
-		now := time.Now()
-		re.db.Model(&event).Update("processed_at", &now)
+	var rows []models.Vehicle
+	if err := re.db.Select("id", "fleet_id", "vehicle_class").Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		logrus.WithError(err).Error("Failed to load vehicles, using default risk rules")
+		return vehicles
+	}
+	for _, vehicle := range rows {
+		vehicles[vehicle.ID] = vehicle
 	}
+	return vehicles
 }
 
 // startDriverScoreCalculation periodically updates driver risk scores
@@ -139,14 +574,40 @@ func (re *RiskEngine) startDriverScoreCalculation() {
 	ticker := time.NewTicker(10 * time.Minute) // Update every 10 minutes
 	defer ticker.Stop()
 
+	var thinnedTo time.Time
 	for {
 		select {
 		case <-ticker.C:
 			re.updateDriverScores()
+			thinnedTo = re.thinScoreSnapshots(thinnedTo)
 		}
 	}
 }
 
+// thinScoreSnapshots thins the score snapshots past scoring.SnapshotRetention
+// out to the last of each hour, once an hour has passed since thinnedTo. It
+// covers the day before the cutoff, so an engine down for less than a day
+// leaves nothing behind, and returns the cutoff it thinned up to.
+func (re *RiskEngine) thinScoreSnapshots(thinnedTo time.Time) time.Time {
+	to := time.Now().Add(-scoring.SnapshotRetention).Truncate(time.Hour)
+	if !to.After(thinnedTo) {
+		return thinnedTo
+	}
+
+	deleted, err := scoring.ThinSnapshots(context.Background(), re.db, to.Add(-24*time.Hour), to)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to thin out driver score snapshots")
+		return thinnedTo
+	}
+	if deleted > 0 {
+		logrus.WithField("deleted", deleted).Info("Thinned out driver score snapshots")
+	}
+	return to
+}
+
+// scoringWindow is how far back driver scores look
+const scoringWindow = 30 * 24 * time.Hour
+
 // updateDriverScores calculates and updates driver risk scores
 func (re *RiskEngine) updateDriverScores() {
 	var drivers []models.Driver
@@ -155,8 +616,25 @@ func (re *RiskEngine) updateDriverScores() {
 		return
 	}
 
+	to := time.Now()
+	from := to.Add(-scoringWindow)
+	fleetFuel := make(map[uint]float64)
+
 	for _, driver := range drivers {
-		score := re.calculateDriverScore(driver.ID)
+		fuel, ok := fleetFuel[driver.FleetID]
+		if !ok {
+			var err error
+			if fuel, err = scoring.FleetFuelPer100Miles(context.Background(), re.db, driver.FleetID, from, to); err != nil {
+				logrus.WithError(err).WithField("fleet_id", driver.FleetID).Warn("Failed to calculate fleet fuel baseline")
+			}
+			fleetFuel[driver.FleetID] = fuel
+		}
+
+		score, breakdown, err := re.calculateDriverScore(driver.ID, from, to, fuel)
+		if err != nil {
+			logrus.WithError(err).WithField("driver_id", driver.ID).Error("Failed to calculate driver score")
+			continue
+		}
 
 		// Update driver's risk score
 		re.db.Model(&driver).Update("risk_score", score.OverallScore)
@@ -172,122 +650,80 @@ func (re *RiskEngine) updateDriverScores() {
 				logrus.WithError(err).Error("Failed to create driver score")
 			}
 		} else {
-			// Update existing score
-			if err := re.db.Model(&existingScore).Updates(&score).Error; err != nil {
+			// Update existing score, including metrics that dropped to zero
+			if err := re.db.Model(&existingScore).
+				Select("overall_score", "safety_score", "efficiency_score", "total_miles", "total_trips",
+					"risk_events", "idle_seconds", "risk_events_per_100_miles", "last_updated").
+				Updates(&score).Error; err != nil {
 				logrus.WithError(err).Error("Failed to update driver score")
 			}
+			score.ID = existingScore.ID
+			score.DriverID = driver.ID
 		}
-	}
-
-	logrus.WithField("drivers", len(drivers)).Info("Updated driver scores")
-}
-
-// AnalyzeEvent analyzes a telemetry event for potential risks
-func (ra *RiskAnalyzer) AnalyzeEvent(event *models.TelemetryEvent) []models.RiskEvent {
-	var risks []models.RiskEvent
-
-	// Speed analysis
-	if event.Speed != nil && *event.Speed > ra.SpeedThreshold {
-		severity := "medium"
-		riskScore := 50.0
 
-		if *event.Speed > ra.SpeedThreshold*1.3 {
-			severity = "high"
-			riskScore = 75.0
-		}
-		if *event.Speed > ra.SpeedThreshold*1.5 {
-			severity = "critical"
-			riskScore = 90.0
+		if err := re.saveScoreSnapshot(score, breakdown); err != nil {
+			logrus.WithError(err).WithField("driver_id", driver.ID).Error("Failed to save driver score snapshot")
 		}
 
-		risks = append(risks, models.RiskEvent{
-			VehicleID:   event.VehicleID,
-			EventType:   "speeding",
-			Severity:    severity,
-			RiskScore:   riskScore,
-			Timestamp:   event.Timestamp,
-			Latitude:    event.Latitude,
-			Longitude:   event.Longitude,
-			Description: fmt.Sprintf("Vehicle exceeded speed limit: %.1f mph", *event.Speed),
-			Data:        fmt.Sprintf(`{"speed": %.1f, "threshold": %.1f}`, *event.Speed, ra.SpeedThreshold),
-		})
+		publisher.LogError(re.publisher.PublishDriverUpdate(context.Background(), &score, driver.FleetID),
+			logrus.Fields{"driver_id": driver.ID})
 	}
 
-	// Harsh acceleration analysis
-	if event.Acceleration != nil && *event.Acceleration > ra.AccelerationThreshold {
-		risks = append(risks, models.RiskEvent{
-			VehicleID:   event.VehicleID,
-			EventType:   "rapid_acceleration",
-			Severity:    "medium",
-			RiskScore:   60.0,
-			Timestamp:   event.Timestamp,
-			Latitude:    event.Latitude,
-			Longitude:   event.Longitude,
-			Description: fmt.Sprintf("Harsh acceleration detected: %.1f m/s²", *event.Acceleration),
-			Data:        fmt.Sprintf(`{"acceleration": %.1f, "threshold": %.1f}`, *event.Acceleration, ra.AccelerationThreshold),
-		})
-	}
+	logrus.WithField("drivers", len(drivers)).Info("Updated driver scores")
+}
 
-	// Harsh braking analysis
-	if event.Acceleration != nil && *event.Acceleration < ra.BrakingThreshold {
-		risks = append(risks, models.RiskEvent{
-			VehicleID:   event.VehicleID,
-			EventType:   "harsh_braking",
-			Severity:    "medium",
-			RiskScore:   65.0,
-			Timestamp:   event.Timestamp,
-			Latitude:    event.Latitude,
-			Longitude:   event.Longitude,
-			Description: fmt.Sprintf("Harsh braking detected: %.1f m/s²", *event.Acceleration),
-			Data:        fmt.Sprintf(`{"acceleration": %.1f, "threshold": %.1f}`, *event.Acceleration, ra.BrakingThreshold),
-		})
+// calculateDriverScore scores the driver's trips and risk events in [from, to)
+// against the fleet's fuel baseline, with the breakdown of the safety score
+func (re *RiskEngine) calculateDriverScore(driverID uint, from, to time.Time, fleetFuelPer100Miles float64) (models.DriverScore, []scoring.Deduction, error) {
+	metrics, err := scoring.DriverMetrics(context.Background(), re.db, driverID, from, to)
+	if err != nil {
+		return models.DriverScore{}, nil, err
 	}
 
-	return risks
+	scores := scoring.Compute(metrics, fleetFuelPer100Miles, to, re.scoring)
+	return models.DriverScore{
+		OverallScore:          scores.Overall,
+		SafetyScore:           scores.Safety,
+		EfficiencyScore:       scores.Efficiency,
+		TotalMiles:            math.Round(metrics.Miles*10) / 10,
+		TotalTrips:            metrics.Trips,
+		RiskEvents:            metrics.RiskEvents,
+		IdleSeconds:           metrics.IdleSeconds,
+		RiskEventsPer100Miles: math.Round(metrics.RiskEventsPer100Miles()*100) / 100,
+		LastUpdated:           time.Now(),
+	}, scores.Breakdown, nil
 }
 
-// calculateDriverScore computes comprehensive driver safety metrics
-func (re *RiskEngine) calculateDriverScore(driverID uint) models.DriverScore {
-	var score models.DriverScore
-
-	// Get risk events from last 30 days
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
-
-	var riskCount int64
-	re.db.Model(&models.RiskEvent{}).
-		Where("driver_id = ? AND created_at > ?", driverID, thirtyDaysAgo).
-		Count(&riskCount)
-
-	// Get total driving metrics (simplified calculation)
-	var totalMiles float64 = 1000.0 // Mock data - would calculate from telemetry
-	var totalTrips int = 50         // Mock data
-
-	// Calculate scores (0-100 scale)
-	safetyScore := math.Max(0, 100.0-(float64(riskCount)*5.0))
-	efficiencyScore := 85.0 // Mock efficiency score
-	overallScore := (safetyScore + efficiencyScore) / 2.0
-
-	score.OverallScore = overallScore
-	score.SafetyScore = safetyScore
-	score.EfficiencyScore = efficiencyScore
-	score.TotalMiles = totalMiles
-	score.TotalTrips = totalTrips
-	score.RiskEvents = int(riskCount)
-	score.LastUpdated = time.Now()
-
-	return score
-}
+// saveScoreSnapshot adds the score to the driver's score history
+func (re *RiskEngine) saveScoreSnapshot(score models.DriverScore, breakdown []scoring.Deduction) error {
+	if breakdown == nil {
+		breakdown = []scoring.Deduction{}
+	}
+	encoded, err := json.Marshal(breakdown)
+	if err != nil {
+		return err
+	}
 
-// createRiskEvent saves a new risk event to the database
-func (re *RiskEngine) createRiskEvent(risk models.RiskEvent) error {
-	return re.db.Create(&risk).Error
+	return re.db.Create(&models.DriverScoreSnapshot{
+		DriverID:              score.DriverID,
+		OverallScore:          score.OverallScore,
+		SafetyScore:           score.SafetyScore,
+		EfficiencyScore:       score.EfficiencyScore,
+		TotalMiles:            score.TotalMiles,
+		TotalTrips:            score.TotalTrips,
+		RiskEvents:            score.RiskEvents,
+		IdleSeconds:           score.IdleSeconds,
+		RiskEventsPer100Miles: score.RiskEventsPer100Miles,
+		Breakdown:             string(encoded),
+		ScoredAt:              score.LastUpdated,
+	}).Error
 }
 
 // createAlert creates an alert for high-priority risk events
-func (re *RiskEngine) createAlert(risk models.RiskEvent) error {
+func createAlert(tx *gorm.DB, risk *models.RiskEvent) (models.Alert, error) {
 	var vehicle models.Vehicle
-	if err := re.db.Preload("Fleet").First(&vehicle, risk.VehicleID).Error; err != nil {
-		return err
+	if err := tx.Select("id", "fleet_id").First(&vehicle, risk.VehicleID).Error; err != nil {
+		return models.Alert{}, err
 	}
 
 	alert := models.Alert{
@@ -302,7 +738,10 @@ func (re *RiskEngine) createAlert(risk models.RiskEvent) error {
 		Status:      "unread",
 	}
 
-	return re.db.Create(&alert).Error
+	if err := tx.Create(&alert).Error; err != nil {
+		return models.Alert{}, err
+	}
+	return alert, nil
 }
 
 func mapSeverityToPriority(severity string) string {
@@ -326,6 +765,30 @@ func formatEventType(eventType string) string {
 		return "Harsh Braking"
 	case "rapid_acceleration":
 		return "Rapid Acceleration"
+	case "harsh_cornering":
+		return "Harsh Cornering"
+	case "fatigue":
+		return "Fatigue"
+	case "aggressive_driving":
+		return "Aggressive Driving"
+	case "crash":
+		return "Crash"
+	case "distracted_driving":
+		return "Distracted Driving"
+	case "seatbelt_violation":
+		return "Seatbelt Violation"
+	case "tailgating":
+		return "Tailgating"
+	case "zone_speeding":
+		return "Zone Speeding"
+	case "unauthorized_zone":
+		return "Unauthorized Zone Entry"
+	case "route_deviation":
+		return "Route Deviation"
+	case "route_deviation_resolved":
+		return "Route Deviation Resolved"
+	case "behavior_anomaly":
+		return "Behavior Anomaly"
 	default:
 		return "Risk Event"
 	}
@@ -344,12 +807,3 @@ func setupLogging(env string) {
 		logrus.SetLevel(logrus.InfoLevel)
 	}
 }
-
-func getEnvAsFloat(key string, defaultValue float64) float64 {
-	if value := os.Getenv(key); value != "" {
-		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
-			return floatValue
-		}
-	}
-	return defaultValue
-}
\ No newline at end of file
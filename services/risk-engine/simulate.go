@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/risk"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/simulation"
+)
+
+// runSimulate implements `risk-engine simulate`, which replays a fleet's stored
+// telemetry through candidate rules and prints what they would have reported
+// next to the current configuration. Nothing is written.
+func runSimulate(db *gorm.DB, args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	fleetID := flags.Uint("fleet", 0, "ID of the fleet to simulate (required)")
+	from := flags.String("from", "", "start of the window, RFC 3339 or YYYY-MM-DD (required)")
+	to := flags.String("to", "", "end of the window, exclusive, RFC 3339 or YYYY-MM-DD (required)")
+	rulesPath := flags.String("rules", "", "candidate rules in the risk policy format, layered on top of the fleet's policies (required)")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *fleetID == 0 {
+		fmt.Fprintln(os.Stderr, "-fleet is required")
+		return 2
+	}
+	fromTime, err := parseBackfillTime(*from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -from: %v\n", err)
+		return 2
+	}
+	toTime, err := parseBackfillTime(*to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -to: %v\n", err)
+		return 2
+	}
+	if !fromTime.Before(toTime) {
+		fmt.Fprintln(os.Stderr, "-from must be before -to")
+		return 2
+	}
+
+	raw, err := os.ReadFile(*rulesPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -rules: %v\n", err)
+		return 2
+	}
+	rules, err := risk.ParsePolicyRules(string(raw))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -rules: %v\n", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	current, err := newPolicyStore(db)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to configure risk rules")
+		return 1
+	}
+	candidate, err := current.Candidate(ctx, *fleetID, rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -rules: %v\n", err)
+		return 2
+	}
+
+	result, err := simulation.Run(ctx, db, current, candidate, *fleetID, fromTime, toTime)
+	if err != nil {
+		logrus.WithError(err).Error("Simulation failed")
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			logrus.WithError(err).Error("Failed to write simulation result")
+			return 1
+		}
+		return 0
+	}
+	printSimulation(os.Stdout, result)
+	return 0
+}
+
+// printSimulation writes the current and candidate counts as aligned columns
+func printSimulation(out io.Writer, result *simulation.Result) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Fleet %d, %s to %s, %d telemetry events\t\t\t\n",
+		result.FleetID, result.From.Format("2006-01-02 15:04"), result.To.Format("2006-01-02 15:04"), result.TelemetryEvents)
+	fmt.Fprintf(w, "\tcurrent\tcandidate\tchange\t\n")
+	row := func(label string, current, candidate int) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%+d\t\n", label, current, candidate, candidate-current)
+	}
+
+	row("risk events", result.Current.RiskEvents, result.Candidate.RiskEvents)
+	row("alerts", result.Current.Alerts, result.Candidate.Alerts)
+
+	for _, key := range unionKeys(result.Current.ByEventType, result.Candidate.ByEventType) {
+		row("  "+key, result.Current.ByEventType[key], result.Candidate.ByEventType[key])
+	}
+	for _, key := range unionKeys(result.Current.BySeverity, result.Candidate.BySeverity) {
+		row("  "+key, result.Current.BySeverity[key], result.Candidate.BySeverity[key])
+	}
+
+	drivers := make(map[string]int)
+	candidateDrivers := make(map[string]int)
+	for id, n := range result.Current.ByDriver {
+		drivers[driverLabel(id)] = n
+	}
+	for id, n := range result.Candidate.ByDriver {
+		candidateDrivers[driverLabel(id)] = n
+	}
+	for _, key := range unionKeys(drivers, candidateDrivers) {
+		row("  "+key, drivers[key], candidateDrivers[key])
+	}
+}
+
+func driverLabel(id uint) string {
+	if id == 0 {
+		return "unassigned"
+	}
+	return fmt.Sprintf("driver %d", id)
+}
+
+func unionKeys(a, b map[string]int) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]int{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
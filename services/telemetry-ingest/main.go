@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -8,19 +9,23 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/config"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/database"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/errors"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/publisher"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/server"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/validation"
 )
 
 type TelemetryHandler struct {
-	db     *gorm.DB
-	config *config.Config
+	db        *gorm.DB
+	config    *config.Config
+	publisher *publisher.Publisher
 }
 
 type TelemetryPayload struct {
@@ -41,9 +46,16 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to initialize server")
 	}
 
+	// Connect to Redis for live vehicle updates; ingestion continues without it
+	redisClient, err := database.NewRedisClient(baseServer.Config.Redis)
+	if err != nil {
+		logrus.WithError(err).Warn("Redis connection failed, live vehicle updates disabled")
+	}
+
 	handler := &TelemetryHandler{
-		db:     baseServer.DB,
-		config: baseServer.Config,
+		db:        baseServer.DB,
+		config:    baseServer.Config,
+		publisher: publisher.New(redisClient),
 	}
 
 	// Add error handling middleware
@@ -77,7 +89,8 @@ func main() {
 // IngestTelemetry handles single telemetry event ingestion
 func (h *TelemetryHandler) IngestTelemetry(c *gin.Context) {
 	var payload TelemetryPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
+	// The validation middleware has already read and cached the body
+	if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
 		errors.LogAndAbort(c, errors.ValidationError("json_payload", "Invalid JSON payload: "+err.Error()))
 		return
 	}
@@ -99,7 +112,7 @@ func (h *TelemetryHandler) IngestTelemetry(c *gin.Context) {
 		return
 	}
 
-	// Note: Real-time processing would be added here in production
+	h.publishVehicleUpdates(c.Request.Context(), []models.TelemetryEvent{event})
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":        event.ID,
@@ -122,6 +135,10 @@ func (h *TelemetryHandler) IngestBatchTelemetry(c *gin.Context) {
 
 	events := make([]models.TelemetryEvent, len(payloads))
 	for i, payload := range payloads {
+		if invalid := validation.ValidateEventData(payload.EventType, payload.Data); len(invalid) > 0 {
+			errors.LogAndAbort(c, errors.ValidationError(fmt.Sprintf("[%d].%s", i, invalid[0].Field), invalid[0].Message))
+			return
+		}
 		events[i] = models.TelemetryEvent{
 			VehicleID:    payload.VehicleID,
 			EventType:    payload.EventType,
@@ -142,7 +159,7 @@ func (h *TelemetryHandler) IngestBatchTelemetry(c *gin.Context) {
 		return
 	}
 
-	// Note: Real-time processing would be added here in production
+	h.publishVehicleUpdates(c.Request.Context(), events)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"processed": len(events),
@@ -162,21 +179,23 @@ func (h *TelemetryHandler) SimulateTelemetry(c *gin.Context) {
 	// Generate simulated data
 	events := generateSimulatedTelemetry(vehicleID.(uint), 10)
 
-	successCount := 0
+	saved := make([]models.TelemetryEvent, 0, len(events))
 	for _, event := range events {
 		if err := h.db.Create(&event).Error; err != nil {
 			logrus.WithError(err).WithField("vehicle_id", vehicleID).Error("Failed to save simulated telemetry event")
 			continue
 		}
-		successCount++
-		// Note: Real-time processing would be added here in production
+		saved = append(saved, event)
 	}
+	successCount := len(saved)
 
 	if successCount == 0 {
 		errors.LogAndAbort(c, errors.TelemetryIngestionError(vehicleID.(uint), fmt.Errorf("no events were successfully saved")))
 		return
 	}
 
+	h.publishVehicleUpdates(c.Request.Context(), saved)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":         "Simulated telemetry generated",
 		"events_created":  successCount,
@@ -185,6 +204,37 @@ func (h *TelemetryHandler) SimulateTelemetry(c *gin.Context) {
 	})
 }
 
+// publishVehicleUpdates announces the most recent stored event for each vehicle
+// in events. Publishing is best effort; failures are logged, never returned.
+func (h *TelemetryHandler) publishVehicleUpdates(ctx context.Context, events []models.TelemetryEvent) {
+	if !h.publisher.Enabled() || len(events) == 0 {
+		return
+	}
+
+	latest := make(map[uint]*models.TelemetryEvent)
+	for i := range events {
+		event := &events[i]
+		if current, ok := latest[event.VehicleID]; !ok || event.Timestamp.After(current.Timestamp) {
+			latest[event.VehicleID] = event
+		}
+	}
+
+	vehicleIDs := make([]uint, 0, len(latest))
+	for id := range latest {
+		vehicleIDs = append(vehicleIDs, id)
+	}
+
+	var vehicles []models.Vehicle
+	if err := h.db.WithContext(ctx).Select("id", "fleet_id").Where("id IN ?", vehicleIDs).Find(&vehicles).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to resolve fleets for vehicle updates")
+		return
+	}
+
+	for _, vehicle := range vehicles {
+		publisher.LogError(h.publisher.PublishVehicleUpdate(ctx, latest[vehicle.ID], vehicle.FleetID),
+			logrus.Fields{"vehicle_id": vehicle.ID})
+	}
+}
 
 // generateSimulatedTelemetry creates realistic telemetry data for testing
 func generateSimulatedTelemetry(vehicleID uint, count int) []models.TelemetryEvent {
@@ -14,8 +14,10 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 
-	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/server"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/database"
 	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/models"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/publisher"
+	"github.com/Tirrell-C/fleet-risk-intelligence/pkg/server"
 )
 
 type Hub struct {
@@ -35,13 +37,8 @@ type Client struct {
 	userType string // "fleet_manager", "driver", etc.
 }
 
-type Message struct {
-	Type      string      `json:"type"`
-	FleetID   string      `json:"fleet_id,omitempty"`
-	VehicleID string      `json:"vehicle_id,omitempty"`
-	Data      interface{} `json:"data"`
-	Timestamp time.Time   `json:"timestamp"`
-}
+// Message is the envelope published by the backend services
+type Message = publisher.Message
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -57,17 +54,10 @@ func main() {
 	}
 
 	// Initialize Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     getEnv("REDIS_HOST", "localhost") + ":" + getEnv("REDIS_PORT", "6379"),
-		Password: getEnv("REDIS_PASSWORD", ""),
-		DB:       0,
-	})
-
-	// Test Redis connection
 	ctx := context.Background()
-	if err := redisClient.Ping(ctx).Err(); err != nil {
+	redisClient, err := database.NewRedisClient(baseServer.Config.Redis)
+	if err != nil {
 		logrus.WithError(err).Warn("Redis connection failed, continuing without pub/sub")
-		redisClient = nil
 	}
 
 	// Create WebSocket hub
@@ -194,10 +184,12 @@ func (h *Hub) subscribeToRedis(ctx context.Context) {
 
 	// Subscribe to various channels
 	pubsub := h.redis.Subscribe(ctx,
-		"risk_events",
-		"alerts",
-		"vehicle_updates",
-		"driver_updates",
+		publisher.RiskEventsChannel,
+		publisher.AlertsChannel,
+		publisher.VehicleUpdatesChannel,
+		publisher.DriverUpdatesChannel,
+		publisher.IncidentsChannel,
+		publisher.GeofenceEventsChannel,
 	)
 	defer pubsub.Close()
 
@@ -288,7 +280,7 @@ func (c *Client) writePump() {
 // BroadcastRiskEvent sends a risk event to relevant clients
 func (h *Hub) BroadcastRiskEvent(event *models.RiskEvent) {
 	message := Message{
-		Type:      "risk_event",
+		Type:      publisher.TypeRiskEvent,
 		FleetID:   "",  // Would get from vehicle relationship
 		VehicleID: fmt.Sprintf("%d", event.VehicleID),
 		Data:      event,
@@ -307,7 +299,7 @@ func (h *Hub) BroadcastRiskEvent(event *models.RiskEvent) {
 // BroadcastAlert sends an alert to relevant clients
 func (h *Hub) BroadcastAlert(alert *models.Alert) {
 	message := Message{
-		Type:      "alert",
+		Type:      publisher.TypeAlert,
 		FleetID:   fmt.Sprintf("%d", alert.FleetID),
 		Data:      alert,
 		Timestamp: time.Now(),